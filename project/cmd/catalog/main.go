@@ -0,0 +1,56 @@
+// Command catalog writes the AsyncAPI document, the Markdown table and the Graphviz graph
+// of the topics of the service, with their messages, producers and consumers.
+//
+//	go run ./cmd/catalog [-dir docs/catalog]
+//
+// The catalog is generated from the router registrations, see catalog.Generate.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"tickets/internal/app"
+	"tickets/internal/interfaces/message/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	dir := flag.String("dir", "docs/catalog", "directory of the catalog files")
+	flag.Parse()
+
+	// the router logs every recorded message
+	logrus.SetLevel(logrus.WarnLevel)
+
+	if err := generate(*dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(dir string) error {
+	c, err := catalog.Generate(context.Background(), app.EventPartitioning)
+	if err != nil {
+		return err
+	}
+
+	files, err := catalog.Render(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
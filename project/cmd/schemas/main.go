@@ -0,0 +1,215 @@
+// Command schemas generates the JSON Schemas and the protobuf definitions of events and commands
+// and checks them for breaking changes.
+//
+//	go run ./cmd/schemas generate [-dir internal/schema] [-allow-breaking]
+//	go run ./cmd/schemas check [-dir internal/schema] [-against <schemas of the previous version>]
+//
+// check fails when the checked-in schemas are out of date with the entities structs,
+// or when they break compatibility with the schemas from -against (by default the checked-in ones).
+// Changed or reused protobuf field numbers always fail, -allow-breaking doesn't write them either.
+// In CI, -against is the schema directory of the base branch, e.g.:
+//
+//	git archive origin/main internal/schema | tar -x -C /tmp/base
+//	go run ./cmd/schemas check -against /tmp/base/internal/schema
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"tickets/internal/protobuf"
+	"tickets/internal/schema"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "generate":
+		err = generate(os.Args[2:])
+	case "check":
+		err = check(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: schemas generate|check [flags]")
+	os.Exit(2)
+}
+
+func generate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	dir := flags.String("dir", "internal/schema", "directory with events/, commands/ and proto/ schemas")
+	allowBreaking := flags.Bool("allow-breaking", false, "write the schemas even if they break compatibility")
+	_ = flags.Parse(args)
+
+	current, currentProto, err := generateAll()
+	if err != nil {
+		return err
+	}
+
+	numbers, conflicts, err := recordFieldNumbers(os.DirFS(*dir), currentProto)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		printChanges(conflicts)
+		return fmt.Errorf("schemas not written: field numbers must never change or be reused, add a new version of the message instead")
+	}
+
+	changes, err := compare(os.DirFS(*dir), current, currentProto)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		printChanges(changes)
+		if !*allowBreaking {
+			return fmt.Errorf("schemas not written: add a new version of the message instead, or use -allow-breaking")
+		}
+	}
+
+	if err := schema.Write(*dir, current); err != nil {
+		return err
+	}
+
+	if err := schema.WriteProto(*dir, currentProto); err != nil {
+		return err
+	}
+
+	return schema.WriteFieldNumbers(*dir, numbers)
+}
+
+func check(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	dir := flags.String("dir", "internal/schema", "directory with events/, commands/ and proto/ schemas")
+	against := flags.String("against", "", "directory with the schemas of the previous version, -dir by default")
+	_ = flags.Parse(args)
+
+	if *against == "" {
+		*against = *dir
+	}
+
+	current, currentProto, err := generateAll()
+	if err != nil {
+		return err
+	}
+
+	changes, err := compare(os.DirFS(*against), current, currentProto)
+	if err != nil {
+		return err
+	}
+
+	_, conflicts, err := recordFieldNumbers(os.DirFS(*against), currentProto)
+	if err != nil {
+		return err
+	}
+	changes = append(changes, conflicts...)
+
+	if len(changes) > 0 {
+		printChanges(changes)
+		return fmt.Errorf("%d breaking schema changes", len(changes))
+	}
+
+	recorded, err := schema.Read(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+
+	outdated, err := schema.Diff(recorded, current)
+	if err != nil {
+		return err
+	}
+
+	recordedProto, err := schema.ReadProto(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(recordedProto, currentProto) {
+		outdated = append(outdated, schema.ProtoFile)
+	}
+
+	recordedNumbers, err := schema.ReadFieldNumbers(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+	currentNumbers, _, err := schema.RecordFieldNumbers(recordedNumbers, currentProto)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(recordedNumbers, currentNumbers) {
+		outdated = append(outdated, schema.FieldNumbersFile)
+	}
+
+	if len(outdated) > 0 {
+		for _, p := range outdated {
+			fmt.Fprintln(os.Stderr, "outdated:", p)
+		}
+		return fmt.Errorf("schemas are out of date, run go generate ./internal/schema")
+	}
+
+	return nil
+}
+
+func generateAll() (map[string]*schema.Schema, []byte, error) {
+	current, err := schema.GenerateAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currentProto, err := schema.GenerateProto()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return current, currentProto, nil
+}
+
+// compare returns the breaking changes of the schemas in fsys.
+func compare(fsys fs.FS, current map[string]*schema.Schema, currentProto []byte) ([]string, error) {
+	previous, err := schema.Read(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	previousProto, err := schema.ReadProto(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	protoChanges, err := schema.CompareProto(previousProto, currentProto)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(schema.Compare(previous, current), protoChanges...), nil
+}
+
+// recordFieldNumbers records the field numbers of currentProto in the field numbers from fsys.
+func recordFieldNumbers(fsys fs.FS, currentProto []byte) (protobuf.FieldNumbers, []string, error) {
+	recorded, err := schema.ReadFieldNumbers(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return schema.RecordFieldNumbers(recorded, currentProto)
+}
+
+func printChanges(changes []string) {
+	fmt.Fprintln(os.Stderr, "breaking changes:")
+	for _, change := range changes {
+		fmt.Fprintln(os.Stderr, "  "+change)
+	}
+}
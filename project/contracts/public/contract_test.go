@@ -0,0 +1,253 @@
+package public
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// run `go test ./contracts/public -update` after adding fields or events,
+// incompatible changes are never recorded
+var update = flag.Bool("update", false, "record compatible contract changes")
+
+const optional = ",optional"
+
+func TestContracts_Compatible(t *testing.T) {
+	names := map[string]struct{}{}
+
+	for _, event := range Events {
+		name := reflect.TypeOf(event).Name()
+		names[name] = struct{}{}
+
+		t.Run(name, func(t *testing.T) {
+			current := describe(reflect.TypeOf(event))
+			contractFile := filepath.Join("testdata", name+".json")
+
+			recorded, err := readContract(contractFile)
+			if errors.Is(err, fs.ErrNotExist) && *update {
+				require.NoError(t, writeContract(contractFile, current))
+				return
+			}
+			require.NoError(t, err, "the contract of a new event is recorded with -update")
+
+			breakingChanges := incompatibleChanges(recorded, current)
+			require.Empty(
+				t,
+				breakingChanges,
+				"%s changed incompatibly, publish a new version of the event instead", name,
+			)
+
+			if *update {
+				require.NoError(t, writeContract(contractFile, current))
+				return
+			}
+			assert.Equal(t, recorded, current, "%s has new fields, record them with -update", name)
+		})
+	}
+
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	require.NoError(t, err)
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		_, ok := names[name]
+		assert.True(t, ok, "public event %s was removed, consumers may still use it", name)
+	}
+}
+
+func TestIncompatibleChanges(t *testing.T) {
+	recorded := map[string]string{
+		"ticket_id":       "string",
+		"price":           "object",
+		"price.amount":    "string",
+		"refunded_amount": "object" + optional,
+	}
+
+	testCases := []struct {
+		name     string
+		current  map[string]string
+		expected []string
+	}{
+		{
+			name: "field added",
+			current: map[string]string{
+				"ticket_id":       "string",
+				"price":           "object",
+				"price.amount":    "string",
+				"refunded_amount": "object" + optional,
+				"show_id":         "string",
+			},
+		},
+		{
+			name: "optional field made required",
+			current: map[string]string{
+				"ticket_id":       "string",
+				"price":           "object",
+				"price.amount":    "string",
+				"refunded_amount": "object",
+			},
+		},
+		{
+			name: "field removed",
+			current: map[string]string{
+				"ticket_id":       "string",
+				"price":           "object",
+				"refunded_amount": "object" + optional,
+			},
+			expected: []string{"price.amount was removed"},
+		},
+		{
+			name: "field type changed",
+			current: map[string]string{
+				"ticket_id":       "integer",
+				"price":           "object",
+				"price.amount":    "string",
+				"refunded_amount": "object" + optional,
+			},
+			expected: []string{"ticket_id changed from string to integer"},
+		},
+		{
+			name: "required field made optional",
+			current: map[string]string{
+				"ticket_id":       "string" + optional,
+				"price":           "object",
+				"price.amount":    "string",
+				"refunded_amount": "object" + optional,
+			},
+			expected: []string{"ticket_id changed from string to string,optional"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, incompatibleChanges(recorded, tc.current))
+		})
+	}
+}
+
+// describe returns the JSON type of each field of the event, nested fields are separated with dots.
+func describe(t reflect.Type) map[string]string {
+	fields := map[string]string{}
+	describeFields(t, "", fields)
+	return fields
+}
+
+func describeFields(t reflect.Type, prefix string, fields map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		path := prefix + name
+
+		fieldType := field.Type
+		isOptional := strings.Contains(options, "omitempty")
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+			isOptional = true
+		}
+
+		jsonType := jsonTypeOf(fieldType)
+		if isOptional {
+			jsonType += optional
+		}
+		fields[path] = jsonType
+
+		if fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}) {
+			describeFields(fieldType, path+".", fields)
+		}
+	}
+}
+
+func jsonTypeOf(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string(date-time)"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array<" + jsonTypeOf(t.Elem()) + ">"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+
+	panic(fmt.Sprintf("unsupported type %s in a public event", t))
+}
+
+// incompatibleChanges returns the changes which break consumers of the recorded contract:
+// removed fields, changed types and required fields made optional.
+func incompatibleChanges(recorded, current map[string]string) []string {
+	var changes []string
+
+	for path, recordedType := range recorded {
+		currentType, ok := current[path]
+		if !ok {
+			changes = append(changes, path+" was removed")
+			continue
+		}
+
+		// consumers of an optional field handle it when it's always set
+		if currentType == recordedType || currentType+optional == recordedType {
+			continue
+		}
+
+		changes = append(changes, fmt.Sprintf("%s changed from %s to %s", path, recordedType, currentType))
+	}
+
+	sort.Strings(changes)
+	return changes
+}
+
+func readContract(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var contract map[string]string
+	if err := json.Unmarshal(content, &contract); err != nil {
+		return nil, fmt.Errorf("invalid contract %s: %w", file, err)
+	}
+
+	return contract, nil
+}
+
+func writeContract(file string, contract map[string]string) error {
+	content, err := json.MarshalIndent(contract, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, append(content, '\n'), 0o644)
+}
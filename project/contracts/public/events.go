@@ -0,0 +1,129 @@
+// Package public is the contract of the events published for other teams and partners.
+//
+// The events are published to the public.<event name> topics and sent by webhooks.
+// They don't depend on the internal events, so internal refactors don't break consumers:
+// fields can be added, but never renamed, removed or changed to another type.
+// Incompatible changes need a new version of the event (e.g. BookingMade_v2) published next to the old one.
+package public
+
+import (
+	"time"
+)
+
+// TopicPrefix is the namespace of the public topics.
+const TopicPrefix = "public."
+
+// Topic returns the topic of the public event.
+func Topic(eventName string) string {
+	return TopicPrefix + eventName
+}
+
+// Events are all public events, the event name is the struct name.
+var Events = []any{
+	BookingMade_v1{},
+	BookingCancelled_v1{},
+	TicketBookingConfirmed_v1{},
+	TicketBookingCanceled_v1{},
+	TicketReceiptIssued_v1{},
+	TicketPrinted_v1{},
+	TicketRefunded_v1{},
+	TicketCheckedIn_v1{},
+	TicketTransferred_v1{},
+	ShowCancelled_v1{},
+}
+
+type Header struct {
+	// EventID is the same for redelivered events, consumers should use it to deduplicate them.
+	EventID     string    `json:"event_id"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Money amount is a decimal string with the number of decimal places of the ISO-4217 currency, e.g. "12.50" for USD.
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type BookingMade_v1 struct {
+	Header          Header    `json:"header"`
+	BookingID       string    `json:"booking_id"`
+	ShowID          string    `json:"show_id"`
+	NumberOfTickets int       `json:"number_of_tickets"`
+	CustomerEmail   string    `json:"customer_email"`
+	BookedAt        time.Time `json:"booked_at"`
+}
+
+type BookingCancelled_v1 struct {
+	Header          Header    `json:"header"`
+	BookingID       string    `json:"booking_id"`
+	ShowID          string    `json:"show_id"`
+	NumberOfTickets int       `json:"number_of_tickets"`
+	CustomerEmail   string    `json:"customer_email"`
+	Reason          string    `json:"reason"`
+	CancelledAt     time.Time `json:"cancelled_at"`
+}
+
+type TicketBookingConfirmed_v1 struct {
+	Header        Header `json:"header"`
+	TicketID      string `json:"ticket_id"`
+	BookingID     string `json:"booking_id"`
+	CustomerEmail string `json:"customer_email"`
+	Price         Money  `json:"price"`
+}
+
+type TicketBookingCanceled_v1 struct {
+	Header        Header `json:"header"`
+	TicketID      string `json:"ticket_id"`
+	BookingID     string `json:"booking_id"`
+	CustomerEmail string `json:"customer_email"`
+	Price         Money  `json:"price"`
+}
+
+type TicketReceiptIssued_v1 struct {
+	Header        Header    `json:"header"`
+	TicketID      string    `json:"ticket_id"`
+	BookingID     string    `json:"booking_id"`
+	ReceiptNumber string    `json:"receipt_number"`
+	IssuedAt      time.Time `json:"issued_at"`
+}
+
+type TicketPrinted_v1 struct {
+	Header    Header    `json:"header"`
+	TicketID  string    `json:"ticket_id"`
+	BookingID string    `json:"booking_id"`
+	FileName  string    `json:"file_name"`
+	PrintedAt time.Time `json:"printed_at"`
+}
+
+type TicketRefunded_v1 struct {
+	Header   Header `json:"header"`
+	TicketID string `json:"ticket_id"`
+	// RefundedAmount is empty when the ticket was refunded in full.
+	RefundedAmount *Money `json:"refunded_amount,omitempty"`
+}
+
+type TicketCheckedIn_v1 struct {
+	Header      Header    `json:"header"`
+	TicketID    string    `json:"ticket_id"`
+	BookingID   string    `json:"booking_id"`
+	ShowID      string    `json:"show_id"`
+	CheckedInAt time.Time `json:"checked_in_at"`
+}
+
+type TicketTransferred_v1 struct {
+	Header        Header    `json:"header"`
+	TransferID    string    `json:"transfer_id"`
+	TicketID      string    `json:"ticket_id"`
+	BookingID     string    `json:"booking_id"`
+	ShowID        string    `json:"show_id"`
+	FromEmail     string    `json:"from_email"`
+	ToEmail       string    `json:"to_email"`
+	TransferredAt time.Time `json:"transferred_at"`
+}
+
+type ShowCancelled_v1 struct {
+	Header      Header    `json:"header"`
+	ShowID      string    `json:"show_id"`
+	Reason      string    `json:"reason"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
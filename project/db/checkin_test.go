@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"tickets/internal/application/usecases/checkin"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"tickets/internal/tickettoken"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCheckInUsecase(t *testing.T) (*checkin.CheckInUsecase, *tickettoken.Signer) {
+	signer, err := tickettoken.NewSigner("db-tests-secret")
+	require.NoError(t, err)
+
+	trManager := manager.Must(trmsqlx.NewDefaultFactory(getDb()))
+
+	return checkin.NewCheckInUsecase(
+		signer,
+		repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, trManager),
+		trManager,
+		newTestOutboxBus(),
+	), signer
+}
+
+func TestCheckIn(t *testing.T) {
+	setupTestDB(t)
+	setupOutbox(t)
+
+	ctx := context.Background()
+	checkIn, signer := newTestCheckInUsecase(t)
+	ticketsRepo := repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, manager.Must(trmsqlx.NewDefaultFactory(getDb())))
+
+	showID := newTestShow(t, 10, time.Now().Add(time.Hour))
+	bookingID := uuid.New()
+
+	t.Run("valid ticket", func(t *testing.T) {
+		ticketID := newTestTicket(t, bookingID, showID, entities.TicketStatusPrinted)
+
+		event, err := checkIn.CheckIn(ctx, signer.Sign(ticketID, 0))
+		require.NoError(t, err)
+		assert.Equal(t, ticketID, event.TicketID)
+		assert.Equal(t, showID.String(), event.ShowID)
+
+		ticket, err := ticketsRepo.Get(ctx, uuid.MustParse(ticketID))
+		require.NoError(t, err)
+		assert.Equal(t, string(entities.TicketStatusCheckedIn), ticket.Status)
+	})
+
+	t.Run("double scan", func(t *testing.T) {
+		ticketID := newTestTicket(t, bookingID, showID, entities.TicketStatusPrinted)
+		token := signer.Sign(ticketID, 0)
+
+		_, err := checkIn.CheckIn(ctx, token)
+		require.NoError(t, err)
+
+		_, err = checkIn.CheckIn(ctx, token)
+		assert.ErrorIs(t, err, entities.ErrTicketAlreadyCheckedIn)
+	})
+
+	t.Run("scanned at two gates at once", func(t *testing.T) {
+		ticketID := newTestTicket(t, bookingID, showID, entities.TicketStatusPrinted)
+		token := signer.Sign(ticketID, 0)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 5)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = checkIn.CheckIn(ctx, token)
+			}()
+		}
+		wg.Wait()
+
+		checkedIn := 0
+		for _, err := range errs {
+			if err == nil {
+				checkedIn++
+				continue
+			}
+			assert.ErrorIs(t, err, entities.ErrTicketAlreadyCheckedIn)
+		}
+		assert.Equal(t, 1, checkedIn)
+	})
+
+	t.Run("refunded ticket", func(t *testing.T) {
+		ticketID := newTestTicket(t, bookingID, showID, entities.TicketStatusRefunded)
+
+		_, err := checkIn.CheckIn(ctx, signer.Sign(ticketID, 0))
+		assert.ErrorIs(t, err, entities.ErrTicketNotValid)
+
+		ticket, err := ticketsRepo.Get(ctx, uuid.MustParse(ticketID))
+		require.NoError(t, err)
+		assert.Equal(t, string(entities.TicketStatusRefunded), ticket.Status)
+	})
+
+	t.Run("unknown ticket", func(t *testing.T) {
+		_, err := checkIn.CheckIn(ctx, signer.Sign(uuid.NewString(), 0))
+		assert.ErrorIs(t, err, repository.ErrTicketNotFound)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		ticketID := newTestTicket(t, bookingID, showID, entities.TicketStatusPrinted)
+		otherSigner, err := tickettoken.NewSigner("other-secret")
+		require.NoError(t, err)
+
+		_, err = checkIn.CheckIn(ctx, otherSigner.Sign(ticketID, 0))
+		assert.ErrorIs(t, err, tickettoken.ErrInvalidToken)
+	})
+}
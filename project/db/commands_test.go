@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"tickets/internal/entities"
+	"tickets/internal/interfaces/message/commands"
+	"tickets/internal/repository"
+	"tickets/internal/schema"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandsRepo(t *testing.T) {
+	setupTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.NewCommandsRepo(getDb(), trmsqlx.DefaultCtxGetter)
+
+	t.Run("succeeded after a failed delivery", func(t *testing.T) {
+		commandID := uuid.New()
+
+		require.NoError(t, repo.Accept(ctx, commandID, "RefundTicket"))
+		command, err := repo.Get(ctx, commandID)
+		require.NoError(t, err)
+		assert.Equal(t, "RefundTicket", command.CommandName)
+		assert.Equal(t, entities.CommandStatusAccepted, command.Status)
+		assert.Equal(t, 0, command.Attempts)
+
+		require.NoError(t, repo.MarkProcessing(ctx, commandID))
+		require.NoError(t, repo.MarkFailed(ctx, commandID, "payment provider unavailable"))
+
+		command, err = repo.Get(ctx, commandID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.CommandStatusFailed, command.Status)
+		assert.Equal(t, "payment provider unavailable", command.FailureReason)
+		assert.Equal(t, 1, command.Attempts)
+
+		require.NoError(t, repo.MarkProcessing(ctx, commandID))
+		require.NoError(t, repo.MarkSucceeded(ctx, commandID))
+
+		command, err = repo.Get(ctx, commandID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.CommandStatusSucceeded, command.Status)
+		assert.Empty(t, command.FailureReason)
+		assert.Equal(t, 2, command.Attempts)
+		assert.False(t, command.UpdatedAt.Before(command.AcceptedAt))
+	})
+
+	t.Run("re-delivery of a succeeded command", func(t *testing.T) {
+		commandID := uuid.New()
+
+		require.NoError(t, repo.Accept(ctx, commandID, "RefundTicket"))
+		require.NoError(t, repo.MarkProcessing(ctx, commandID))
+		require.NoError(t, repo.MarkSucceeded(ctx, commandID))
+
+		require.NoError(t, repo.MarkProcessing(ctx, commandID))
+		require.NoError(t, repo.MarkFailed(ctx, commandID, "duplicate"))
+
+		command, err := repo.Get(ctx, commandID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.CommandStatusSucceeded, command.Status)
+		assert.Empty(t, command.FailureReason)
+		assert.Equal(t, 1, command.Attempts)
+	})
+
+	t.Run("accepted twice", func(t *testing.T) {
+		commandID := uuid.New()
+
+		require.NoError(t, repo.Accept(ctx, commandID, "RefundTicket"))
+		require.NoError(t, repo.MarkProcessing(ctx, commandID))
+		require.NoError(t, repo.Accept(ctx, commandID, "RefundTicket"))
+
+		command, err := repo.Get(ctx, commandID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.CommandStatusProcessing, command.Status)
+	})
+
+	t.Run("not tracked command", func(t *testing.T) {
+		commandID := uuid.New()
+
+		require.NoError(t, repo.MarkProcessing(ctx, commandID))
+		require.NoError(t, repo.MarkSucceeded(ctx, commandID))
+
+		_, err := repo.Get(ctx, commandID)
+		assert.ErrorIs(t, err, repository.ErrCommandNotFound)
+	})
+}
+
+// publisherStub records the messages published directly, without the outbox.
+type publisherStub struct {
+	mu        sync.Mutex
+	published []*message.Message
+}
+
+func (p *publisherStub) Publish(_ string, messages ...*message.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, messages...)
+	return nil
+}
+
+func (p *publisherStub) Close() error {
+	return nil
+}
+
+func (p *publisherStub) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.published)
+}
+
+func TestCommandBus_Outbox(t *testing.T) {
+	setupTestDB(t)
+	setupOutbox(t)
+
+	ctx := context.Background()
+	trManager := manager.Must(trmsqlx.NewDefaultFactory(getDb()))
+	repo := repository.NewCommandsRepo(getDb(), trmsqlx.DefaultCtxGetter)
+	publisher := &publisherStub{}
+
+	commandBus, err := commands.NewBus(publisher, watermill.NopLogger{}, repo, trmsqlx.DefaultCtxGetter, schema.Encodings{})
+	require.NoError(t, err)
+
+	t.Run("rolled back transaction sends nothing", func(t *testing.T) {
+		commandID := uuid.New()
+		ticketID := uuid.NewString()
+
+		errRollback := errors.New("booking not stored")
+		err := trManager.Do(ctx, func(ctx context.Context) error {
+			err := commandBus.Send(
+				commands.ContextWithCommandID(ctx, commandID),
+				&entities.RefundTicket{Header: entities.NewEventHeader(), TicketID: ticketID},
+			)
+			require.NoError(t, err)
+
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+
+		assert.Equal(t, 0, countOutboxMessages(t, ctx, ticketID))
+		assert.Equal(t, 0, publisher.count())
+
+		// the command is accepted in the same transaction
+		_, err = repo.Get(ctx, commandID)
+		assert.ErrorIs(t, err, repository.ErrCommandNotFound)
+	})
+
+	t.Run("committed transaction sends the command once", func(t *testing.T) {
+		commandID := uuid.New()
+		ticketID := uuid.NewString()
+
+		err := trManager.Do(ctx, func(ctx context.Context) error {
+			return commandBus.Send(
+				commands.ContextWithCommandID(ctx, commandID),
+				&entities.RefundTicket{Header: entities.NewEventHeader(), TicketID: ticketID},
+			)
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, countOutboxMessages(t, ctx, ticketID))
+		assert.Equal(t, 0, publisher.count(), "commands sent in a transaction go only through the outbox")
+
+		command, err := repo.Get(ctx, commandID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.CommandStatusAccepted, command.Status)
+	})
+
+	t.Run("without transaction", func(t *testing.T) {
+		ticketID := uuid.NewString()
+
+		err := commandBus.Send(ctx, &entities.RefundTicket{Header: entities.NewEventHeader(), TicketID: ticketID})
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, countOutboxMessages(t, ctx, ticketID))
+		assert.Equal(t, 1, publisher.count())
+	})
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	ticketsHttp "tickets/internal/interfaces/http"
+	"tickets/internal/repository"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIdempotencyKeyLockTimeout = 500 * time.Millisecond
+
+func newTestIdempotentServer(t *testing.T) *echo.Echo {
+	setupTestDB(t)
+
+	repo := repository.NewIdempotentRequestsRepo(getDb(), trmsqlx.DefaultCtxGetter, testIdempotencyKeyLockTimeout)
+
+	e := echo.New()
+	e.Use(ticketsHttp.IdempotencyMiddleware(repo))
+
+	return e
+}
+
+func sendIdempotentRequest(e *echo.Echo, path string, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Idempotency-Key", key)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	e := newTestIdempotentServer(t)
+
+	var created atomic.Int32
+	e.POST("/bookings", func(c echo.Context) error {
+		created.Add(1)
+		bookingID := uuid.NewString()
+
+		c.Response().Header().Set(echo.HeaderLocation, "/bookings/"+bookingID)
+		return c.JSON(http.StatusCreated, map[string]string{"booking_id": bookingID})
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		key := uuid.NewString()
+		calls := created.Load()
+
+		first := sendIdempotentRequest(e, "/bookings", key, `{"number_of_tickets": 2}`)
+		require.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+		retry := sendIdempotentRequest(e, "/bookings", key, `{"number_of_tickets": 2}`)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, first.Header().Get(echo.HeaderLocation), retry.Header().Get(echo.HeaderLocation))
+		assert.Equal(t, first.Header().Get(echo.HeaderContentType), retry.Header().Get(echo.HeaderContentType))
+		assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+		assert.Equal(t, calls+1, created.Load(), "the retry is not handled")
+	})
+
+	t.Run("different request with the same key", func(t *testing.T) {
+		key := uuid.NewString()
+
+		first := sendIdempotentRequest(e, "/bookings", key, `{"number_of_tickets": 2}`)
+		require.Equal(t, http.StatusCreated, first.Code)
+
+		otherBody := sendIdempotentRequest(e, "/bookings", key, `{"number_of_tickets": 3}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, otherBody.Code)
+
+		// keys are global, they can't be used for another endpoint
+		otherPath := sendIdempotentRequest(e, "/shows", key, `{"number_of_tickets": 2}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, otherPath.Code)
+	})
+
+	t.Run("in progress", func(t *testing.T) {
+		key := uuid.NewString()
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		e.POST("/slow", func(c echo.Context) error {
+			close(started)
+			<-release
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		firstDone := make(chan *httptest.ResponseRecorder)
+		go func() {
+			firstDone <- sendIdempotentRequest(e, "/slow", key, `{}`)
+		}()
+		<-started
+
+		retry := sendIdempotentRequest(e, "/slow", key, `{}`)
+		assert.Equal(t, http.StatusConflict, retry.Code)
+
+		close(release)
+		assert.Equal(t, http.StatusNoContent, (<-firstDone).Code)
+
+		retry = sendIdempotentRequest(e, "/slow", key, `{}`)
+		assert.Equal(t, http.StatusNoContent, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("retried after a server error", func(t *testing.T) {
+		key := uuid.NewString()
+		var calls atomic.Int32
+
+		e.POST("/flaky", func(c echo.Context) error {
+			if calls.Add(1) == 1 {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "payment provider unavailable")
+			}
+			return c.JSON(http.StatusCreated, map[string]string{"status": "created"})
+		})
+
+		first := sendIdempotentRequest(e, "/flaky", key, `{}`)
+		require.Equal(t, http.StatusServiceUnavailable, first.Code)
+
+		retry := sendIdempotentRequest(e, "/flaky", key, `{}`)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Empty(t, retry.Header().Get("Idempotent-Replayed"))
+
+		replayed := sendIdempotentRequest(e, "/flaky", key, `{}`)
+		assert.Equal(t, http.StatusCreated, replayed.Code)
+		assert.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("client errors are replayed", func(t *testing.T) {
+		key := uuid.NewString()
+		var calls atomic.Int32
+
+		e.POST("/invalid", func(c echo.Context) error {
+			calls.Add(1)
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid booking")
+		})
+
+		first := sendIdempotentRequest(e, "/invalid", key, `{}`)
+		require.Equal(t, http.StatusBadRequest, first.Code)
+
+		retry := sendIdempotentRequest(e, "/invalid", key, `{}`)
+		assert.Equal(t, http.StatusBadRequest, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("taken over after the lock timeout", func(t *testing.T) {
+		key := uuid.NewString()
+		var calls atomic.Int32
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		e.POST("/stuck", func(c echo.Context) error {
+			// the first request hangs, as if its instance crashed before completing it
+			if calls.Add(1) == 1 {
+				close(started)
+				<-release
+			}
+			return c.JSON(http.StatusCreated, map[string]string{"status": "created"})
+		})
+
+		stuckDone := make(chan struct{})
+		go func() {
+			defer close(stuckDone)
+			sendIdempotentRequest(e, "/stuck", key, `{}`)
+		}()
+		<-started
+		defer func() {
+			close(release)
+			<-stuckDone
+		}()
+
+		retry := sendIdempotentRequest(e, "/stuck", key, `{}`)
+		assert.Equal(t, http.StatusConflict, retry.Code)
+
+		time.Sleep(testIdempotencyKeyLockTimeout + 100*time.Millisecond)
+
+		retry = sendIdempotentRequest(e, "/stuck", key, `{}`)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Empty(t, retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, int32(2), calls.Load())
+
+		replayed := sendIdempotentRequest(e, "/stuck", key, `{}`)
+		assert.Equal(t, http.StatusCreated, replayed.Code)
+		assert.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("client gone before the response", func(t *testing.T) {
+		key := uuid.NewString()
+		var calls atomic.Int32
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		e.POST("/timed-out", func(c echo.Context) error {
+			calls.Add(1)
+			// the client times out after the booking was made, before getting the response
+			cancel()
+			return c.JSON(http.StatusCreated, map[string]string{"status": "created"})
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/timed-out", strings.NewReader(`{}`)).WithContext(ctx)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("Idempotency-Key", key)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		retry := sendIdempotentRequest(e, "/timed-out", key, `{}`)
+		assert.Equal(t, http.StatusCreated, retry.Code, "the response is stored and the key is not locked")
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("without key", func(t *testing.T) {
+		calls := created.Load()
+
+		for i := 0; i < 2; i++ {
+			rec := sendIdempotentRequest(e, "/bookings", "", `{}`)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+		}
+
+		assert.Equal(t, calls+2, created.Load())
+	})
+}
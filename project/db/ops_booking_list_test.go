@@ -0,0 +1,384 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type opsTicketFixture struct {
+	CustomerEmail   string
+	ConfirmedAt     *time.Time
+	RefundedAt      *time.Time
+	ReceiptIssuedAt *time.Time
+}
+
+type opsBookingFixture struct {
+	BookingID  uuid.UUID
+	BookedAt   time.Time
+	LastUpdate time.Time
+	Tickets    []opsTicketFixture
+}
+
+func insertOpsBooking(t *testing.T, showID uuid.UUID, booking opsBookingFixture) {
+	ctx := context.Background()
+
+	_, err := getDb().ExecContext(ctx, `
+		INSERT INTO ops_bookings (booking_id, booked_at, show_id, last_update) VALUES ($1, $2, $3, $4)`,
+		booking.BookingID, booking.BookedAt, showID, booking.LastUpdate,
+	)
+	require.NoError(t, err)
+
+	for _, ticket := range booking.Tickets {
+		_, err := getDb().ExecContext(ctx, `
+			INSERT INTO ops_tickets (ticket_id, booking_id, customer_email, confirmed_at, refunded_at, receipt_issued_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			uuid.New(), booking.BookingID, ticket.CustomerEmail, ticket.ConfirmedAt, ticket.RefundedAt, ticket.ReceiptIssuedAt,
+		)
+		require.NoError(t, err)
+	}
+}
+
+// listAllOpsBookings follows the cursors until the last page and returns the IDs in the order of the pages.
+func listAllOpsBookings(t *testing.T, repo *repository.OpsBookingReadModelRepo, query repository.OpsBookingsQuery) ([]uuid.UUID, int) {
+	var (
+		bookingIDs []uuid.UUID
+		pages      int
+	)
+	for {
+		page, err := repo.List(context.Background(), query)
+		require.NoError(t, err)
+		pages++
+
+		for _, booking := range page.Bookings {
+			bookingIDs = append(bookingIDs, booking.BookingID)
+		}
+		require.LessOrEqual(t, len(page.Bookings), query.Limit)
+
+		if page.NextCursor == "" {
+			return bookingIDs, pages
+		}
+		require.Less(t, pages, 100, "the cursor doesn't move")
+
+		query.Cursor = page.NextCursor
+	}
+}
+
+func TestOpsBookingReadModelRepo_List(t *testing.T) {
+	setupTestReadModelOpsDB(t)
+
+	repo := repository.NewOpsBookingReadModelRepo(
+		getDb(),
+		trmsqlx.DefaultCtxGetter,
+		manager.Must(trmsqlx.NewDefaultFactory(getDb())),
+		noopPublisher{},
+	)
+	ctx := context.Background()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := base.Add(d)
+		return &t
+	}
+	receiptIssuedAt := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	// all bookings are in a new show, so bookings of other tests are filtered out
+	showID := uuid.New()
+	fixtures := []opsBookingFixture{
+		{
+			BookingID:  uuid.New(),
+			BookedAt:   base,
+			LastUpdate: base.Add(50 * time.Minute),
+			Tickets: []opsTicketFixture{
+				{CustomerEmail: "a@example.com", ConfirmedAt: at(0), ReceiptIssuedAt: &receiptIssuedAt},
+			},
+		},
+		{
+			BookingID:  uuid.New(),
+			BookedAt:   base.Add(time.Minute),
+			LastUpdate: base.Add(40 * time.Minute),
+			Tickets: []opsTicketFixture{
+				{CustomerEmail: "b@example.com", ConfirmedAt: at(time.Minute), RefundedAt: at(time.Hour)},
+				{CustomerEmail: "b@example.com", ConfirmedAt: at(time.Minute)},
+			},
+		},
+		{
+			// booked at the same time as the previous booking
+			BookingID:  uuid.New(),
+			BookedAt:   base.Add(time.Minute),
+			LastUpdate: base.Add(30 * time.Minute),
+			Tickets: []opsTicketFixture{
+				{CustomerEmail: "c@example.com", ConfirmedAt: at(time.Minute), RefundedAt: at(time.Hour)},
+			},
+		},
+		{
+			BookingID:  uuid.New(),
+			BookedAt:   base.Add(2 * time.Minute),
+			LastUpdate: base.Add(20 * time.Minute),
+			Tickets: []opsTicketFixture{
+				{CustomerEmail: "d@example.com"},
+			},
+		},
+		{
+			BookingID:  uuid.New(),
+			BookedAt:   base.Add(3 * time.Minute),
+			LastUpdate: base.Add(10 * time.Minute),
+		},
+	}
+	for _, fixture := range fixtures {
+		insertOpsBooking(t, showID, fixture)
+	}
+	a, b, c, d, e := fixtures[0].BookingID, fixtures[1].BookingID, fixtures[2].BookingID, fixtures[3].BookingID, fixtures[4].BookingID
+
+	// ties are sorted by the booking ID
+	tied := []uuid.UUID{b, c}
+	slices.SortFunc(tied, func(x, y uuid.UUID) int { return bytes.Compare(x[:], y[:]) })
+	byBookedAt := []uuid.UUID{a, tied[0], tied[1], d, e}
+	byLastUpdate := []uuid.UUID{e, d, c, b, a}
+
+	reversed := func(ids []uuid.UUID) []uuid.UUID {
+		r := slices.Clone(ids)
+		slices.Reverse(r)
+		return r
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		testCases := []struct {
+			name       string
+			sortBy     repository.OpsBookingsSort
+			descending bool
+			expected   []uuid.UUID
+		}{
+			{name: "booked_at ascending", sortBy: repository.OpsBookingsSortBookedAt, expected: byBookedAt},
+			{name: "booked_at descending", sortBy: repository.OpsBookingsSortBookedAt, descending: true, expected: reversed(byBookedAt)},
+			{name: "last_update ascending", sortBy: repository.OpsBookingsSortLastUpdate, expected: byLastUpdate},
+			{name: "last_update descending", sortBy: repository.OpsBookingsSortLastUpdate, descending: true, expected: reversed(byLastUpdate)},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				bookingIDs, pages := listAllOpsBookings(t, repo, repository.OpsBookingsQuery{
+					Filters:    repository.Filters{ShowID: &showID},
+					SortBy:     tc.sortBy,
+					Descending: tc.descending,
+					Limit:      2,
+				})
+
+				assert.Equal(t, tc.expected, bookingIDs)
+				assert.Equal(t, 3, pages)
+			})
+		}
+	})
+
+	t.Run("ties on booked_at are split between pages", func(t *testing.T) {
+		for _, descending := range []bool{false, true} {
+			expected := byBookedAt
+			if descending {
+				expected = reversed(byBookedAt)
+			}
+
+			bookingIDs, pages := listAllOpsBookings(t, repo, repository.OpsBookingsQuery{
+				Filters:    repository.Filters{ShowID: &showID},
+				SortBy:     repository.OpsBookingsSortBookedAt,
+				Descending: descending,
+				Limit:      1,
+			})
+
+			assert.Equal(t, expected, bookingIDs, "descending: %t", descending)
+			assert.Equal(t, len(fixtures), pages)
+		}
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		page, err := repo.List(ctx, repository.OpsBookingsQuery{
+			Filters: repository.Filters{ShowID: &showID},
+			SortBy:  repository.OpsBookingsSortBookedAt,
+			Limit:   len(fixtures),
+		})
+		require.NoError(t, err)
+
+		assert.Len(t, page.Bookings, len(fixtures))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("cursor of another sort", func(t *testing.T) {
+		page, err := repo.List(ctx, repository.OpsBookingsQuery{
+			Filters: repository.Filters{ShowID: &showID},
+			SortBy:  repository.OpsBookingsSortBookedAt,
+			Limit:   2,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, page.NextCursor)
+
+		_, err = repo.List(ctx, repository.OpsBookingsQuery{
+			Filters: repository.Filters{ShowID: &showID},
+			SortBy:  repository.OpsBookingsSortLastUpdate,
+			Cursor:  page.NextCursor,
+			Limit:   2,
+		})
+		assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+
+		_, err = repo.List(ctx, repository.OpsBookingsQuery{
+			Filters:    repository.Filters{ShowID: &showID},
+			SortBy:     repository.OpsBookingsSortBookedAt,
+			Descending: true,
+			Cursor:     page.NextCursor,
+			Limit:      2,
+		})
+		assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+
+		_, err = repo.List(ctx, repository.OpsBookingsQuery{
+			Filters: repository.Filters{ShowID: &showID},
+			SortBy:  repository.OpsBookingsSortBookedAt,
+			Cursor:  "not a cursor",
+			Limit:   2,
+		})
+		assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			filters  repository.Filters
+			expected []uuid.UUID
+		}{
+			{
+				name:     "show",
+				filters:  repository.Filters{},
+				expected: []uuid.UUID{a, b, c, d, e},
+			},
+			{
+				name:     "customer email",
+				filters:  repository.Filters{CustomerEmail: "b@example.com"},
+				expected: []uuid.UUID{b},
+			},
+			{
+				name:     "receipt issue date",
+				filters:  repository.Filters{ReceiptIssueDate: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)},
+				expected: []uuid.UUID{a},
+			},
+			{
+				name:     "receipt issue date without receipts",
+				filters:  repository.Filters{ReceiptIssueDate: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+				expected: nil,
+			},
+			{
+				name:     "pending ticket",
+				filters:  repository.Filters{TicketStatus: entities.OpsTicketStatusPending},
+				expected: []uuid.UUID{d},
+			},
+			{
+				name:     "confirmed ticket",
+				filters:  repository.Filters{TicketStatus: entities.OpsTicketStatusConfirmed},
+				expected: []uuid.UUID{a, b},
+			},
+			{
+				name:     "refunded ticket",
+				filters:  repository.Filters{TicketStatus: entities.OpsTicketStatusRefunded},
+				expected: []uuid.UUID{b, c},
+			},
+			{
+				name:     "not refunded",
+				filters:  repository.Filters{RefundState: entities.OpsBookingRefundStateNone},
+				expected: []uuid.UUID{a, d, e},
+			},
+			{
+				name:     "partially refunded",
+				filters:  repository.Filters{RefundState: entities.OpsBookingRefundStatePartial},
+				expected: []uuid.UUID{b},
+			},
+			{
+				name:     "fully refunded",
+				filters:  repository.Filters{RefundState: entities.OpsBookingRefundStateFull},
+				expected: []uuid.UUID{c},
+			},
+			{
+				name:     "booked from",
+				filters:  repository.Filters{BookedFrom: at(time.Minute)},
+				expected: []uuid.UUID{b, c, d, e},
+			},
+			{
+				name:     "booked to",
+				filters:  repository.Filters{BookedTo: at(time.Minute)},
+				expected: []uuid.UUID{a, b, c},
+			},
+			{
+				name:     "updated from",
+				filters:  repository.Filters{UpdatedFrom: at(40 * time.Minute)},
+				expected: []uuid.UUID{a, b},
+			},
+			{
+				name:     "updated to",
+				filters:  repository.Filters{UpdatedTo: at(20 * time.Minute)},
+				expected: []uuid.UUID{d, e},
+			},
+			{
+				name: "combined",
+				filters: repository.Filters{
+					TicketStatus: entities.OpsTicketStatusConfirmed,
+					RefundState:  entities.OpsBookingRefundStateNone,
+				},
+				expected: []uuid.UUID{a},
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.filters.ShowID = &showID
+
+				bookingIDs, _ := listAllOpsBookings(t, repo, repository.OpsBookingsQuery{
+					Filters: tc.filters,
+					SortBy:  repository.OpsBookingsSortBookedAt,
+					Limit:   2,
+				})
+
+				assert.ElementsMatch(t, tc.expected, bookingIDs)
+			})
+		}
+
+		t.Run("other show", func(t *testing.T) {
+			otherShowID := uuid.New()
+
+			page, err := repo.List(ctx, repository.OpsBookingsQuery{
+				Filters: repository.Filters{ShowID: &otherShowID},
+				SortBy:  repository.OpsBookingsSortBookedAt,
+				Limit:   2,
+			})
+			require.NoError(t, err)
+			assert.Empty(t, page.Bookings)
+		})
+
+		t.Run("unsupported values", func(t *testing.T) {
+			_, err := repo.List(ctx, repository.OpsBookingsQuery{
+				Filters: repository.Filters{ShowID: &showID, TicketStatus: "lost"},
+				SortBy:  repository.OpsBookingsSortBookedAt,
+				Limit:   2,
+			})
+			assert.Error(t, err)
+
+			_, err = repo.List(ctx, repository.OpsBookingsQuery{
+				Filters: repository.Filters{ShowID: &showID, RefundState: "some"},
+				SortBy:  repository.OpsBookingsSortBookedAt,
+				Limit:   2,
+			})
+			assert.Error(t, err)
+
+			_, err = repo.List(ctx, repository.OpsBookingsQuery{
+				Filters: repository.Filters{ShowID: &showID},
+				SortBy:  "customer_email",
+				Limit:   2,
+			})
+			assert.Error(t, err)
+		})
+	})
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Compares the JSONB documents stored in read_model_ops_bookings before the read model was normalized
+// with ops_bookings and ops_tickets. Run with:
+//
+//	POSTGRES_URL=... go test ./db -run '^$' -bench OpsBookingReadModel -benchtime 2000x
+const benchBookingsCount = 100_000
+
+var seedBenchBookingsOnce sync.Once
+
+func benchBookingID(i int) uuid.UUID {
+	return uuid.UUID(md5.Sum([]byte(fmt.Sprintf("bench-booking-%d", i))))
+}
+
+func benchTicketID(i int, n int) uuid.UUID {
+	return uuid.UUID(md5.Sum([]byte(fmt.Sprintf("bench-ticket-%d-%d", i, n))))
+}
+
+// seedBenchBookings stores bookings with two tickets each as the old JSONB documents,
+// InitializeDBSchema migrates them to the normalized tables, so both have the same data.
+func seedBenchBookings(b *testing.B) {
+	seedBenchBookingsOnce.Do(func() {
+		require.NoError(b, repository.InitializeDBSchema(getDb()))
+
+		_, err := getDb().Exec(`
+INSERT INTO read_model_ops_bookings (booking_id, payload)
+SELECT
+	md5('bench-booking-' || i)::uuid,
+	jsonb_build_object(
+		'booking_id', md5('bench-booking-' || i)::uuid,
+		'booked_at', '2024-01-01T00:00:00Z'::timestamptz + i * interval '1 second',
+		'last_update', '2024-01-01T00:00:00Z'::timestamptz + i * interval '1 second',
+		'tickets', jsonb_build_object(
+			md5('bench-ticket-' || i || '-1')::uuid, jsonb_build_object(
+				'price_amount', '100.00',
+				'price_currency', 'USD',
+				'customer_email', 'customer-' || i || '@example.com',
+				'confirmed_at', '2024-01-01T00:00:00Z'::timestamptz + i * interval '1 second'
+			),
+			md5('bench-ticket-' || i || '-2')::uuid, jsonb_build_object(
+				'price_amount', '100.00',
+				'price_currency', 'USD',
+				'customer_email', 'customer-' || i || '@example.com',
+				'confirmed_at', '2024-01-01T00:00:00Z'::timestamptz + i * interval '1 second'
+			)
+		)
+	)
+FROM generate_series(1, $1) i
+ON CONFLICT DO NOTHING`, benchBookingsCount)
+		require.NoError(b, err)
+
+		require.NoError(b, repository.InitializeDBSchema(getDb()))
+	})
+}
+
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(context.Context, any) error {
+	return nil
+}
+
+func newBenchOpsBookingRepo() *repository.OpsBookingReadModelRepo {
+	return repository.NewOpsBookingReadModelRepo(
+		getDb(),
+		trmsqlx.DefaultCtxGetter,
+		manager.Must(trmsqlx.NewDefaultFactory(getDb())),
+		noopPublisher{},
+	)
+}
+
+func BenchmarkOpsBookingReadModel_GetByTicketID(b *testing.B) {
+	seedBenchBookings(b)
+	ctx := context.Background()
+
+	b.Run("jsonb", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			var payload []byte
+			err := getDb().QueryRowContext(
+				ctx,
+				"SELECT payload FROM read_model_ops_bookings WHERE payload::jsonb -> 'tickets' ? $1",
+				benchTicketID(rand.Intn(benchBookingsCount)+1, 1).String(),
+			).Scan(&payload)
+			require.NoError(b, err)
+
+			var booking entities.OpsBooking
+			require.NoError(b, json.Unmarshal(payload, &booking))
+		}
+	})
+
+	b.Run("normalized", func(b *testing.B) {
+		repo := newBenchOpsBookingRepo()
+
+		for n := 0; n < b.N; n++ {
+			booking, err := repo.GetByTicketID(ctx, benchTicketID(rand.Intn(benchBookingsCount)+1, 1))
+			require.NoError(b, err)
+			require.NotNil(b, booking)
+		}
+	})
+}
+
+func BenchmarkOpsBookingReadModel_UpdateTicket(b *testing.B) {
+	seedBenchBookings(b)
+	ctx := context.Background()
+
+	b.Run("jsonb", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			i := rand.Intn(benchBookingsCount) + 1
+			ticketID := benchTicketID(i, 1).String()
+
+			tx, err := getDb().BeginTxx(ctx, nil)
+			require.NoError(b, err)
+
+			var payload []byte
+			err = tx.QueryRowContext(
+				ctx,
+				"SELECT payload FROM read_model_ops_bookings WHERE booking_id = $1 FOR UPDATE",
+				benchBookingID(i),
+			).Scan(&payload)
+			require.NoError(b, err)
+
+			var booking entities.OpsBooking
+			require.NoError(b, json.Unmarshal(payload, &booking))
+
+			ticket := booking.Tickets[ticketID]
+			ticket.PrintedAt = time.Now().UTC()
+			ticket.PrintedFileName = ticketID + "-ticket.html"
+			booking.Tickets[ticketID] = ticket
+
+			payload, err = json.Marshal(booking)
+			require.NoError(b, err)
+
+			_, err = tx.ExecContext(
+				ctx,
+				"UPDATE read_model_ops_bookings SET payload = $1 WHERE booking_id = $2",
+				payload,
+				booking.BookingID,
+			)
+			require.NoError(b, err)
+			require.NoError(b, tx.Commit())
+		}
+	})
+
+	b.Run("normalized", func(b *testing.B) {
+		repo := newBenchOpsBookingRepo()
+
+		for n := 0; n < b.N; n++ {
+			i := rand.Intn(benchBookingsCount) + 1
+			ticketID := benchTicketID(i, 1).String()
+
+			err := repo.OnTicketPrintedEvent(ctx, &entities.TicketPrinted_v1{
+				Header:    entities.NewEventHeader(),
+				TicketID:  ticketID,
+				BookingID: benchBookingID(i).String(),
+				FileName:  ticketID + "-ticket.html",
+				PrintedAt: time.Now().UTC(),
+			})
+			require.NoError(b, err)
+		}
+	})
+}
+
+func BenchmarkOpsBookingReadModel_FilterByCustomerEmail(b *testing.B) {
+	seedBenchBookings(b)
+	ctx := context.Background()
+
+	b.Run("jsonb", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			rows, err := getDb().QueryContext(
+				ctx,
+				`SELECT payload FROM read_model_ops_bookings
+				WHERE jsonb_path_exists(payload, '$.tickets.* ? (@.customer_email == $email)', jsonb_build_object('email', $1::text))`,
+				fmt.Sprintf("customer-%d@example.com", rand.Intn(benchBookingsCount)+1),
+			)
+			require.NoError(b, err)
+
+			for rows.Next() {
+				var payload []byte
+				require.NoError(b, rows.Scan(&payload))
+			}
+			require.NoError(b, rows.Err())
+			require.NoError(b, rows.Close())
+		}
+	})
+
+	b.Run("normalized", func(b *testing.B) {
+		repo := newBenchOpsBookingRepo()
+
+		for n := 0; n < b.N; n++ {
+			page, err := repo.List(ctx, repository.OpsBookingsQuery{
+				Filters: repository.Filters{
+					CustomerEmail: fmt.Sprintf("customer-%d@example.com", rand.Intn(benchBookingsCount)+1),
+				},
+				SortBy: repository.OpsBookingsSortBookedAt,
+				Limit:  100,
+			})
+			require.NoError(b, err)
+			require.Len(b, page.Bookings, 1)
+		}
+	})
+}
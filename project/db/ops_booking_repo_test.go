@@ -17,13 +17,7 @@ import (
 )
 
 func setupTestReadModelOpsDB(t *testing.T) {
-	db := getDb()
-	_, err := db.Exec(`
-        CREATE TABLE IF NOT EXISTS read_model_ops_bookings (
-            booking_id UUID PRIMARY KEY,
-            payload JSONB NOT NULL
-        )
-    `)
+	err := repository.InitializeDBSchema(getDb())
 	require.NoError(t, err)
 }
 
@@ -54,7 +48,7 @@ func TestOpsBookingReadModelRepo_Integration(t *testing.T) {
 
 	t.Run("handle BookingMade event", func(t *testing.T) {
 		bookingID := uuid.New()
-		bookedAt := time.Now().UTC()
+		bookedAt := time.Now().UTC().Truncate(time.Microsecond) // precision of Postgres timestamps
 
 		event := &entities.BookingMade_v1{
 			BookingID: bookingID,
@@ -96,10 +90,7 @@ func TestOpsBookingReadModelRepo_Integration(t *testing.T) {
 			BookingID:     bookingID.String(),
 			TicketID:      ticketID.String(),
 			CustomerEmail: "test@example.com",
-			Price: entities.Money{
-				Amount:   "100.00",
-				Currency: "USD",
-			},
+			Price:         entities.MustNewMoney("100.00", "USD"),
 		}
 
 		err = repo.OnTicketBookingConfirmedEvent(ctx, event)
@@ -120,7 +111,7 @@ func TestOpsBookingReadModelRepo_Integration(t *testing.T) {
 	t.Run("handle TicketReceiptIssued_v1 event", func(t *testing.T) {
 		bookingID := uuid.New()
 		ticketID := uuid.New()
-		issuedAt := time.Now().UTC()
+		issuedAt := time.Now().UTC().Truncate(time.Microsecond)
 
 		// Create initial booking
 		err := repo.OnBookingMadeEvent(ctx, &entities.BookingMade_v1{
@@ -134,10 +125,7 @@ func TestOpsBookingReadModelRepo_Integration(t *testing.T) {
 			BookingID:     bookingID.String(),
 			TicketID:      ticketID.String(),
 			CustomerEmail: "test@example.com",
-			Price: entities.Money{
-				Amount:   "100.00",
-				Currency: "USD",
-			},
+			Price:         entities.MustNewMoney("100.00", "USD"),
 		})
 
 		require.NoError(t, err)
@@ -178,7 +166,7 @@ func TestOpsBookingReadModelRepo_Integration(t *testing.T) {
 			BookingID:     bookingID.String(),
 			TicketID:      ticketID.String(),
 			CustomerEmail: "test@example.com",
-			Price:         entities.Money{Amount: "100.00", Currency: "USD"},
+			Price:         entities.MustNewMoney("100.00", "USD"),
 		})
 		require.NoError(t, err)
 
@@ -199,6 +187,109 @@ func TestOpsBookingReadModelRepo_Integration(t *testing.T) {
 		assert.NotNil(t, ticket.RefundedAt)
 	})
 
+	t.Run("handle ticket events before BookingMade_v1", func(t *testing.T) {
+		bookingID := uuid.New()
+		ticketID := uuid.New()
+		bookedAt := time.Now().UTC().Truncate(time.Microsecond)
+
+		err := repo.OnTicketRefundedEvent(ctx, &entities.TicketRefunded_v1{
+			Header:   entities.NewEventHeader(),
+			TicketID: ticketID.String(),
+		})
+		require.NoError(t, err)
+
+		err = repo.OnTicketPrintedEvent(ctx, &entities.TicketPrinted_v1{
+			Header:    entities.NewEventHeader(),
+			TicketID:  ticketID.String(),
+			BookingID: bookingID.String(),
+			FileName:  "ticket.html",
+			PrintedAt: time.Now().UTC(),
+		})
+		require.NoError(t, err)
+
+		placeholder, err := repo.GetByID(ctx, bookingID)
+		require.NoError(t, err)
+		require.NotNil(t, placeholder)
+		assert.True(t, placeholder.BookedAt.IsZero())
+
+		err = repo.OnTicketBookingConfirmedEvent(ctx, &entities.TicketBookingConfirmed_v1{
+			Header:        entities.NewEventHeader(),
+			BookingID:     bookingID.String(),
+			TicketID:      ticketID.String(),
+			CustomerEmail: "test@example.com",
+			Price:         entities.MustNewMoney("100.00", "USD"),
+		})
+		require.NoError(t, err)
+
+		err = repo.OnBookingMadeEvent(ctx, &entities.BookingMade_v1{
+			BookingID: bookingID,
+			BookedAt:  bookedAt,
+		})
+		require.NoError(t, err)
+
+		booking, err := repo.GetByTicketID(ctx, ticketID)
+		require.NoError(t, err)
+		require.NotNil(t, booking)
+		assert.Equal(t, bookedAt, booking.BookedAt)
+		assert.Greater(t, booking.Version, placeholder.Version)
+
+		ticket, exists := booking.Tickets[ticketID.String()]
+		require.True(t, exists)
+		assert.Equal(t, "ticket.html", ticket.PrintedFileName)
+		assert.Equal(t, "100.00", ticket.RefundedAmount)
+		assert.Equal(t, entities.OpsTicketStatusRefunded, ticket.Status())
+	})
+
+	t.Run("ignore stale ticket updates", func(t *testing.T) {
+		bookingID := uuid.New()
+		ticketID := uuid.New()
+		transferredAt := time.Now().UTC().Truncate(time.Microsecond)
+
+		err := repo.OnBookingMadeEvent(ctx, &entities.BookingMade_v1{
+			BookingID: bookingID,
+			BookedAt:  time.Now().UTC(),
+		})
+		require.NoError(t, err)
+
+		err = repo.OnTicketTransferredEvent(ctx, &entities.TicketTransferred_v1{
+			Header:        entities.NewEventHeader(),
+			TicketID:      ticketID.String(),
+			BookingID:     bookingID.String(),
+			ToEmail:       "new-owner@example.com",
+			TransferredAt: transferredAt,
+		})
+		require.NoError(t, err)
+
+		// older transfer delivered late
+		err = repo.OnTicketTransferredEvent(ctx, &entities.TicketTransferred_v1{
+			Header:        entities.NewEventHeader(),
+			TicketID:      ticketID.String(),
+			BookingID:     bookingID.String(),
+			ToEmail:       "previous-owner@example.com",
+			TransferredAt: transferredAt.Add(-time.Minute),
+		})
+		require.NoError(t, err)
+
+		// confirmation delivered after the transfer doesn't bring back the original owner
+		err = repo.OnTicketBookingConfirmedEvent(ctx, &entities.TicketBookingConfirmed_v1{
+			Header:        entities.NewEventHeader(),
+			BookingID:     bookingID.String(),
+			TicketID:      ticketID.String(),
+			CustomerEmail: "test@example.com",
+			Price:         entities.MustNewMoney("100.00", "USD"),
+		})
+		require.NoError(t, err)
+
+		booking, err := repo.GetByID(ctx, bookingID)
+		require.NoError(t, err)
+
+		ticket, exists := booking.Tickets[ticketID.String()]
+		require.True(t, exists)
+		assert.Equal(t, "new-owner@example.com", ticket.CustomerEmail)
+		assert.Equal(t, transferredAt, ticket.TransferredAt)
+		assert.Equal(t, "100.00", ticket.PriceAmount)
+	})
+
 	//t.Run("GetAll returns all bookings", func(t *testing.T) {
 	//	cleanupTestDB(t) // Start fresh
 	//
@@ -219,3 +310,89 @@ func TestOpsBookingReadModelRepo_Integration(t *testing.T) {
 	//	//assert.Len(t, bookings, 2)
 	//})
 }
+
+func TestOpsBookingReadModelRepo_PublishesAfterCommit(t *testing.T) {
+	setupTestReadModelOpsDB(t)
+	setupOutbox(t)
+
+	trManager := manager.Must(trmsqlx.NewDefaultFactory(getDb()))
+	repo := repository.NewOpsBookingReadModelRepo(getDb(), trmsqlx.DefaultCtxGetter, trManager, newTestOutboxBus())
+	ctx := context.Background()
+
+	t.Run("rolled back", func(t *testing.T) {
+		bookingID := uuid.New()
+
+		err := trManager.Do(ctx, func(ctx context.Context) error {
+			require.NoError(t, repo.OnBookingMadeEvent(ctx, &entities.BookingMade_v1{
+				BookingID: bookingID,
+				BookedAt:  time.Now().UTC(),
+			}))
+			return assert.AnError
+		})
+		require.ErrorIs(t, err, assert.AnError)
+
+		assert.Equal(t, 0, countOutboxMessages(t, ctx, bookingID.String()))
+	})
+
+	t.Run("committed", func(t *testing.T) {
+		bookingID := uuid.New()
+
+		require.NoError(t, repo.OnBookingMadeEvent(ctx, &entities.BookingMade_v1{
+			BookingID: bookingID,
+			BookedAt:  time.Now().UTC(),
+		}))
+
+		assert.Equal(t, 1, countOutboxMessages(t, ctx, bookingID.String()))
+	})
+}
+
+// The update sequence is the ID of streamed updates, a stream resumed after an update
+// must get all updates committed after it, also of transactions which started earlier.
+func TestOpsBookingReadModelRepo_UpdateSequenceInCommitOrder(t *testing.T) {
+	setupTestReadModelOpsDB(t)
+
+	ctx := context.Background()
+	db := getDb()
+
+	first := uuid.New()
+	second := uuid.New()
+	for _, bookingID := range []uuid.UUID{first, second} {
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO ops_bookings (booking_id, booked_at, last_update) VALUES ($1, NOW(), NOW())`,
+			bookingID,
+		)
+		require.NoError(t, err)
+	}
+
+	slowTx, err := db.BeginTxx(ctx, nil)
+	require.NoError(t, err)
+	defer slowTx.Rollback()
+
+	_, err = slowTx.ExecContext(ctx, `UPDATE ops_bookings SET version = version + 1 WHERE booking_id = $1`, first)
+	require.NoError(t, err)
+
+	// updated after the slow transaction, but committed before it
+	_, err = db.ExecContext(ctx, `UPDATE ops_bookings SET version = version + 1 WHERE booking_id = $1`, second)
+	require.NoError(t, err)
+
+	var secondSeq int64
+	require.NoError(t, db.GetContext(ctx, &secondSeq, `SELECT update_seq FROM ops_bookings WHERE booking_id = $1`, second))
+
+	require.NoError(t, slowTx.Commit())
+
+	repo := repository.NewOpsBookingReadModelRepo(
+		db,
+		trmsqlx.DefaultCtxGetter,
+		manager.Must(trmsqlx.NewDefaultFactory(db)),
+		noopPublisher{},
+	)
+
+	updated, err := repo.ListUpdatedAfter(ctx, secondSeq, 100)
+	require.NoError(t, err)
+
+	var bookingIDs []uuid.UUID
+	for _, booking := range updated {
+		bookingIDs = append(bookingIDs, booking.BookingID)
+	}
+	assert.Contains(t, bookingIDs, first, "the update committed later is listed after the earlier committed one")
+}
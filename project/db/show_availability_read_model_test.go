@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowAvailabilityReadModel(t *testing.T) {
+	setupTestDB(t)
+
+	ctx := context.Background()
+	repo := repository.NewShowAvailabilityReadModelRepo(
+		getDb(),
+		trmsqlx.DefaultCtxGetter,
+		manager.Must(trmsqlx.NewDefaultFactory(getDb())),
+	)
+
+	bookingMade := func(showID uuid.UUID, numberOfTickets int) *entities.BookingMade_v1 {
+		return &entities.BookingMade_v1{
+			Header:          entities.NewEventHeader(),
+			BookingID:       uuid.New(),
+			NumberOfTickets: numberOfTickets,
+			CustomerEmail:   "customer@example.com",
+			ShowID:          showID,
+			BookedAt:        time.Now().UTC(),
+		}
+	}
+	bookingCancelled := func(made *entities.BookingMade_v1) *entities.BookingCancelled_v1 {
+		return &entities.BookingCancelled_v1{
+			Header:          entities.NewEventHeader(),
+			BookingID:       made.BookingID,
+			ShowID:          made.ShowID,
+			NumberOfTickets: made.NumberOfTickets,
+			CustomerEmail:   made.CustomerEmail,
+			CancelledAt:     time.Now().UTC(),
+		}
+	}
+
+	t.Run("show without bookings", func(t *testing.T) {
+		showID := newTestShow(t, 10, time.Now().Add(24*time.Hour))
+
+		availability, err := repo.GetByShowID(ctx, showID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, availability.BookedTickets)
+		assert.Equal(t, 10, availability.AvailableTickets)
+		assert.False(t, availability.IsCancelled)
+		assert.True(t, availability.LastUpdate.IsZero())
+	})
+
+	t.Run("bookings and cancellations", func(t *testing.T) {
+		showID := newTestShow(t, 10, time.Now().Add(24*time.Hour))
+
+		first := bookingMade(showID, 3)
+		second := bookingMade(showID, 4)
+		require.NoError(t, repo.OnBookingMadeEvent(ctx, first))
+		require.NoError(t, repo.OnBookingMadeEvent(ctx, second))
+		// re-delivery
+		require.NoError(t, repo.OnBookingMadeEvent(ctx, first))
+
+		availability, err := repo.GetByShowID(ctx, showID)
+		require.NoError(t, err)
+		assert.Equal(t, 7, availability.BookedTickets)
+		assert.Equal(t, 3, availability.AvailableTickets)
+		assert.False(t, availability.LastUpdate.IsZero())
+
+		require.NoError(t, repo.OnBookingCancelledEvent(ctx, bookingCancelled(first)))
+		// re-delivery
+		require.NoError(t, repo.OnBookingCancelledEvent(ctx, bookingCancelled(first)))
+
+		availability, err = repo.GetByShowID(ctx, showID)
+		require.NoError(t, err)
+		assert.Equal(t, 4, availability.BookedTickets)
+		assert.Equal(t, 6, availability.AvailableTickets)
+	})
+
+	t.Run("cancellation processed before the booking", func(t *testing.T) {
+		showID := newTestShow(t, 10, time.Now().Add(24*time.Hour))
+
+		made := bookingMade(showID, 2)
+		require.NoError(t, repo.OnBookingCancelledEvent(ctx, bookingCancelled(made)))
+		require.NoError(t, repo.OnBookingMadeEvent(ctx, made))
+
+		availability, err := repo.GetByShowID(ctx, showID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, availability.BookedTickets)
+		assert.Equal(t, 10, availability.AvailableTickets)
+	})
+
+	t.Run("sold out", func(t *testing.T) {
+		showID := newTestShow(t, 2, time.Now().Add(24*time.Hour))
+		require.NoError(t, repo.OnBookingMadeEvent(ctx, bookingMade(showID, 2)))
+
+		availability, err := repo.GetByShowID(ctx, showID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, availability.AvailableTickets)
+
+		shows, err := repo.GetWithFilters(ctx, repository.ShowAvailabilityFilters{OnlyAvailable: true, Venue: "Test Venue"})
+		require.NoError(t, err)
+		for _, show := range shows {
+			assert.NotEqual(t, showID, show.ShowID)
+		}
+	})
+
+	t.Run("cancelled show", func(t *testing.T) {
+		showID := newTestShow(t, 10, time.Now().Add(24*time.Hour))
+		require.NoError(t, repo.OnBookingMadeEvent(ctx, bookingMade(showID, 2)))
+
+		err := repo.OnShowCancelledEvent(ctx, &entities.ShowCancelled_v1{
+			Header:      entities.NewEventHeader(),
+			ShowID:      showID,
+			Reason:      "venue flooded",
+			CancelledAt: time.Now().UTC(),
+		})
+		require.NoError(t, err)
+
+		availability, err := repo.GetByShowID(ctx, showID)
+		require.NoError(t, err)
+		assert.True(t, availability.IsCancelled)
+		assert.Equal(t, 2, availability.BookedTickets)
+		assert.Equal(t, 0, availability.AvailableTickets)
+
+		contains := func(shows []entities.ShowAvailability) bool {
+			for _, show := range shows {
+				if show.ShowID == showID {
+					return true
+				}
+			}
+			return false
+		}
+
+		shows, err := repo.GetWithFilters(ctx, repository.ShowAvailabilityFilters{})
+		require.NoError(t, err)
+		assert.False(t, contains(shows))
+
+		shows, err = repo.GetWithFilters(ctx, repository.ShowAvailabilityFilters{IncludeCancelled: true})
+		require.NoError(t, err)
+		assert.True(t, contains(shows))
+	})
+
+	t.Run("unknown show", func(t *testing.T) {
+		_, err := repo.GetByShowID(ctx, uuid.New())
+		assert.ErrorIs(t, err, repository.ErrShowNotFound)
+	})
+}
@@ -0,0 +1,471 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"tickets/internal/application/usecases/booking"
+	"tickets/internal/application/usecases/cancellation"
+	"tickets/internal/application/usecases/shows"
+	"tickets/internal/entities"
+	"tickets/internal/interfaces/message/events"
+	"tickets/internal/interfaces/message/outbox"
+	"tickets/internal/repository"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	watermillSQL "github.com/ThreeDotsLabs/watermill-sql/v2/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupOutbox creates the outbox table, usecases publish their events to it in their transactions.
+func setupOutbox(t *testing.T) {
+	subscriber, err := watermillSQL.NewSubscriber(
+		getDb(),
+		watermillSQL.SubscriberConfig{
+			SchemaAdapter:  watermillSQL.DefaultPostgreSQLSchema{},
+			OffsetsAdapter: watermillSQL.DefaultPostgreSQLOffsetsAdapter{},
+		},
+		watermill.NopLogger{},
+	)
+	require.NoError(t, err)
+	require.NoError(t, subscriber.SubscribeInitialize(outbox.Topic))
+}
+
+// countOutboxMessages counts the messages in the outbox whose payload contains the given text.
+// The forwarder wraps the messages in an envelope, with the original payload base64 encoded.
+func countOutboxMessages(t *testing.T, ctx context.Context, contains string) int {
+	var count int
+	err := getDb().QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT COUNT(*) FROM "watermill_%s" WHERE convert_from(decode(payload->>'payload', 'base64'), 'UTF8') LIKE '%%' || $1 || '%%'`,
+		outbox.Topic,
+	), contains).Scan(&count)
+	require.NoError(t, err)
+
+	return count
+}
+
+// newTestOutboxBus publishes events of the usecases to the outbox, it must be set up with setupOutbox.
+func newTestOutboxBus() *events.OutboxBusFactory {
+	return events.NewOutboxBusFactory(trmsqlx.DefaultCtxGetter, watermill.NopLogger{}, events.BusConfig{})
+}
+
+func newTestShow(t *testing.T, numberOfTickets int, startTime time.Time) uuid.UUID {
+	repo := repository.NewShowsRepo(getDb(), trmsqlx.DefaultCtxGetter)
+
+	showID, err := repo.CreateShow(context.Background(), entities.Show{
+		DeadNationId:    uuid.New(),
+		NumberOfTickets: numberOfTickets,
+		StartTime:       startTime,
+		Title:           "Test Show",
+		Venue:           "Test Venue",
+	})
+	require.NoError(t, err)
+
+	return showID
+}
+
+func newTestBookTicketsUsecase(t *testing.T) *booking.BookTicketsUsecase {
+	// BookingFailed_v1 of shows without enough tickets is published directly, nobody is listening in the tests
+	eb, err := events.NewEventBus(gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), watermill.NopLogger{}, events.BusConfig{})
+	require.NoError(t, err)
+
+	return booking.NewBookTicketsUsecase(
+		eb,
+		repository.NewBookingsRepo(getDb(), trmsqlx.DefaultCtxGetter),
+		repository.NewShowsRepo(getDb(), trmsqlx.DefaultCtxGetter),
+		manager.Must(trmsqlx.NewDefaultFactory(getDb())),
+		newTestOutboxBus(),
+	)
+}
+
+func newTestUpdateShowUsecase() *shows.UpdateShowUsecase {
+	return shows.NewUpdateShowUsecase(
+		repository.NewShowsRepo(getDb(), trmsqlx.DefaultCtxGetter),
+		repository.NewBookingsRepo(getDb(), trmsqlx.DefaultCtxGetter),
+		manager.Must(trmsqlx.NewDefaultFactory(getDb())),
+	)
+}
+
+func TestUpdateShow(t *testing.T) {
+	setupTestDB(t)
+	setupOutbox(t)
+
+	ctx := context.Background()
+	bookTickets := newTestBookTicketsUsecase(t)
+	updateShow := newTestUpdateShowUsecase()
+
+	showID := newTestShow(t, 10, time.Now().Add(24*time.Hour))
+	_, err := bookTickets.BookTickets(ctx, booking.CreateBookingReq{
+		ShowId:          showID,
+		NumberOfTickets: 4,
+		CustomerEmail:   "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	t.Run("change fields", func(t *testing.T) {
+		title := "New Title"
+		startTime := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+
+		show, err := updateShow.UpdateShow(ctx, shows.UpdateShowReq{
+			ShowID:    showID,
+			Title:     &title,
+			StartTime: &startTime,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "New Title", show.Title)
+		assert.Equal(t, "Test Venue", show.Venue)
+		assert.Equal(t, 10, show.NumberOfTickets)
+		assert.True(t, startTime.Equal(show.StartTime))
+	})
+
+	t.Run("lower capacity to booked tickets", func(t *testing.T) {
+		numberOfTickets := 4
+
+		show, err := updateShow.UpdateShow(ctx, shows.UpdateShowReq{ShowID: showID, NumberOfTickets: &numberOfTickets})
+		require.NoError(t, err)
+		assert.Equal(t, 4, show.NumberOfTickets)
+	})
+
+	t.Run("lower capacity below booked tickets", func(t *testing.T) {
+		numberOfTickets := 3
+
+		_, err := updateShow.UpdateShow(ctx, shows.UpdateShowReq{ShowID: showID, NumberOfTickets: &numberOfTickets})
+		assert.ErrorIs(t, err, entities.ErrShowCapacityBelowBookedTickets)
+
+		show, err := repository.NewShowsRepo(getDb(), trmsqlx.DefaultCtxGetter).GetShow(ctx, showID)
+		require.NoError(t, err)
+		assert.Equal(t, 4, show.NumberOfTickets)
+	})
+
+	t.Run("cancelled show", func(t *testing.T) {
+		showID := newTestShow(t, 10, time.Now().Add(24*time.Hour))
+		err := repository.NewShowsRepo(getDb(), trmsqlx.DefaultCtxGetter).CancelShow(ctx, entities.ShowCancellation{
+			ShowID:      showID,
+			Reason:      "cancelled",
+			CancelledAt: time.Now().UTC(),
+		})
+		require.NoError(t, err)
+
+		title := "New Title"
+		_, err = updateShow.UpdateShow(ctx, shows.UpdateShowReq{ShowID: showID, Title: &title})
+		assert.ErrorIs(t, err, entities.ErrShowCancelled)
+	})
+}
+
+// Bookings and capacity decreases running at the same time must never leave more tickets booked than the show has.
+func TestUpdateShow_ConcurrentBookings(t *testing.T) {
+	setupTestDB(t)
+	setupOutbox(t)
+
+	ctx := context.Background()
+	bookTickets := newTestBookTicketsUsecase(t)
+	updateShow := newTestUpdateShowUsecase()
+	showsRepo := repository.NewShowsRepo(getDb(), trmsqlx.DefaultCtxGetter)
+	bookingsRepo := repository.NewBookingsRepo(getDb(), trmsqlx.DefaultCtxGetter)
+
+	for i := 0; i < 20; i++ {
+		showID := newTestShow(t, 10, time.Now().Add(24*time.Hour))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 6)
+
+		for j := 0; j < 5; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// not enough tickets is not an error, the booking is just not created
+				_, err := bookTickets.BookTickets(ctx, booking.CreateBookingReq{
+					ShowId:          showID,
+					NumberOfTickets: 2,
+					CustomerEmail:   "customer@example.com",
+				})
+				if err != nil {
+					errs <- fmt.Errorf("book tickets: %w", err)
+				}
+			}()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			numberOfTickets := 4
+			_, err := updateShow.UpdateShow(ctx, shows.UpdateShowReq{ShowID: showID, NumberOfTickets: &numberOfTickets})
+			if err != nil && !errors.Is(err, entities.ErrShowCapacityBelowBookedTickets) {
+				errs <- fmt.Errorf("update show: %w", err)
+			}
+		}()
+
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		show, err := showsRepo.GetShow(ctx, showID)
+		require.NoError(t, err)
+		booked, err := bookingsRepo.GetBookingsCountByShowID(ctx, showID)
+		require.NoError(t, err)
+
+		assert.LessOrEqual(t, booked, int64(show.NumberOfTickets), "show %s is overbooked", showID)
+	}
+}
+
+// refundRequesterStub accepts all refunds except the denied tickets.
+type refundRequesterStub struct {
+	mu        sync.Mutex
+	denied    map[string]bool
+	requested []entities.RefundRequest
+}
+
+func (s *refundRequesterStub) RequestRefund(_ context.Context, req entities.RefundRequest) (entities.RefundDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requested = append(s.requested, req)
+	if s.denied[req.TicketID] {
+		return entities.RefundDecision{}, entities.ErrRefundDenied
+	}
+
+	return entities.RefundDecision{TicketID: req.TicketID}, nil
+}
+
+func newTestBooking(t *testing.T, showID uuid.UUID, numberOfTickets int) (uuid.UUID, []string) {
+	ctx := context.Background()
+
+	bookingID, err := repository.NewBookingsRepo(getDb(), trmsqlx.DefaultCtxGetter).CreateBooking(ctx, entities.Booking{
+		Id:              uuid.New(),
+		ShowId:          showID,
+		NumberOfTickets: numberOfTickets,
+		CustomerEmail:   "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	var ticketIDs []string
+	for i := 0; i < numberOfTickets; i++ {
+		ticketIDs = append(ticketIDs, newTestTicket(t, bookingID, showID, entities.TicketStatusConfirmed))
+	}
+
+	return bookingID, ticketIDs
+}
+
+func newTestTicket(t *testing.T, bookingID uuid.UUID, showID uuid.UUID, status entities.TicketStatus) string {
+	ticketID := uuid.NewString()
+	_, err := getDb().ExecContext(context.Background(), `
+		INSERT INTO tickets (ticket_id, price_amount, price_currency, customer_email, booking_id, show_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		ticketID, "100.00", "USD", "customer@example.com", bookingID, showID, status,
+	)
+	require.NoError(t, err)
+
+	return ticketID
+}
+
+func TestCancelShow(t *testing.T) {
+	setupTestDB(t)
+	setupOutbox(t)
+
+	ctx := context.Background()
+	trManager := manager.Must(trmsqlx.NewDefaultFactory(getDb()))
+	showsRepo := repository.NewShowsRepo(getDb(), trmsqlx.DefaultCtxGetter)
+	bookingsRepo := repository.NewBookingsRepo(getDb(), trmsqlx.DefaultCtxGetter)
+	cancellationsRepo := repository.NewBookingCancellationsRepo(getDb(), trmsqlx.DefaultCtxGetter)
+	ticketsRepo := repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, trManager)
+
+	cancelBooking := cancellation.NewCancelBookingUsecase(
+		cancellationsRepo,
+		bookingsRepo,
+		showsRepo,
+		entities.DefaultRefundPolicy(),
+		ticketsRepo,
+		trManager,
+		newTestOutboxBus(),
+	)
+	cancelShow := cancellation.NewCancelShowUsecase(
+		showsRepo,
+		bookingsRepo,
+		cancellationsRepo,
+		cancelBooking,
+		trManager,
+		newTestOutboxBus(),
+	)
+	refundRequester := &refundRequesterStub{denied: map[string]bool{}}
+	processManager := events.NewBookingCancellationProcessManager(
+		refundRequester,
+		cancellationsRepo,
+		bookingsRepo,
+		trManager,
+		newTestOutboxBus(),
+	)
+
+	// the organizer refunds everything, even right before the show
+	showID := newTestShow(t, 10, time.Now().Add(time.Hour))
+	refundedBookingID, refundedTicketIDs := newTestBooking(t, showID, 2)
+	deniedBookingID, deniedTicketIDs := newTestBooking(t, showID, 2)
+	refundRequester.denied[deniedTicketIDs[0]] = true
+	// the tickets are not confirmed yet
+	unconfirmedBookingID, err := bookingsRepo.CreateBooking(ctx, entities.Booking{
+		Id:              uuid.New(),
+		ShowId:          showID,
+		NumberOfTickets: 1,
+		CustomerEmail:   "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	showCancellation, err := cancelShow.CancelShow(ctx, cancellation.CancelShowReq{ShowID: showID, Reason: "venue flooded"})
+	require.NoError(t, err)
+	assert.Equal(t, "venue flooded", showCancellation.Reason)
+
+	t.Run("cancelling again returns the first cancellation", func(t *testing.T) {
+		again, err := cancelShow.CancelShow(ctx, cancellation.CancelShowReq{ShowID: showID, Reason: "other reason"})
+		require.NoError(t, err)
+		assert.Equal(t, "venue flooded", again.Reason)
+		assert.True(t, showCancellation.CancelledAt.Equal(again.CancelledAt))
+
+		assert.Equal(t, 1, countOutboxMessages(t, ctx, showID.String()), "ShowCancelled_v1 is published only once")
+	})
+
+	t.Run("unknown show", func(t *testing.T) {
+		_, err := cancelShow.CancelShow(ctx, cancellation.CancelShowReq{ShowID: uuid.New()})
+		assert.Error(t, err)
+	})
+
+	t.Run("booking cancelled show", func(t *testing.T) {
+		bookingID := uuid.New()
+
+		_, err := newTestBookTicketsUsecase(t).BookTickets(ctx, booking.CreateBookingReq{
+			BookingID:       &bookingID,
+			ShowId:          showID,
+			NumberOfTickets: 1,
+			CustomerEmail:   "customer@example.com",
+		})
+		assert.ErrorIs(t, err, entities.ErrShowCancelled)
+
+		assert.Equal(t, 1, countOutboxMessages(t, ctx, bookingID.String()), "BookingFailed_v1 is published through the outbox")
+
+		_, err = bookingsRepo.GetBooking(ctx, bookingID)
+		assert.ErrorIs(t, err, repository.ErrBookingNotFound)
+	})
+
+	event := &entities.ShowCancelled_v1{
+		Header:      entities.NewEventHeader(),
+		ShowID:      showID,
+		Reason:      showCancellation.Reason,
+		CancelledAt: showCancellation.CancelledAt,
+	}
+	require.NoError(t, cancelShow.OnShowCancelled(ctx, event))
+	// re-delivery
+	require.NoError(t, cancelShow.OnShowCancelled(ctx, event))
+
+	progress, err := cancelShow.GetProgress(ctx, showID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, progress.BookingsTotal)
+	assert.Equal(t, 0, progress.BookingsCancelled)
+	assert.Equal(t, 4, progress.TicketsTotal)
+	assert.Equal(t, 0, progress.TicketsRefunded)
+	assert.ElementsMatch(t, []uuid.UUID{refundedBookingID, deniedBookingID}, progress.BookingsInProgress)
+	assert.Equal(t, []uuid.UUID{unconfirmedBookingID}, progress.BookingsWaitingForTickets)
+	assert.Empty(t, progress.BookingsNotStarted)
+
+	for _, bookingID := range []uuid.UUID{refundedBookingID, deniedBookingID} {
+		c, err := cancellationsRepo.Get(ctx, bookingID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.RefundInitiatorOrganizer, c.Initiator)
+		assert.Equal(t, 2, c.Attempts)
+
+		err = processManager.OnBookingCancellationInitialized(ctx, &entities.BookingCancellationInitialized_v1{
+			Header:    entities.NewEventHeader(),
+			BookingID: bookingID,
+		})
+		require.NoError(t, err)
+	}
+
+	requestedTicketIDs := make([]string, 0, len(refundRequester.requested))
+	for _, req := range refundRequester.requested {
+		assert.Equal(t, entities.RefundInitiatorOrganizer, req.Initiator)
+		requestedTicketIDs = append(requestedTicketIDs, req.TicketID)
+	}
+	assert.ElementsMatch(t, append(append([]string{}, refundedTicketIDs...), deniedTicketIDs...), requestedTicketIDs)
+
+	for _, ticketID := range append([]string{deniedTicketIDs[1]}, refundedTicketIDs...) {
+		err := processManager.OnTicketRefunded(ctx, &entities.TicketRefunded_v1{
+			Header:   entities.NewEventHeader(),
+			TicketID: ticketID,
+		})
+		require.NoError(t, err)
+	}
+
+	progress, err = cancelShow.GetProgress(ctx, showID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, progress.BookingsCancelled)
+	assert.Equal(t, 4, progress.TicketsTotal)
+	assert.Equal(t, 4, progress.TicketsRefunded)
+	assert.Empty(t, progress.BookingsInProgress)
+	assert.Equal(t, []uuid.UUID{unconfirmedBookingID}, progress.BookingsWaitingForTickets)
+
+	denied, err := cancellationsRepo.Get(ctx, deniedBookingID)
+	require.NoError(t, err)
+	assert.True(t, denied.IsFinalized)
+	assert.Equal(t, entities.BookingCancellationTicketRefundDenied, denied.Tickets[deniedTicketIDs[0]].Status)
+
+	// the seats of the cancelled bookings are released, only the unconfirmed booking is left
+	booked, err := bookingsRepo.GetBookingsCountByShowID(ctx, showID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), booked)
+
+	t.Run("booking waiting for tickets is cancelled once they are confirmed", func(t *testing.T) {
+		confirmed := &entities.TicketBookingConfirmed_v1{
+			Header:        entities.NewEventHeader(),
+			TicketID:      uuid.NewString(),
+			CustomerEmail: "customer@example.com",
+			Price:         entities.MustNewMoney("100.00", "USD"),
+			BookingID:     unconfirmedBookingID.String(),
+		}
+
+		// the ticket is not stored yet, the event is retried
+		assert.Error(t, cancelShow.OnTicketBookingConfirmed(ctx, confirmed))
+
+		_, err := getDb().ExecContext(ctx, `
+			INSERT INTO tickets (ticket_id, price_amount, price_currency, customer_email, booking_id, show_id, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			confirmed.TicketID, "100.00", "USD", confirmed.CustomerEmail, unconfirmedBookingID, showID, entities.TicketStatusConfirmed,
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, cancelShow.OnTicketBookingConfirmed(ctx, confirmed))
+
+		c, err := cancellationsRepo.Get(ctx, unconfirmedBookingID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.RefundInitiatorOrganizer, c.Initiator)
+		assert.Equal(t, "venue flooded", c.Reason)
+		assert.Contains(t, c.Tickets, confirmed.TicketID)
+
+		progress, err := cancelShow.GetProgress(ctx, showID)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{unconfirmedBookingID}, progress.BookingsInProgress)
+		assert.Empty(t, progress.BookingsWaitingForTickets)
+		assert.Empty(t, progress.BookingsNotStarted)
+	})
+
+	t.Run("tickets of shows which are not cancelled", func(t *testing.T) {
+		otherShowID := newTestShow(t, 10, time.Now().Add(time.Hour))
+		bookingID, ticketIDs := newTestBooking(t, otherShowID, 1)
+
+		err := cancelShow.OnTicketBookingConfirmed(ctx, &entities.TicketBookingConfirmed_v1{
+			Header:    entities.NewEventHeader(),
+			TicketID:  ticketIDs[0],
+			BookingID: bookingID.String(),
+		})
+		require.NoError(t, err)
+
+		_, err = cancellationsRepo.Get(ctx, bookingID)
+		assert.ErrorIs(t, err, repository.ErrBookingCancellationNotFound)
+	})
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -12,6 +14,7 @@ import (
 	"testing"
 	"tickets/internal/entities"
 	"tickets/internal/repository"
+	"time"
 )
 
 var db *sqlx.DB
@@ -29,15 +32,7 @@ func getDb() *sqlx.DB {
 }
 
 func setupTestDB(t *testing.T) {
-	db := getDb()
-	_, err := db.Exec(`
-        CREATE TABLE IF NOT EXISTS tickets (
-            ticket_id UUID PRIMARY KEY,
-            price_amount DECIMAL NOT NULL,
-            price_currency VARCHAR(3) NOT NULL,
-            customer_email VARCHAR(255) NOT NULL
-        )
-    `)
+	err := repository.InitializeDBSchema(getDb())
 	require.NoError(t, err)
 }
 
@@ -51,7 +46,7 @@ func TestTicketsRepo_Create_Integration(t *testing.T) {
 	setupTestDB(t)
 	t.Cleanup(func() { cleanupTestDB(t) })
 
-	repo := repository.NewTicketsRepo(getDb())
+	repo := repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, manager.Must(trmsqlx.NewDefaultFactory(getDb())))
 	ctx := context.Background()
 
 	t.Run("successful creation and idempotency", func(t *testing.T) {
@@ -59,10 +54,7 @@ func TestTicketsRepo_Create_Integration(t *testing.T) {
 		ticket := &entities.Ticket{
 			TicketId:      ticketID.String(),
 			CustomerEmail: "test@example.com",
-			Price: entities.Money{
-				Amount:   "100.00",
-				Currency: "USD",
-			},
+			Price:         entities.MustNewMoney("100.00", "USD"),
 		}
 
 		// First creation
@@ -99,7 +91,7 @@ func TestTicketsRepo_Create_Integration(t *testing.T) {
 
 		require.NoError(t, err)
 		assert.Equal(t, ticketID.String(), savedTicket.TicketId)
-		//assert.Equal(t, "100.00", savedTicket.Price.Amount)
+		assert.Equal(t, "100.00", savedTicket.Price.AmountString())
 		assert.Equal(t, "USD", savedTicket.Price.Currency)
 		assert.Equal(t, "test@example.com", savedTicket.CustomerEmail)
 	})
@@ -108,10 +100,7 @@ func TestTicketsRepo_Create_Integration(t *testing.T) {
 		invalidTicket := &entities.Ticket{
 			TicketId:      "invalid-uuid",
 			CustomerEmail: "test@example.com",
-			Price: entities.Money{
-				Amount:   "100.00",
-				Currency: "USD",
-			},
+			Price:         entities.MustNewMoney("100.00", "USD"),
 		}
 
 		err := repo.Create(ctx, invalidTicket)
@@ -124,10 +113,7 @@ func TestTicketsRepo_Create_Integration(t *testing.T) {
 		ticket := &entities.Ticket{
 			TicketId:      ticketID.String(),
 			CustomerEmail: "concurrent@example.com",
-			Price: entities.Money{
-				Amount:   "200.00",
-				Currency: "EUR",
-			},
+			Price:         entities.MustNewMoney("200.00", "EUR"),
 		}
 
 		// Launch multiple goroutines trying to create the same ticket
@@ -153,3 +139,96 @@ func TestTicketsRepo_Create_Integration(t *testing.T) {
 		assert.Equal(t, 1, count)
 	})
 }
+
+func TestTicketsRepo_UpdateStatus_Integration(t *testing.T) {
+	setupTestDB(t)
+	t.Cleanup(func() { cleanupTestDB(t) })
+
+	repo := repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, manager.Must(trmsqlx.NewDefaultFactory(getDb())))
+	ctx := context.Background()
+
+	ticketID := uuid.New()
+	err := repo.Create(ctx, &entities.Ticket{
+		TicketId:      ticketID.String(),
+		CustomerEmail: "lifecycle@example.com",
+		Price:         entities.MustNewMoney("50.00", "USD"),
+	})
+	require.NoError(t, err)
+
+	getStatus := func() entities.TicketStatus {
+		var status entities.TicketStatus
+		err := getDb().QueryRow("SELECT status FROM tickets WHERE ticket_id = $1", ticketID).Scan(&status)
+		require.NoError(t, err)
+		return status
+	}
+
+	assert.Equal(t, entities.TicketStatusConfirmed, getStatus())
+
+	now := time.Now().UTC()
+
+	err = repo.UpdateStatus(ctx, ticketID, entities.TicketStatusReceiptIssued, now)
+	require.NoError(t, err)
+	assert.Equal(t, entities.TicketStatusReceiptIssued, getStatus())
+
+	// printed after the receipt was issued is only recorded in the history
+	err = repo.UpdateStatus(ctx, ticketID, entities.TicketStatusPrinted, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, entities.TicketStatusReceiptIssued, getStatus())
+
+	err = repo.UpdateStatus(ctx, ticketID, entities.TicketStatusRefunded, now.Add(2*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, entities.TicketStatusRefunded, getStatus())
+
+	// re-delivery
+	err = repo.UpdateStatus(ctx, ticketID, entities.TicketStatusRefunded, now.Add(3*time.Second))
+	require.NoError(t, err)
+
+	history, err := repo.GetStatusHistory(ctx, ticketID)
+	require.NoError(t, err)
+
+	statuses := make([]entities.TicketStatus, 0, len(history))
+	for _, change := range history {
+		statuses = append(statuses, change.Status)
+	}
+	assert.Equal(t, []entities.TicketStatus{
+		entities.TicketStatusConfirmed,
+		entities.TicketStatusReceiptIssued,
+		entities.TicketStatusPrinted,
+		entities.TicketStatusRefunded,
+	}, statuses)
+
+	err = repo.UpdateStatus(ctx, uuid.New(), entities.TicketStatusPrinted, now)
+	assert.ErrorIs(t, err, repository.ErrTicketNotFound)
+}
+
+func TestTicketsRepo_ChangeCustomer_Integration(t *testing.T) {
+	setupTestDB(t)
+	t.Cleanup(func() { cleanupTestDB(t) })
+
+	repo := repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, manager.Must(trmsqlx.NewDefaultFactory(getDb())))
+	ctx := context.Background()
+
+	ticketID := uuid.New()
+	err := repo.Create(ctx, &entities.Ticket{
+		TicketId:      ticketID.String(),
+		CustomerEmail: "seller@example.com",
+		Price:         entities.MustNewMoney("50.00", "USD"),
+	})
+	require.NoError(t, err)
+
+	ticket, err := repo.Get(ctx, ticketID)
+	require.NoError(t, err)
+	assert.Equal(t, "seller@example.com", ticket.CustomerEmail)
+	assert.Equal(t, 0, ticket.TokenVersion)
+
+	err = repo.ChangeCustomer(ctx, ticketID, "buyer@example.com")
+	require.NoError(t, err)
+
+	ticket, err = repo.Get(ctx, ticketID)
+	require.NoError(t, err)
+	assert.Equal(t, "buyer@example.com", ticket.CustomerEmail)
+	assert.Equal(t, 1, ticket.TokenVersion)
+
+	err = repo.ChangeCustomer(ctx, uuid.New(), "buyer@example.com")
+	assert.ErrorIs(t, err, repository.ErrTicketNotFound)
+}
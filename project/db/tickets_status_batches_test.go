@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"tickets/internal/application/usecases/tickets"
+	"tickets/internal/entities"
+	"tickets/internal/idempotency"
+	"tickets/internal/interfaces/message/outbox"
+	"tickets/internal/repository"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failOutboxPublishOf makes inserts to the outbox fail for messages with the given text in the payload,
+// as if the outbox was unavailable in the middle of the batch.
+func failOutboxPublishOf(t *testing.T, contains string) {
+	ctx := context.Background()
+	function := "fail_outbox_publish_" + uuid.NewString()[:8]
+
+	_, err := getDb().ExecContext(ctx, fmt.Sprintf(`
+		CREATE FUNCTION %s() RETURNS trigger AS $$
+		BEGIN
+			IF convert_from(decode(NEW.payload->>'payload', 'base64'), 'UTF8') LIKE '%%' || TG_ARGV[0] || '%%' THEN
+				RAISE EXCEPTION 'outbox unavailable';
+			END IF;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql`, function))
+	require.NoError(t, err)
+
+	_, err = getDb().ExecContext(ctx, fmt.Sprintf(
+		`CREATE TRIGGER %s BEFORE INSERT ON "watermill_%s" FOR EACH ROW EXECUTE FUNCTION %s('%s')`,
+		function, outbox.Topic, function, contains,
+	))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, err := getDb().ExecContext(ctx, fmt.Sprintf(`DROP TRIGGER %s ON "watermill_%s"`, function, outbox.Topic))
+		assert.NoError(t, err)
+		_, err = getDb().ExecContext(ctx, fmt.Sprintf(`DROP FUNCTION %s`, function))
+		assert.NoError(t, err)
+	})
+}
+
+func TestProcessTickets_Outbox(t *testing.T) {
+	setupTestDB(t)
+	setupOutbox(t)
+
+	trManager := manager.Must(trmsqlx.NewDefaultFactory(getDb()))
+	batchesRepo := repository.NewTicketsStatusBatchesRepo(getDb(), trmsqlx.DefaultCtxGetter)
+	usecase := tickets.NewTicketConfirmationService(
+		repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, trManager),
+		batchesRepo,
+		trManager,
+		newTestOutboxBus(),
+	)
+
+	newTickets := func() []entities.Ticket {
+		return []entities.Ticket{
+			{TicketId: uuid.NewString(), Status: "confirmed", CustomerEmail: "customer@example.com", Price: entities.MustNewMoney("50.00", "EUR")},
+			{TicketId: uuid.NewString(), Status: "canceled", CustomerEmail: "customer@example.com", Price: entities.MustNewMoney("50.00", "EUR")},
+			{TicketId: uuid.NewString(), Status: "confirmed", CustomerEmail: "customer@example.com", Price: entities.MustNewMoney("50.00", "EUR")},
+		}
+	}
+	countPublished := func(t *testing.T, batch []entities.Ticket) []int {
+		published := make([]int, 0, len(batch))
+		for _, ticket := range batch {
+			published = append(published, countOutboxMessages(t, context.Background(), ticket.TicketId))
+		}
+		return published
+	}
+	countBatches := func(t *testing.T, idempotencyKey string) int {
+		var count int
+		err := getDb().QueryRowContext(
+			context.Background(),
+			`SELECT COUNT(*) FROM tickets_status_batches WHERE idempotency_key = $1`,
+			idempotencyKey,
+		).Scan(&count)
+		require.NoError(t, err)
+		return count
+	}
+
+	t.Run("publishing fails in the middle of the batch", func(t *testing.T) {
+		idempotencyKey := uuid.NewString()
+		ctx := idempotency.WithKey(context.Background(), idempotencyKey)
+		batch := newTickets()
+
+		failOutboxPublishOf(t, batch[2].TicketId)
+
+		_, _, err := usecase.ProcessTickets(ctx, batch)
+		require.Error(t, err)
+
+		assert.Equal(t, []int{0, 0, 0}, countPublished(t, batch), "events of the first tickets are rolled back")
+		assert.Equal(t, 0, countBatches(t, idempotencyKey), "the batch is not stored, so it can be retried")
+	})
+
+	t.Run("retried after the failure", func(t *testing.T) {
+		idempotencyKey := uuid.NewString()
+		ctx := idempotency.WithKey(context.Background(), idempotencyKey)
+		batch := newTickets()
+
+		t.Run("failed", func(t *testing.T) {
+			failOutboxPublishOf(t, batch[2].TicketId)
+
+			_, _, err := usecase.ProcessTickets(ctx, batch)
+			require.Error(t, err)
+		})
+
+		processed, replayed, err := usecase.ProcessTickets(ctx, batch)
+		require.NoError(t, err)
+		assert.False(t, replayed)
+		assert.Equal(t, []int{1, 1, 1}, countPublished(t, batch))
+
+		again, replayed, err := usecase.ProcessTickets(ctx, batch)
+		require.NoError(t, err)
+		assert.True(t, replayed)
+		assert.Equal(t, processed.BatchID, again.BatchID)
+		assert.Equal(t, []int{1, 1, 1}, countPublished(t, batch), "the replayed batch publishes nothing")
+	})
+
+	t.Run("rolled back with the outer transaction", func(t *testing.T) {
+		idempotencyKey := uuid.NewString()
+		ctx := idempotency.WithKey(context.Background(), idempotencyKey)
+		batch := newTickets()
+
+		errRollback := fmt.Errorf("request cancelled")
+		err := trManager.Do(ctx, func(ctx context.Context) error {
+			_, _, err := usecase.ProcessTickets(ctx, batch)
+			require.NoError(t, err)
+
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+
+		assert.Equal(t, []int{0, 0, 0}, countPublished(t, batch))
+		assert.Equal(t, 0, countBatches(t, idempotencyKey))
+	})
+}
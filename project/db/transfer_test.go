@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"tickets/internal/application/usecases/transfer"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTransferTicketUsecase() *transfer.TransferTicketUsecase {
+	trManager := manager.Must(trmsqlx.NewDefaultFactory(getDb()))
+
+	return transfer.NewTransferTicketUsecase(
+		repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, trManager),
+		repository.NewTicketTransfersRepo(getDb(), trmsqlx.DefaultCtxGetter),
+		trManager,
+		newTestOutboxBus(),
+	)
+}
+
+func TestTransferTicket_TokenVersion(t *testing.T) {
+	setupTestDB(t)
+	setupOutbox(t)
+
+	ctx := context.Background()
+	transferTicket := newTestTransferTicketUsecase()
+	checkIn, signer := newTestCheckInUsecase(t)
+
+	showID := newTestShow(t, 10, time.Now().Add(time.Hour))
+	bookingID := uuid.New()
+
+	t.Run("legacy token of a ticket which was never transferred", func(t *testing.T) {
+		ticketID := newTestTicket(t, bookingID, showID, entities.TicketStatusPrinted)
+
+		_, err := checkIn.CheckIn(ctx, signer.Sign(ticketID, 0))
+		assert.NoError(t, err)
+	})
+
+	t.Run("token printed before the transfer", func(t *testing.T) {
+		ticketID := newTestTicket(t, bookingID, showID, entities.TicketStatusPrinted)
+		oldToken := signer.Sign(ticketID, 0)
+
+		_, err := transferTicket.RequestTransfer(ctx, transfer.TransferTicketReq{
+			TicketID: uuid.MustParse(ticketID),
+			ToEmail:  "friend@example.com",
+		})
+		require.NoError(t, err)
+
+		_, err = checkIn.CheckIn(ctx, oldToken)
+		assert.ErrorIs(t, err, entities.ErrTicketNotValid)
+
+		// the ticket reprinted for the recipient
+		_, err = checkIn.CheckIn(ctx, signer.Sign(ticketID, 1))
+		assert.NoError(t, err)
+	})
+
+	t.Run("token of the previous transfer", func(t *testing.T) {
+		ticketID := newTestTicket(t, bookingID, showID, entities.TicketStatusPrinted)
+
+		for _, email := range []string{"friend@example.com", "other-friend@example.com"} {
+			_, err := transferTicket.RequestTransfer(ctx, transfer.TransferTicketReq{
+				TicketID: uuid.MustParse(ticketID),
+				ToEmail:  email,
+			})
+			require.NoError(t, err)
+		}
+
+		_, err := checkIn.CheckIn(ctx, signer.Sign(ticketID, 1))
+		assert.ErrorIs(t, err, entities.ErrTicketNotValid)
+
+		_, err = checkIn.CheckIn(ctx, signer.Sign(ticketID, 2))
+		assert.NoError(t, err)
+	})
+}
+
+// Accepting and declining the same transfer at once resolves it only once, both lock the ticket first.
+func TestTransferTicket_AcceptAndDeclineConcurrently(t *testing.T) {
+	setupTestDB(t)
+	setupOutbox(t)
+
+	ctx := context.Background()
+	transferTicket := newTestTransferTicketUsecase()
+	ticketsRepo := repository.NewTicketsRepo(getDb(), trmsqlx.DefaultCtxGetter, manager.Must(trmsqlx.NewDefaultFactory(getDb())))
+
+	showID := newTestShow(t, 10, time.Now().Add(time.Hour))
+
+	for i := 0; i < 10; i++ {
+		ticketID := uuid.MustParse(newTestTicket(t, uuid.New(), showID, entities.TicketStatusPrinted))
+
+		requested, err := transferTicket.RequestTransfer(ctx, transfer.TransferTicketReq{
+			TicketID:           ticketID,
+			ToEmail:            "friend@example.com",
+			RequiresAcceptance: true,
+		})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		var acceptErr, declineErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, acceptErr = transferTicket.AcceptTransfer(ctx, ticketID, requested.TransferID)
+		}()
+		go func() {
+			defer wg.Done()
+			_, declineErr = transferTicket.DeclineTransfer(ctx, ticketID, requested.TransferID)
+		}()
+		wg.Wait()
+
+		ticket, err := ticketsRepo.Get(ctx, ticketID)
+		require.NoError(t, err)
+
+		if acceptErr == nil {
+			assert.ErrorIs(t, declineErr, entities.ErrTicketTransferNotPending)
+			assert.Equal(t, "friend@example.com", ticket.CustomerEmail)
+			assert.Equal(t, 1, ticket.TokenVersion)
+		} else {
+			require.NoError(t, declineErr)
+			assert.ErrorIs(t, acceptErr, entities.ErrTicketTransferNotPending)
+			assert.Equal(t, "customer@example.com", ticket.CustomerEmail)
+			assert.Equal(t, 0, ticket.TokenVersion)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"tickets/internal/application/usecases/webhooks"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookStubRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// webhookStub is a partner endpoint responding with the current status.
+type webhookStub struct {
+	*httptest.Server
+
+	status atomic.Int32
+
+	mu       sync.Mutex
+	requests []webhookStubRequest
+}
+
+func newWebhookStub(t *testing.T, status int) *webhookStub {
+	stub := &webhookStub{}
+	stub.status.Store(int32(status))
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, webhookStubRequest{Header: r.Header.Clone(), Body: body})
+		stub.mu.Unlock()
+
+		w.WriteHeader(int(stub.status.Load()))
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+func (s *webhookStub) Requests() []webhookStubRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]webhookStubRequest(nil), s.requests...)
+}
+
+func TestWebhooks_Integration(t *testing.T) {
+	setupTestDB(t)
+
+	ctx := context.Background()
+	trManager := manager.Must(trmsqlx.NewDefaultFactory(getDb()))
+	repo := repository.NewWebhooksRepo(getDb(), trmsqlx.DefaultCtxGetter)
+	// the stubs listen on the loopback interface
+	usecase := webhooks.NewWebhooksUsecase(repo, trManager, webhooks.EndpointPolicy{AllowPrivateHosts: true})
+
+	config := webhooks.DispatcherConfig{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		MaxBackoff:           10 * time.Millisecond,
+		DisableAfterFailures: 1,
+		RequestTimeout:       time.Second,
+		PollInterval:         10 * time.Millisecond,
+		BatchSize:            100,
+	}
+	dispatcher := webhooks.NewDispatcher(repo, trManager, http.DefaultClient, config)
+
+	// deliveries of other tests may be due as well, so the dispatcher runs until the delivery is done
+	deliverUntil := func(t *testing.T, deliveryID uuid.UUID, status entities.WebhookDeliveryStatus) entities.WebhookDelivery {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			_, err := dispatcher.DeliverDue(ctx)
+			require.NoError(t, err)
+
+			delivery, err := repo.GetDelivery(ctx, deliveryID)
+			require.NoError(t, err)
+
+			if delivery.Status == status {
+				return delivery
+			}
+			require.True(t, time.Now().Before(deadline), "delivery is %s, expected %s", delivery.Status, status)
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	enqueue := func(t *testing.T, subscriptionID uuid.UUID, eventName string) (uuid.UUID, entities.WebhookDelivery) {
+		eventID := uuid.New()
+		payload := []byte(`{"header":{"id":"` + eventID.String() + `"},"booking_id":"` + uuid.NewString() + `"}`)
+
+		err := usecase.Enqueue(ctx, eventID, eventName, payload)
+		require.NoError(t, err)
+		// events are redelivered by the broker
+		err = usecase.Enqueue(ctx, eventID, eventName, payload)
+		require.NoError(t, err)
+
+		deliveries, err := usecase.GetDeliveries(ctx, subscriptionID, 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, eventID, deliveries[0].EventID)
+
+		return eventID, deliveries[0]
+	}
+
+	t.Run("deliver signed payload", func(t *testing.T) {
+		stub := newWebhookStub(t, http.StatusNoContent)
+		eventName := "BookingMade_v1"
+
+		subscription, err := usecase.Register(ctx, webhooks.RegisterReq{
+			URL:        stub.URL,
+			EventNames: []string{eventName},
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, subscription.Secret)
+
+		eventID, delivery := enqueue(t, subscription.SubscriptionID, eventName)
+
+		delivery = deliverUntil(t, delivery.DeliveryID, entities.WebhookDeliveryStatusDelivered)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.NotNil(t, delivery.DeliveredAt)
+
+		requests := stub.Requests()
+		require.Len(t, requests, 1)
+		req := requests[0]
+
+		assert.Equal(t, eventID.String(), req.Header.Get(webhooks.HeaderWebhookID))
+		assert.Equal(t, eventName, req.Header.Get(webhooks.HeaderWebhookEvent))
+
+		timestamp, err := strconv.ParseInt(req.Header.Get(webhooks.HeaderWebhookTimestamp), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			webhooks.Sign(subscription.Secret, time.Unix(timestamp, 0), req.Body),
+			req.Header.Get(webhooks.HeaderWebhookSignature),
+		)
+		assert.NotEqual(
+			t,
+			webhooks.Sign("other secret", time.Unix(timestamp, 0), req.Body),
+			req.Header.Get(webhooks.HeaderWebhookSignature),
+		)
+
+		var payload webhooks.WebhookPayload
+		require.NoError(t, json.Unmarshal(req.Body, &payload))
+		assert.Equal(t, eventID, payload.EventID)
+		assert.Equal(t, eventName, payload.EventName)
+		assert.JSONEq(t, string(delivery.Payload), string(payload.Data))
+	})
+
+	t.Run("retry, disable failing endpoint and redeliver", func(t *testing.T) {
+		stub := newWebhookStub(t, http.StatusInternalServerError)
+		eventName := "TicketRefunded_v1"
+
+		subscription, err := usecase.Register(ctx, webhooks.RegisterReq{
+			URL:        stub.URL,
+			EventNames: []string{eventName},
+		})
+		require.NoError(t, err)
+
+		_, delivery := enqueue(t, subscription.SubscriptionID, eventName)
+
+		delivery = deliverUntil(t, delivery.DeliveryID, entities.WebhookDeliveryStatusFailed)
+		assert.Equal(t, config.MaxAttempts, delivery.Attempts)
+		assert.Contains(t, delivery.LastError, "500")
+		assert.Len(t, stub.Requests(), config.MaxAttempts)
+
+		_, attempts, err := usecase.GetDelivery(ctx, delivery.DeliveryID)
+		require.NoError(t, err)
+		require.Len(t, attempts, config.MaxAttempts)
+		for _, attempt := range attempts {
+			assert.Equal(t, http.StatusInternalServerError, attempt.StatusCode)
+			assert.False(t, attempt.Succeeded())
+		}
+
+		subscription, err = repo.GetSubscription(ctx, subscription.SubscriptionID)
+		require.NoError(t, err)
+		assert.True(t, subscription.IsDisabled())
+
+		// events of disabled subscriptions are queued until they are enabled
+		err = usecase.Enqueue(ctx, uuid.New(), eventName, []byte(`{}`))
+		require.NoError(t, err)
+
+		deliveries, err := usecase.GetDeliveries(ctx, subscription.SubscriptionID, 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		queued := deliveries[0]
+
+		_, err = dispatcher.DeliverDue(ctx)
+		require.NoError(t, err)
+		assert.Len(t, stub.Requests(), config.MaxAttempts)
+
+		_, err = usecase.Redeliver(ctx, delivery.DeliveryID)
+		assert.ErrorIs(t, err, entities.ErrWebhookSubscriptionDisabled)
+
+		stub.status.Store(http.StatusOK)
+
+		subscription, err = usecase.Enable(ctx, subscription.SubscriptionID)
+		require.NoError(t, err)
+		assert.False(t, subscription.IsDisabled())
+		assert.Empty(t, subscription.Secret)
+
+		deliverUntil(t, queued.DeliveryID, entities.WebhookDeliveryStatusDelivered)
+
+		delivery, err = usecase.Redeliver(ctx, delivery.DeliveryID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.WebhookDeliveryStatusPending, delivery.Status)
+
+		_, err = usecase.Redeliver(ctx, delivery.DeliveryID)
+		assert.ErrorIs(t, err, entities.ErrWebhookDeliveryPending)
+
+		delivery = deliverUntil(t, delivery.DeliveryID, entities.WebhookDeliveryStatusDelivered)
+		assert.Equal(t, 1, delivery.Attempts)
+
+		_, attempts, err = usecase.GetDelivery(ctx, delivery.DeliveryID)
+		require.NoError(t, err)
+		require.Len(t, attempts, config.MaxAttempts+1)
+		assert.True(t, attempts[len(attempts)-1].Succeeded())
+	})
+
+	t.Run("reject invalid subscription", func(t *testing.T) {
+		_, err := usecase.Register(ctx, webhooks.RegisterReq{
+			URL:        "ftp://example.com",
+			EventNames: []string{"BookingMade_v1"},
+		})
+		assert.ErrorIs(t, err, entities.ErrInvalidWebhookSubscription)
+
+		_, err = usecase.Register(ctx, webhooks.RegisterReq{
+			URL: "https://example.com/webhooks",
+		})
+		assert.ErrorIs(t, err, entities.ErrInvalidWebhookSubscription)
+
+		_, err = usecase.Register(ctx, webhooks.RegisterReq{
+			URL:        "https://example.com/webhooks",
+			EventNames: []string{"BookingMade_v1", "BookingMade"},
+		})
+		assert.ErrorIs(t, err, entities.ErrInvalidWebhookSubscription)
+
+		publicOnly := webhooks.NewWebhooksUsecase(repo, trManager, webhooks.EndpointPolicy{})
+		for _, endpoint := range []string{
+			"http://localhost:8080/webhooks",
+			"http://127.0.0.1/webhooks",
+			"http://169.254.169.254/latest/meta-data",
+			"http://10.0.0.1/webhooks",
+			"http://192.168.1.10/webhooks",
+			"http://[::1]/webhooks",
+		} {
+			_, err = publicOnly.Register(ctx, webhooks.RegisterReq{
+				URL:        endpoint,
+				EventNames: []string{"BookingMade_v1"},
+			})
+			assert.ErrorIs(t, err, entities.ErrInvalidWebhookSubscription, endpoint)
+		}
+	})
+
+	t.Run("don't dial private addresses", func(t *testing.T) {
+		stub := newWebhookStub(t, http.StatusNoContent)
+		eventName := "TicketPrinted_v1"
+
+		// the host may resolve to a private address only after the registration
+		subscription, err := usecase.Register(ctx, webhooks.RegisterReq{
+			URL:        stub.URL,
+			EventNames: []string{eventName},
+		})
+		require.NoError(t, err)
+
+		_, delivery := enqueue(t, subscription.SubscriptionID, eventName)
+
+		publicOnly := webhooks.NewDispatcher(repo, trManager, webhooks.EndpointPolicy{}.HTTPClient(), config)
+		deadline := time.Now().Add(5 * time.Second)
+		for delivery.Status != entities.WebhookDeliveryStatusFailed {
+			require.True(t, time.Now().Before(deadline), "delivery is %s", delivery.Status)
+
+			_, err = publicOnly.DeliverDue(ctx)
+			require.NoError(t, err)
+
+			delivery, err = repo.GetDelivery(ctx, delivery.DeliveryID)
+			require.NoError(t, err)
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		assert.Contains(t, delivery.LastError, "not a public address")
+		assert.Empty(t, stub.Requests())
+	})
+}
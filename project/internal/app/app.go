@@ -6,11 +6,18 @@ import (
 	"fmt"
 	"os"
 	"tickets/internal/application/usecases/booking"
+	"tickets/internal/application/usecases/cancellation"
+	"tickets/internal/application/usecases/checkin"
+	"tickets/internal/application/usecases/opsbookings"
+	"tickets/internal/application/usecases/refund"
 	"tickets/internal/application/usecases/shows"
 	"tickets/internal/application/usecases/tickets"
+	"tickets/internal/application/usecases/transfer"
 	"tickets/internal/application/usecases/vipbundle"
+	"tickets/internal/application/usecases/webhooks"
 	"tickets/internal/entities"
 	"tickets/internal/infrastructure/event_publisher"
+	"tickets/internal/infrastructure/rendering"
 	"tickets/internal/interfaces/http"
 	"tickets/internal/interfaces/message"
 	"tickets/internal/interfaces/message/commands"
@@ -18,6 +25,8 @@ import (
 	outbox "tickets/internal/interfaces/message/outbox"
 	"tickets/internal/observability"
 	"tickets/internal/repository"
+	"tickets/internal/schema"
+	"tickets/internal/tickettoken"
 	"time"
 
 	commonHTTP "github.com/ThreeDotsLabs/go-event-driven/common/http"
@@ -26,7 +35,6 @@ import (
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
 	watermillSQL "github.com/ThreeDotsLabs/watermill-sql/v2/pkg/sql"
-	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
 	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
@@ -50,6 +58,43 @@ var (
 	})
 )
 
+// refundReplyTimeout is how long synchronous refunds wait for the payment to be refunded.
+const refundReplyTimeout = 10 * time.Second
+
+// idempotencyKeyLockTimeout is how long a request with an Idempotency-Key blocks its retries when it's never completed.
+const idempotencyKeyLockTimeout = time.Minute
+
+// eventPartitions is the number of partitions of partitioned events.
+const eventPartitions = 8
+
+// EventPartitioning keeps the events of each booking in order when the handlers are scaled horizontally.
+var EventPartitioning = events.Partitioning{
+	"BookingMade_v1":            {Key: "booking_id", Partitions: eventPartitions},
+	"TicketBookingConfirmed_v1": {Key: "booking_id", Partitions: eventPartitions},
+	"TicketReceiptIssued_v1":    {Key: "booking_id", Partitions: eventPartitions},
+	"TicketPrinted_v1":          {Key: "booking_id", Partitions: eventPartitions},
+	"TicketTransferred_v1":      {Key: "booking_id", Partitions: eventPartitions},
+	// refunds don't have the booking ID
+	"TicketRefunded_v1": {Key: "ticket_id", Partitions: eventPartitions},
+}
+
+// eventEncodings and commandEncodings select the encodings of sent messages, JSON by default.
+// Consumers read both encodings, so a message can be switched to protobuf once all its consumers are deployed, e.g.
+//
+//	schema.Encodings{ByName: map[string]schema.Encoding{"TicketPrinted_v1": schema.EncodingProtobuf}}
+var (
+	eventEncodings   = schema.Encodings{Default: schema.EncodingJSON}
+	commandEncodings = schema.Encodings{Default: schema.EncodingJSON}
+)
+
+// eventCloudEvents publishes the events as CloudEvents 1.0 when its Mode is set, it's disabled by default.
+// The binary mode only adds metadata, so it can be enabled anytime; consumers read the structured mode
+// since it was introduced, so switch to it only when no older instances consume the events.
+var eventCloudEvents = events.CloudEventsConfig{
+	Source:      "/svc-tickets",
+	SubjectKeys: []string{"booking_id", "ticket_id", "show_id", "vip_bundle_id"},
+}
+
 type App struct {
 	watermillLogger         watermill.LoggerAdapter
 	logger                  zerolog.Logger
@@ -58,6 +103,7 @@ type App struct {
 	db                      *sqlx.DB
 	eventsRepo              *repository.EventsRepository
 	opsBookingReadModelRepo *repository.OpsBookingReadModelRepo
+	webhookDispatcher       *webhooks.Dispatcher
 	traceProviver           *trace.TracerProvider
 }
 
@@ -72,7 +118,22 @@ func NewApp(
 	redisClient *redis.Client,
 	db *sqlx.DB,
 	tp *trace.TracerProvider,
+	ticketSigner *tickettoken.Signer,
+	refundPolicy entities.RefundPolicy,
 ) (*App, error) {
+	if err := EventPartitioning.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event partitioning: %w", err)
+	}
+	if err := eventEncodings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event encodings: %w", err)
+	}
+	if err := commandEncodings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid command encodings: %w", err)
+	}
+	if err := eventCloudEvents.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid CloudEvents config: %w", err)
+	}
+
 	trManager := manager.Must(trmsqlx.NewDefaultFactory(db))
 	var redisPublisher watermillMessage.Publisher
 	redisPublisher, err := redisstream.NewPublisher(redisstream.PublisherConfig{
@@ -88,31 +149,130 @@ func NewApp(
 	redisPublisher = observability.PublisherWithTracing{
 		Publisher: redisPublisher,
 	}
-	eventBus, err := events.NewEventBus(redisPublisher, watermillLogger)
+	eventBusConfig := events.BusConfig{
+		Partitioning: EventPartitioning,
+		Encodings:    eventEncodings,
+		CloudEvents:  eventCloudEvents,
+	}
+	eventBus, err := events.NewEventBus(redisPublisher, watermillLogger, eventBusConfig)
+	outboxBus := events.NewOutboxBusFactory(trmsqlx.DefaultCtxGetter, watermillLogger, eventBusConfig)
 
-	ticketsRepo := repository.NewTicketsRepo(db)
+	ticketsRepo := repository.NewTicketsRepo(db, trmsqlx.DefaultCtxGetter, trManager)
 	showsRepo := repository.NewShowsRepo(db, trmsqlx.DefaultCtxGetter)
 	bookingsRepo := repository.NewBookingsRepo(db, trmsqlx.DefaultCtxGetter)
 	opsBookingReadModelRepo := repository.NewOpsBookingReadModelRepo(
-		db, trmsqlx.DefaultCtxGetter, trManager, eventBus)
+		db, trmsqlx.DefaultCtxGetter, trManager, outboxBus)
+	showAvailabilityReadModelRepo := repository.NewShowAvailabilityReadModelRepo(
+		db, trmsqlx.DefaultCtxGetter, trManager)
+	showAttendanceReadModelRepo := repository.NewShowAttendanceReadModelRepo(db, trmsqlx.DefaultCtxGetter)
+	opsBookingUpdates := opsbookings.NewUpdatesBroadcaster()
 	eventsRepo := repository.NewEventsRepo(db)
 	vipBundleRepo := repository.NewVipBundle(db, trmsqlx.DefaultCtxGetter)
+	bookingCancellationsRepo := repository.NewBookingCancellationsRepo(db, trmsqlx.DefaultCtxGetter)
+	ticketTransfersRepo := repository.NewTicketTransfersRepo(db, trmsqlx.DefaultCtxGetter)
+	ticketsStatusBatchesRepo := repository.NewTicketsStatusBatchesRepo(db, trmsqlx.DefaultCtxGetter)
+	idempotentRequestsRepo := repository.NewIdempotentRequestsRepo(db, trmsqlx.DefaultCtxGetter, idempotencyKeyLockTimeout)
+	refundDecisionsRepo := repository.NewRefundDecisionsRepo(db, trmsqlx.DefaultCtxGetter)
+	commandsRepo := repository.NewCommandsRepo(db, trmsqlx.DefaultCtxGetter)
+	webhooksRepo := repository.NewWebhooksRepo(db, trmsqlx.DefaultCtxGetter)
+
+	commandBus, err := commands.NewBus(redisPublisher, watermillLogger, commandsRepo, trmsqlx.DefaultCtxGetter, commandEncodings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command bus: %w", err)
+	}
 
-	commandBus, err := commands.NewBus(redisPublisher, watermillLogger)
+	refundReplies, err := commands.NewReplyBackend[entities.RefundTicketResult](
+		redisClient,
+		redisPublisher,
+		watermillLogger,
+		refundReplyTimeout,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refund reply backend: %w", err)
+	}
 
-	ticketsService := tickets.NewTicketConfirmationService(eventBus, ticketsRepo)
+	ticketsService := tickets.NewTicketConfirmationService(
+		ticketsRepo,
+		ticketsStatusBatchesRepo,
+		trManager,
+		outboxBus,
+	)
 	showsService := shows.NewShowsService(showsRepo)
 	bookingsService := booking.NewBookTicketsUsecase(
 		eventBus,
 		bookingsRepo,
 		showsRepo,
 		trManager,
-		trmsqlx.DefaultCtxGetter,
-		watermillLogger,
+		outboxBus,
+	)
+	vipBundleCreateUsecase := vipbundle.NewCreateBundleUsecase(
+		vipBundleRepo,
+		bookingsService,
+		trManager,
+		outboxBus,
+	)
+
+	cancelBookingUsecase := cancellation.NewCancelBookingUsecase(
+		bookingCancellationsRepo,
+		bookingsRepo,
+		showsRepo,
+		refundPolicy,
+		ticketsRepo,
+		trManager,
+		outboxBus,
+	)
+
+	updateShowUsecase := shows.NewUpdateShowUsecase(showsRepo, bookingsRepo, trManager)
+	cancelShowUsecase := cancellation.NewCancelShowUsecase(
+		showsRepo,
+		bookingsRepo,
+		bookingCancellationsRepo,
+		cancelBookingUsecase,
+		trManager,
+		outboxBus,
+	)
+
+	checkInUsecase := checkin.NewCheckInUsecase(
+		ticketSigner,
+		ticketsRepo,
+		trManager,
+		outboxBus,
 	)
-	vipBundleCreateUsecase := vipbundle.NewCreateBundleUsecase(vipBundleRepo, bookingsService, trManager, trmsqlx.DefaultCtxGetter, watermillLogger)
 
-	vipBundleEventHandler := events.NewVipBundleProcessManager(commandBus, eventBus, vipBundleRepo)
+	transferTicketUsecase := transfer.NewTransferTicketUsecase(
+		ticketsRepo,
+		ticketTransfersRepo,
+		trManager,
+		outboxBus,
+	)
+
+	refundTicketUsecase := refund.NewRefundTicketUsecase(
+		refundPolicy,
+		ticketsRepo,
+		showsRepo,
+		refundDecisionsRepo,
+		commandBus,
+		refundReplies,
+		trManager,
+	)
+
+	webhookEndpoints := webhooks.EndpointPolicy{}
+	webhooksUsecase := webhooks.NewWebhooksUsecase(webhooksRepo, trManager, webhookEndpoints)
+	webhookDispatcher := webhooks.NewDispatcher(
+		webhooksRepo,
+		trManager,
+		webhookEndpoints.HTTPClient(),
+		webhooks.DefaultDispatcherConfig(),
+	)
+
+	vipBundleEventHandler := events.NewVipBundleProcessManager(commandBus, eventBus, vipBundleRepo, refundTicketUsecase, trManager)
+	bookingCancellationProcessManager := events.NewBookingCancellationProcessManager(
+		refundTicketUsecase,
+		bookingCancellationsRepo,
+		bookingsRepo,
+		trManager,
+		outboxBus,
+	)
 
 	e := commonHTTP.NewEcho()
 	srv := http.NewServer(
@@ -123,6 +283,18 @@ func NewApp(
 		bookingsService,
 		opsBookingReadModelRepo,
 		vipBundleCreateUsecase,
+		cancelBookingUsecase,
+		updateShowUsecase,
+		cancelShowUsecase,
+		showAvailabilityReadModelRepo,
+		checkInUsecase,
+		showAttendanceReadModelRepo,
+		transferTicketUsecase,
+		refundTicketUsecase,
+		commandsRepo,
+		idempotentRequestsRepo,
+		opsBookingUpdates,
+		webhooksUsecase,
 	)
 
 	redisSubscriber, err := redisstream.NewSubscriber(redisstream.SubscriberConfig{Client: redisClient}, watermillLogger)
@@ -151,6 +323,11 @@ func NewApp(
 		return nil, err
 	}
 
+	ticketRenderer, err := rendering.NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
 	eventHandler := events.NewHandler(
 		eventBus,
 		spreadsheetsClient,
@@ -159,6 +336,9 @@ func NewApp(
 		deadNationClient,
 		ticketsRepo,
 		showsRepo,
+		bookingsRepo,
+		ticketSigner,
+		ticketRenderer,
 	)
 
 	commandHandler := commands.NewHandler(
@@ -167,8 +347,24 @@ func NewApp(
 		receiptsClient,
 		bookingsService,
 		transportationClient,
+		refundReplies,
 	)
 
+	routerMarshaler, err := schema.NewMarshaler(eventEncodings)
+	if err != nil {
+		return nil, err
+	}
+
+	eventProcessorConfig, err := events.NewEventProcessorConfig(redisClient, watermillLogger, EventPartitioning)
+	if err != nil {
+		return nil, err
+	}
+
+	commandProcessorConfig, err := commands.NewCommandProcessorConfig(redisClient, watermillLogger)
+	if err != nil {
+		return nil, err
+	}
+
 	router, err := message.NewRouter(
 		watermillLogger,
 		postgresSubscriber,
@@ -178,14 +374,20 @@ func NewApp(
 		eventHandler,
 		commandHandler,
 
-		cqrs.JSONMarshaler{
-			GenerateName: cqrs.StructName,
-		},
-		events.NewEventProcessorConfig(redisClient, watermillLogger),
-		commands.NewCommandProcessorConfig(redisClient, watermillLogger),
+		routerMarshaler,
+		eventProcessorConfig,
+		commandProcessorConfig,
 		eventsRepo,
 		opsBookingReadModelRepo,
+		opsBookingUpdates,
 		vipBundleEventHandler,
+		bookingCancellationProcessManager,
+		cancelShowUsecase,
+		showAvailabilityReadModelRepo,
+		showAttendanceReadModelRepo,
+		webhooksUsecase,
+		commandsRepo,
+		EventPartitioning,
 	)
 	if err != nil {
 		return nil, err
@@ -199,6 +401,7 @@ func NewApp(
 		db:                      db,
 		eventsRepo:              eventsRepo,
 		opsBookingReadModelRepo: opsBookingReadModelRepo,
+		webhookDispatcher:       webhookDispatcher,
 		traceProviver:           tp,
 	}, nil
 }
@@ -228,6 +431,13 @@ func (a *App) Run(ctx context.Context) error {
 		return a.srv.Start()
 	})
 
+	g.Go(func() error {
+		<-a.router.Running()
+		a.logger.Info().Msg("starting webhook dispatcher")
+
+		return a.webhookDispatcher.Run(ctx)
+	})
+
 	g.Go(func() error {
 		a.logger.Info().Msg("migrating events")
 		err := a.MigrateEvents()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"tickets/internal/entities"
 	"tickets/internal/infrastructure/clients"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -32,7 +33,7 @@ type FileStorageService interface {
 //go:generate mockgen -destination=mocks/tickets_repository_mock.go -package=mocks . TicketsRepository
 type TicketsRepository interface {
 	Create(ctx context.Context, t *entities.Ticket) error
-	Delete(ctx context.Context, ticketID uuid.UUID) error
+	UpdateStatus(ctx context.Context, ticketID uuid.UUID, status entities.TicketStatus, changedAt time.Time) error
 }
 
 //go:generate mockgen -destination=mocks/shows_repository_mock.go -package=mocks . ShowsRepository
@@ -42,7 +43,7 @@ type ShowsRepository interface {
 
 //go:generate mockgen -destination=mocks/payments_service_mock.go -package=mocks . PaymentsService
 type PaymentsService interface {
-	Refund(ctx context.Context, ticketID, idempotencyKey string) error
+	Refund(ctx context.Context, ticketID, reason, idempotencyKey string) error
 }
 
 //go:generate mockgen -destination=mocks/transportation_service_mock.go -package=mocks . TransportationService
@@ -35,15 +35,15 @@ func (m *MockPaymentsService) EXPECT() *MockPaymentsServiceMockRecorder {
 }
 
 // Refund mocks base method.
-func (m *MockPaymentsService) Refund(arg0 context.Context, arg1, arg2 string) error {
+func (m *MockPaymentsService) Refund(arg0 context.Context, arg1, arg2, arg3 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Refund", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "Refund", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Refund indicates an expected call of Refund.
-func (mr *MockPaymentsServiceMockRecorder) Refund(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockPaymentsServiceMockRecorder) Refund(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockPaymentsService)(nil).Refund), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockPaymentsService)(nil).Refund), arg0, arg1, arg2, arg3)
 }
@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: tickets/internal/app (interfaces: TransportationService)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	clients "tickets/internal/infrastructure/clients"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockTransportationService is a mock of TransportationService interface.
+type MockTransportationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransportationServiceMockRecorder
+}
+
+// MockTransportationServiceMockRecorder is the mock recorder for MockTransportationService.
+type MockTransportationServiceMockRecorder struct {
+	mock *MockTransportationService
+}
+
+// NewMockTransportationService creates a new mock instance.
+func NewMockTransportationService(ctrl *gomock.Controller) *MockTransportationService {
+	mock := &MockTransportationService{ctrl: ctrl}
+	mock.recorder = &MockTransportationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransportationService) EXPECT() *MockTransportationServiceMockRecorder {
+	return m.recorder
+}
+
+// BookFlightTicket mocks base method.
+func (m *MockTransportationService) BookFlightTicket(arg0 context.Context, arg1 *clients.BookFlightTicketRequest) (*clients.BookFlightTicketResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BookFlightTicket", arg0, arg1)
+	ret0, _ := ret[0].(*clients.BookFlightTicketResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BookFlightTicket indicates an expected call of BookFlightTicket.
+func (mr *MockTransportationServiceMockRecorder) BookFlightTicket(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BookFlightTicket", reflect.TypeOf((*MockTransportationService)(nil).BookFlightTicket), arg0, arg1)
+}
+
+// BookTaxi mocks base method.
+func (m *MockTransportationService) BookTaxi(arg0 context.Context, arg1 *clients.BookTaxiRequest) (*clients.BookTaxiResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BookTaxi", arg0, arg1)
+	ret0, _ := ret[0].(*clients.BookTaxiResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BookTaxi indicates an expected call of BookTaxi.
+func (mr *MockTransportationServiceMockRecorder) BookTaxi(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BookTaxi", reflect.TypeOf((*MockTransportationService)(nil).BookTaxi), arg0, arg1)
+}
+
+// CancelFlightTickets mocks base method.
+func (m *MockTransportationService) CancelFlightTickets(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelFlightTickets", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelFlightTickets indicates an expected call of CancelFlightTickets.
+func (mr *MockTransportationServiceMockRecorder) CancelFlightTickets(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelFlightTickets", reflect.TypeOf((*MockTransportationService)(nil).CancelFlightTickets), arg0, arg1)
+}
@@ -7,15 +7,12 @@ import (
 	"fmt"
 	"tickets/internal/entities"
 	"tickets/internal/interfaces/message/events"
-	"tickets/internal/interfaces/message/outbox"
 	"tickets/internal/repository"
 	"time"
 
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
-	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
-	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 	"github.com/avito-tech/go-transaction-manager/trm/v2/settings"
 	"github.com/google/uuid"
@@ -30,16 +27,15 @@ type BookingsRepo interface {
 
 //go:generate mockgen -destination=mocks/mock_shows_repo.go -package=mocks tickets/internal/application/usecases/booking ShowsRepo
 type ShowsRepo interface {
-	GetShow(ctx context.Context, id uuid.UUID) (*entities.Show, error)
+	GetShowForUpdate(ctx context.Context, id uuid.UUID) (*entities.Show, error)
 }
 
 type BookTicketsUsecase struct {
-	eb              *cqrs.EventBus
-	bookingRepo     BookingsRepo
-	showsRepo       ShowsRepo
-	trManager       *trmanager.Manager
-	trGetter        *trmsqlx.CtxGetter
-	watermillLogger watermill.LoggerAdapter
+	eb          *cqrs.EventBus
+	bookingRepo BookingsRepo
+	showsRepo   ShowsRepo
+	trManager   *trmanager.Manager
+	outboxBus   *events.OutboxBusFactory
 }
 
 func NewBookTicketsUsecase(
@@ -47,16 +43,14 @@ func NewBookTicketsUsecase(
 	bookingRepo BookingsRepo,
 	showsRepo ShowsRepo,
 	trManager *trmanager.Manager,
-	trGetter *trmsqlx.CtxGetter,
-	watermillLogger watermill.LoggerAdapter,
+	outboxBus *events.OutboxBusFactory,
 ) *BookTicketsUsecase {
 	return &BookTicketsUsecase{
-		eb:              eb,
-		bookingRepo:     bookingRepo,
-		showsRepo:       showsRepo,
-		trManager:       trManager,
-		trGetter:        trGetter,
-		watermillLogger: watermillLogger,
+		eb:          eb,
+		bookingRepo: bookingRepo,
+		showsRepo:   showsRepo,
+		trManager:   trManager,
+		outboxBus:   outboxBus,
 	}
 }
 func WithRetry(attempts int, f func(context.Context) error) func(context.Context) error {
@@ -88,16 +82,21 @@ type CreateBookingReq struct {
 	ShowId          uuid.UUID
 	NumberOfTickets int
 	CustomerEmail   string
+	Locale          string
 }
 
+// BookTickets books the tickets if the show has enough of them left.
+// The show is locked until the booking is stored, so concurrent bookings and capacity changes
+// are checked against each other; READ COMMITTED makes bookings counted after the lock up to date.
 func (s *BookTicketsUsecase) BookTickets(ctx context.Context, req CreateBookingReq) (uuid.UUID, error) {
 	var id uuid.UUID
+	var showCancelled bool
 	var err error
 	err = s.trManager.DoWithSettings(
 		ctx,
 		trmsql.MustSettings(
 			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
+			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelReadCommitted}),
 		),
 		func(ctx context.Context) error {
 			var bookingID uuid.UUID
@@ -108,10 +107,29 @@ func (s *BookTicketsUsecase) BookTickets(ctx context.Context, req CreateBookingR
 			}
 
 			var show *entities.Show
-			show, err = s.showsRepo.GetShow(ctx, req.ShowId)
+			show, err = s.showsRepo.GetShowForUpdate(ctx, req.ShowId)
 			if err != nil {
 				return fmt.Errorf("failed to get show: %w", err)
 			}
+			if show.IsCancelled() {
+				eb, err := s.outboxBus.EventBus(ctx)
+				if err != nil {
+					return err
+				}
+
+				err = eb.Publish(ctx, &entities.BookingFailed_v1{
+					Header:        entities.NewEventHeader(),
+					BookingID:     bookingID,
+					FailureReason: "show is cancelled",
+				})
+				if err != nil {
+					return fmt.Errorf("failed to publish booking failed event: %w", err)
+				}
+
+				// the error is returned after the commit, otherwise the event would be rolled back
+				showCancelled = true
+				return nil
+			}
 			log.FromContext(ctx).Info("show number of tickets: ", show.NumberOfTickets)
 
 			var bookingsCount int64
@@ -139,6 +157,7 @@ func (s *BookTicketsUsecase) BookTickets(ctx context.Context, req CreateBookingR
 				ShowId:          req.ShowId,
 				NumberOfTickets: req.NumberOfTickets,
 				CustomerEmail:   req.CustomerEmail,
+				Locale:          req.Locale,
 			}
 
 			id, err = s.bookingRepo.CreateBooking(ctx, booking)
@@ -149,19 +168,9 @@ func (s *BookTicketsUsecase) BookTickets(ctx context.Context, req CreateBookingR
 				return fmt.Errorf("failed to create booking: %w", err)
 			}
 
-			tr := s.trGetter.DefaultTrOrDB(ctx, nil)
-			if tr == nil {
-				return fmt.Errorf("failed to get transaction from context")
-			}
-
-			publisher, err := outbox.NewPublisher(tr, s.watermillLogger)
+			eb, err := s.outboxBus.EventBus(ctx)
 			if err != nil {
-				return fmt.Errorf("failed to create event publisher: %w", err)
-			}
-
-			eb, err := events.NewEventBus(publisher, s.watermillLogger)
-			if err != nil {
-				return fmt.Errorf("failed to create event bus: %w", err)
+				return err
 			}
 
 			log.FromContext(ctx).Info("publishing booking made event")
@@ -179,6 +188,9 @@ func (s *BookTicketsUsecase) BookTickets(ctx context.Context, req CreateBookingR
 
 			return nil
 		})
+	if err == nil && showCancelled {
+		return uuid.Nil, entities.ErrShowCancelled
+	}
 
 	return id, err
 
@@ -36,17 +36,17 @@ func (m *MockShowsRepo) EXPECT() *MockShowsRepoMockRecorder {
 	return m.recorder
 }
 
-// GetShow mocks base method.
-func (m *MockShowsRepo) GetShow(arg0 context.Context, arg1 uuid.UUID) (*entities.Show, error) {
+// GetShowForUpdate mocks base method.
+func (m *MockShowsRepo) GetShowForUpdate(arg0 context.Context, arg1 uuid.UUID) (*entities.Show, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetShow", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetShowForUpdate", arg0, arg1)
 	ret0, _ := ret[0].(*entities.Show)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetShow indicates an expected call of GetShow.
-func (mr *MockShowsRepoMockRecorder) GetShow(arg0, arg1 interface{}) *gomock.Call {
+// GetShowForUpdate indicates an expected call of GetShowForUpdate.
+func (mr *MockShowsRepoMockRecorder) GetShowForUpdate(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShow", reflect.TypeOf((*MockShowsRepo)(nil).GetShow), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShowForUpdate", reflect.TypeOf((*MockShowsRepo)(nil).GetShowForUpdate), arg0, arg1)
 }
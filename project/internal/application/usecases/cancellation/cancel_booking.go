@@ -0,0 +1,245 @@
+package cancellation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+	"tickets/internal/interfaces/message/events"
+	"tickets/internal/repository"
+	"time"
+
+	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/settings"
+	"github.com/google/uuid"
+)
+
+const DefaultReason = "customer requested cancellation"
+
+type BookingsRepo interface {
+	GetBooking(ctx context.Context, bookingID uuid.UUID) (*entities.Booking, error)
+}
+
+type Repository interface {
+	Add(ctx context.Context, cancellation entities.BookingCancellation) error
+	Get(ctx context.Context, bookingID uuid.UUID) (entities.BookingCancellation, error)
+	Update(
+		ctx context.Context,
+		bookingID uuid.UUID,
+		updateFn func(cancellation entities.BookingCancellation) (entities.BookingCancellation, error),
+	) (entities.BookingCancellation, error)
+}
+
+// BookingTicketsRepo is used to find out which tickets were issued for the booking.
+// It's read in the transaction of the cancellation, so no ticket confirmed meanwhile is missed.
+type BookingTicketsRepo interface {
+	List(ctx context.Context, filters repository.TicketsFilters) ([]entities.Ticket, error)
+}
+
+type CancelBookingUsecase struct {
+	repo         Repository
+	bookingsRepo BookingsRepo
+	showsRepo    ShowsRepo
+	refundPolicy entities.RefundPolicy
+	ticketsRepo  BookingTicketsRepo
+	trManager    *trmanager.Manager
+	outboxBus    *events.OutboxBusFactory
+}
+
+func NewCancelBookingUsecase(
+	repo Repository,
+	bookingsRepo BookingsRepo,
+	showsRepo ShowsRepo,
+	refundPolicy entities.RefundPolicy,
+	ticketsRepo BookingTicketsRepo,
+	trManager *trmanager.Manager,
+	outboxBus *events.OutboxBusFactory,
+) *CancelBookingUsecase {
+	return &CancelBookingUsecase{
+		repo:         repo,
+		bookingsRepo: bookingsRepo,
+		showsRepo:    showsRepo,
+		refundPolicy: refundPolicy,
+		ticketsRepo:  ticketsRepo,
+		trManager:    trManager,
+		outboxBus:    outboxBus,
+	}
+}
+
+type CancelBookingReq struct {
+	BookingID uuid.UUID
+	Reason    string
+	// Initiator is the customer by default, cancelled shows are refunded by the organizer rules.
+	Initiator entities.RefundInitiator
+}
+
+// CancelBooking starts the cancellation of the whole booking.
+// Calling it again for a cancellation which is not finalized yet re-sends refunds
+// for all tickets which were not refunded so far. The organizer takes over cancellations
+// started by the customer, so they are refunded by the organizer rules.
+func (u *CancelBookingUsecase) CancelBooking(ctx context.Context, req CancelBookingReq) (*entities.BookingCancellation, error) {
+	if req.Reason == "" {
+		req.Reason = DefaultReason
+	}
+	if req.Initiator == "" {
+		req.Initiator = entities.RefundInitiatorCustomer
+	}
+
+	var cancellation entities.BookingCancellation
+
+	err := u.trManager.DoWithSettings(
+		ctx,
+		trmsql.MustSettings(
+			settings.Must(settings.WithCancelable(true)),
+			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
+		),
+		func(ctx context.Context) error {
+			var err error
+
+			cancellation, err = u.repo.Update(ctx, req.BookingID, func(c entities.BookingCancellation) (entities.BookingCancellation, error) {
+				if c.IsFinalized {
+					return c, nil
+				}
+
+				c.Attempts++
+				if req.Initiator == entities.RefundInitiatorOrganizer && c.RefundInitiator() != entities.RefundInitiatorOrganizer {
+					c.CancelByOrganizer(req.Reason)
+				}
+				return c, nil
+			})
+			switch {
+			case errors.Is(err, repository.ErrBookingCancellationNotFound):
+				cancellation, err = u.newCancellation(ctx, req)
+				if err != nil {
+					return err
+				}
+
+				err = u.repo.Add(ctx, cancellation)
+				if err != nil {
+					return fmt.Errorf("add booking cancellation: %w", err)
+				}
+			case err != nil:
+				return fmt.Errorf("update booking cancellation: %w", err)
+			}
+
+			if cancellation.IsFinalized {
+				return nil
+			}
+
+			return u.publishInitialized(ctx, cancellation.BookingID)
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cancel booking: %w", err)
+	}
+
+	return &cancellation, nil
+}
+
+func (u *CancelBookingUsecase) GetCancellation(ctx context.Context, bookingID uuid.UUID) (*entities.BookingCancellation, error) {
+	cancellation, err := u.repo.Get(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cancellation, nil
+}
+
+func (u *CancelBookingUsecase) newCancellation(ctx context.Context, req CancelBookingReq) (entities.BookingCancellation, error) {
+	booking, err := u.bookingsRepo.GetBooking(ctx, req.BookingID)
+	if err != nil {
+		return entities.BookingCancellation{}, fmt.Errorf("get booking: %w", err)
+	}
+
+	requestedAt := time.Now().UTC()
+
+	show, err := u.showsRepo.GetShow(ctx, booking.ShowId)
+	if err != nil {
+		return entities.BookingCancellation{}, fmt.Errorf("get show: %w", err)
+	}
+	// refunds of each ticket are evaluated again with the price, but the time before the show is the same for all of them
+	if !u.refundPolicy.AllowsRefund(req.Initiator, show.StartTime, requestedAt) {
+		return entities.BookingCancellation{}, entities.ErrRefundDenied
+	}
+
+	tickets, err := u.bookingTickets(ctx, booking)
+	if err != nil {
+		return entities.BookingCancellation{}, err
+	}
+
+	return entities.BookingCancellation{
+		BookingID:       booking.Id,
+		ShowID:          booking.ShowId,
+		NumberOfTickets: booking.NumberOfTickets,
+		CustomerEmail:   booking.CustomerEmail,
+		Reason:          req.Reason,
+		Initiator:       req.Initiator,
+		Tickets:         tickets,
+		Attempts:        1,
+		RequestedAt:     requestedAt,
+	}, nil
+}
+
+// bookingTickets returns the tickets to refund, it fails until all tickets of the booking are confirmed,
+// otherwise tickets confirmed after the cancellation would never be refunded.
+func (u *CancelBookingUsecase) bookingTickets(
+	ctx context.Context,
+	booking *entities.Booking,
+) (map[string]entities.BookingCancellationTicket, error) {
+	active, err := u.ticketsRepo.List(ctx, repository.TicketsFilters{BookingID: &booking.Id})
+	if err != nil {
+		return nil, fmt.Errorf("get booking tickets: %w", err)
+	}
+	// cancelled by the provider, they are never refunded, but they were confirmed
+	cancelled, err := u.ticketsRepo.List(ctx, repository.TicketsFilters{
+		BookingID: &booking.Id,
+		Status:    entities.TicketStatusCancelled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get cancelled booking tickets: %w", err)
+	}
+
+	if len(active) == 0 {
+		return nil, entities.ErrBookingHasNoTickets
+	}
+	if len(active)+len(cancelled) < booking.NumberOfTickets {
+		return nil, fmt.Errorf(
+			"%w: %d of %d tickets confirmed",
+			entities.ErrBookingTicketsNotConfirmed, len(active)+len(cancelled), booking.NumberOfTickets,
+		)
+	}
+
+	tickets := make(map[string]entities.BookingCancellationTicket, len(active))
+	for _, t := range active {
+		ticket := entities.BookingCancellationTicket{
+			Status: entities.BookingCancellationTicketPending,
+		}
+		if entities.TicketStatus(t.Status) == entities.TicketStatusRefunded {
+			// refunded before the booking was cancelled
+			ticket.Status = entities.BookingCancellationTicketRefunded
+		}
+
+		tickets[t.TicketId] = ticket
+	}
+
+	return tickets, nil
+}
+
+func (u *CancelBookingUsecase) publishInitialized(ctx context.Context, bookingID uuid.UUID) error {
+	eb, err := u.outboxBus.EventBus(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = eb.Publish(ctx, &entities.BookingCancellationInitialized_v1{
+		Header:    entities.NewEventHeader(),
+		BookingID: bookingID,
+	})
+	if err != nil {
+		return fmt.Errorf("publish booking cancellation initialized event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package cancellation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"tickets/internal/entities"
+	"tickets/internal/interfaces/message/events"
+	"tickets/internal/repository"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+)
+
+const DefaultShowCancellationReason = "show cancelled by the organizer"
+
+type ShowsRepo interface {
+	GetShow(ctx context.Context, id uuid.UUID) (*entities.Show, error)
+	CancelShow(ctx context.Context, cancellation entities.ShowCancellation) error
+	GetShowCancellation(ctx context.Context, showID uuid.UUID) (*entities.ShowCancellation, error)
+}
+
+type ShowBookingsRepo interface {
+	GetBookingsByShowID(ctx context.Context, showID uuid.UUID) ([]entities.Booking, error)
+}
+
+type ShowBookingCancellationsRepo interface {
+	GetByShowID(ctx context.Context, showID uuid.UUID) ([]entities.BookingCancellation, error)
+}
+
+// CancelShowUsecase cancels the show and all its bookings.
+// Each booking is cancelled with CancelBookingUsecase, so all tickets are refunded the same way
+// as when the customer cancels the booking.
+type CancelShowUsecase struct {
+	showsRepo            ShowsRepo
+	bookingsRepo         ShowBookingsRepo
+	cancellationsRepo    ShowBookingCancellationsRepo
+	cancelBookingUsecase *CancelBookingUsecase
+	trManager            *trmanager.Manager
+	outboxBus            *events.OutboxBusFactory
+}
+
+func NewCancelShowUsecase(
+	showsRepo ShowsRepo,
+	bookingsRepo ShowBookingsRepo,
+	cancellationsRepo ShowBookingCancellationsRepo,
+	cancelBookingUsecase *CancelBookingUsecase,
+	trManager *trmanager.Manager,
+	outboxBus *events.OutboxBusFactory,
+) *CancelShowUsecase {
+	return &CancelShowUsecase{
+		showsRepo:            showsRepo,
+		bookingsRepo:         bookingsRepo,
+		cancellationsRepo:    cancellationsRepo,
+		cancelBookingUsecase: cancelBookingUsecase,
+		trManager:            trManager,
+		outboxBus:            outboxBus,
+	}
+}
+
+type CancelShowReq struct {
+	ShowID uuid.UUID
+	Reason string
+}
+
+// CancelShow marks the show as cancelled and emits ShowCancelled_v1.
+// Cancelling an already cancelled show doesn't emit the event again.
+func (u *CancelShowUsecase) CancelShow(ctx context.Context, req CancelShowReq) (*entities.ShowCancellation, error) {
+	if req.Reason == "" {
+		req.Reason = DefaultShowCancellationReason
+	}
+
+	var showCancellation *entities.ShowCancellation
+
+	err := u.trManager.Do(ctx, func(ctx context.Context) error {
+		_, err := u.showsRepo.GetShow(ctx, req.ShowID)
+		if err != nil {
+			return fmt.Errorf("get show: %w", err)
+		}
+
+		c := entities.ShowCancellation{
+			ShowID:      req.ShowID,
+			Reason:      req.Reason,
+			CancelledAt: time.Now().UTC(),
+		}
+
+		err = u.showsRepo.CancelShow(ctx, c)
+		if errors.Is(err, repository.ErrShowAlreadyCancelled) {
+			showCancellation, err = u.showsRepo.GetShowCancellation(ctx, req.ShowID)
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("cancel show: %w", err)
+		}
+		showCancellation = &c
+
+		eb, err := u.outboxBus.EventBus(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = eb.Publish(ctx, &entities.ShowCancelled_v1{
+			Header:      entities.NewEventHeader(),
+			ShowID:      c.ShowID,
+			Reason:      c.Reason,
+			CancelledAt: c.CancelledAt,
+		})
+		if err != nil {
+			return fmt.Errorf("publish show cancelled event: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return showCancellation, nil
+}
+
+// OnShowCancelled starts the cancellation of every booking of the show.
+// Re-delivery of the event retries refunds which didn't succeed so far.
+func (u *CancelShowUsecase) OnShowCancelled(ctx context.Context, event *entities.ShowCancelled_v1) error {
+	bookings, err := u.bookingsRepo.GetBookingsByShowID(ctx, event.ShowID)
+	if err != nil {
+		return fmt.Errorf("get show bookings: %w", err)
+	}
+
+	for _, booking := range bookings {
+		err := u.cancelBooking(ctx, booking.Id, event.Reason)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.FromContext(ctx).Info("Cancellation started for ", len(bookings), " bookings of show ", event.ShowID)
+
+	return nil
+}
+
+// OnTicketBookingConfirmed cancels bookings of a cancelled show which were waiting for their tickets.
+func (u *CancelShowUsecase) OnTicketBookingConfirmed(ctx context.Context, event *entities.TicketBookingConfirmed_v1) error {
+	bookingID, err := uuid.Parse(event.BookingID)
+	if err != nil {
+		// tickets booked outside of this service
+		return nil
+	}
+
+	// the ticket is stored by another handler, the cancellation started before it would miss the ticket
+	tickets, err := u.cancelBookingUsecase.ticketsRepo.List(ctx, repository.TicketsFilters{BookingID: &bookingID})
+	if err != nil {
+		return fmt.Errorf("get booking tickets: %w", err)
+	}
+	if !slices.ContainsFunc(tickets, func(t entities.Ticket) bool { return t.TicketId == event.TicketID }) {
+		return fmt.Errorf("ticket %s is not stored yet", event.TicketID)
+	}
+
+	booking, err := u.cancelBookingUsecase.bookingsRepo.GetBooking(ctx, bookingID)
+	if errors.Is(err, repository.ErrBookingNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get booking: %w", err)
+	}
+
+	showCancellation, err := u.showsRepo.GetShowCancellation(ctx, booking.ShowId)
+	if errors.Is(err, entities.ErrShowNotCancelled) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get show cancellation: %w", err)
+	}
+
+	return u.cancelBooking(ctx, bookingID, showCancellation.Reason)
+}
+
+// cancelBooking cancels the booking of a cancelled show.
+// Bookings with tickets which are not confirmed yet are cancelled by OnTicketBookingConfirmed
+// once their last ticket is confirmed, until then GetProgress reports them as waiting for tickets.
+func (u *CancelShowUsecase) cancelBooking(ctx context.Context, bookingID uuid.UUID, reason string) error {
+	_, err := u.cancelBookingUsecase.CancelBooking(ctx, CancelBookingReq{
+		BookingID: bookingID,
+		Reason:    reason,
+		Initiator: entities.RefundInitiatorOrganizer,
+	})
+	if isWaitingForTickets(err) {
+		log.FromContext(ctx).Info("Booking ", bookingID, " of cancelled show waits for its tickets to be confirmed")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cancel booking %s: %w", bookingID, err)
+	}
+
+	return nil
+}
+
+func isWaitingForTickets(err error) bool {
+	return errors.Is(err, entities.ErrBookingHasNoTickets) || errors.Is(err, entities.ErrBookingTicketsNotConfirmed)
+}
+
+func (u *CancelShowUsecase) GetProgress(ctx context.Context, showID uuid.UUID) (*entities.ShowCancellationProgress, error) {
+	showCancellation, err := u.showsRepo.GetShowCancellation(ctx, showID)
+	if err != nil {
+		return nil, err
+	}
+
+	bookings, err := u.bookingsRepo.GetBookingsByShowID(ctx, showID)
+	if err != nil {
+		return nil, fmt.Errorf("get show bookings: %w", err)
+	}
+
+	bookingCancellations, err := u.cancellationsRepo.GetByShowID(ctx, showID)
+	if err != nil {
+		return nil, fmt.Errorf("get booking cancellations: %w", err)
+	}
+
+	progress := entities.ShowCancellationProgress{
+		ShowCancellation:          *showCancellation,
+		BookingsTotal:             len(bookings),
+		BookingsInProgress:        []uuid.UUID{},
+		BookingsWaitingForTickets: []uuid.UUID{},
+		BookingsNotStarted:        []uuid.UUID{},
+	}
+
+	cancellationsByBookingID := make(map[uuid.UUID]entities.BookingCancellation, len(bookingCancellations))
+	for _, c := range bookingCancellations {
+		cancellationsByBookingID[c.BookingID] = c
+	}
+
+	for _, booking := range bookings {
+		c, ok := cancellationsByBookingID[booking.Id]
+		if !ok {
+			_, err := u.cancelBookingUsecase.bookingTickets(ctx, &booking)
+			switch {
+			case isWaitingForTickets(err):
+				progress.BookingsWaitingForTickets = append(progress.BookingsWaitingForTickets, booking.Id)
+			case err != nil:
+				return nil, err
+			default:
+				progress.BookingsNotStarted = append(progress.BookingsNotStarted, booking.Id)
+			}
+			continue
+		}
+
+		progress.TicketsTotal += len(c.Tickets)
+		progress.TicketsRefunded += len(c.Tickets) - len(c.PendingTicketIDs())
+
+		if c.IsFinalized {
+			progress.BookingsCancelled++
+		} else {
+			progress.BookingsInProgress = append(progress.BookingsInProgress, booking.Id)
+		}
+	}
+
+	return &progress, nil
+}
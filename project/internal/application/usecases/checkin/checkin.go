@@ -0,0 +1,106 @@
+package checkin
+
+import (
+	"context"
+	"fmt"
+	"tickets/internal/entities"
+	"tickets/internal/interfaces/message/events"
+	"tickets/internal/tickettoken"
+	"time"
+
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+)
+
+type TokenVerifier interface {
+	Verify(token string) (tickettoken.Claims, error)
+}
+
+type TicketsRepo interface {
+	GetForUpdate(ctx context.Context, ticketID uuid.UUID) (*entities.Ticket, error)
+	UpdateStatus(ctx context.Context, ticketID uuid.UUID, status entities.TicketStatus, changedAt time.Time) error
+}
+
+type CheckInUsecase struct {
+	tokenVerifier TokenVerifier
+	ticketsRepo   TicketsRepo
+	trManager     *trmanager.Manager
+	outboxBus     *events.OutboxBusFactory
+}
+
+func NewCheckInUsecase(
+	tokenVerifier TokenVerifier,
+	ticketsRepo TicketsRepo,
+	trManager *trmanager.Manager,
+	outboxBus *events.OutboxBusFactory,
+) *CheckInUsecase {
+	return &CheckInUsecase{
+		tokenVerifier: tokenVerifier,
+		ticketsRepo:   ticketsRepo,
+		trManager:     trManager,
+		outboxBus:     outboxBus,
+	}
+}
+
+// CheckIn validates the token scanned at the door. The ticket is locked while checking,
+// so the same ticket scanned at two gates at once is let in only once.
+func (u *CheckInUsecase) CheckIn(ctx context.Context, token string) (*entities.TicketCheckedIn_v1, error) {
+	claims, err := u.tokenVerifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	ticketID := claims.TicketID
+
+	var event entities.TicketCheckedIn_v1
+
+	err = u.trManager.Do(ctx, func(ctx context.Context) error {
+		ticket, err := u.ticketsRepo.GetForUpdate(ctx, ticketID)
+		if err != nil {
+			return fmt.Errorf("get ticket: %w", err)
+		}
+
+		if claims.Version != ticket.TokenVersion {
+			return fmt.Errorf("ticket was transferred to another customer: %w", entities.ErrTicketNotValid)
+		}
+
+		status := entities.TicketStatus(ticket.Status)
+		if status == entities.TicketStatusCheckedIn {
+			return entities.ErrTicketAlreadyCheckedIn
+		}
+		if !status.CanTransitionTo(entities.TicketStatusCheckedIn) {
+			return fmt.Errorf("ticket is %s: %w", status, entities.ErrTicketNotValid)
+		}
+
+		checkedInAt := time.Now().UTC()
+
+		err = u.ticketsRepo.UpdateStatus(ctx, ticketID, entities.TicketStatusCheckedIn, checkedInAt)
+		if err != nil {
+			return fmt.Errorf("update ticket status: %w", err)
+		}
+
+		eb, err := u.outboxBus.EventBus(ctx)
+		if err != nil {
+			return err
+		}
+
+		event = entities.TicketCheckedIn_v1{
+			Header:      entities.NewEventHeader(),
+			TicketID:    ticket.TicketId,
+			BookingID:   ticket.BookingId,
+			ShowID:      ticket.ShowId,
+			CheckedInAt: checkedInAt,
+		}
+
+		err = eb.Publish(ctx, &event)
+		if err != nil {
+			return fmt.Errorf("publish ticket checked in event: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
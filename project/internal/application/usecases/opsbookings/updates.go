@@ -0,0 +1,137 @@
+package opsbookings
+
+import (
+	"context"
+	"sync"
+	"tickets/internal/entities"
+
+	"github.com/google/uuid"
+)
+
+// UpdatesBroadcaster fans out InternalOpsReadModelUpdated from one subscription to all listeners.
+// It never blocks the subscription: updates of the same booking are coalesced until the listener takes them.
+type UpdatesBroadcaster struct {
+	mu        sync.Mutex
+	listeners map[*UpdatesListener]struct{}
+	closed    bool
+}
+
+func NewUpdatesBroadcaster() *UpdatesBroadcaster {
+	return &UpdatesBroadcaster{
+		listeners: map[*UpdatesListener]struct{}{},
+	}
+}
+
+// UpdatesListener receives the IDs of updated bookings.
+type UpdatesListener struct {
+	broadcaster *UpdatesBroadcaster
+	// bookingID is nil when listening to all bookings
+	bookingID *uuid.UUID
+
+	mu      sync.Mutex
+	pending []uuid.UUID
+	queued  map[uuid.UUID]struct{}
+	ready   chan struct{}
+	done    chan struct{}
+}
+
+// Listen returns a listener of the booking, or of all bookings when bookingID is nil.
+// The listener must be closed.
+func (b *UpdatesBroadcaster) Listen(bookingID *uuid.UUID) *UpdatesListener {
+	l := &UpdatesListener{
+		broadcaster: b,
+		bookingID:   bookingID,
+		queued:      map[uuid.UUID]struct{}{},
+		ready:       make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(l.done)
+	} else {
+		b.listeners[l] = struct{}{}
+	}
+
+	return l
+}
+
+func (b *UpdatesBroadcaster) OnOpsReadModelUpdated(ctx context.Context, event *entities.InternalOpsReadModelUpdated) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for l := range b.listeners {
+		if l.bookingID == nil || *l.bookingID == event.BookingID {
+			l.notify(event.BookingID)
+		}
+	}
+
+	return nil
+}
+
+// Close closes all listeners, for example before the HTTP server is stopped.
+func (b *UpdatesBroadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for l := range b.listeners {
+		close(l.done)
+	}
+	b.listeners = map[*UpdatesListener]struct{}{}
+}
+
+func (l *UpdatesListener) notify(bookingID uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.queued[bookingID]; !ok {
+		l.queued[bookingID] = struct{}{}
+		l.pending = append(l.pending, bookingID)
+	}
+
+	select {
+	case l.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready is signalled when there are updates to take.
+func (l *UpdatesListener) Ready() <-chan struct{} {
+	return l.ready
+}
+
+// Done is closed when the listener or the broadcaster is closed.
+func (l *UpdatesListener) Done() <-chan struct{} {
+	return l.done
+}
+
+// Take returns the IDs of bookings updated since the last call, in the order of their first update.
+func (l *UpdatesListener) Take() []uuid.UUID {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pending := l.pending
+	l.pending = nil
+	l.queued = map[uuid.UUID]struct{}{}
+
+	return pending
+}
+
+func (l *UpdatesListener) Close() {
+	b := l.broadcaster
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.listeners[l]; ok {
+		delete(b.listeners, l)
+		close(l.done)
+	}
+}
@@ -0,0 +1,113 @@
+package opsbookings
+
+import (
+	"context"
+	"testing"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func updated(t *testing.T, b *UpdatesBroadcaster, bookingID uuid.UUID) {
+	err := b.OnOpsReadModelUpdated(context.Background(), &entities.InternalOpsReadModelUpdated{BookingID: bookingID})
+	require.NoError(t, err)
+}
+
+func assertReady(t *testing.T, l *UpdatesListener) {
+	select {
+	case <-l.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("listener was not notified")
+	}
+}
+
+func assertNotReady(t *testing.T, l *UpdatesListener) {
+	select {
+	case <-l.Ready():
+		t.Fatal("listener was notified")
+	default:
+	}
+}
+
+func TestUpdatesBroadcaster(t *testing.T) {
+	b := NewUpdatesBroadcaster()
+
+	bookingID := uuid.New()
+	otherBookingID := uuid.New()
+
+	all := b.Listen(nil)
+	defer all.Close()
+	one := b.Listen(&bookingID)
+	defer one.Close()
+
+	t.Run("updates are coalesced until taken", func(t *testing.T) {
+		updated(t, b, bookingID)
+		updated(t, b, otherBookingID)
+		updated(t, b, bookingID)
+
+		assertReady(t, all)
+		assert.Equal(t, []uuid.UUID{bookingID, otherBookingID}, all.Take())
+		assertNotReady(t, all)
+		assert.Empty(t, all.Take())
+
+		assertReady(t, one)
+		assert.Equal(t, []uuid.UUID{bookingID}, one.Take())
+	})
+
+	t.Run("listener of other booking", func(t *testing.T) {
+		updated(t, b, otherBookingID)
+
+		assertReady(t, all)
+		assert.Equal(t, []uuid.UUID{otherBookingID}, all.Take())
+		assertNotReady(t, one)
+	})
+
+	t.Run("closed listener", func(t *testing.T) {
+		l := b.Listen(nil)
+		l.Close()
+
+		select {
+		case <-l.Done():
+		default:
+			t.Fatal("listener is not done")
+		}
+
+		b.mu.Lock()
+		_, listening := b.listeners[l]
+		b.mu.Unlock()
+		assert.False(t, listening, "closed listener is removed")
+
+		// closing again is a no-op
+		l.Close()
+	})
+}
+
+func TestUpdatesBroadcaster_Close(t *testing.T) {
+	b := NewUpdatesBroadcaster()
+
+	l := b.Listen(nil)
+	b.Close()
+
+	select {
+	case <-l.Done():
+	default:
+		t.Fatal("listener is not done after the broadcaster was closed")
+	}
+
+	// the listener is closed by the stream handler afterwards
+	l.Close()
+
+	late := b.Listen(nil)
+	defer late.Close()
+	select {
+	case <-late.Done():
+	default:
+		t.Fatal("listener of a closed broadcaster is not done")
+	}
+
+	updated(t, b, uuid.New())
+	assertNotReady(t, late)
+}
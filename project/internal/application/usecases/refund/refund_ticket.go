@@ -0,0 +1,244 @@
+package refund
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/components/requestreply"
+	"github.com/ThreeDotsLabs/watermill/message"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+)
+
+type TicketsRepo interface {
+	GetForUpdate(ctx context.Context, ticketID uuid.UUID) (*entities.Ticket, error)
+}
+
+type ShowsRepo interface {
+	GetShow(ctx context.Context, id uuid.UUID) (*entities.Show, error)
+}
+
+type DecisionsRepo interface {
+	Add(ctx context.Context, decision entities.RefundDecision, idempotencyKey string) (entities.RefundDecision, error)
+	GetByIdempotencyKey(ctx context.Context, idempotencyKey string) (entities.RefundDecision, error)
+	GetByTicketID(ctx context.Context, ticketID uuid.UUID) ([]entities.RefundDecision, error)
+}
+
+type CommandBus interface {
+	Send(ctx context.Context, command any) error
+	SendWithModifiedMessage(ctx context.Context, command any, modify func(*message.Message) error) error
+}
+
+// RefundTicketUsecase evaluates the refund policy before RefundTicket is sent.
+// Every decision is stored, so it's visible why the refund was denied or only partial.
+type RefundTicketUsecase struct {
+	policy        entities.RefundPolicy
+	ticketsRepo   TicketsRepo
+	showsRepo     ShowsRepo
+	decisionsRepo DecisionsRepo
+	commandBus    CommandBus
+	refundReplies requestreply.Backend[entities.RefundTicketResult]
+	trManager     *trmanager.Manager
+}
+
+func NewRefundTicketUsecase(
+	policy entities.RefundPolicy,
+	ticketsRepo TicketsRepo,
+	showsRepo ShowsRepo,
+	decisionsRepo DecisionsRepo,
+	commandBus CommandBus,
+	refundReplies requestreply.Backend[entities.RefundTicketResult],
+	trManager *trmanager.Manager,
+) *RefundTicketUsecase {
+	return &RefundTicketUsecase{
+		policy:        policy,
+		ticketsRepo:   ticketsRepo,
+		showsRepo:     showsRepo,
+		decisionsRepo: decisionsRepo,
+		commandBus:    commandBus,
+		refundReplies: refundReplies,
+		trManager:     trManager,
+	}
+}
+
+// RequestRefund sends RefundTicket with the refunded amount decided by the policy.
+// Denied refunds return the decision together with entities.ErrRefundDenied.
+//
+// Requests with the same idempotency key get the decision made for the first one,
+// even if the policy would decide differently now.
+// Other requests fail with entities.ErrTicketNotRefundable for refunded or cancelled tickets,
+// and with entities.ErrRefundInProgress while an approved refund of the ticket is not done yet,
+// so a ticket is never refunded twice.
+func (u *RefundTicketUsecase) RequestRefund(ctx context.Context, req entities.RefundRequest) (entities.RefundDecision, error) {
+	return u.requestRefund(ctx, req, func(ctx context.Context, command *entities.RefundTicket) error {
+		return u.commandBus.Send(ctx, command)
+	})
+}
+
+// RequestRefundAndWait is RequestRefund which waits until the payment is refunded.
+// A requestreply.ReplyTimeoutError is returned when the refund is not done in time, it may still succeed later.
+func (u *RefundTicketUsecase) RequestRefundAndWait(
+	ctx context.Context,
+	req entities.RefundRequest,
+) (entities.RefundDecision, entities.RefundTicketResult, error) {
+	var result entities.RefundTicketResult
+
+	decision, err := u.requestRefund(ctx, req, func(ctx context.Context, command *entities.RefundTicket) error {
+		reply, err := requestreply.SendWithReply[entities.RefundTicketResult](ctx, u.commandBus, u.refundReplies, command)
+		if err != nil {
+			return err
+		}
+		if reply.Error != nil {
+			return reply.Error
+		}
+
+		result = reply.HandlerResult
+		return nil
+	})
+	if err != nil {
+		return decision, entities.RefundTicketResult{}, err
+	}
+
+	return decision, result, nil
+}
+
+func (u *RefundTicketUsecase) requestRefund(
+	ctx context.Context,
+	req entities.RefundRequest,
+	send func(ctx context.Context, command *entities.RefundTicket) error,
+) (entities.RefundDecision, error) {
+	ticketID, err := uuid.Parse(req.TicketID)
+	if err != nil {
+		return entities.RefundDecision{}, fmt.Errorf("parse ticket id: %w", err)
+	}
+
+	// the command is sent after the commit, RequestRefundAndWait can't wait for the reply inside a transaction,
+	// a failed send is retried by a request with the same idempotency key
+	decision, err := u.decide(ctx, ticketID, req)
+	if err != nil {
+		return entities.RefundDecision{}, err
+	}
+
+	if decision.IsDenied() {
+		return decision, entities.ErrRefundDenied
+	}
+
+	refundedAmount := decision.RefundedAmount
+	err = send(ctx, &entities.RefundTicket{
+		Header:         entities.NewEventHeaderWithIdempotencyKey(req.IdempotencyKey),
+		TicketID:       decision.TicketID,
+		Initiator:      decision.Initiator,
+		DecisionID:     decision.DecisionID,
+		RefundedAmount: &refundedAmount,
+		Reason:         decision.Reason(),
+	})
+	if err != nil {
+		return decision, fmt.Errorf("send refund ticket: %w", err)
+	}
+
+	return decision, nil
+}
+
+// decide stores the decision of the request, the ticket is locked,
+// so concurrent requests with different idempotency keys can't both be approved.
+func (u *RefundTicketUsecase) decide(
+	ctx context.Context,
+	ticketID uuid.UUID,
+	req entities.RefundRequest,
+) (entities.RefundDecision, error) {
+	var decision entities.RefundDecision
+
+	err := u.trManager.Do(ctx, func(ctx context.Context) error {
+		ticket, err := u.ticketsRepo.GetForUpdate(ctx, ticketID)
+		if err != nil {
+			return fmt.Errorf("get ticket: %w", err)
+		}
+
+		decision, err = u.decisionsRepo.GetByIdempotencyKey(ctx, req.IdempotencyKey)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrRefundDecisionNotFound) {
+			return err
+		}
+
+		if err := u.checkRefundable(ctx, ticketID, ticket); err != nil {
+			return err
+		}
+
+		showStartTime, err := u.showStartTime(ctx, ticket)
+		if err != nil {
+			return err
+		}
+
+		decision = u.policy.Evaluate(req.Initiator, ticket.Price, showStartTime, req.RequestedAt)
+		decision.TicketID = ticket.TicketId
+
+		decision, err = u.decisionsRepo.Add(ctx, decision, req.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("add refund decision: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return entities.RefundDecision{}, err
+	}
+
+	return decision, nil
+}
+
+// checkRefundable fails for tickets in a final status and for tickets with an approved decision,
+// which is done only when the ticket becomes refunded. Denied decisions don't block new requests,
+// e.g. the organizer refunds tickets after the customer was denied.
+func (u *RefundTicketUsecase) checkRefundable(ctx context.Context, ticketID uuid.UUID, ticket *entities.Ticket) error {
+	status := entities.TicketStatus(ticket.Status)
+	if status == entities.TicketStatusRefunded {
+		return entities.ErrTicketAlreadyRefunded
+	}
+	if !status.CanTransitionTo(entities.TicketStatusRefunded) {
+		return fmt.Errorf("ticket is %s: %w", status, entities.ErrTicketNotRefundable)
+	}
+
+	decisions, err := u.decisionsRepo.GetByTicketID(ctx, ticketID)
+	if err != nil {
+		return fmt.Errorf("get refund decisions: %w", err)
+	}
+	for _, d := range decisions {
+		if !d.IsDenied() {
+			return fmt.Errorf("decision %s: %w", d.DecisionID, entities.ErrRefundInProgress)
+		}
+	}
+
+	return nil
+}
+
+func (u *RefundTicketUsecase) GetDecisions(ctx context.Context, ticketID uuid.UUID) ([]entities.RefundDecision, error) {
+	return u.decisionsRepo.GetByTicketID(ctx, ticketID)
+}
+
+func (u *RefundTicketUsecase) showStartTime(ctx context.Context, ticket *entities.Ticket) (*time.Time, error) {
+	if ticket.ShowId == "" {
+		// ticket booked before shows were stored with tickets
+		return nil, nil
+	}
+
+	showID, err := uuid.Parse(ticket.ShowId)
+	if err != nil {
+		return nil, fmt.Errorf("parse show id: %w", err)
+	}
+
+	show, err := u.showsRepo.GetShow(ctx, showID)
+	if err != nil {
+		if errors.Is(err, repository.ErrShowNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get show: %w", err)
+	}
+
+	return &show.StartTime, nil
+}
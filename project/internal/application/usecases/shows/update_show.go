@@ -0,0 +1,103 @@
+package shows
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"tickets/internal/entities"
+	"time"
+
+	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/settings"
+	"github.com/google/uuid"
+)
+
+type UpdateShowsRepo interface {
+	UpdateShow(
+		ctx context.Context,
+		id uuid.UUID,
+		updateFn func(show entities.Show) (entities.Show, error),
+	) (entities.Show, error)
+}
+
+type BookingsRepo interface {
+	GetBookingsCountByShowID(ctx context.Context, showID uuid.UUID) (int64, error)
+}
+
+type UpdateShowUsecase struct {
+	showsRepo    UpdateShowsRepo
+	bookingsRepo BookingsRepo
+	trManager    *trmanager.Manager
+}
+
+func NewUpdateShowUsecase(
+	showsRepo UpdateShowsRepo,
+	bookingsRepo BookingsRepo,
+	trManager *trmanager.Manager,
+) *UpdateShowUsecase {
+	return &UpdateShowUsecase{
+		showsRepo:    showsRepo,
+		bookingsRepo: bookingsRepo,
+		trManager:    trManager,
+	}
+}
+
+// UpdateShowReq contains only fields which should be changed, nil fields are left as they are.
+type UpdateShowReq struct {
+	ShowID          uuid.UUID
+	NumberOfTickets *int
+	StartTime       *time.Time
+	Title           *string
+	Venue           *string
+}
+
+// UpdateShow changes the show, the capacity can't be lowered below the booked tickets.
+// The show is locked like by bookings, see repository.ShowsRepo.UpdateShow.
+func (u *UpdateShowUsecase) UpdateShow(ctx context.Context, req UpdateShowReq) (*entities.Show, error) {
+	var show entities.Show
+
+	err := u.trManager.DoWithSettings(
+		ctx,
+		trmsql.MustSettings(
+			settings.Must(settings.WithCancelable(true)),
+			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelReadCommitted}),
+		),
+		func(ctx context.Context) error {
+			var err error
+			show, err = u.showsRepo.UpdateShow(ctx, req.ShowID, func(show entities.Show) (entities.Show, error) {
+				if show.IsCancelled() {
+					return show, entities.ErrShowCancelled
+				}
+
+				if req.NumberOfTickets != nil {
+					bookedTickets, err := u.bookingsRepo.GetBookingsCountByShowID(ctx, req.ShowID)
+					if err != nil {
+						return show, fmt.Errorf("failed to get bookings count: %w", err)
+					}
+					if int64(*req.NumberOfTickets) < bookedTickets {
+						return show, entities.ErrShowCapacityBelowBookedTickets
+					}
+					show.NumberOfTickets = *req.NumberOfTickets
+				}
+				if req.StartTime != nil {
+					show.StartTime = *req.StartTime
+				}
+				if req.Title != nil {
+					show.Title = *req.Title
+				}
+				if req.Venue != nil {
+					show.Venue = *req.Venue
+				}
+
+				return show, nil
+			})
+			return err
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("update show: %w", err)
+	}
+
+	return &show, nil
+}
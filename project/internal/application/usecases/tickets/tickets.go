@@ -5,79 +5,138 @@ import (
 	"fmt"
 	"tickets/internal/entities"
 	"tickets/internal/idempotency"
+	"tickets/internal/interfaces/message/events"
+	"tickets/internal/repository"
+	"time"
 
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
-	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
 )
 
 type TicketsRepository interface {
-	List(ctx context.Context) ([]entities.Ticket, error)
+	List(ctx context.Context, filters repository.TicketsFilters) ([]entities.Ticket, error)
+	GetStatusHistory(ctx context.Context, ticketID uuid.UUID) ([]entities.TicketStatusChange, error)
+}
+
+type TicketsStatusBatchesRepository interface {
+	Add(ctx context.Context, batch entities.TicketsStatusBatch) (entities.TicketsStatusBatch, error)
 }
 
 type ProcessTicketsUsecase struct {
-	eb          *cqrs.EventBus
 	ticketsRepo TicketsRepository
+	batchesRepo TicketsStatusBatchesRepository
+	trManager   *trmanager.Manager
+	outboxBus   *events.OutboxBusFactory
 }
 
 func NewTicketConfirmationService(
-	eb *cqrs.EventBus,
 	ticketsRepo TicketsRepository,
+	batchesRepo TicketsStatusBatchesRepository,
+	trManager *trmanager.Manager,
+	outboxBus *events.OutboxBusFactory,
 ) *ProcessTicketsUsecase {
 	return &ProcessTicketsUsecase{
-		eb:          eb,
 		ticketsRepo: ticketsRepo,
+		batchesRepo: batchesRepo,
+		trManager:   trManager,
+		outboxBus:   outboxBus,
 	}
 }
 
+// ProcessTickets publishes the status events of all tickets through the outbox, in one transaction
+// with the batch stored under the idempotency key. Either all events are published or none.
+//
+// A request retried with the same idempotency key publishes nothing, the batch stored
+// for the first request is returned with replayed set.
 func (s *ProcessTicketsUsecase) ProcessTickets(
 	ctx context.Context,
 	tickets []entities.Ticket,
-) error {
+) (batch entities.TicketsStatusBatch, replayed bool, err error) {
+	idempotencyKey := idempotency.GetKey(ctx)
+
+	ticketIDs := make([]string, 0, len(tickets))
 	for _, ticket := range tickets {
-		if ticket.Status == "confirmed" {
-			log.FromContext(ctx).Info("Publishing TicketBookingConfirmed_v1 with id:"+ticket.TicketId, " Booking BookingID: ", ticket.BookingId)
-			err := s.eb.Publish(ctx, entities.TicketBookingConfirmed_v1{
-				Header: entities.NewEventHeaderWithIdempotencyKey(
-					idempotency.GetKey(ctx) + ticket.TicketId,
-				),
-				TicketID:      ticket.TicketId,
-				CustomerEmail: ticket.CustomerEmail,
-				Price: entities.Money{
-					Amount:   ticket.Price.Amount,
-					Currency: ticket.Price.Currency,
-				},
-				BookingID: ticket.BookingId,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to publish TicketBookingConfirmed_v1: %w", err)
-			}
-		} else {
-			err := s.eb.Publish(ctx, entities.TicketBookingCanceled_v1{
-				Header: entities.NewEventHeaderWithIdempotencyKey(
-					idempotency.GetKey(ctx) + ticket.TicketId,
-				),
-				TicketId:      ticket.TicketId,
-				CustomerEmail: ticket.CustomerEmail,
-				Price: entities.Money{
-					Amount:   ticket.Price.Amount,
-					Currency: ticket.Price.Currency,
-				},
-				BookingId: ticket.BookingId,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to publish TicketBookingCanceled_v1: %w", err)
+		ticketIDs = append(ticketIDs, ticket.TicketId)
+	}
+
+	newBatch := entities.TicketsStatusBatch{
+		BatchID:        uuid.New(),
+		IdempotencyKey: idempotencyKey,
+		TicketIDs:      ticketIDs,
+		ProcessedAt:    time.Now().UTC(),
+	}
+
+	err = s.trManager.Do(ctx, func(ctx context.Context) error {
+		batch, err = s.batchesRepo.Add(ctx, newBatch)
+		if err != nil {
+			return fmt.Errorf("failed to add tickets status batch: %w", err)
+		}
+
+		if batch.BatchID != newBatch.BatchID {
+			replayed = true
+			return nil
+		}
+
+		eb, err := s.outboxBus.EventBus(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, ticket := range tickets {
+			if ticket.Status == "confirmed" {
+				log.FromContext(ctx).Info("Publishing TicketBookingConfirmed_v1 with id:"+ticket.TicketId, " Booking BookingID: ", ticket.BookingId)
+				err := eb.Publish(ctx, entities.TicketBookingConfirmed_v1{
+					Header: entities.NewEventHeaderWithIdempotencyKey(
+						idempotencyKey + ticket.TicketId,
+					),
+					TicketID:      ticket.TicketId,
+					CustomerEmail: ticket.CustomerEmail,
+					Price:         ticket.Price,
+					BookingID:     ticket.BookingId,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to publish TicketBookingConfirmed_v1: %w", err)
+				}
+			} else {
+				err := eb.Publish(ctx, entities.TicketBookingCanceled_v1{
+					Header: entities.NewEventHeaderWithIdempotencyKey(
+						idempotencyKey + ticket.TicketId,
+					),
+					TicketId:      ticket.TicketId,
+					CustomerEmail: ticket.CustomerEmail,
+					Price:         ticket.Price,
+					BookingId:     ticket.BookingId,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to publish TicketBookingCanceled_v1: %w", err)
+				}
 			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return entities.TicketsStatusBatch{}, false, err
 	}
-	return nil
+
+	if replayed {
+		log.FromContext(ctx).Info("Tickets status batch ", batch.BatchID, " already processed, skipping")
+	}
+
+	return batch, replayed, nil
 }
 
-func (s *ProcessTicketsUsecase) GetTickets(ctx context.Context) ([]entities.Ticket, error) {
-	tickets, err := s.ticketsRepo.List(ctx)
+func (s *ProcessTicketsUsecase) GetTickets(ctx context.Context, filters repository.TicketsFilters) ([]entities.Ticket, error) {
+	tickets, err := s.ticketsRepo.List(ctx, filters)
 
 	return tickets, err
 }
 
+func (s *ProcessTicketsUsecase) GetTicketStatusHistory(ctx context.Context, ticketID uuid.UUID) ([]entities.TicketStatusChange, error) {
+	return s.ticketsRepo.GetStatusHistory(ctx, ticketID)
+}
+
 type Service struct {
 	transportationClient TransportationClient
 	// add other dependencies as needed
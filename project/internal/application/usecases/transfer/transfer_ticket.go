@@ -0,0 +1,257 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"tickets/internal/entities"
+	"tickets/internal/interfaces/message/events"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+)
+
+type TicketsRepo interface {
+	GetForUpdate(ctx context.Context, ticketID uuid.UUID) (*entities.Ticket, error)
+	ChangeCustomer(ctx context.Context, ticketID uuid.UUID, customerEmail string) error
+}
+
+type TicketTransfersRepo interface {
+	Add(ctx context.Context, transfer entities.TicketTransfer) error
+	GetForUpdate(ctx context.Context, transferID uuid.UUID) (entities.TicketTransfer, error)
+	Resolve(ctx context.Context, transferID uuid.UUID, status entities.TicketTransferStatus, resolvedAt time.Time) error
+	GetByTicketID(ctx context.Context, ticketID uuid.UUID) ([]entities.TicketTransfer, error)
+}
+
+// TransferTicketUsecase moves tickets between customers.
+// The ticket is locked during the whole transfer, so it can't be checked in or transferred twice in the meantime.
+type TransferTicketUsecase struct {
+	ticketsRepo   TicketsRepo
+	transfersRepo TicketTransfersRepo
+	trManager     *trmanager.Manager
+	outboxBus     *events.OutboxBusFactory
+}
+
+func NewTransferTicketUsecase(
+	ticketsRepo TicketsRepo,
+	transfersRepo TicketTransfersRepo,
+	trManager *trmanager.Manager,
+	outboxBus *events.OutboxBusFactory,
+) *TransferTicketUsecase {
+	return &TransferTicketUsecase{
+		ticketsRepo:   ticketsRepo,
+		transfersRepo: transfersRepo,
+		trManager:     trManager,
+		outboxBus:     outboxBus,
+	}
+}
+
+type TransferTicketReq struct {
+	TicketID           uuid.UUID
+	ToEmail            string
+	RequiresAcceptance bool
+	RequesterIP        string
+}
+
+// RequestTransfer transfers the ticket right away, or waits for the recipient
+// to accept the transfer when RequiresAcceptance is set.
+func (u *TransferTicketUsecase) RequestTransfer(ctx context.Context, req TransferTicketReq) (*entities.TicketTransfer, error) {
+	var transfer entities.TicketTransfer
+
+	err := u.trManager.Do(ctx, func(ctx context.Context) error {
+		ticket, err := u.ticketsRepo.GetForUpdate(ctx, req.TicketID)
+		if err != nil {
+			return fmt.Errorf("get ticket: %w", err)
+		}
+
+		err = checkTransferable(ticket)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(ticket.CustomerEmail, req.ToEmail) {
+			return entities.ErrTicketTransferToSameCustomer
+		}
+
+		transfer = entities.TicketTransfer{
+			TransferID:         uuid.New(),
+			TicketID:           req.TicketID,
+			FromEmail:          ticket.CustomerEmail,
+			ToEmail:            req.ToEmail,
+			Status:             entities.TicketTransferStatusPending,
+			RequiresAcceptance: req.RequiresAcceptance,
+			RequesterIP:        req.RequesterIP,
+			RequestedAt:        time.Now().UTC(),
+		}
+
+		err = u.transfersRepo.Add(ctx, transfer)
+		if err != nil {
+			return fmt.Errorf("add ticket transfer: %w", err)
+		}
+
+		eb, err := u.outboxBus.EventBus(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !req.RequiresAcceptance {
+			return u.complete(ctx, eb, ticket, &transfer)
+		}
+
+		err = eb.Publish(ctx, &entities.TicketTransferRequested_v1{
+			Header:      entities.NewEventHeader(),
+			TransferID:  transfer.TransferID,
+			TicketID:    ticket.TicketId,
+			FromEmail:   transfer.FromEmail,
+			ToEmail:     transfer.ToEmail,
+			RequestedAt: transfer.RequestedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("publish ticket transfer requested event: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+func (u *TransferTicketUsecase) AcceptTransfer(ctx context.Context, ticketID, transferID uuid.UUID) (*entities.TicketTransfer, error) {
+	var transfer entities.TicketTransfer
+
+	err := u.trManager.Do(ctx, func(ctx context.Context) error {
+		ticket, err := u.ticketsRepo.GetForUpdate(ctx, ticketID)
+		if err != nil {
+			return fmt.Errorf("get ticket: %w", err)
+		}
+
+		transfer, err = u.getPendingTransfer(ctx, ticketID, transferID)
+		if err != nil {
+			return err
+		}
+
+		err = checkTransferable(ticket)
+		if err != nil {
+			return err
+		}
+
+		eb, err := u.outboxBus.EventBus(ctx)
+		if err != nil {
+			return err
+		}
+
+		return u.complete(ctx, eb, ticket, &transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// DeclineTransfer locks the ticket before the transfer, in the same order as AcceptTransfer,
+// so accepting and declining the same transfer at once can't deadlock.
+func (u *TransferTicketUsecase) DeclineTransfer(ctx context.Context, ticketID, transferID uuid.UUID) (*entities.TicketTransfer, error) {
+	var transfer entities.TicketTransfer
+
+	err := u.trManager.Do(ctx, func(ctx context.Context) error {
+		_, err := u.ticketsRepo.GetForUpdate(ctx, ticketID)
+		if err != nil {
+			return fmt.Errorf("get ticket: %w", err)
+		}
+
+		transfer, err = u.getPendingTransfer(ctx, ticketID, transferID)
+		if err != nil {
+			return err
+		}
+
+		resolvedAt := time.Now().UTC()
+
+		err = u.transfersRepo.Resolve(ctx, transferID, entities.TicketTransferStatusDeclined, resolvedAt)
+		if err != nil {
+			return fmt.Errorf("resolve ticket transfer: %w", err)
+		}
+
+		transfer.Status = entities.TicketTransferStatusDeclined
+		transfer.ResolvedAt = &resolvedAt
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+func (u *TransferTicketUsecase) GetTransfers(ctx context.Context, ticketID uuid.UUID) ([]entities.TicketTransfer, error) {
+	return u.transfersRepo.GetByTicketID(ctx, ticketID)
+}
+
+func (u *TransferTicketUsecase) getPendingTransfer(ctx context.Context, ticketID, transferID uuid.UUID) (entities.TicketTransfer, error) {
+	transfer, err := u.transfersRepo.GetForUpdate(ctx, transferID)
+	if err != nil {
+		return entities.TicketTransfer{}, fmt.Errorf("get ticket transfer: %w", err)
+	}
+	if transfer.TicketID != ticketID {
+		return entities.TicketTransfer{}, fmt.Errorf("transfer %s is not for ticket %s: %w", transferID, ticketID, entities.ErrTicketTransferNotPending)
+	}
+	if !transfer.IsPending() {
+		return entities.TicketTransfer{}, fmt.Errorf("transfer is %s: %w", transfer.Status, entities.ErrTicketTransferNotPending)
+	}
+
+	return transfer, nil
+}
+
+// complete moves the ticket to the recipient. The ticket is reprinted in reaction to TicketTransferred_v1.
+func (u *TransferTicketUsecase) complete(
+	ctx context.Context,
+	eb *cqrs.EventBus,
+	ticket *entities.Ticket,
+	transfer *entities.TicketTransfer,
+) error {
+	transferredAt := time.Now().UTC()
+
+	err := u.ticketsRepo.ChangeCustomer(ctx, transfer.TicketID, transfer.ToEmail)
+	if err != nil {
+		return fmt.Errorf("change ticket customer: %w", err)
+	}
+
+	err = u.transfersRepo.Resolve(ctx, transfer.TransferID, entities.TicketTransferStatusCompleted, transferredAt)
+	if err != nil {
+		return fmt.Errorf("resolve ticket transfer: %w", err)
+	}
+
+	transfer.Status = entities.TicketTransferStatusCompleted
+	transfer.ResolvedAt = &transferredAt
+
+	err = eb.Publish(ctx, &entities.TicketTransferred_v1{
+		Header:        entities.NewEventHeader(),
+		TransferID:    transfer.TransferID,
+		TicketID:      ticket.TicketId,
+		BookingID:     ticket.BookingId,
+		ShowID:        ticket.ShowId,
+		FromEmail:     transfer.FromEmail,
+		ToEmail:       transfer.ToEmail,
+		TransferredAt: transferredAt,
+	})
+	if err != nil {
+		return fmt.Errorf("publish ticket transferred event: %w", err)
+	}
+
+	return nil
+}
+
+// checkTransferable rejects tickets which were already used or are no longer valid.
+func checkTransferable(ticket *entities.Ticket) error {
+	status := entities.TicketStatus(ticket.Status)
+	if status.IsFinal() || status == entities.TicketStatusCheckedIn {
+		return fmt.Errorf("ticket is %s: %w", status, entities.ErrTicketNotTransferable)
+	}
+
+	return nil
+}
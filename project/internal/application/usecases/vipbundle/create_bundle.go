@@ -7,11 +7,8 @@ import (
 	"tickets/internal/application/usecases/booking"
 	"tickets/internal/entities"
 	"tickets/internal/interfaces/message/events"
-	"tickets/internal/interfaces/message/outbox"
 
-	"github.com/ThreeDotsLabs/watermill"
 	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
-	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 	"github.com/avito-tech/go-transaction-manager/trm/v2/settings"
 	"github.com/google/uuid"
@@ -29,23 +26,20 @@ type CreateBundleUsecase struct {
 	bookTicketsUsecase TicketBooker
 	repo               Repository
 	trManager          *trmanager.Manager
-	trGetter           *trmsqlx.CtxGetter
-	watermillLogger    watermill.LoggerAdapter
+	outboxBus          *events.OutboxBusFactory
 }
 
 func NewCreateBundleUsecase(
 	repo Repository,
 	bookTicketsUsecase TicketBooker,
 	trManager *trmanager.Manager,
-	trGetter *trmsqlx.CtxGetter,
-	watermillLogger watermill.LoggerAdapter,
+	outboxBus *events.OutboxBusFactory,
 ) *CreateBundleUsecase {
 	return &CreateBundleUsecase{
 		repo:               repo,
 		bookTicketsUsecase: bookTicketsUsecase,
 		trManager:          trManager,
-		trGetter:           trGetter,
-		watermillLogger:    watermillLogger,
+		outboxBus:          outboxBus,
 	}
 }
 
@@ -92,18 +86,9 @@ func (u *CreateBundleUsecase) CreateBundle(ctx context.Context, req CreateBundle
 				return fmt.Errorf("repo vip bundle: %w", err)
 			}
 
-			tr := u.trGetter.DefaultTrOrDB(ctx, nil)
-			if tr == nil {
-				return fmt.Errorf("failed to get transaction from context")
-			}
-
-			publisher, err := outbox.NewPublisher(tr, u.watermillLogger)
-			if err != nil {
-				return fmt.Errorf("failed to create event publisher: %w", err)
-			}
-			eb, err := events.NewEventBus(publisher, u.watermillLogger)
+			eb, err := u.outboxBus.EventBus(ctx)
 			if err != nil {
-				return fmt.Errorf("failed to create event bus: %w", err)
+				return err
 			}
 
 			err = eb.Publish(ctx, &entities.VipBundleInitialized_v1{
@@ -0,0 +1,282 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderWebhookID        = "Webhook-Id"
+	HeaderWebhookEvent     = "Webhook-Event"
+	HeaderWebhookTimestamp = "Webhook-Timestamp"
+	HeaderWebhookSignature = "Webhook-Signature"
+)
+
+type DispatcherConfig struct {
+	// MaxAttempts is the number of attempts before the delivery fails.
+	MaxAttempts int
+	// InitialBackoff is the delay after the first failed attempt, it's doubled after each next one up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// DisableAfterFailures is the number of consecutive failed deliveries which disables the subscription.
+	DisableAfterFailures int
+
+	RequestTimeout time.Duration
+	PollInterval   time.Duration
+	// BatchSize is the number of deliveries sent concurrently.
+	BatchSize int
+}
+
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		MaxAttempts:          8,
+		InitialBackoff:       10 * time.Second,
+		MaxBackoff:           time.Hour,
+		DisableAfterFailures: 5,
+		RequestTimeout:       10 * time.Second,
+		PollInterval:         time.Second,
+		BatchSize:            20,
+	}
+}
+
+// Dispatcher sends the queued deliveries to the partner endpoints.
+// Many dispatchers can run at once, each delivery is claimed by one of them.
+type Dispatcher struct {
+	repo       WebhooksRepo
+	trManager  *trmanager.Manager
+	httpClient *http.Client
+	config     DispatcherConfig
+}
+
+func NewDispatcher(
+	repo WebhooksRepo,
+	trManager *trmanager.Manager,
+	httpClient *http.Client,
+	config DispatcherConfig,
+) *Dispatcher {
+	return &Dispatcher{
+		repo:       repo,
+		trManager:  trManager,
+		httpClient: httpClient,
+		config:     config,
+	}
+}
+
+// WebhookPayload is the body POSTed to the partner endpoints.
+type WebhookPayload struct {
+	EventID   uuid.UUID       `json:"event_id"`
+	EventName string          `json:"event_name"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sign returns the Webhook-Signature header of the body sent at timestamp.
+// Partners compute the HMAC-SHA256 of "<Webhook-Timestamp>.<body>" with their secret and compare it with the hex after "v1=".
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run sends due deliveries every PollInterval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := d.DeliverDue(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				log.FromContext(ctx).Error("failed to deliver webhooks, err:", err)
+				break
+			}
+			if sent < d.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends one batch of due deliveries and returns how many were sent.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	// a delivery is retried by any dispatcher when it's not updated before the lease ends
+	leaseUntil := now.Add(2 * d.config.RequestTimeout)
+
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, now, leaseUntil, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery entities.WebhookDelivery) {
+			defer wg.Done()
+
+			if err := d.deliver(ctx, delivery); err != nil {
+				log.FromContext(ctx).
+					WithField("delivery_id", delivery.DeliveryID).
+					Error("failed to deliver webhook, err:", err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery entities.WebhookDelivery) error {
+	subscription, err := d.repo.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return fmt.Errorf("get webhook subscription: %w", err)
+	}
+
+	attempt := d.send(ctx, subscription, delivery)
+
+	return d.trManager.Do(ctx, func(ctx context.Context) error {
+		err := d.repo.AddAttempt(ctx, attempt)
+		if err != nil {
+			return err
+		}
+
+		delivery.Attempts++
+
+		if attempt.Succeeded() {
+			delivery.Status = entities.WebhookDeliveryStatusDelivered
+			delivery.LastError = ""
+			delivery.DeliveredAt = &attempt.AttemptedAt
+
+			err = d.repo.ResetFailures(ctx, subscription.SubscriptionID)
+			if err != nil {
+				return err
+			}
+
+			return d.repo.UpdateDelivery(ctx, delivery)
+		}
+
+		delivery.LastError = attempt.Error
+
+		if delivery.Attempts < d.config.MaxAttempts {
+			delivery.NextAttemptAt = attempt.AttemptedAt.Add(d.backoff(delivery.Attempts))
+			return d.repo.UpdateDelivery(ctx, delivery)
+		}
+
+		delivery.Status = entities.WebhookDeliveryStatusFailed
+
+		disabled, err := d.repo.RecordDeliveryFailure(
+			ctx,
+			subscription.SubscriptionID,
+			d.config.DisableAfterFailures,
+			attempt.AttemptedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if disabled && !subscription.IsDisabled() {
+			log.FromContext(ctx).
+				WithField("subscription_id", subscription.SubscriptionID).
+				Info("Webhook subscription disabled after failed deliveries")
+		}
+
+		return d.repo.UpdateDelivery(ctx, delivery)
+	})
+}
+
+// send POSTs the delivery, only 2xx responses are successful.
+func (d *Dispatcher) send(
+	ctx context.Context,
+	subscription entities.WebhookSubscription,
+	delivery entities.WebhookDelivery,
+) entities.WebhookDeliveryAttempt {
+	attempt := entities.WebhookDeliveryAttempt{
+		DeliveryID:  delivery.DeliveryID,
+		AttemptedAt: time.Now().UTC(),
+	}
+
+	err := func() error {
+		body, err := json.Marshal(WebhookPayload{
+			EventID:   delivery.EventID,
+			EventName: delivery.EventName,
+			Data:      delivery.Payload,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal payload: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, d.config.RequestTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		// the event ID stays the same on retries, so partners can deduplicate deliveries
+		req.Header.Set(HeaderWebhookID, delivery.EventID.String())
+		req.Header.Set(HeaderWebhookEvent, delivery.EventName)
+		req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(attempt.AttemptedAt.Unix(), 10))
+		req.Header.Set(HeaderWebhookSignature, Sign(subscription.Secret, attempt.AttemptedAt, body))
+
+		resp, err := d.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		// drained, so the connection is reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+		attempt.StatusCode = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+
+		return nil
+	}()
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	attempt.Duration = time.Since(attempt.AttemptedAt)
+
+	return attempt
+}
+
+// backoff returns the delay after the attempts-th failed attempt.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.config.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+
+	return min(backoff, d.config.MaxBackoff)
+}
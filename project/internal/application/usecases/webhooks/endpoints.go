@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"tickets/internal/entities"
+	"time"
+)
+
+// EndpointPolicy decides which hosts webhooks are sent to.
+// Partner endpoints must be public, otherwise the dispatcher could be used to reach internal services.
+type EndpointPolicy struct {
+	// AllowPrivateHosts allows loopback, link-local and private hosts, it's meant for tests only.
+	AllowPrivateHosts bool
+}
+
+// CheckURL checks the endpoint registered by the partner.
+// Hosts resolved by DNS are checked again by the HTTPClient on every connection,
+// as they may resolve to another address later.
+func (p EndpointPolicy) CheckURL(endpoint *url.URL) error {
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", entities.ErrInvalidWebhookSubscription)
+	}
+	if p.AllowPrivateHosts {
+		return nil
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(endpoint.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: url must be a public host", entities.ErrInvalidWebhookSubscription)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if err := p.CheckIP(ip); err != nil {
+			return fmt.Errorf("%w: %s", entities.ErrInvalidWebhookSubscription, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckIP rejects loopback, link-local, private and other non-public addresses.
+func (p EndpointPolicy) CheckIP(ip net.IP) error {
+	if p.AllowPrivateHosts {
+		return nil
+	}
+
+	if ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return fmt.Errorf("%s is not a public address", ip)
+	}
+
+	return nil
+}
+
+// HTTPClient returns the client of the Dispatcher.
+// It checks the address of every connection after DNS resolution,
+// so hosts which resolve to internal addresses can't be reached.
+func (p EndpointPolicy) HTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("dial %s: not an IP address", address)
+			}
+
+			return p.CheckIP(ip)
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would dial the endpoint instead of the dialer
+	transport.Proxy = nil
+	// redirects are dialed by the same transport, so they are checked as well
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Transport: transport}
+}
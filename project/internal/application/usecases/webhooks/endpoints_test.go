@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"tickets/internal/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointPolicy_CheckURL(t *testing.T) {
+	testCases := []struct {
+		URL     string
+		Allowed bool
+	}{
+		{URL: "https://partner.example.com/webhooks", Allowed: true},
+		{URL: "http://93.184.216.34:8080/webhooks", Allowed: true},
+		{URL: "ftp://partner.example.com/webhooks"},
+		{URL: "https:///webhooks"},
+		{URL: "http://localhost/webhooks"},
+		{URL: "http://api.localhost./webhooks"},
+		{URL: "http://127.0.0.1:8080/webhooks"},
+		{URL: "http://[::1]/webhooks"},
+		{URL: "http://0.0.0.0/webhooks"},
+		{URL: "http://169.254.169.254/latest/meta-data"},
+		{URL: "http://[fe80::1]/webhooks"},
+		{URL: "http://10.1.2.3/webhooks"},
+		{URL: "http://172.16.0.1/webhooks"},
+		{URL: "http://192.168.0.1/webhooks"},
+		{URL: "http://[fd00::1]/webhooks"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.URL, func(t *testing.T) {
+			endpoint, err := url.Parse(tc.URL)
+			require.NoError(t, err)
+
+			err = EndpointPolicy{}.CheckURL(endpoint)
+			if tc.Allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, entities.ErrInvalidWebhookSubscription)
+			}
+		})
+	}
+
+	endpoint, err := url.Parse("http://127.0.0.1:8080/webhooks")
+	require.NoError(t, err)
+	assert.NoError(t, EndpointPolicy{AllowPrivateHosts: true}.CheckURL(endpoint))
+}
+
+func TestEndpointPolicy_HTTPClient(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// the loopback address is checked after the host is resolved
+	_, err := EndpointPolicy{}.HTTPClient().Get(server.URL)
+	assert.ErrorContains(t, err, "not a public address")
+	assert.Equal(t, 0, requests)
+
+	resp, err := EndpointPolicy{AllowPrivateHosts: true}.HTTPClient().Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
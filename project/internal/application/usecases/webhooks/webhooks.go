@@ -0,0 +1,215 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"reflect"
+	"tickets/contracts/public"
+	"tickets/internal/entities"
+	"time"
+
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+)
+
+type WebhooksRepo interface {
+	AddSubscription(ctx context.Context, subscription entities.WebhookSubscription) error
+	GetSubscription(ctx context.Context, subscriptionID uuid.UUID) (entities.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]entities.WebhookSubscription, error)
+	EnableSubscription(ctx context.Context, subscriptionID uuid.UUID) error
+	RecordDeliveryFailure(ctx context.Context, subscriptionID uuid.UUID, disableAfter int, failedAt time.Time) (bool, error)
+	ResetFailures(ctx context.Context, subscriptionID uuid.UUID) error
+
+	AddDeliveries(ctx context.Context, eventID uuid.UUID, eventName string, payload []byte, createdAt time.Time) (int, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]entities.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, deliveryID uuid.UUID) (entities.WebhookDelivery, error)
+	GetDeliveryForUpdate(ctx context.Context, deliveryID uuid.UUID) (entities.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery entities.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]entities.WebhookDelivery, error)
+	AddAttempt(ctx context.Context, attempt entities.WebhookDeliveryAttempt) error
+	ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]entities.WebhookDeliveryAttempt, error)
+}
+
+// WebhooksUsecase manages the partner subscriptions, the deliveries are sent by the Dispatcher.
+type WebhooksUsecase struct {
+	repo      WebhooksRepo
+	trManager *trmanager.Manager
+	endpoints EndpointPolicy
+}
+
+func NewWebhooksUsecase(
+	repo WebhooksRepo,
+	trManager *trmanager.Manager,
+	endpoints EndpointPolicy,
+) *WebhooksUsecase {
+	return &WebhooksUsecase{
+		repo:      repo,
+		trManager: trManager,
+		endpoints: endpoints,
+	}
+}
+
+// publicEventNames are the events partners can subscribe to.
+var publicEventNames = func() map[string]struct{} {
+	names := map[string]struct{}{}
+	for _, event := range public.Events {
+		names[reflect.TypeOf(event).Name()] = struct{}{}
+	}
+	return names
+}()
+
+type RegisterReq struct {
+	URL        string
+	EventNames []string
+}
+
+// Register returns the subscription with the secret used to sign its payloads,
+// it's the only time when the secret is returned.
+func (u *WebhooksUsecase) Register(ctx context.Context, req RegisterReq) (entities.WebhookSubscription, error) {
+	endpoint, err := url.Parse(req.URL)
+	if err != nil {
+		return entities.WebhookSubscription{}, fmt.Errorf("%w: url must be an absolute http or https URL", entities.ErrInvalidWebhookSubscription)
+	}
+	if err := u.endpoints.CheckURL(endpoint); err != nil {
+		return entities.WebhookSubscription{}, err
+	}
+
+	if len(req.EventNames) == 0 {
+		return entities.WebhookSubscription{}, fmt.Errorf("%w: event_names can't be empty", entities.ErrInvalidWebhookSubscription)
+	}
+	for _, eventName := range req.EventNames {
+		// subscriptions to unknown events would never be delivered
+		if _, ok := publicEventNames[eventName]; !ok {
+			return entities.WebhookSubscription{}, fmt.Errorf("%w: %s is not a public event", entities.ErrInvalidWebhookSubscription, eventName)
+		}
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return entities.WebhookSubscription{}, err
+	}
+
+	subscription := entities.WebhookSubscription{
+		SubscriptionID: uuid.New(),
+		URL:            endpoint.String(),
+		EventNames:     req.EventNames,
+		Secret:         secret,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	err = u.repo.AddSubscription(ctx, subscription)
+	if err != nil {
+		return entities.WebhookSubscription{}, fmt.Errorf("add webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (u *WebhooksUsecase) GetSubscriptions(ctx context.Context) ([]entities.WebhookSubscription, error) {
+	return u.repo.ListSubscriptions(ctx)
+}
+
+// Enable resumes the deliveries of a subscription disabled after failures,
+// the deliveries queued in the meantime are sent.
+func (u *WebhooksUsecase) Enable(ctx context.Context, subscriptionID uuid.UUID) (entities.WebhookSubscription, error) {
+	var subscription entities.WebhookSubscription
+
+	err := u.trManager.Do(ctx, func(ctx context.Context) error {
+		err := u.repo.EnableSubscription(ctx, subscriptionID)
+		if err != nil {
+			return err
+		}
+
+		subscription, err = u.repo.GetSubscription(ctx, subscriptionID)
+		return err
+	})
+	if err != nil {
+		return entities.WebhookSubscription{}, err
+	}
+
+	return subscription.WithoutSecret(), nil
+}
+
+func (u *WebhooksUsecase) GetDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]entities.WebhookDelivery, error) {
+	// the subscription must exist, so clients can tell an unknown subscription from one without deliveries
+	_, err := u.repo.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.repo.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+func (u *WebhooksUsecase) GetDelivery(ctx context.Context, deliveryID uuid.UUID) (entities.WebhookDelivery, []entities.WebhookDeliveryAttempt, error) {
+	delivery, err := u.repo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return entities.WebhookDelivery{}, nil, err
+	}
+
+	attempts, err := u.repo.ListAttempts(ctx, deliveryID)
+	if err != nil {
+		return entities.WebhookDelivery{}, nil, err
+	}
+
+	return delivery, attempts, nil
+}
+
+// Redeliver sends a delivered or failed delivery again, with all attempts available.
+func (u *WebhooksUsecase) Redeliver(ctx context.Context, deliveryID uuid.UUID) (entities.WebhookDelivery, error) {
+	var delivery entities.WebhookDelivery
+
+	err := u.trManager.Do(ctx, func(ctx context.Context) error {
+		var err error
+		delivery, err = u.repo.GetDeliveryForUpdate(ctx, deliveryID)
+		if err != nil {
+			return err
+		}
+
+		if delivery.Status == entities.WebhookDeliveryStatusPending {
+			return entities.ErrWebhookDeliveryPending
+		}
+
+		subscription, err := u.repo.GetSubscription(ctx, delivery.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		if subscription.IsDisabled() {
+			return entities.ErrWebhookSubscriptionDisabled
+		}
+
+		delivery.Status = entities.WebhookDeliveryStatusPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now().UTC()
+		delivery.LastError = ""
+		delivery.DeliveredAt = nil
+
+		return u.repo.UpdateDelivery(ctx, delivery)
+	})
+	if err != nil {
+		return entities.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// Enqueue queues the event for the subscriptions of the event, it's idempotent.
+func (u *WebhooksUsecase) Enqueue(ctx context.Context, eventID uuid.UUID, eventName string, payload []byte) error {
+	_, err := u.repo.AddDeliveries(ctx, eventID, eventName, payload, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("add webhook deliveries of %s: %w", eventName, err)
+	}
+
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
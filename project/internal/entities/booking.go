@@ -7,4 +7,7 @@ type Booking struct {
 	ShowId          uuid.UUID `json:"show_id"`
 	NumberOfTickets int       `json:"number_of_tickets"`
 	CustomerEmail   string    `json:"customer_email"`
+
+	// Locale is a BCP 47 language tag used to render the tickets, empty means the default locale.
+	Locale string `json:"locale,omitempty"`
 }
@@ -0,0 +1,94 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BookingCancellationTicketStatus string
+
+const (
+	BookingCancellationTicketPending  BookingCancellationTicketStatus = "pending"
+	BookingCancellationTicketRefunded BookingCancellationTicketStatus = "refunded"
+	// BookingCancellationTicketRefundDenied is cancelled without a refund, as the refund policy didn't allow it.
+	BookingCancellationTicketRefundDenied BookingCancellationTicketStatus = "refund_denied"
+)
+
+type BookingCancellation struct {
+	BookingID       uuid.UUID `json:"booking_id"`
+	ShowID          uuid.UUID `json:"show_id"`
+	NumberOfTickets int       `json:"number_of_tickets"`
+	CustomerEmail   string    `json:"customer_email"`
+	Reason          string    `json:"reason"`
+	// Initiator decides which refund policy rules apply to the tickets.
+	// It's empty for cancellations started before the refund policy was introduced, they are treated as customer cancellations.
+	Initiator RefundInitiator `json:"initiator,omitempty"`
+
+	Tickets map[string]BookingCancellationTicket `json:"tickets"`
+
+	// Attempts is incremented every time the cancellation is (re)started,
+	// so it's visible how many times the refunds had to be retried.
+	Attempts    int        `json:"attempts"`
+	RequestedAt time.Time  `json:"requested_at"`
+	CancelledAt *time.Time `json:"cancelled_at"`
+
+	IsFinalized bool `json:"finalized"`
+}
+
+type BookingCancellationTicket struct {
+	Status            BookingCancellationTicketStatus `json:"status"`
+	RefundRequestedAt *time.Time                      `json:"refund_requested_at"`
+	RefundedAt        *time.Time                      `json:"refunded_at"`
+	RefundedAmount    *Money                          `json:"refunded_amount,omitempty"`
+}
+
+func (c BookingCancellation) PendingTicketIDs() []string {
+	var ticketIDs []string
+	for ticketID, ticket := range c.Tickets {
+		if ticket.Status == BookingCancellationTicketPending {
+			ticketIDs = append(ticketIDs, ticketID)
+		}
+	}
+
+	return ticketIDs
+}
+
+// AllTicketsRefunded is true when no refund is pending, tickets with denied refunds are not waited for.
+func (c BookingCancellation) AllTicketsRefunded() bool {
+	return len(c.PendingTicketIDs()) == 0
+}
+
+func (c BookingCancellation) RefundInitiator() RefundInitiator {
+	if c.Initiator == "" {
+		return RefundInitiatorCustomer
+	}
+	return c.Initiator
+}
+
+// CancelByOrganizer applies the organizer rules to a cancellation which the customer started,
+// e.g. when the show was cancelled before the refunds were done. Tickets with denied refunds are evaluated again.
+func (c *BookingCancellation) CancelByOrganizer(reason string) {
+	c.Initiator = RefundInitiatorOrganizer
+	c.Reason = reason
+
+	for ticketID, ticket := range c.Tickets {
+		if ticket.Status == BookingCancellationTicketRefundDenied {
+			ticket.Status = BookingCancellationTicketPending
+			c.Tickets[ticketID] = ticket
+		}
+	}
+}
+
+// RefundIdempotencyKey is stable across retries, so re-sending RefundTicket
+// for the same ticket never results in a second refund.
+func (c BookingCancellation) RefundIdempotencyKey(ticketID string) string {
+	key := "booking-cancellation-" + c.BookingID.String() + "-" + ticketID
+	if c.RefundInitiator() == RefundInitiatorOrganizer {
+		// refunds denied to the customer are decided again for the organizer,
+		// refunds approved for the customer are still in progress, so they are not requested twice
+		key += "-" + string(RefundInitiatorOrganizer)
+	}
+
+	return key
+}
@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookingCancellation_CancelByOrganizer(t *testing.T) {
+	c := BookingCancellation{
+		BookingID: uuid.New(),
+		Reason:    "can't make it",
+		Initiator: RefundInitiatorCustomer,
+		Tickets: map[string]BookingCancellationTicket{
+			"denied":   {Status: BookingCancellationTicketRefundDenied},
+			"pending":  {Status: BookingCancellationTicketPending},
+			"refunded": {Status: BookingCancellationTicketRefunded},
+		},
+	}
+	customerKey := c.RefundIdempotencyKey("denied")
+
+	c.CancelByOrganizer("venue flooded")
+
+	assert.Equal(t, RefundInitiatorOrganizer, c.RefundInitiator())
+	assert.Equal(t, "venue flooded", c.Reason)
+	assert.ElementsMatch(t, []string{"denied", "pending"}, c.PendingTicketIDs())
+	assert.Equal(t, BookingCancellationTicketRefunded, c.Tickets["refunded"].Status)
+
+	// the decision denied to the customer is not reused
+	assert.NotEqual(t, customerKey, c.RefundIdempotencyKey("denied"))
+}
@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CommandStatus string
+
+const (
+	CommandStatusAccepted   CommandStatus = "accepted"
+	CommandStatusProcessing CommandStatus = "processing"
+	CommandStatusSucceeded  CommandStatus = "succeeded"
+	// CommandStatusFailed is set when all retries failed. The command is still re-delivered later,
+	// so it can become processing and succeeded again.
+	CommandStatusFailed CommandStatus = "failed"
+)
+
+// TrackedCommand is the status of a command sent through the command bus.
+type TrackedCommand struct {
+	CommandID     uuid.UUID     `json:"command_id"`
+	CommandName   string        `json:"command_name"`
+	Status        CommandStatus `json:"status"`
+	FailureReason string        `json:"failure_reason,omitempty"`
+	// Attempts counts deliveries of the command, retries within one delivery are not counted.
+	Attempts   int       `json:"attempts"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
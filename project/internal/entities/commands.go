@@ -1,6 +1,21 @@
 package entities
 
+import "github.com/google/uuid"
+
 type RefundTicket struct {
-	Header   EventHeader `json:"header"`
-	TicketID string      `json:"ticket_id"`
+	Header   EventHeader `json:"header" protobuf:"1"`
+	TicketID string      `json:"ticket_id" protobuf:"2"`
+
+	// Initiator, DecisionID and RefundedAmount are empty in commands sent before the refund policy was introduced,
+	// such tickets are refunded in full.
+	Initiator      RefundInitiator `json:"initiator,omitempty" protobuf:"3"`
+	DecisionID     uuid.UUID       `json:"decision_id" jsonschema:"optional" protobuf:"4"`
+	RefundedAmount *Money          `json:"refunded_amount,omitempty" protobuf:"5"`
+	Reason         string          `json:"reason,omitempty" protobuf:"6"`
+}
+
+// RefundTicketResult is the reply to RefundTicket sent with request-reply.
+type RefundTicketResult struct {
+	TicketID       string `json:"ticket_id"`
+	RefundedAmount *Money `json:"refunded_amount,omitempty"`
 }
@@ -3,3 +3,39 @@ package entities
 import "fmt"
 
 var ErrNotEnoughTickets = fmt.Errorf("not enough tickets available")
+
+var ErrBookingHasNoTickets = fmt.Errorf("booking has no confirmed tickets")
+
+var ErrBookingTicketsNotConfirmed = fmt.Errorf("not all tickets of the booking are confirmed yet")
+
+var ErrShowCancelled = fmt.Errorf("show is cancelled")
+
+var ErrShowNotCancelled = fmt.Errorf("show is not cancelled")
+
+var ErrShowCapacityBelowBookedTickets = fmt.Errorf("show capacity is lower than number of booked tickets")
+
+var ErrTicketAlreadyCheckedIn = fmt.Errorf("ticket already checked in")
+
+var ErrTicketNotValid = fmt.Errorf("ticket is not valid")
+
+var ErrTicketNotTransferable = fmt.Errorf("ticket can't be transferred")
+
+var ErrTicketTransferToSameCustomer = fmt.Errorf("ticket already belongs to this customer")
+
+var ErrTicketTransferPending = fmt.Errorf("ticket has a transfer waiting for acceptance")
+
+var ErrTicketTransferNotPending = fmt.Errorf("ticket transfer is not waiting for acceptance")
+
+var ErrRefundDenied = fmt.Errorf("refund denied by the refund policy")
+
+var ErrTicketNotRefundable = fmt.Errorf("ticket can't be refunded")
+
+var ErrTicketAlreadyRefunded = fmt.Errorf("ticket is already refunded: %w", ErrTicketNotRefundable)
+
+var ErrRefundInProgress = fmt.Errorf("ticket has a refund in progress")
+
+var ErrInvalidWebhookSubscription = fmt.Errorf("invalid webhook subscription")
+
+var ErrWebhookSubscriptionDisabled = fmt.Errorf("webhook subscription is disabled")
+
+var ErrWebhookDeliveryPending = fmt.Errorf("webhook delivery is still pending")
@@ -11,11 +11,11 @@ type Event interface {
 }
 
 type TicketBookingConfirmed_v1 struct {
-	Header        EventHeader `json:"header"`
-	TicketID      string      `json:"ticket_id"`
-	CustomerEmail string      `json:"customer_email"`
-	Price         Money       `json:"price"`
-	BookingID     string      `json:"booking_id"`
+	Header        EventHeader `json:"header" protobuf:"1"`
+	TicketID      string      `json:"ticket_id" protobuf:"2"`
+	CustomerEmail string      `json:"customer_email" protobuf:"3"`
+	Price         Money       `json:"price" protobuf:"4"`
+	BookingID     string      `json:"booking_id" protobuf:"5"`
 }
 
 func (t TicketBookingConfirmed_v1) IsInternal() bool {
@@ -23,11 +23,11 @@ func (t TicketBookingConfirmed_v1) IsInternal() bool {
 }
 
 type TicketBookingCanceled_v1 struct {
-	Header        EventHeader `json:"header"`
-	TicketId      string      `json:"ticket_id"`
-	BookingId     string      `json:"booking_id"`
-	CustomerEmail string      `json:"customer_email"`
-	Price         Money       `json:"price"`
+	Header        EventHeader `json:"header" protobuf:"1"`
+	TicketId      string      `json:"ticket_id" protobuf:"2"`
+	BookingId     string      `json:"booking_id" protobuf:"3"`
+	CustomerEmail string      `json:"customer_email" protobuf:"4"`
+	Price         Money       `json:"price" protobuf:"5"`
 }
 
 func (t TicketBookingCanceled_v1) IsInternal() bool {
@@ -35,12 +35,12 @@ func (t TicketBookingCanceled_v1) IsInternal() bool {
 }
 
 type TicketPrinted_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	TicketID  string    `json:"ticket_id"`
-	BookingID string    `json:"booking_id"`
-	FileName  string    `json:"file_name"`
-	PrintedAt time.Time `json:"printed_at"`
+	TicketID  string    `json:"ticket_id" protobuf:"2"`
+	BookingID string    `json:"booking_id" protobuf:"3"`
+	FileName  string    `json:"file_name" protobuf:"4"`
+	PrintedAt time.Time `json:"printed_at" protobuf:"5"`
 }
 
 func (t TicketPrinted_v1) IsInternal() bool {
@@ -48,13 +48,13 @@ func (t TicketPrinted_v1) IsInternal() bool {
 }
 
 type TicketReceiptIssued_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	TicketId      string `json:"ticket_id"`
-	ReceiptNumber string `json:"receipt_number"`
+	TicketId      string `json:"ticket_id" protobuf:"2"`
+	ReceiptNumber string `json:"receipt_number" protobuf:"3"`
 
-	IssuedAt  time.Time `json:"issued_at"`
-	BookingId string    `json:"booking_id"`
+	IssuedAt  time.Time `json:"issued_at" protobuf:"4"`
+	BookingId string    `json:"booking_id" protobuf:"5"`
 }
 
 func (t TicketReceiptIssued_v1) IsInternal() bool {
@@ -62,9 +62,13 @@ func (t TicketReceiptIssued_v1) IsInternal() bool {
 }
 
 type TicketRefunded_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	TicketID string `json:"ticket_id"`
+	TicketID string `json:"ticket_id" protobuf:"2"`
+
+	// RefundedAmount is less than the ticket price for partial refunds.
+	// It's empty in events published before the refund policy was introduced, when tickets were refunded in full.
+	RefundedAmount *Money `json:"refunded_amount,omitempty" protobuf:"3"`
 }
 
 func (t TicketRefunded_v1) IsInternal() bool {
@@ -72,12 +76,12 @@ func (t TicketRefunded_v1) IsInternal() bool {
 }
 
 type BookingMade_v1 struct {
-	Header          EventHeader `json:"header"`
-	BookingID       uuid.UUID   `json:"booking_id"`
-	NumberOfTickets int         `json:"number_of_tickets"`
-	CustomerEmail   string      `json:"customer_email"`
-	ShowID          uuid.UUID   `json:"show_id"`
-	BookedAt        time.Time   `json:"booked_at"`
+	Header          EventHeader `json:"header" protobuf:"1"`
+	BookingID       uuid.UUID   `json:"booking_id" protobuf:"2"`
+	NumberOfTickets int         `json:"number_of_tickets" protobuf:"3"`
+	CustomerEmail   string      `json:"customer_email" protobuf:"4"`
+	ShowID          uuid.UUID   `json:"show_id" protobuf:"5"`
+	BookedAt        time.Time   `json:"booked_at" protobuf:"6"`
 }
 
 func (b BookingMade_v1) IsInternal() bool {
@@ -173,3 +177,81 @@ type VipBundleCancelled_v1 struct {
 	VipBundleID uuid.UUID   `json:"vip_bundle_id"`
 	// Add other relevant fields as necessary
 }
+
+type BookingCancellationInitialized_v1 struct {
+	Header EventHeader `json:"header" protobuf:"1"`
+
+	BookingID uuid.UUID `json:"booking_id" protobuf:"2"`
+}
+
+func (b BookingCancellationInitialized_v1) IsInternal() bool {
+	return false
+}
+
+type BookingCancelled_v1 struct {
+	Header          EventHeader `json:"header" protobuf:"1"`
+	BookingID       uuid.UUID   `json:"booking_id" protobuf:"2"`
+	ShowID          uuid.UUID   `json:"show_id" protobuf:"3"`
+	NumberOfTickets int         `json:"number_of_tickets" protobuf:"4"`
+	CustomerEmail   string      `json:"customer_email" protobuf:"5"`
+	Reason          string      `json:"reason" protobuf:"6"`
+	CancelledAt     time.Time   `json:"cancelled_at" protobuf:"7"`
+}
+
+func (b BookingCancelled_v1) IsInternal() bool {
+	return false
+}
+
+type ShowCancelled_v1 struct {
+	Header      EventHeader `json:"header" protobuf:"1"`
+	ShowID      uuid.UUID   `json:"show_id" protobuf:"2"`
+	Reason      string      `json:"reason" protobuf:"3"`
+	CancelledAt time.Time   `json:"cancelled_at" protobuf:"4"`
+}
+
+func (s ShowCancelled_v1) IsInternal() bool {
+	return false
+}
+
+type TicketCheckedIn_v1 struct {
+	Header EventHeader `json:"header" protobuf:"1"`
+
+	TicketID    string    `json:"ticket_id" protobuf:"2"`
+	BookingID   string    `json:"booking_id" protobuf:"3"`
+	ShowID      string    `json:"show_id" protobuf:"4"`
+	CheckedInAt time.Time `json:"checked_in_at" protobuf:"5"`
+}
+
+func (t TicketCheckedIn_v1) IsInternal() bool {
+	return false
+}
+
+type TicketTransferRequested_v1 struct {
+	Header EventHeader `json:"header" protobuf:"1"`
+
+	TransferID  uuid.UUID `json:"transfer_id" protobuf:"2"`
+	TicketID    string    `json:"ticket_id" protobuf:"3"`
+	FromEmail   string    `json:"from_email" protobuf:"4"`
+	ToEmail     string    `json:"to_email" protobuf:"5"`
+	RequestedAt time.Time `json:"requested_at" protobuf:"6"`
+}
+
+func (t TicketTransferRequested_v1) IsInternal() bool {
+	return false
+}
+
+type TicketTransferred_v1 struct {
+	Header EventHeader `json:"header" protobuf:"1"`
+
+	TransferID    uuid.UUID `json:"transfer_id" protobuf:"2"`
+	TicketID      string    `json:"ticket_id" protobuf:"3"`
+	BookingID     string    `json:"booking_id" protobuf:"4"`
+	ShowID        string    `json:"show_id" protobuf:"5"`
+	FromEmail     string    `json:"from_email" protobuf:"6"`
+	ToEmail       string    `json:"to_email" protobuf:"7"`
+	TransferredAt time.Time `json:"transferred_at" protobuf:"8"`
+}
+
+func (t TicketTransferred_v1) IsInternal() bool {
+	return false
+}
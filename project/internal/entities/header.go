@@ -6,9 +6,9 @@ import (
 )
 
 type EventHeader struct {
-	Id             string    `json:"id"`
-	PublishedAt    time.Time `json:"published_at"`
-	IdempotencyKey string    `json:"idempotency_key"`
+	Id             string    `json:"id" protobuf:"1"`
+	PublishedAt    time.Time `json:"published_at" protobuf:"2"`
+	IdempotencyKey string    `json:"idempotency_key" protobuf:"3"`
 }
 
 func NewEventHeader() EventHeader {
@@ -0,0 +1,25 @@
+package entities
+
+import "time"
+
+// IdempotentRequest is an HTTP request sent with the Idempotency-Key header.
+// Response is nil while the first request with the key is handled.
+type IdempotentRequest struct {
+	Key         string
+	Fingerprint string
+	Response    *IdempotentResponse
+	LockedAt    time.Time
+}
+
+func (r IdempotentRequest) IsCompleted() bool {
+	return r.Response != nil
+}
+
+// IdempotentResponse is replayed to every retry of the request.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	// Headers are the response headers replayed with the body, for example Location of the created resource.
+	Headers map[string]string
+	Body    []byte
+}
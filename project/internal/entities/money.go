@@ -1,6 +1,145 @@
 package entities
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/currency"
+)
+
+// DefaultCurrency is used when the price comes without the currency.
+// It's the only place where the currency is defaulted.
+const DefaultCurrency = "USD"
+
+var ErrInvalidMoney = fmt.Errorf("invalid money")
+
+// Money is a decimal amount in an ISO-4217 currency.
+// The amount is never converted to float, so it's the same in events, the database and receipts.
 type Money struct {
-	Amount   string `json:"amount"`
-	Currency string `json:"currency"`
+	Amount   decimal.Decimal `protobuf:"1"`
+	Currency string          `protobuf:"2"`
+}
+
+// NewMoney parses the amount and validates the currency.
+// The amount can't be more precise than the minor unit of the currency, e.g. cents for USD.
+func NewMoney(amount string, currencyCode string) (Money, error) {
+	if currencyCode == "" {
+		currencyCode = DefaultCurrency
+	}
+
+	unit, err := currency.ParseISO(currencyCode)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: unknown currency %q", ErrInvalidMoney, currencyCode)
+	}
+
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: amount %q is not a decimal number", ErrInvalidMoney, amount)
+	}
+
+	m := Money{Amount: value, Currency: unit.String()}
+	if !value.Equal(value.Round(m.scale())) {
+		return Money{}, fmt.Errorf("%w: amount %s has more than %d decimal places for %s", ErrInvalidMoney, amount, m.scale(), m.Currency)
+	}
+
+	return m, nil
+}
+
+// MustNewMoney is NewMoney for amounts known to be valid, like constants in tests.
+func MustNewMoney(amount string, currencyCode string) Money {
+	m, err := NewMoney(amount, currencyCode)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// AmountString formats the amount with the number of decimal places of the currency, e.g. "12.50" for USD.
+func (m Money) AmountString() string {
+	return m.Amount.StringFixed(m.scale())
+}
+
+func (m Money) String() string {
+	return m.AmountString() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount.IsZero()
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.Currency == other.Currency && m.Amount.Equal(other.Amount)
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: can't add %s to %s", ErrInvalidMoney, other.Currency, m.Currency)
+	}
+
+	return Money{Amount: m.Amount.Add(other.Amount), Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: can't subtract %s from %s", ErrInvalidMoney, other.Currency, m.Currency)
+	}
+
+	return Money{Amount: m.Amount.Sub(other.Amount), Currency: m.Currency}, nil
+}
+
+// Mul multiplies the amount, rounding half away from zero to the minor unit of the currency.
+func (m Money) Mul(factor decimal.Decimal) Money {
+	return Money{Amount: m.Amount.Mul(factor).Round(m.scale()), Currency: m.Currency}
+}
+
+func (m Money) scale() int32 {
+	unit, err := currency.ParseISO(m.Currency)
+	if err != nil {
+		// zero value Money
+		return 2
+	}
+
+	scale, _ := currency.Standard.Rounding(unit)
+	return int32(scale)
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount, err := json.Marshal(m.AmountString())
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(moneyJSON{
+		Amount:   amount,
+		Currency: m.Currency,
+	})
+}
+
+// UnmarshalJSON accepts the amount both as a string and as a number.
+// A missing amount is an error, so malformed payloads are never read as free tickets.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	amount := string(bytes.Trim(raw.Amount, `"`))
+	if amount == "" || amount == "null" {
+		return fmt.Errorf("%w: amount is missing", ErrInvalidMoney)
+	}
+
+	parsed, err := NewMoney(amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
 }
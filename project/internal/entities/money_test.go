@@ -0,0 +1,239 @@
+package entities
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMoney(t *testing.T) {
+	testCases := []struct {
+		name             string
+		amount           string
+		currency         string
+		expectedAmount   string
+		expectedCurrency string
+		expectedErr      bool
+	}{
+		{
+			name:             "usd",
+			amount:           "12.5",
+			currency:         "USD",
+			expectedAmount:   "12.50",
+			expectedCurrency: "USD",
+		},
+		{
+			name:             "default currency",
+			amount:           "10",
+			expectedAmount:   "10.00",
+			expectedCurrency: DefaultCurrency,
+		},
+		{
+			name:             "lower case currency",
+			amount:           "10",
+			currency:         "eur",
+			expectedAmount:   "10.00",
+			expectedCurrency: "EUR",
+		},
+		{
+			name:             "currency without minor unit",
+			amount:           "1500",
+			currency:         "JPY",
+			expectedAmount:   "1500",
+			expectedCurrency: "JPY",
+		},
+		{
+			name:             "currency with three decimal places",
+			amount:           "1.125",
+			currency:         "KWD",
+			expectedAmount:   "1.125",
+			expectedCurrency: "KWD",
+		},
+		{
+			name:        "unknown currency",
+			amount:      "10",
+			currency:    "XYZ1",
+			expectedErr: true,
+		},
+		{
+			name:        "amount is not a number",
+			amount:      "ten",
+			currency:    "USD",
+			expectedErr: true,
+		},
+		{
+			name:        "empty amount",
+			amount:      "",
+			currency:    "USD",
+			expectedErr: true,
+		},
+		{
+			name:        "more decimal places than the currency has",
+			amount:      "12.345",
+			currency:    "USD",
+			expectedErr: true,
+		},
+		{
+			name:        "decimal places for currency without minor unit",
+			amount:      "1500.5",
+			currency:    "JPY",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewMoney(tc.amount, tc.currency)
+			if tc.expectedErr {
+				assert.ErrorIs(t, err, ErrInvalidMoney)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedAmount, m.AmountString())
+			assert.Equal(t, tc.expectedCurrency, m.Currency)
+		})
+	}
+}
+
+func TestMoney_AddSub(t *testing.T) {
+	a := MustNewMoney("10.25", "USD")
+	b := MustNewMoney("0.75", "USD")
+
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	assert.True(t, sum.Equal(MustNewMoney("11", "USD")), sum.String())
+
+	diff, err := a.Sub(b)
+	require.NoError(t, err)
+	assert.True(t, diff.Equal(MustNewMoney("9.5", "USD")), diff.String())
+
+	_, err = a.Add(MustNewMoney("1", "EUR"))
+	assert.ErrorIs(t, err, ErrInvalidMoney)
+
+	_, err = a.Sub(MustNewMoney("1", "EUR"))
+	assert.ErrorIs(t, err, ErrInvalidMoney)
+}
+
+func TestMoney_Mul(t *testing.T) {
+	testCases := []struct {
+		name     string
+		money    Money
+		factor   string
+		expected string
+	}{
+		{
+			name:     "half",
+			money:    MustNewMoney("100", "USD"),
+			factor:   "0.5",
+			expected: "50.00",
+		},
+		{
+			name:     "half cent is rounded up",
+			money:    MustNewMoney("0.25", "USD"),
+			factor:   "0.5",
+			expected: "0.13",
+		},
+		{
+			name:     "below half cent is rounded down",
+			money:    MustNewMoney("10.01", "USD"),
+			factor:   "0.333",
+			expected: "3.33",
+		},
+		{
+			name:     "negative half cent is rounded away from zero",
+			money:    MustNewMoney("-0.25", "USD"),
+			factor:   "0.5",
+			expected: "-0.13",
+		},
+		{
+			name:     "currency without minor unit",
+			money:    MustNewMoney("15", "JPY"),
+			factor:   "0.5",
+			expected: "8",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.money.Mul(decimal.RequireFromString(tc.factor))
+
+			assert.Equal(t, tc.expected, m.AmountString())
+			assert.Equal(t, tc.money.Currency, m.Currency)
+		})
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		m := MustNewMoney("12.5", "EUR")
+
+		data, err := json.Marshal(m)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"amount":"12.50","currency":"EUR"}`, string(data))
+
+		var decoded Money
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.True(t, m.Equal(decoded), decoded.String())
+	})
+
+	testCases := []struct {
+		name        string
+		data        string
+		expected    Money
+		expectedErr bool
+	}{
+		{
+			name:     "string amount",
+			data:     `{"amount":"49.90","currency":"USD"}`,
+			expected: MustNewMoney("49.90", "USD"),
+		},
+		{
+			name:     "number amount",
+			data:     `{"amount":49.9,"currency":"USD"}`,
+			expected: MustNewMoney("49.90", "USD"),
+		},
+		{
+			name:     "without currency",
+			data:     `{"amount":"10"}`,
+			expected: MustNewMoney("10", DefaultCurrency),
+		},
+		{
+			name:        "missing amount",
+			data:        `{"currency":"USD"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "empty amount",
+			data:        `{"amount":"","currency":"USD"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "null amount",
+			data:        `{"amount":null,"currency":"USD"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid amount",
+			data:        `{"amount":"12.345","currency":"USD"}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var m Money
+			err := json.Unmarshal([]byte(tc.data), &m)
+			if tc.expectedErr {
+				assert.ErrorIs(t, err, ErrInvalidMoney)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.True(t, tc.expected.Equal(m), m.String())
+		})
+	}
+}
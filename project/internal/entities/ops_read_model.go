@@ -7,11 +7,19 @@ import (
 
 type OpsBooking struct {
 	BookingID uuid.UUID `json:"booking_id"`
-	BookedAt  time.Time `json:"booked_at"`
+	// BookedAt is zero for placeholders of bookings whose BookingMade_v1 didn't arrive yet.
+	BookedAt time.Time `json:"booked_at"`
+	// ShowID is not known for bookings made before it was added to the read model.
+	ShowID uuid.UUID `json:"show_id"`
 
 	Tickets map[string]OpsTicket `json:"tickets"`
 
+	// LastUpdate is the time of the latest event applied to the booking.
 	LastUpdate time.Time `json:"last_update"`
+	// Version is incremented with every applied event.
+	Version int64 `json:"version"`
+	// UpdateSequence orders the updates of all bookings, it's the ID of the streamed updates.
+	UpdateSequence int64 `json:"-"`
 }
 
 type OpsTicket struct {
@@ -21,18 +29,69 @@ type OpsTicket struct {
 
 	ConfirmedAt time.Time `json:"confirmed_at"`
 	RefundedAt  time.Time `json:"refunded_at"`
+	// RefundedAmount is in PriceCurrency, it's lower than PriceAmount for partial refunds.
+	RefundedAmount string `json:"refunded_amount"`
 
 	PrintedAt       time.Time `json:"printed_at"`
 	PrintedFileName string    `json:"printed_file_name"`
 
 	ReceiptIssuedAt time.Time `json:"receipt_issued_at"`
 	ReceiptNumber   string    `json:"receipt_number"`
+
+	TransferredAt time.Time `json:"transferred_at"`
+}
+
+type OpsTicketStatus string
+
+const (
+	// OpsTicketStatusPending tickets got other events before TicketBookingConfirmed_v1.
+	OpsTicketStatusPending   OpsTicketStatus = "pending"
+	OpsTicketStatusConfirmed OpsTicketStatus = "confirmed"
+	OpsTicketStatusRefunded  OpsTicketStatus = "refunded"
+)
+
+func (t OpsTicket) Status() OpsTicketStatus {
+	switch {
+	case !t.RefundedAt.IsZero():
+		return OpsTicketStatusRefunded
+	case !t.ConfirmedAt.IsZero():
+		return OpsTicketStatusConfirmed
+	default:
+		return OpsTicketStatusPending
+	}
+}
+
+// OpsBookingRefundState tells how many tickets of the booking were refunded.
+type OpsBookingRefundState string
+
+const (
+	OpsBookingRefundStateNone    OpsBookingRefundState = "none"
+	OpsBookingRefundStatePartial OpsBookingRefundState = "partial"
+	OpsBookingRefundStateFull    OpsBookingRefundState = "full"
+)
+
+func (b OpsBooking) RefundState() OpsBookingRefundState {
+	refunded := 0
+	for _, ticket := range b.Tickets {
+		if ticket.Status() == OpsTicketStatusRefunded {
+			refunded++
+		}
+	}
+
+	switch {
+	case refunded == 0:
+		return OpsBookingRefundStateNone
+	case refunded == len(b.Tickets):
+		return OpsBookingRefundStateFull
+	default:
+		return OpsBookingRefundStatePartial
+	}
 }
 
 type InternalOpsReadModelUpdated struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	BookingID uuid.UUID `json:"booking_id"`
+	BookingID uuid.UUID `json:"booking_id" protobuf:"2"`
 }
 
 func (InternalOpsReadModelUpdated) IsInternal() bool {
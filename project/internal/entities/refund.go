@@ -0,0 +1,200 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type RefundInitiator string
+
+const (
+	// RefundInitiatorCustomer is a refund or a booking cancellation requested by the customer.
+	RefundInitiatorCustomer RefundInitiator = "customer"
+	// RefundInitiatorOrganizer is a refund caused by the show cancellation.
+	RefundInitiatorOrganizer RefundInitiator = "organizer"
+	// RefundInitiatorSystem is a compensation of a failed process, like a VIP bundle which couldn't be booked.
+	// It's always refunded in full.
+	RefundInitiatorSystem RefundInitiator = "system"
+)
+
+type RefundOutcome string
+
+const (
+	RefundOutcomeFull    RefundOutcome = "full"
+	RefundOutcomePartial RefundOutcome = "partial"
+	RefundOutcomeDenied  RefundOutcome = "denied"
+)
+
+// RefundRule applies when at least MinHoursBeforeShow are left until the show starts.
+type RefundRule struct {
+	MinHoursBeforeShow int `json:"min_hours_before_show"`
+	Percent            int `json:"percent"`
+}
+
+type RefundRules struct {
+	Rules []RefundRule `json:"rules"`
+	// OtherwisePercent is refunded when no rule applies, e.g. after the show started.
+	OtherwisePercent int `json:"otherwise_percent"`
+}
+
+// RefundPolicy decides how much of the ticket price is refunded.
+type RefundPolicy struct {
+	Customer  RefundRules `json:"customer"`
+	Organizer RefundRules `json:"organizer"`
+}
+
+func DefaultRefundPolicy() RefundPolicy {
+	return RefundPolicy{
+		Customer: RefundRules{
+			Rules: []RefundRule{
+				{MinHoursBeforeShow: 7 * 24, Percent: 100},
+				{MinHoursBeforeShow: 48, Percent: 50},
+			},
+			OtherwisePercent: 0,
+		},
+		Organizer: RefundRules{
+			OtherwisePercent: 100,
+		},
+	}
+}
+
+// ParseRefundPolicy parses the policy from JSON, the default policy is used when it's empty.
+func ParseRefundPolicy(data string) (RefundPolicy, error) {
+	if data == "" {
+		return DefaultRefundPolicy(), nil
+	}
+
+	var policy RefundPolicy
+	if err := json.Unmarshal([]byte(data), &policy); err != nil {
+		return RefundPolicy{}, fmt.Errorf("invalid refund policy: %w", err)
+	}
+
+	for _, rules := range []RefundRules{policy.Customer, policy.Organizer} {
+		if !isValidRefundPercent(rules.OtherwisePercent) {
+			return RefundPolicy{}, fmt.Errorf("invalid refund policy: otherwise_percent %d is not between 0 and 100", rules.OtherwisePercent)
+		}
+		// rules with the same hours would be applied depending on their order
+		hours := make(map[int]bool, len(rules.Rules))
+		for _, rule := range rules.Rules {
+			if !isValidRefundPercent(rule.Percent) {
+				return RefundPolicy{}, fmt.Errorf("invalid refund policy: percent %d is not between 0 and 100", rule.Percent)
+			}
+			if hours[rule.MinHoursBeforeShow] {
+				return RefundPolicy{}, fmt.Errorf("invalid refund policy: more than one rule for %d hours before the show", rule.MinHoursBeforeShow)
+			}
+			hours[rule.MinHoursBeforeShow] = true
+		}
+	}
+
+	return policy, nil
+}
+
+func isValidRefundPercent(percent int) bool {
+	return percent >= 0 && percent <= 100
+}
+
+// Evaluate decides the refund of the ticket. showStartTime is nil for tickets of unknown shows,
+// they are refunded in full as there is nothing to evaluate the rules against.
+func (p RefundPolicy) Evaluate(
+	initiator RefundInitiator,
+	price Money,
+	showStartTime *time.Time,
+	requestedAt time.Time,
+) RefundDecision {
+	decision := RefundDecision{
+		DecisionID:    uuid.New(),
+		Initiator:     initiator,
+		Price:         price,
+		ShowStartTime: showStartTime,
+		RequestedAt:   requestedAt,
+	}
+
+	switch {
+	case initiator == RefundInitiatorSystem:
+		decision.Percent = 100
+		decision.Rule = "system refunds are always full"
+	case showStartTime == nil:
+		decision.Percent = 100
+		decision.Rule = "show is not known"
+	default:
+		rules := p.Customer
+		if initiator == RefundInitiatorOrganizer {
+			rules = p.Organizer
+		}
+		decision.Percent, decision.Rule = rules.evaluate(showStartTime.Sub(requestedAt))
+	}
+
+	decision.RefundedAmount = price.Mul(decimal.NewFromInt(int64(decision.Percent)).Div(decimal.NewFromInt(100)))
+
+	switch decision.Percent {
+	case 100:
+		decision.Outcome = RefundOutcomeFull
+	case 0:
+		decision.Outcome = RefundOutcomeDenied
+	default:
+		decision.Outcome = RefundOutcomePartial
+	}
+
+	return decision
+}
+
+// AllowsRefund tells if the policy allows any refund for the show, without knowing the ticket price.
+func (p RefundPolicy) AllowsRefund(initiator RefundInitiator, showStartTime time.Time, requestedAt time.Time) bool {
+	return !p.Evaluate(initiator, Money{}, &showStartTime, requestedAt).IsDenied()
+}
+
+func (r RefundRules) evaluate(timeBeforeShow time.Duration) (int, string) {
+	rules := make([]RefundRule, len(r.Rules))
+	copy(rules, r.Rules)
+
+	// the rule closest to the show start wins
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].MinHoursBeforeShow > rules[j].MinHoursBeforeShow
+	})
+
+	for _, rule := range rules {
+		if timeBeforeShow >= time.Duration(rule.MinHoursBeforeShow)*time.Hour {
+			return rule.Percent, fmt.Sprintf("at least %dh before the show", rule.MinHoursBeforeShow)
+		}
+	}
+
+	return r.OtherwisePercent, "no rule matched the time before the show"
+}
+
+// RefundDecision is stored for every evaluation of the refund policy, including denied refunds.
+type RefundDecision struct {
+	DecisionID     uuid.UUID       `json:"decision_id"`
+	TicketID       string          `json:"ticket_id"`
+	Initiator      RefundInitiator `json:"initiator"`
+	Outcome        RefundOutcome   `json:"outcome"`
+	Percent        int             `json:"percent"`
+	Price          Money           `json:"price"`
+	RefundedAmount Money           `json:"refunded_amount"`
+	Rule           string          `json:"rule"`
+
+	ShowStartTime *time.Time `json:"show_start_time,omitempty"`
+	RequestedAt   time.Time  `json:"requested_at"`
+}
+
+func (d RefundDecision) IsDenied() bool {
+	return d.Outcome == RefundOutcomeDenied
+}
+
+// Reason describes the decision for the payment provider and the support team.
+func (d RefundDecision) Reason() string {
+	return fmt.Sprintf("%s refund of %s (%d%% of %s) requested by %s: %s", d.Outcome, d.RefundedAmount, d.Percent, d.Price, d.Initiator, d.Rule)
+}
+
+// RefundRequest asks for the refund of a single ticket.
+// The policy is evaluated for RequestedAt, so retried requests get the same decision.
+type RefundRequest struct {
+	TicketID       string
+	Initiator      RefundInitiator
+	RequestedAt    time.Time
+	IdempotencyKey string
+}
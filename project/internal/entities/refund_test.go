@@ -0,0 +1,279 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundRules_evaluate(t *testing.T) {
+	testCases := []struct {
+		name            string
+		rules           RefundRules
+		timeBeforeShow  time.Duration
+		expectedPercent int
+	}{
+		{
+			name:            "exactly 168h before the show",
+			rules:           DefaultRefundPolicy().Customer,
+			timeBeforeShow:  168 * time.Hour,
+			expectedPercent: 100,
+		},
+		{
+			name:            "just under 168h before the show",
+			rules:           DefaultRefundPolicy().Customer,
+			timeBeforeShow:  168*time.Hour - time.Second,
+			expectedPercent: 50,
+		},
+		{
+			name:            "exactly 48h before the show",
+			rules:           DefaultRefundPolicy().Customer,
+			timeBeforeShow:  48 * time.Hour,
+			expectedPercent: 50,
+		},
+		{
+			name:            "just under 48h before the show",
+			rules:           DefaultRefundPolicy().Customer,
+			timeBeforeShow:  48*time.Hour - time.Second,
+			expectedPercent: 0,
+		},
+		{
+			name:            "after the show started",
+			rules:           DefaultRefundPolicy().Customer,
+			timeBeforeShow:  -time.Hour,
+			expectedPercent: 0,
+		},
+		{
+			name: "rules out of order",
+			rules: RefundRules{
+				Rules: []RefundRule{
+					{MinHoursBeforeShow: 24, Percent: 25},
+					{MinHoursBeforeShow: 168, Percent: 100},
+					{MinHoursBeforeShow: 72, Percent: 50},
+				},
+				OtherwisePercent: 10,
+			},
+			timeBeforeShow:  100 * time.Hour,
+			expectedPercent: 50,
+		},
+		{
+			name: "rules out of order, no rule applies",
+			rules: RefundRules{
+				Rules: []RefundRule{
+					{MinHoursBeforeShow: 24, Percent: 25},
+					{MinHoursBeforeShow: 168, Percent: 100},
+				},
+				OtherwisePercent: 10,
+			},
+			timeBeforeShow:  23 * time.Hour,
+			expectedPercent: 10,
+		},
+		{
+			name:            "without rules",
+			rules:           DefaultRefundPolicy().Organizer,
+			timeBeforeShow:  time.Hour,
+			expectedPercent: 100,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			percent, rule := tc.rules.evaluate(tc.timeBeforeShow)
+			assert.Equal(t, tc.expectedPercent, percent)
+			assert.NotEmpty(t, rule)
+		})
+	}
+}
+
+func TestRefundPolicy_Evaluate(t *testing.T) {
+	requestedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	showIn := func(d time.Duration) *time.Time {
+		showStartTime := requestedAt.Add(d)
+		return &showStartTime
+	}
+
+	testCases := []struct {
+		name string
+		// policy is the default policy when it's nil
+		policy          *RefundPolicy
+		initiator       RefundInitiator
+		price           Money
+		showStartTime   *time.Time
+		expectedOutcome RefundOutcome
+		expectedPercent int
+		expectedAmount  Money
+	}{
+		{
+			name:            "customer, a week before the show",
+			initiator:       RefundInitiatorCustomer,
+			price:           MustNewMoney("100.00", "USD"),
+			showStartTime:   showIn(168 * time.Hour),
+			expectedOutcome: RefundOutcomeFull,
+			expectedPercent: 100,
+			expectedAmount:  MustNewMoney("100.00", "USD"),
+		},
+		{
+			name:            "customer, two days before the show",
+			initiator:       RefundInitiatorCustomer,
+			price:           MustNewMoney("100.00", "USD"),
+			showStartTime:   showIn(48 * time.Hour),
+			expectedOutcome: RefundOutcomePartial,
+			expectedPercent: 50,
+			expectedAmount:  MustNewMoney("50.00", "USD"),
+		},
+		{
+			name:            "customer, a day before the show",
+			initiator:       RefundInitiatorCustomer,
+			price:           MustNewMoney("100.00", "USD"),
+			showStartTime:   showIn(24 * time.Hour),
+			expectedOutcome: RefundOutcomeDenied,
+			expectedPercent: 0,
+			expectedAmount:  MustNewMoney("0", "USD"),
+		},
+		{
+			name:            "organizer, after the show started",
+			initiator:       RefundInitiatorOrganizer,
+			price:           MustNewMoney("100.00", "USD"),
+			showStartTime:   showIn(-time.Hour),
+			expectedOutcome: RefundOutcomeFull,
+			expectedPercent: 100,
+			expectedAmount:  MustNewMoney("100.00", "USD"),
+		},
+		{
+			name:            "system, after the show started",
+			initiator:       RefundInitiatorSystem,
+			price:           MustNewMoney("100.00", "USD"),
+			showStartTime:   showIn(-time.Hour),
+			expectedOutcome: RefundOutcomeFull,
+			expectedPercent: 100,
+			expectedAmount:  MustNewMoney("100.00", "USD"),
+		},
+		{
+			name:            "system ignores the rules",
+			policy:          &RefundPolicy{},
+			initiator:       RefundInitiatorSystem,
+			price:           MustNewMoney("100.00", "USD"),
+			showStartTime:   showIn(time.Hour),
+			expectedOutcome: RefundOutcomeFull,
+			expectedPercent: 100,
+			expectedAmount:  MustNewMoney("100.00", "USD"),
+		},
+		{
+			name:            "unknown show",
+			initiator:       RefundInitiatorCustomer,
+			price:           MustNewMoney("100.00", "USD"),
+			showStartTime:   nil,
+			expectedOutcome: RefundOutcomeFull,
+			expectedPercent: 100,
+			expectedAmount:  MustNewMoney("100.00", "USD"),
+		},
+		{
+			name:            "rounded half up to cents",
+			initiator:       RefundInitiatorCustomer,
+			price:           MustNewMoney("0.05", "USD"),
+			showStartTime:   showIn(48 * time.Hour),
+			expectedOutcome: RefundOutcomePartial,
+			expectedPercent: 50,
+			expectedAmount:  MustNewMoney("0.03", "USD"),
+		},
+		{
+			name: "rounded down to cents",
+			policy: &RefundPolicy{
+				Customer: RefundRules{OtherwisePercent: 33},
+			},
+			initiator:       RefundInitiatorCustomer,
+			price:           MustNewMoney("10.01", "EUR"),
+			showStartTime:   showIn(time.Hour),
+			expectedOutcome: RefundOutcomePartial,
+			expectedPercent: 33,
+			expectedAmount:  MustNewMoney("3.30", "EUR"),
+		},
+		{
+			name:            "rounded to currency without minor unit",
+			initiator:       RefundInitiatorCustomer,
+			price:           MustNewMoney("101", "JPY"),
+			showStartTime:   showIn(48 * time.Hour),
+			expectedOutcome: RefundOutcomePartial,
+			expectedPercent: 50,
+			expectedAmount:  MustNewMoney("51", "JPY"),
+		},
+		{
+			name:            "rounded to currency with three decimal places",
+			initiator:       RefundInitiatorCustomer,
+			price:           MustNewMoney("0.005", "KWD"),
+			showStartTime:   showIn(48 * time.Hour),
+			expectedOutcome: RefundOutcomePartial,
+			expectedPercent: 50,
+			expectedAmount:  MustNewMoney("0.003", "KWD"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := DefaultRefundPolicy()
+			if tc.policy != nil {
+				policy = *tc.policy
+			}
+
+			decision := policy.Evaluate(tc.initiator, tc.price, tc.showStartTime, requestedAt)
+			assert.Equal(t, tc.expectedOutcome, decision.Outcome)
+			assert.Equal(t, tc.expectedPercent, decision.Percent)
+			assert.True(t, tc.expectedAmount.Equal(decision.RefundedAmount), "expected %s, got %s", tc.expectedAmount, decision.RefundedAmount)
+			assert.Equal(t, tc.expectedAmount.AmountString(), decision.RefundedAmount.AmountString())
+			assert.Equal(t, tc.initiator, decision.Initiator)
+			assert.Equal(t, requestedAt, decision.RequestedAt)
+			assert.NotEmpty(t, decision.Rule)
+		})
+	}
+}
+
+func TestParseRefundPolicy(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		policy, err := ParseRefundPolicy("")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultRefundPolicy(), policy)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		policy, err := ParseRefundPolicy(`{
+			"customer": {
+				"rules": [
+					{"min_hours_before_show": 24, "percent": 50},
+					{"min_hours_before_show": 72, "percent": 100}
+				],
+				"otherwise_percent": 0
+			},
+			"organizer": {"otherwise_percent": 100}
+		}`)
+		require.NoError(t, err)
+		assert.Equal(t, RefundPolicy{
+			Customer: RefundRules{
+				Rules: []RefundRule{
+					{MinHoursBeforeShow: 24, Percent: 50},
+					{MinHoursBeforeShow: 72, Percent: 100},
+				},
+				OtherwisePercent: 0,
+			},
+			Organizer: RefundRules{OtherwisePercent: 100},
+		}, policy)
+	})
+
+	invalid := map[string]string{
+		"invalid JSON":               `{"customer": `,
+		"percent above 100":          `{"customer": {"rules": [{"min_hours_before_show": 24, "percent": 101}]}}`,
+		"negative percent":           `{"customer": {"rules": [{"min_hours_before_show": 24, "percent": -1}]}}`,
+		"otherwise above 100":        `{"organizer": {"otherwise_percent": 150}}`,
+		"negative otherwise":         `{"customer": {"otherwise_percent": -10}}`,
+		"duplicated hours":           `{"customer": {"rules": [{"min_hours_before_show": 48, "percent": 50}, {"min_hours_before_show": 48, "percent": 100}]}}`,
+		"duplicated organizer hours": `{"organizer": {"rules": [{"min_hours_before_show": 0, "percent": 50}, {"min_hours_before_show": 0, "percent": 100}]}}`,
+	}
+
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRefundPolicy(data)
+			assert.Error(t, err)
+		})
+	}
+}
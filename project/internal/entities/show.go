@@ -12,4 +12,39 @@ type Show struct {
 	StartTime       time.Time `json:"start_time"`
 	Title           string    `json:"title"`
 	Venue           string    `json:"venue"`
+
+	// CancelledAt is set when the show was cancelled, see ShowCancellation.
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+}
+
+func (s Show) IsCancelled() bool {
+	return s.CancelledAt != nil
+}
+
+type ShowCancellation struct {
+	ShowID      uuid.UUID `json:"show_id"`
+	Reason      string    `json:"reason"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+// ShowCancellationProgress reports how far the refunds of a cancelled show got.
+type ShowCancellationProgress struct {
+	ShowCancellation
+
+	BookingsTotal     int `json:"bookings_total"`
+	BookingsCancelled int `json:"bookings_cancelled"`
+	TicketsTotal      int `json:"tickets_total"`
+	TicketsRefunded   int `json:"tickets_refunded"`
+
+	// BookingsInProgress are bookings with refunds which are not finished yet.
+	BookingsInProgress []uuid.UUID `json:"bookings_in_progress"`
+	// BookingsWaitingForTickets are bookings with tickets which are not confirmed yet,
+	// they are cancelled once their last ticket is confirmed.
+	BookingsWaitingForTickets []uuid.UUID `json:"bookings_waiting_for_tickets"`
+	// BookingsNotStarted are bookings whose cancellation didn't start yet, ShowCancelled_v1 is still being handled.
+	BookingsNotStarted []uuid.UUID `json:"bookings_not_started"`
+}
+
+func (p ShowCancellationProgress) IsCompleted() bool {
+	return p.BookingsCancelled == p.BookingsTotal
 }
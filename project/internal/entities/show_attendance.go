@@ -0,0 +1,13 @@
+package entities
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ShowAttendance struct {
+	ShowID          uuid.UUID  `json:"show_id"`
+	CheckedIn       int        `json:"checked_in"`
+	BookedTickets   int        `json:"booked_tickets"`
+	LastCheckedInAt *time.Time `json:"last_checked_in_at"`
+}
@@ -0,0 +1,20 @@
+package entities
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ShowAvailability struct {
+	ShowID    uuid.UUID `json:"show_id"`
+	Title     string    `json:"title"`
+	Venue     string    `json:"venue"`
+	StartTime time.Time `json:"start_time"`
+
+	NumberOfTickets  int  `json:"number_of_tickets"`
+	BookedTickets    int  `json:"booked_tickets"`
+	AvailableTickets int  `json:"available_tickets"`
+	IsCancelled      bool `json:"is_cancelled"`
+
+	LastUpdate time.Time `json:"last_update"`
+}
@@ -1,9 +1,62 @@
 package entities
 
+import "time"
+
 type Ticket struct {
 	TicketId      string `json:"ticket_id"`
 	Status        string `json:"status"`
 	CustomerEmail string `json:"customer_email"`
 	Price         Money  `json:"price"`
 	BookingId     string `json:"booking_id"`
+	ShowId        string `json:"show_id"`
+
+	// TokenVersion is bumped on every transfer, check-in codes signed for older versions are rejected.
+	TokenVersion int `json:"-"`
+}
+
+type TicketStatus string
+
+const (
+	TicketStatusConfirmed     TicketStatus = "confirmed"
+	TicketStatusPrinted       TicketStatus = "printed"
+	TicketStatusReceiptIssued TicketStatus = "receipt_issued"
+	TicketStatusCheckedIn     TicketStatus = "checked_in"
+	TicketStatusRefunded      TicketStatus = "refunded"
+	TicketStatusCancelled     TicketStatus = "cancelled"
+)
+
+// ticketStatusOrder is the order in which statuses are reached in the ticket lifecycle.
+// Printing and issuing the receipt happen in parallel, so the ticket can become
+// printed after its receipt was issued; such change is only recorded in the history.
+var ticketStatusOrder = map[TicketStatus]int{
+	TicketStatusConfirmed:     0,
+	TicketStatusPrinted:       1,
+	TicketStatusReceiptIssued: 2,
+	TicketStatusCheckedIn:     3,
+	TicketStatusRefunded:      4,
+	TicketStatusCancelled:     4,
+}
+
+func (s TicketStatus) IsValid() bool {
+	_, ok := ticketStatusOrder[s]
+	return ok
+}
+
+// IsFinal is true for statuses after which the ticket can't be used anymore.
+func (s TicketStatus) IsFinal() bool {
+	return s == TicketStatusRefunded || s == TicketStatusCancelled
+}
+
+func (s TicketStatus) CanTransitionTo(next TicketStatus) bool {
+	if s.IsFinal() || !next.IsValid() {
+		return false
+	}
+
+	return ticketStatusOrder[next] > ticketStatusOrder[s]
+}
+
+type TicketStatusChange struct {
+	TicketID  string       `json:"ticket_id"`
+	Status    TicketStatus `json:"status"`
+	ChangedAt time.Time    `json:"changed_at"`
 }
@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TicketTransferStatus string
+
+const (
+	TicketTransferStatusPending   TicketTransferStatus = "pending"
+	TicketTransferStatusCompleted TicketTransferStatus = "completed"
+	TicketTransferStatusDeclined  TicketTransferStatus = "declined"
+)
+
+// TicketTransfer is a request to move the ticket to another customer.
+// Transfers are never deleted, so they can be used in fraud investigations.
+type TicketTransfer struct {
+	TransferID uuid.UUID            `json:"transfer_id"`
+	TicketID   uuid.UUID            `json:"ticket_id"`
+	FromEmail  string               `json:"from_email"`
+	ToEmail    string               `json:"to_email"`
+	Status     TicketTransferStatus `json:"status"`
+
+	// RequiresAcceptance transfers wait in the pending status until the recipient accepts or declines them.
+	RequiresAcceptance bool   `json:"requires_acceptance"`
+	RequesterIP        string `json:"requester_ip"`
+
+	RequestedAt time.Time  `json:"requested_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+func (t TicketTransfer) IsPending() bool {
+	return t.Status == TicketTransferStatusPending
+}
@@ -0,0 +1,16 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TicketsStatusBatch is a processed POST /tickets-status request.
+// Requests retried with the same idempotency key get the batch stored for the first one.
+type TicketsStatusBatch struct {
+	BatchID        uuid.UUID `json:"batch_id"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	TicketIDs      []string  `json:"ticket_ids"`
+	ProcessedAt    time.Time `json:"processed_at"`
+}
@@ -83,11 +83,11 @@ func NewVipBundle(
 }
 
 type BookShowTickets struct {
-	BookingID uuid.UUID `json:"booking_id"`
+	BookingID uuid.UUID `json:"booking_id" protobuf:"1"`
 
-	CustomerEmail   string    `json:"customer_email"`
-	NumberOfTickets int       `json:"number_of_tickets"`
-	ShowId          uuid.UUID `json:"show_id"`
+	CustomerEmail   string    `json:"customer_email" protobuf:"2"`
+	NumberOfTickets int       `json:"number_of_tickets" protobuf:"3"`
+	ShowId          uuid.UUID `json:"show_id" protobuf:"4"`
 }
 
 func (b BookShowTickets) IsInternal() bool {
@@ -95,29 +95,29 @@ func (b BookShowTickets) IsInternal() bool {
 }
 
 type BookFlight struct {
-	CustomerEmail  string    `json:"customer_email"`
-	FlightID       uuid.UUID `json:"to_flight_id"`
-	Passengers     []string  `json:"passengers"`
-	ReferenceID    string    `json:"reference_id"`
-	IdempotencyKey string    `json:"idempotency_key"`
+	CustomerEmail  string    `json:"customer_email" protobuf:"1"`
+	FlightID       uuid.UUID `json:"to_flight_id" protobuf:"2"`
+	Passengers     []string  `json:"passengers" protobuf:"3"`
+	ReferenceID    string    `json:"reference_id" protobuf:"4"`
+	IdempotencyKey string    `json:"idempotency_key" protobuf:"5"`
 }
 
 type BookTaxi struct {
-	CustomerEmail      string `json:"customer_email"`
-	CustomerName       string `json:"customer_name"`
-	NumberOfPassengers int    `json:"number_of_passengers"`
-	ReferenceID        string `json:"reference_id"`
-	IdempotencyKey     string `json:"idempotency_key"`
+	CustomerEmail      string `json:"customer_email" protobuf:"1"`
+	CustomerName       string `json:"customer_name" protobuf:"2"`
+	NumberOfPassengers int    `json:"number_of_passengers" protobuf:"3"`
+	ReferenceID        string `json:"reference_id" protobuf:"4"`
+	IdempotencyKey     string `json:"idempotency_key" protobuf:"5"`
 }
 
 type CancelFlightTickets struct {
-	FlightTicketIDs []uuid.UUID `json:"flight_ticket_id"`
+	FlightTicketIDs []uuid.UUID `json:"flight_ticket_id" protobuf:"1"`
 }
 
 type VipBundleInitialized_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	VipBundleID uuid.UUID `json:"vip_bundle_id"`
+	VipBundleID uuid.UUID `json:"vip_bundle_id" protobuf:"2"`
 }
 
 func (t VipBundleInitialized_v1) IsInternal() bool {
@@ -125,10 +125,10 @@ func (t VipBundleInitialized_v1) IsInternal() bool {
 }
 
 type BookingFailed_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	BookingID     uuid.UUID `json:"booking_id"`
-	FailureReason string    `json:"failure_reason"`
+	BookingID     uuid.UUID `json:"booking_id" protobuf:"2"`
+	FailureReason string    `json:"failure_reason" protobuf:"3"`
 }
 
 func (t BookingFailed_v1) IsInternal() bool {
@@ -136,12 +136,12 @@ func (t BookingFailed_v1) IsInternal() bool {
 }
 
 type FlightBooked_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	FlightID  uuid.UUID   `json:"flight_id"`
-	TicketIDs []uuid.UUID `json:"flight_tickets_ids"`
+	FlightID  uuid.UUID   `json:"flight_id" protobuf:"2"`
+	TicketIDs []uuid.UUID `json:"flight_tickets_ids" protobuf:"3"`
 
-	ReferenceID string `json:"reference_id"`
+	ReferenceID string `json:"reference_id" protobuf:"4"`
 }
 
 func (t FlightBooked_v1) IsInternal() bool {
@@ -149,15 +149,15 @@ func (t FlightBooked_v1) IsInternal() bool {
 }
 
 type FlightBookingFailed_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	FlightID      uuid.UUID `json:"flight_id"`
-	FailureReason string    `json:"failure_reason"`
+	FlightID      uuid.UUID `json:"flight_id" protobuf:"2"`
+	FailureReason string    `json:"failure_reason" protobuf:"3"`
 
 	// used for tieing events from our system with external systems
 	// will be returned from the external system in the response and used
 	// to find the corresponding entity
-	ReferenceID string `json:"reference_id"`
+	ReferenceID string `json:"reference_id" protobuf:"4"`
 }
 
 func (t FlightBookingFailed_v1) IsInternal() bool {
@@ -165,11 +165,11 @@ func (t FlightBookingFailed_v1) IsInternal() bool {
 }
 
 type TaxiBooked_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	TaxiBookingID uuid.UUID `json:"taxi_booking_id"`
+	TaxiBookingID uuid.UUID `json:"taxi_booking_id" protobuf:"2"`
 
-	ReferenceID string `json:"reference_id"`
+	ReferenceID string `json:"reference_id" protobuf:"3"`
 }
 
 func (t TaxiBooked_v1) IsInternal() bool {
@@ -177,9 +177,9 @@ func (t TaxiBooked_v1) IsInternal() bool {
 }
 
 type VipBundleFinalized_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	VipBundleID uuid.UUID `json:"vip_bundle_id"`
+	VipBundleID uuid.UUID `json:"vip_bundle_id" protobuf:"2"`
 }
 
 func (t VipBundleFinalized_v1) IsInternal() bool {
@@ -187,11 +187,11 @@ func (t VipBundleFinalized_v1) IsInternal() bool {
 }
 
 type TaxiBookingFailed_v1 struct {
-	Header EventHeader `json:"header"`
+	Header EventHeader `json:"header" protobuf:"1"`
 
-	FailureReason string `json:"failure_reason"`
+	FailureReason string `json:"failure_reason" protobuf:"2"`
 
-	ReferenceID string `json:"reference_id"`
+	ReferenceID string `json:"reference_id" protobuf:"3"`
 }
 
 func (t TaxiBookingFailed_v1) IsInternal() bool {
@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscription is a partner endpoint which receives the events from EventNames.
+type WebhookSubscription struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	URL            string    `json:"url"`
+	EventNames     []string  `json:"event_names"`
+
+	// Secret signs the payloads, it's returned only when the subscription is registered.
+	Secret string `json:"secret,omitempty"`
+
+	// ConsecutiveFailures counts deliveries which failed after all attempts since the last successful delivery.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	CreatedAt time.Time `json:"created_at"`
+	// DisabledAt is set when the endpoint keeps failing, deliveries wait until the subscription is enabled again.
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+func (s WebhookSubscription) IsDisabled() bool {
+	return s.DisabledAt != nil
+}
+
+func (s WebhookSubscription) WithoutSecret() WebhookSubscription {
+	s.Secret = ""
+	return s
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent to one subscription.
+type WebhookDelivery struct {
+	DeliveryID     uuid.UUID             `json:"delivery_id"`
+	SubscriptionID uuid.UUID             `json:"subscription_id"`
+	EventID        uuid.UUID             `json:"event_id"`
+	EventName      string                `json:"event_name"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+
+	// Attempts are counted from the last manual redelivery.
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+type WebhookDeliveryAttempt struct {
+	DeliveryID  uuid.UUID `json:"delivery_id"`
+	AttemptedAt time.Time `json:"attempted_at"`
+	// StatusCode is 0 when the endpoint didn't respond.
+	StatusCode int           `json:"status_code"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+}
+
+func (a WebhookDeliveryAttempt) Succeeded() bool {
+	return a.Error == ""
+}
@@ -18,10 +18,16 @@ func NewPaymentsClient(clients *clients.Clients) PaymentsClient {
 	}
 }
 
-func (c PaymentsClient) Refund(ctx context.Context, ticketID, idempotencyKey string) error {
+// Refund refunds the payment of the ticket. The payments API doesn't accept the refunded amount,
+// so partial refunds are described in the reason.
+func (c PaymentsClient) Refund(ctx context.Context, ticketID, reason, idempotencyKey string) error {
+	if reason == "" {
+		reason = "customer requested refund"
+	}
+
 	resp, err := c.clients.Payments.PutRefundsWithResponse(ctx, payments.PaymentRefundRequest{
 		PaymentReference: ticketID,
-		Reason:           "customer requested refund",
+		Reason:           reason,
 		DeduplicationId:  pointer.To(idempotencyKey),
 	})
 	if err != nil {
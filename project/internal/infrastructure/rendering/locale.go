@@ -0,0 +1,125 @@
+package rendering
+
+import (
+	"tickets/internal/entities"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+type labels struct {
+	Ticket      string
+	Show        string
+	Venue       string
+	Date        string
+	Customer    string
+	Price       string
+	TicketID    string
+	BookingID   string
+	CheckInCode string
+	CheckInHint string
+}
+
+type locale struct {
+	tag        language.Tag
+	labels     labels
+	dateLayout string
+}
+
+// supportedLocales are ordered by preference, the first one is the fallback.
+var supportedLocales = []locale{
+	{
+		tag: language.English,
+		labels: labels{
+			Ticket:      "Ticket",
+			Show:        "Show",
+			Venue:       "Venue",
+			Date:        "Date",
+			Customer:    "Customer",
+			Price:       "Price",
+			TicketID:    "Ticket ID",
+			BookingID:   "Booking ID",
+			CheckInCode: "Check-in code",
+			CheckInHint: "Show this code at the entrance.",
+		},
+		dateLayout: "Monday, January 2, 2006, 3:04 PM MST",
+	},
+	{
+		tag: language.German,
+		labels: labels{
+			Ticket:      "Ticket",
+			Show:        "Veranstaltung",
+			Venue:       "Veranstaltungsort",
+			Date:        "Datum",
+			Customer:    "Kunde",
+			Price:       "Preis",
+			TicketID:    "Ticket-ID",
+			BookingID:   "Buchungs-ID",
+			CheckInCode: "Check-in-Code",
+			CheckInHint: "Zeigen Sie diesen Code am Eingang vor.",
+		},
+		dateLayout: "02.01.2006, 15:04 MST",
+	},
+	{
+		tag: language.Polish,
+		labels: labels{
+			Ticket:      "Bilet",
+			Show:        "Wydarzenie",
+			Venue:       "Miejsce",
+			Date:        "Data",
+			Customer:    "Klient",
+			Price:       "Cena",
+			TicketID:    "ID biletu",
+			BookingID:   "ID rezerwacji",
+			CheckInCode: "Kod wejścia",
+			CheckInHint: "Pokaż ten kod przy wejściu.",
+		},
+		dateLayout: "02.01.2006, 15:04 MST",
+	},
+}
+
+var localeMatcher = func() language.Matcher {
+	tags := make([]language.Tag, len(supportedLocales))
+	for i, l := range supportedLocales {
+		tags[i] = l.tag
+	}
+	return language.NewMatcher(tags)
+}()
+
+// resolveLocale picks the closest supported locale, for example "de-AT" is rendered in German.
+func resolveLocale(tag string) locale {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return supportedLocales[0]
+	}
+
+	_, index, confidence := localeMatcher.Match(parsed)
+	if confidence == language.No {
+		return supportedLocales[0]
+	}
+
+	l := supportedLocales[index]
+	// keep the region, so numbers are formatted the way the customer is used to
+	l.tag = parsed
+
+	return l
+}
+
+func (l locale) formatDate(t time.Time) string {
+	return t.Format(l.dateLayout)
+}
+
+func (l locale) formatMoney(money entities.Money) string {
+	p := message.NewPrinter(l.tag)
+
+	unit, err := currency.ParseISO(money.Currency)
+	if err != nil {
+		// zero value Money
+		return p.Sprintf("%s %s", money.AmountString(), money.Currency)
+	}
+
+	// the amount is already validated against the currency precision, so float is precise enough to print it
+	return p.Sprint(currency.Symbol(unit.Amount(money.Amount.InexactFloat64())))
+}
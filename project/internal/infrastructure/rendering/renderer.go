@@ -0,0 +1,214 @@
+package rendering
+
+import (
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+const (
+	templatesDir       = "templates"
+	defaultTemplateKey = "default"
+	qrCodeSize         = 256
+)
+
+// Ticket contains everything which is printed on the ticket.
+type Ticket struct {
+	TicketID      string
+	BookingID     string
+	CustomerEmail string
+	Price         entities.Money
+	CheckInCode   string
+
+	// Locale is a BCP 47 language tag of the customer, English is used when it's empty or not supported.
+	Locale string
+
+	// Show details are empty when the booking of the ticket is not known.
+	ShowID      uuid.UUID
+	OrganizerID uuid.UUID
+	ShowTitle   string
+	Venue       string
+	StartTime   time.Time
+}
+
+// Renderer renders tickets to HTML.
+//
+// Templates are stored as templates/<key>/v<version>.html.tmpl, where key is
+// "show-<show_id>", "organizer-<dead_nation_id>" or "default".
+// The most specific key wins and only the latest version of each key is used,
+// so a template can be changed by adding a new version without touching the old one.
+type Renderer struct {
+	templates map[string]versionedTemplate
+}
+
+type versionedTemplate struct {
+	name     string
+	version  int
+	template *template.Template
+}
+
+func NewRenderer() (*Renderer, error) {
+	return newRenderer(templatesFS)
+}
+
+func newRenderer(fsys fs.FS) (*Renderer, error) {
+	keys, err := fs.ReadDir(fsys, templatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("read templates dir: %w", err)
+	}
+
+	templates := make(map[string]versionedTemplate, len(keys))
+	for _, key := range keys {
+		if !key.IsDir() {
+			continue
+		}
+
+		latest, err := loadLatestTemplate(fsys, key.Name())
+		if err != nil {
+			return nil, fmt.Errorf("load %s template: %w", key.Name(), err)
+		}
+		templates[key.Name()] = latest
+	}
+
+	if _, ok := templates[defaultTemplateKey]; !ok {
+		return nil, fmt.Errorf("missing %s template", defaultTemplateKey)
+	}
+
+	return &Renderer{templates: templates}, nil
+}
+
+func loadLatestTemplate(fsys fs.FS, key string) (versionedTemplate, error) {
+	files, err := fs.ReadDir(fsys, path.Join(templatesDir, key))
+	if err != nil {
+		return versionedTemplate{}, err
+	}
+
+	latestVersion := 0
+	latestFile := ""
+	for _, f := range files {
+		version, ok := parseTemplateVersion(f.Name())
+		if !ok {
+			continue
+		}
+		if version > latestVersion {
+			latestVersion = version
+			latestFile = f.Name()
+		}
+	}
+	if latestFile == "" {
+		return versionedTemplate{}, fmt.Errorf("no template versions found")
+	}
+
+	name := fmt.Sprintf("%s/v%d", key, latestVersion)
+	tmpl, err := template.New(name).ParseFS(fsys, path.Join(templatesDir, key, latestFile))
+	if err != nil {
+		return versionedTemplate{}, fmt.Errorf("parse %s: %w", name, err)
+	}
+
+	return versionedTemplate{
+		name:     name,
+		version:  latestVersion,
+		template: tmpl.Lookup(latestFile),
+	}, nil
+}
+
+// parseTemplateVersion parses file names like v2.html.tmpl.
+func parseTemplateVersion(fileName string) (int, bool) {
+	base, ok := strings.CutSuffix(fileName, ".html.tmpl")
+	if !ok {
+		return 0, false
+	}
+	base, ok = strings.CutPrefix(base, "v")
+	if !ok {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(base)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+func (r *Renderer) templateFor(ticket Ticket) versionedTemplate {
+	if ticket.ShowID != uuid.Nil {
+		if t, ok := r.templates["show-"+ticket.ShowID.String()]; ok {
+			return t
+		}
+	}
+	if ticket.OrganizerID != uuid.Nil {
+		if t, ok := r.templates["organizer-"+ticket.OrganizerID.String()]; ok {
+			return t
+		}
+	}
+
+	return r.templates[defaultTemplateKey]
+}
+
+type ticketView struct {
+	Lang     string
+	Template string
+	Labels   labels
+
+	TicketID      string
+	BookingID     string
+	CustomerEmail string
+	Price         string
+	CheckInCode   string
+	QRCode        template.URL
+
+	ShowTitle string
+	Venue     string
+	StartTime string
+}
+
+func (r *Renderer) Render(ticket Ticket) ([]byte, error) {
+	loc := resolveLocale(ticket.Locale)
+
+	qrCode, err := qrcode.Encode(ticket.CheckInCode, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("generate qr code: %w", err)
+	}
+
+	view := ticketView{
+		Lang:          loc.tag.String(),
+		Labels:        loc.labels,
+		TicketID:      ticket.TicketID,
+		BookingID:     ticket.BookingID,
+		CustomerEmail: ticket.CustomerEmail,
+		Price:         loc.formatMoney(ticket.Price),
+		CheckInCode:   ticket.CheckInCode,
+		// html/template doesn't allow data URLs unless they are explicitly marked as safe
+		QRCode:    template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode)),
+		ShowTitle: ticket.ShowTitle,
+		Venue:     ticket.Venue,
+	}
+	if !ticket.StartTime.IsZero() {
+		view.StartTime = loc.formatDate(ticket.StartTime)
+	}
+
+	tmpl := r.templateFor(ticket)
+	view.Template = tmpl.name
+
+	var buf bytes.Buffer
+	if err := tmpl.template.Execute(&buf, view); err != nil {
+		return nil, fmt.Errorf("execute %s template: %w", tmpl.name, err)
+	}
+
+	return buf.Bytes(), nil
+}
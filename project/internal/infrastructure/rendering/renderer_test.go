@@ -0,0 +1,121 @@
+package rendering
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// run `go test ./internal/infrastructure/rendering -update` after changing the templates
+var update = flag.Bool("update", false, "update golden files")
+
+var (
+	testShowID      = uuid.MustParse("3f7c1e2a-9b8d-4c6e-a5f4-1d2e3f4a5b6c")
+	testOrganizerID = uuid.MustParse("6a1ff8c4-1b2c-4d3e-8f90-0a1b2c3d4e5f")
+)
+
+func testTicket(locale string) Ticket {
+	return Ticket{
+		TicketID:      "8b7e5e0a-3f0c-4d6b-9d44-7c0b1f1f6a2e",
+		BookingID:     "c9f1b0a4-5b7e-4e1a-8f3c-2d6e9a7b4c1d",
+		CustomerEmail: "customer@example.com",
+		Price:         entities.MustNewMoney("1234.5", "EUR"),
+		CheckInCode:   "test-check-in-code",
+		Locale:        locale,
+		ShowID:        testShowID,
+		OrganizerID:   testOrganizerID,
+		ShowTitle:     "The Rolling Stones <Live>",
+		Venue:         "Olympiastadion, Berlin",
+		StartTime:     time.Date(2025, time.June, 21, 19, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestRenderer_Render_Golden(t *testing.T) {
+	renderer, err := NewRenderer()
+	require.NoError(t, err)
+
+	withoutShow := testTicket("en")
+	withoutShow.ShowID = uuid.Nil
+	withoutShow.OrganizerID = uuid.Nil
+	withoutShow.ShowTitle = ""
+	withoutShow.Venue = ""
+	withoutShow.StartTime = time.Time{}
+	withoutShow.Price = entities.MustNewMoney("50", "USD")
+
+	testCases := []struct {
+		name   string
+		ticket Ticket
+	}{
+		{name: "en", ticket: testTicket("en")},
+		{name: "de", ticket: testTicket("de-DE")},
+		{name: "pl", ticket: testTicket("pl")},
+		{name: "unsupported_locale", ticket: testTicket("ja")},
+		{name: "empty_locale", ticket: testTicket("")},
+		{name: "without_show", ticket: withoutShow},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := renderer.Render(tc.ticket)
+			require.NoError(t, err)
+
+			goldenFile := filepath.Join("testdata", tc.name+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(goldenFile, content, 0o644))
+			}
+
+			expected, err := os.ReadFile(goldenFile)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(content))
+		})
+	}
+}
+
+func TestRenderer_Render_TemplateSelection(t *testing.T) {
+	renderer, err := newRenderer(os.DirFS("testdata"))
+	require.NoError(t, err)
+
+	otherShowTicket := testTicket("en")
+	otherShowTicket.ShowID = uuid.New()
+
+	otherOrganizerTicket := testTicket("en")
+	otherOrganizerTicket.ShowID = uuid.New()
+	otherOrganizerTicket.OrganizerID = uuid.New()
+
+	testCases := []struct {
+		name     string
+		ticket   Ticket
+		expected string
+	}{
+		{
+			name:     "show template, latest version",
+			ticket:   testTicket("en"),
+			expected: "<p>show show-3f7c1e2a-9b8d-4c6e-a5f4-1d2e3f4a5b6c/v2 8b7e5e0a-3f0c-4d6b-9d44-7c0b1f1f6a2e The Rolling Stones &lt;Live&gt;</p>\n",
+		},
+		{
+			name:     "organizer template",
+			ticket:   otherShowTicket,
+			expected: "<p>organizer organizer-6a1ff8c4-1b2c-4d3e-8f90-0a1b2c3d4e5f/v1 8b7e5e0a-3f0c-4d6b-9d44-7c0b1f1f6a2e</p>\n",
+		},
+		{
+			name:     "default template",
+			ticket:   otherOrganizerTicket,
+			expected: "<p>default default/v1 8b7e5e0a-3f0c-4d6b-9d44-7c0b1f1f6a2e</p>\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := renderer.Render(tc.ticket)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(content))
+		})
+	}
+}
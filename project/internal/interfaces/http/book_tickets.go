@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/lib/pq"
+	"golang.org/x/text/language"
 	"net/http"
 	"tickets/internal/application/usecases/booking"
 	"tickets/internal/entities"
@@ -15,6 +16,9 @@ type BookTicketsRequest struct {
 	ShowId          uuid.UUID `json:"show_id"`
 	NumberOfTickets int       `json:"number_of_tickets"`
 	CustomerEmail   string    `json:"customer_email"`
+
+	// Locale is optional, the Accept-Language header is used when it's not set.
+	Locale string `json:"locale"`
 }
 
 type BookTicketsResponse struct {
@@ -30,6 +34,13 @@ func (s *Server) BookTicketsHandler(c echo.Context) error {
 		return err
 	}
 
+	locale, err := customerLocale(request.Locale, c.Request().Header.Get("Accept-Language"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"reason": "Invalid locale",
+		})
+	}
+
 	bookingID := uuid.Nil
 	pgErr := &pq.Error{}
 	for i := 0; i < 5; i++ {
@@ -38,6 +49,7 @@ func (s *Server) BookTicketsHandler(c echo.Context) error {
 				ShowId:          request.ShowId,
 				NumberOfTickets: request.NumberOfTickets,
 				CustomerEmail:   request.CustomerEmail,
+				Locale:          locale,
 			})
 		if err != nil {
 			if errors.As(err, &pgErr); pgErr.Code == "40001" {
@@ -54,6 +66,11 @@ func (s *Server) BookTicketsHandler(c echo.Context) error {
 				"reason": "Not enough tickets available",
 			})
 		}
+		if errors.Is(err, entities.ErrShowCancelled) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"reason": "Show is cancelled",
+			})
+		}
 		return err
 	}
 
@@ -63,3 +80,22 @@ func (s *Server) BookTicketsHandler(c echo.Context) error {
 		},
 	)
 }
+
+// customerLocale returns the canonical form of the requested locale.
+// The Accept-Language header is only a hint, so when it can't be parsed the default locale is used.
+func customerLocale(requested string, acceptLanguage string) (string, error) {
+	if requested != "" {
+		tag, err := language.Parse(requested)
+		if err != nil {
+			return "", err
+		}
+		return tag.String(), nil
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 || tags[0] == language.Und {
+		return "", nil
+	}
+
+	return tags[0].String(), nil
+}
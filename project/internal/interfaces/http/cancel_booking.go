@@ -0,0 +1,124 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"tickets/internal/application/usecases/cancellation"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type cancelBookingRequest struct {
+	Reason string `json:"reason"`
+}
+
+type bookingCancellationTicketResponse struct {
+	TicketID          string          `json:"ticket_id"`
+	Status            string          `json:"status"`
+	RefundRequestedAt *time.Time      `json:"refund_requested_at"`
+	RefundedAt        *time.Time      `json:"refunded_at"`
+	RefundedAmount    *entities.Money `json:"refunded_amount,omitempty"`
+}
+
+type bookingCancellationResponse struct {
+	BookingID   uuid.UUID                           `json:"booking_id"`
+	Status      string                              `json:"status"`
+	Reason      string                              `json:"reason"`
+	Attempts    int                                 `json:"attempts"`
+	RequestedAt time.Time                           `json:"requested_at"`
+	CancelledAt *time.Time                          `json:"cancelled_at"`
+	Tickets     []bookingCancellationTicketResponse `json:"tickets"`
+}
+
+func (s *Server) CancelBookingHandler(c echo.Context) error {
+	bookingID, err := uuid.Parse(c.Param("booking_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "booking_id is not a valid UUID")
+	}
+
+	var req cancelBookingRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("bind request: %w", err)
+	}
+
+	bookingCancellation, err := s.cancelBookingUsecase.CancelBooking(
+		c.Request().Context(),
+		cancellation.CancelBookingReq{
+			BookingID: bookingID,
+			Reason:    req.Reason,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookingNotFound) {
+			return c.JSON(http.StatusNotFound, "booking not found")
+		}
+		if errors.Is(err, entities.ErrBookingHasNoTickets) || errors.Is(err, entities.ErrBookingTicketsNotConfirmed) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"reason": "booking has no confirmed tickets yet",
+			})
+		}
+		if errors.Is(err, entities.ErrRefundDenied) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"reason": "refund is not allowed this close to the show",
+			})
+		}
+		return fmt.Errorf("cancel booking: %w", err)
+	}
+
+	status := http.StatusAccepted
+	if bookingCancellation.IsFinalized {
+		status = http.StatusOK
+	}
+
+	return c.JSON(status, newBookingCancellationResponse(bookingCancellation))
+}
+
+func (s *Server) GetBookingCancellationHandler(c echo.Context) error {
+	bookingID, err := uuid.Parse(c.Param("booking_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "booking_id is not a valid UUID")
+	}
+
+	bookingCancellation, err := s.cancelBookingUsecase.GetCancellation(c.Request().Context(), bookingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookingCancellationNotFound) {
+			return c.JSON(http.StatusNotFound, "booking cancellation not found")
+		}
+		return fmt.Errorf("get booking cancellation: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, newBookingCancellationResponse(bookingCancellation))
+}
+
+func newBookingCancellationResponse(c *entities.BookingCancellation) bookingCancellationResponse {
+	status := "in_progress"
+	if c.IsFinalized {
+		status = "cancelled"
+	}
+
+	tickets := make([]bookingCancellationTicketResponse, 0, len(c.Tickets))
+	for ticketID, ticket := range c.Tickets {
+		tickets = append(tickets, bookingCancellationTicketResponse{
+			TicketID:          ticketID,
+			Status:            string(ticket.Status),
+			RefundRequestedAt: ticket.RefundRequestedAt,
+			RefundedAt:        ticket.RefundedAt,
+			RefundedAmount:    ticket.RefundedAmount,
+		})
+	}
+
+	return bookingCancellationResponse{
+		BookingID:   c.BookingID,
+		Status:      status,
+		Reason:      c.Reason,
+		Attempts:    c.Attempts,
+		RequestedAt: c.RequestedAt,
+		CancelledAt: c.CancelledAt,
+		Tickets:     tickets,
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"tickets/internal/application/usecases/cancellation"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+)
+
+type CancelShowRequest struct {
+	Reason string `json:"reason"`
+}
+
+type showCancellationProgressResponse struct {
+	entities.ShowCancellationProgress
+
+	Completed bool `json:"completed"`
+}
+
+func (s *Server) CancelShowHandler(c echo.Context) error {
+	showID, err := uuid.Parse(c.Param("show_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "show_id is not a valid UUID")
+	}
+
+	var request CancelShowRequest
+	err = c.Bind(&request)
+	if err != nil {
+		return err
+	}
+
+	showCancellation, err := s.cancelShowUsecase.CancelShow(c.Request().Context(), cancellation.CancelShowReq{
+		ShowID: showID,
+		Reason: request.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrShowNotFound) {
+			return c.JSON(http.StatusNotFound, "show not found")
+		}
+		return fmt.Errorf("cancel show: %w", err)
+	}
+
+	return c.JSON(http.StatusAccepted, showCancellation)
+}
+
+func (s *Server) GetShowCancellationHandler(c echo.Context) error {
+	showID, err := uuid.Parse(c.Param("show_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "show_id is not a valid UUID")
+	}
+
+	progress, err := s.cancelShowUsecase.GetProgress(c.Request().Context(), showID)
+	if err != nil {
+		if errors.Is(err, entities.ErrShowNotCancelled) {
+			return c.JSON(http.StatusNotFound, "show is not cancelled")
+		}
+		return fmt.Errorf("get show cancellation progress: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, showCancellationProgressResponse{
+		ShowCancellationProgress: *progress,
+		Completed:                progress.IsCompleted(),
+	})
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"tickets/internal/tickettoken"
+)
+
+type CheckInRequest struct {
+	Token string `json:"token"`
+}
+
+func (s *Server) CheckInHandler(c echo.Context) error {
+	var request CheckInRequest
+	err := c.Bind(&request)
+	if err != nil {
+		return err
+	}
+
+	checkedIn, err := s.checkInUsecase.CheckIn(c.Request().Context(), request.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, tickettoken.ErrInvalidToken):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"reason": "Invalid ticket token",
+			})
+		case errors.Is(err, repository.ErrTicketNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"reason": "Ticket not found",
+			})
+		case errors.Is(err, entities.ErrTicketAlreadyCheckedIn):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"reason": "Ticket already checked in",
+			})
+		case errors.Is(err, entities.ErrTicketNotValid):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"reason": "Ticket is not valid: " + err.Error(),
+			})
+		}
+		return fmt.Errorf("check in: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, checkedIn)
+}
+
+func (s *Server) GetShowAttendanceHandler(c echo.Context) error {
+	showID, err := uuid.Parse(c.Param("show_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "show_id is not a valid UUID")
+	}
+
+	attendance, err := s.showAttendanceReadModelRepo.GetByShowID(c.Request().Context(), showID)
+	if err != nil {
+		return fmt.Errorf("get show attendance: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, attendance)
+}
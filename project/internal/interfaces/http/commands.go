@@ -0,0 +1,28 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"tickets/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) GetCommandHandler(c echo.Context) error {
+	commandID, err := uuid.Parse(c.Param("command_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "command_id is not a valid UUID")
+	}
+
+	command, err := s.commandsRepo.Get(c.Request().Context(), commandID)
+	if err != nil {
+		if errors.Is(err, repository.ErrCommandNotFound) {
+			return c.JSON(http.StatusNotFound, "command not found")
+		}
+		return fmt.Errorf("get command: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, command)
+}
@@ -1,43 +1,131 @@
 package http
 
 import (
+	"errors"
+	"fmt"
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"strconv"
+	"strings"
 	"tickets/internal/entities"
 	"tickets/internal/repository"
 	"time"
 )
 
+const (
+	defaultOpsBookingsLimit = 100
+	maxOpsBookingsLimit     = 1000
+)
+
+// GetBookingsHandler returns a page of bookings as a JSON array.
+// The cursor of the next page is returned in the X-Next-Cursor header (and in the Link header),
+// it's not set on the last page.
+//
+// sort is booked_at or last_update, prefixed with "-" for the descending order (default: -booked_at).
 func (s *Server) GetBookingsHandler(c echo.Context) error {
-	var (
-		bookings []entities.OpsBooking
-	)
-	receiptIssueDate := c.QueryParam("receipt_issue_date")
-	if receiptIssueDate != "" {
-		var err error
-		issueDate, err := time.Parse("2006-01-02", receiptIssueDate)
+	query := repository.OpsBookingsQuery{
+		SortBy:     repository.OpsBookingsSortBookedAt,
+		Descending: true,
+		Cursor:     c.QueryParam("cursor"),
+		Limit:      defaultOpsBookingsLimit,
+	}
+
+	if sort := c.QueryParam("sort"); sort != "" {
+		query.Descending = strings.HasPrefix(sort, "-")
+		query.SortBy = repository.OpsBookingsSort(strings.TrimPrefix(sort, "-"))
+		if query.SortBy != repository.OpsBookingsSortBookedAt && query.SortBy != repository.OpsBookingsSortLastUpdate {
+			return c.JSON(http.StatusBadRequest, "sort must be booked_at or last_update")
+		}
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		v, err := strconv.Atoi(limit)
+		if err != nil || v < 1 || v > maxOpsBookingsLimit {
+			return c.JSON(http.StatusBadRequest, fmt.Sprintf("limit must be a number between 1 and %d", maxOpsBookingsLimit))
+		}
+		query.Limit = v
+	}
+
+	filters := &query.Filters
+
+	if receiptIssueDate := c.QueryParam("receipt_issue_date"); receiptIssueDate != "" {
+		issueDate, err := time.Parse(time.DateOnly, receiptIssueDate)
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, "receipt_issue_date is not a valid date")
 		}
+		filters.ReceiptIssueDate = issueDate
+	}
 
-		bookings, err = s.opsBookingReadModelRepo.GetWithFilters(c.Request().Context(),
-			repository.Filters{
-				ReceiptIssueDate: issueDate,
-			})
+	filters.CustomerEmail = c.QueryParam("customer_email")
+
+	if showID := c.QueryParam("show_id"); showID != "" {
+		id, err := uuid.Parse(showID)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err)
+			return c.JSON(http.StatusBadRequest, "show_id is not a valid UUID")
+		}
+		filters.ShowID = &id
+	}
+
+	if status := entities.OpsTicketStatus(c.QueryParam("ticket_status")); status != "" {
+		switch status {
+		case entities.OpsTicketStatusPending, entities.OpsTicketStatusConfirmed, entities.OpsTicketStatusRefunded:
+			filters.TicketStatus = status
+		default:
+			return c.JSON(http.StatusBadRequest, "ticket_status must be pending, confirmed or refunded")
+		}
+	}
+
+	if refundState := entities.OpsBookingRefundState(c.QueryParam("refund_state")); refundState != "" {
+		switch refundState {
+		case entities.OpsBookingRefundStateNone, entities.OpsBookingRefundStatePartial, entities.OpsBookingRefundStateFull:
+			filters.RefundState = refundState
+		default:
+			return c.JSON(http.StatusBadRequest, "refund_state must be none, partial or full")
+		}
+	}
+
+	timeParams := []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"booked_from", &filters.BookedFrom},
+		{"booked_to", &filters.BookedTo},
+		{"updated_from", &filters.UpdatedFrom},
+		{"updated_to", &filters.UpdatedTo},
+	}
+	for _, param := range timeParams {
+		value := c.QueryParam(param.name)
+		if value == "" {
+			continue
 		}
-	} else {
-		var err error
-		bookings, err = s.opsBookingReadModelRepo.GetAll(c.Request().Context())
+		t, err := time.Parse(time.RFC3339, value)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err)
+			return c.JSON(http.StatusBadRequest, param.name+" is not a valid RFC3339 time")
 		}
+		*param.dst = &t
+	}
+
+	page, err := s.opsBookingReadModelRepo.List(c.Request().Context(), query)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.JSON(http.StatusBadRequest, "cursor is not valid for this query")
+		}
+		return fmt.Errorf("list bookings: %w", err)
+	}
+
+	if page.NextCursor != "" {
+		nextURL := *c.Request().URL
+		params := nextURL.Query()
+		params.Set("cursor", page.NextCursor)
+		nextURL.RawQuery = params.Encode()
+
+		c.Response().Header().Set("X-Next-Cursor", page.NextCursor)
+		c.Response().Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextURL.RequestURI()))
 	}
 
-	return c.JSON(http.StatusOK, bookings)
+	return c.JSON(http.StatusOK, page.Bookings)
 }
 
 func (s *Server) GetBookingHandler(c echo.Context) error {
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"tickets/internal/repository"
+	"time"
+)
+
+// availabilityMaxAge is short, because availability changes with every booking,
+// but it's enough to take storefront traffic spikes off the database.
+const availabilityMaxAge = 5 * time.Second
+
+func (s *Server) GetShowsHandler(c echo.Context) error {
+	var filters repository.ShowAvailabilityFilters
+
+	filters.Venue = c.QueryParam("venue")
+
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "from is not a valid RFC3339 time")
+		}
+		filters.StartTimeFrom = &t
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "to is not a valid RFC3339 time")
+		}
+		filters.StartTimeTo = &t
+	}
+	if onlyAvailable := c.QueryParam("only_available"); onlyAvailable != "" {
+		v, err := strconv.ParseBool(onlyAvailable)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "only_available is not a valid bool")
+		}
+		filters.OnlyAvailable = v
+	}
+	if includeCancelled := c.QueryParam("include_cancelled"); includeCancelled != "" {
+		v, err := strconv.ParseBool(includeCancelled)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, "include_cancelled is not a valid bool")
+		}
+		filters.IncludeCancelled = v
+	}
+
+	shows, err := s.showAvailabilityReadModelRepo.GetWithFilters(c.Request().Context(), filters)
+	if err != nil {
+		return fmt.Errorf("get shows: %w", err)
+	}
+
+	return cacheableJSON(c, shows, availabilityMaxAge)
+}
+
+func (s *Server) GetShowAvailabilityHandler(c echo.Context) error {
+	showID, err := uuid.Parse(c.Param("show_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "show_id is not a valid UUID")
+	}
+
+	availability, err := s.showAvailabilityReadModelRepo.GetByShowID(c.Request().Context(), showID)
+	if err != nil {
+		if errors.Is(err, repository.ErrShowNotFound) {
+			return c.JSON(http.StatusNotFound, "show not found")
+		}
+		return fmt.Errorf("get show availability: %w", err)
+	}
+
+	if !availability.LastUpdate.IsZero() {
+		c.Response().Header().Set(echo.HeaderLastModified, availability.LastUpdate.UTC().Format(http.TimeFormat))
+	}
+
+	return cacheableJSON(c, availability, availabilityMaxAge)
+}
+
+// cacheableJSON responds with ETag and Cache-Control headers,
+// and with 304 Not Modified when the client already has the same response.
+func cacheableJSON(c echo.Context, body any, maxAge time.Duration) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+
+	hash := sha256.Sum256(payload)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	header.Set("ETag", etag)
+
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSONBlob(http.StatusOK, payload)
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheableJSON(t *testing.T) {
+	e := echo.New()
+
+	get := func(body any, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/shows", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+
+		require.NoError(t, cacheableJSON(e.NewContext(req, rec), body, 5*time.Second))
+
+		return rec
+	}
+
+	body := map[string]int{"available_tickets": 10}
+
+	first := get(body, "")
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.JSONEq(t, `{"available_tickets": 10}`, first.Body.String())
+	assert.Equal(t, "public, max-age=5", first.Header().Get(echo.HeaderCacheControl))
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	t.Run("same response", func(t *testing.T) {
+		rec := get(body, etag)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("changed response", func(t *testing.T) {
+		rec := get(map[string]int{"available_tickets": 9}, etag)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"available_tickets": 9}`, rec.Body.String())
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("other etag", func(t *testing.T) {
+		rec := get(body, `"other"`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+	})
+}
@@ -1,21 +1,48 @@
 package http
 
 import (
+	"errors"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
 )
 
 type TicketResponse struct {
-	TicketId      string `json:"ticket_id"`
-	CustomerEmail string `json:"customer_email"`
-	Price         struct {
-		Amount   string `json:"amount"`
-		Currency string `json:"currency"`
-	} `json:"price"`
+	TicketId      string         `json:"ticket_id"`
+	CustomerEmail string         `json:"customer_email"`
+	Price         entities.Money `json:"price"`
+	Status        string         `json:"status"`
+	BookingId     string         `json:"booking_id,omitempty"`
+	ShowId        string         `json:"show_id,omitempty"`
 }
 
 func (s *Server) GetTicketsHandler(ctx echo.Context) error {
-	tickets, err := s.ticketsService.GetTickets(ctx.Request().Context())
+	var filters repository.TicketsFilters
+
+	if status := ctx.QueryParam("status"); status != "" {
+		filters.Status = entities.TicketStatus(status)
+		if !filters.Status.IsValid() {
+			return ctx.JSON(http.StatusBadRequest, "status is not a valid ticket status")
+		}
+	}
+	if bookingID := ctx.QueryParam("booking_id"); bookingID != "" {
+		id, err := uuid.Parse(bookingID)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, "booking_id is not a valid UUID")
+		}
+		filters.BookingID = &id
+	}
+	if showID := ctx.QueryParam("show_id"); showID != "" {
+		id, err := uuid.Parse(showID)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, "show_id is not a valid UUID")
+		}
+		filters.ShowID = &id
+	}
+
+	tickets, err := s.ticketsService.GetTickets(ctx.Request().Context(), filters)
 	if err != nil {
 		return err
 	}
@@ -25,15 +52,29 @@ func (s *Server) GetTicketsHandler(ctx echo.Context) error {
 		getTickets = append(getTickets, TicketResponse{
 			TicketId:      ticket.TicketId,
 			CustomerEmail: ticket.CustomerEmail,
-			Price: struct {
-				Amount   string `json:"amount"`
-				Currency string `json:"currency"`
-			}{
-				Amount:   ticket.Price.Amount,
-				Currency: ticket.Price.Currency,
-			},
+			Price:         ticket.Price,
+			Status:        ticket.Status,
+			BookingId:     ticket.BookingId,
+			ShowId:        ticket.ShowId,
 		})
 	}
 
 	return ctx.JSON(http.StatusOK, getTickets)
 }
+
+func (s *Server) GetTicketStatusHistoryHandler(ctx echo.Context) error {
+	ticketID, err := uuid.Parse(ctx.Param("ticket_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, "ticket_id is not a valid UUID")
+	}
+
+	history, err := s.ticketsService.GetTicketStatusHistory(ctx.Request().Context(), ticketID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTicketNotFound) {
+			return ctx.JSON(http.StatusNotFound, "ticket not found")
+		}
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/labstack/echo/v4"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// replayedResponseHeaders are stored with the response and replayed to retries.
+var replayedResponseHeaders = []string{
+	echo.HeaderLocation,
+}
+
+// IdempotencyMiddleware makes POST, PUT, PATCH and DELETE requests sent with the Idempotency-Key header idempotent.
+// The first response (other than 5xx) is stored and replayed to every retry with the same key.
+//
+// A retry sent while the first request is still handled gets 409, and a different request
+// sent with the same key gets 422. Requests which failed can be retried with the same key.
+//
+// Keys are global, they are not scoped by client or endpoint: a key used for one endpoint
+// can't be used for another one, so clients should send random keys (UUIDs).
+func IdempotencyMiddleware(repo *repository.IdempotentRequestsRepo) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(idempotencyKeyHeader)
+			if key == "" || !isMutatingMethod(c.Request().Method) {
+				return next(c)
+			}
+
+			ctx := c.Request().Context()
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return err
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := requestFingerprint(c.Request(), body)
+
+			stored, locked, err := repo.Lock(ctx, key, fingerprint)
+			if err != nil {
+				return err
+			}
+
+			if !locked {
+				return replayIdempotentRequest(c, stored, fingerprint)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			handlerErr := next(c)
+			if handlerErr != nil {
+				// the error response is written by the error handler, the response is committed
+				// so it's not written again when the error is returned
+				c.Error(handlerErr)
+			}
+
+			// a client which timed out cancels the request context, the response must be stored anyway,
+			// otherwise the key stays locked and its retries run the request again after the lock timeout
+			ctx = context.WithoutCancel(ctx)
+
+			if c.Response().Status >= http.StatusInternalServerError {
+				if err := repo.Release(ctx, key); err != nil {
+					log.FromContext(ctx).WithField("error", err).Error("Failed to release idempotency key")
+				}
+				return handlerErr
+			}
+
+			headers := map[string]string{}
+			for _, name := range replayedResponseHeaders {
+				if value := c.Response().Header().Get(name); value != "" {
+					headers[name] = value
+				}
+			}
+
+			err = repo.Complete(ctx, key, entities.IdempotentResponse{
+				StatusCode:  c.Response().Status,
+				ContentType: c.Response().Header().Get(echo.HeaderContentType),
+				Headers:     headers,
+				Body:        recorder.body.Bytes(),
+			})
+			if err != nil {
+				log.FromContext(ctx).WithField("error", err).Error("Failed to store idempotent response")
+			}
+
+			return handlerErr
+		}
+	}
+}
+
+func replayIdempotentRequest(c echo.Context, stored entities.IdempotentRequest, fingerprint string) error {
+	if stored.Fingerprint != fingerprint {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+			"reason": "Idempotency-Key was already used with a different request",
+		})
+	}
+
+	if !stored.IsCompleted() {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"reason": "request with this Idempotency-Key is still in progress",
+		})
+	}
+
+	c.Response().Header().Set("Idempotent-Replayed", "true")
+	for name, value := range stored.Response.Headers {
+		c.Response().Header().Set(name, value)
+	}
+
+	if len(stored.Response.Body) == 0 {
+		return c.NoContent(stored.Response.StatusCode)
+	}
+
+	return c.Blob(stored.Response.StatusCode, stored.Response.ContentType, stored.Response.Body)
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestFingerprint identifies the request sent with the key, the same key can't be used for a different request.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body written by the handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -1,12 +1,30 @@
 package http
 
 import (
+	"errors"
+	"fmt"
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/components/requestreply"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"tickets/internal/entities"
+	"tickets/internal/interfaces/message/commands"
+	"tickets/internal/repository"
+	"time"
 )
 
+type refundAcceptedResponse struct {
+	// CommandID can be polled at GET /commands/:command_id to find out if the refund succeeded.
+	CommandID uuid.UUID               `json:"command_id"`
+	Decision  entities.RefundDecision `json:"decision"`
+}
+
+type refundDeniedResponse struct {
+	Reason   string                  `json:"reason"`
+	Decision entities.RefundDecision `json:"decision"`
+}
+
 func (s *Server) RefundTicketHandler(ctx echo.Context) error {
 	ticketId := ctx.Param("ticket_id")
 	if ticketId == "" {
@@ -14,16 +32,122 @@ func (s *Server) RefundTicketHandler(ctx echo.Context) error {
 			"reason": "ticket_id is required",
 		})
 	}
+	if _, err := uuid.Parse(ticketId); err != nil {
+		return ctx.JSON(http.StatusBadRequest, "ticket_id is not a valid UUID")
+	}
+
+	// the same key returns the decision made for the first request
+	idempotencyKey := ctx.Request().Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = uuid.NewString()
+	}
 
 	log.FromContext(ctx.Request().Context()).Info("Refunding ticket: ", ticketId)
 
-	err := s.commandBus.Send(ctx.Request().Context(), &entities.RefundTicket{
-		Header:   entities.NewEventHeader(),
-		TicketID: ticketId,
+	commandID := uuid.New()
+	decision, err := s.refundTicketUsecase.RequestRefund(commands.ContextWithCommandID(ctx.Request().Context(), commandID), entities.RefundRequest{
+		TicketID:       ticketId,
+		Initiator:      entities.RefundInitiatorCustomer,
+		RequestedAt:    time.Now().UTC(),
+		IdempotencyKey: idempotencyKey,
 	})
 	if err != nil {
-		return err
+		if errors.Is(err, entities.ErrRefundDenied) {
+			return ctx.JSON(http.StatusConflict, refundDeniedResponse{
+				Reason:   "refund is not allowed this close to the show",
+				Decision: decision,
+			})
+		}
+		if errors.Is(err, repository.ErrTicketNotFound) {
+			return ctx.JSON(http.StatusNotFound, "ticket not found")
+		}
+		if errors.Is(err, entities.ErrTicketNotRefundable) || errors.Is(err, entities.ErrRefundInProgress) {
+			return ctx.JSON(http.StatusConflict, map[string]string{
+				"reason": err.Error(),
+			})
+		}
+		return fmt.Errorf("request refund: %w", err)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderLocation, "/commands/"+commandID.String())
+
+	return ctx.JSON(http.StatusAccepted, refundAcceptedResponse{
+		CommandID: commandID,
+		Decision:  decision,
+	})
+}
+
+type refundDoneResponse struct {
+	CommandID uuid.UUID                   `json:"command_id"`
+	Decision  entities.RefundDecision     `json:"decision"`
+	Result    entities.RefundTicketResult `json:"result"`
+}
+
+// OpsRefundTicketHandler refunds the ticket synchronously, so the ops panel can show the result right away.
+func (s *Server) OpsRefundTicketHandler(ctx echo.Context) error {
+	ticketId := ctx.Param("ticket_id")
+	if _, err := uuid.Parse(ticketId); err != nil {
+		return ctx.JSON(http.StatusBadRequest, "ticket_id is not a valid UUID")
+	}
+
+	idempotencyKey := ctx.Request().Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = uuid.NewString()
+	}
+
+	commandID := uuid.New()
+	decision, result, err := s.refundTicketUsecase.RequestRefundAndWait(commands.ContextWithCommandID(ctx.Request().Context(), commandID), entities.RefundRequest{
+		TicketID:       ticketId,
+		Initiator:      entities.RefundInitiatorCustomer,
+		RequestedAt:    time.Now().UTC(),
+		IdempotencyKey: idempotencyKey,
+	})
+	if err != nil {
+		if errors.Is(err, entities.ErrRefundDenied) {
+			return ctx.JSON(http.StatusConflict, refundDeniedResponse{
+				Reason:   "refund is not allowed this close to the show",
+				Decision: decision,
+			})
+		}
+		if errors.Is(err, repository.ErrTicketNotFound) {
+			return ctx.JSON(http.StatusNotFound, "ticket not found")
+		}
+		if errors.Is(err, entities.ErrTicketNotRefundable) || errors.Is(err, entities.ErrRefundInProgress) {
+			return ctx.JSON(http.StatusConflict, map[string]string{
+				"reason": err.Error(),
+			})
+		}
+
+		var timeoutErr requestreply.ReplyTimeoutError
+		if errors.As(err, &timeoutErr) {
+			// the refund is still being processed, the ops panel can poll the command
+			ctx.Response().Header().Set(echo.HeaderLocation, "/commands/"+commandID.String())
+			return ctx.JSON(http.StatusAccepted, refundAcceptedResponse{
+				CommandID: commandID,
+				Decision:  decision,
+			})
+		}
+
+		return fmt.Errorf("refund ticket: %w", err)
+	}
+
+	return ctx.JSON(http.StatusOK, refundDoneResponse{
+		CommandID: commandID,
+		Decision:  decision,
+		Result:    result,
+	})
+}
+
+func (s *Server) GetRefundDecisionsHandler(ctx echo.Context) error {
+	ticketID, err := uuid.Parse(ctx.Param("ticket_id"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, "ticket_id is not a valid UUID")
+	}
+
+	decisions, err := s.refundTicketUsecase.GetDecisions(ctx.Request().Context(), ticketID)
+	if err != nil {
+		return fmt.Errorf("get refund decisions: %w", err)
 	}
 
-	return ctx.NoContent(http.StatusAccepted)
+	return ctx.JSON(http.StatusOK, decisions)
 }
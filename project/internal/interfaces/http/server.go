@@ -13,9 +13,15 @@ import (
 	"go.opentelemetry.io/otel/codes"
 	"net/http"
 	"tickets/internal/application/usecases/booking"
+	"tickets/internal/application/usecases/cancellation"
+	"tickets/internal/application/usecases/checkin"
+	"tickets/internal/application/usecases/opsbookings"
+	"tickets/internal/application/usecases/refund"
 	"tickets/internal/application/usecases/shows"
 	"tickets/internal/application/usecases/tickets"
+	"tickets/internal/application/usecases/transfer"
 	"tickets/internal/application/usecases/vipbundle"
+	"tickets/internal/application/usecases/webhooks"
 	"tickets/internal/repository"
 )
 
@@ -25,9 +31,25 @@ type Server struct {
 	commandBus              *cqrs.CommandBus
 	ticketsService          *tickets.ProcessTicketsUsecase
 	showsService            *shows.CreateShowUsecase
+	updateShowUsecase       *shows.UpdateShowUsecase
+	cancelShowUsecase       *cancellation.CancelShowUsecase
 	bookingsService         *booking.BookTicketsUsecase
 	vipBundleUsecase        *vipbundle.CreateBundleUsecase
+	cancelBookingUsecase    *cancellation.CancelBookingUsecase
 	opsBookingReadModelRepo *repository.OpsBookingReadModelRepo
+	opsBookingUpdates       *opsbookings.UpdatesBroadcaster
+
+	showAvailabilityReadModelRepo *repository.ShowAvailabilityReadModelRepo
+
+	checkInUsecase              *checkin.CheckInUsecase
+	showAttendanceReadModelRepo *repository.ShowAttendanceReadModelRepo
+
+	transferTicketUsecase *transfer.TransferTicketUsecase
+	refundTicketUsecase   *refund.RefundTicketUsecase
+
+	webhooksUsecase *webhooks.WebhooksUsecase
+
+	commandsRepo *repository.CommandsRepo
 }
 
 func NewServer(
@@ -38,6 +60,18 @@ func NewServer(
 	bookingsService *booking.BookTicketsUsecase,
 	opsBookingReadModelRepo *repository.OpsBookingReadModelRepo,
 	vipBundleUsecase *vipbundle.CreateBundleUsecase,
+	cancelBookingUsecase *cancellation.CancelBookingUsecase,
+	updateShowUsecase *shows.UpdateShowUsecase,
+	cancelShowUsecase *cancellation.CancelShowUsecase,
+	showAvailabilityReadModelRepo *repository.ShowAvailabilityReadModelRepo,
+	checkInUsecase *checkin.CheckInUsecase,
+	showAttendanceReadModelRepo *repository.ShowAttendanceReadModelRepo,
+	transferTicketUsecase *transfer.TransferTicketUsecase,
+	refundTicketUsecase *refund.RefundTicketUsecase,
+	commandsRepo *repository.CommandsRepo,
+	idempotentRequestsRepo *repository.IdempotentRequestsRepo,
+	opsBookingUpdates *opsbookings.UpdatesBroadcaster,
+	webhooksUsecase *webhooks.WebhooksUsecase,
 ) *Server {
 	srv := &Server{
 		e:                       e,
@@ -46,21 +80,64 @@ func NewServer(
 		showsService:            showsService,
 		bookingsService:         bookingsService,
 		opsBookingReadModelRepo: opsBookingReadModelRepo,
+		opsBookingUpdates:       opsBookingUpdates,
 		vipBundleUsecase:        vipBundleUsecase,
+		cancelBookingUsecase:    cancelBookingUsecase,
+		updateShowUsecase:       updateShowUsecase,
+		cancelShowUsecase:       cancelShowUsecase,
+
+		showAvailabilityReadModelRepo: showAvailabilityReadModelRepo,
+
+		checkInUsecase:              checkInUsecase,
+		showAttendanceReadModelRepo: showAttendanceReadModelRepo,
+
+		transferTicketUsecase: transferTicketUsecase,
+		refundTicketUsecase:   refundTicketUsecase,
+
+		webhooksUsecase: webhooksUsecase,
+
+		commandsRepo: commandsRepo,
 	}
 	e.POST("/tickets-status", srv.TicketsStatusHandler)
 	e.GET("/tickets", srv.GetTicketsHandler)
+	e.GET("/tickets/:ticket_id/history", srv.GetTicketStatusHistoryHandler)
+	e.POST("/tickets/:ticket_id/transfer", srv.TransferTicketHandler)
+	e.GET("/tickets/:ticket_id/transfers", srv.GetTicketTransfersHandler)
+	e.POST("/tickets/:ticket_id/transfers/:transfer_id/accept", srv.AcceptTicketTransferHandler)
+	e.POST("/tickets/:ticket_id/transfers/:transfer_id/decline", srv.DeclineTicketTransferHandler)
 
 	e.PUT("/ticket-refund/:ticket_id", srv.RefundTicketHandler)
+	e.GET("/tickets/:ticket_id/refund-decisions", srv.GetRefundDecisionsHandler)
 
 	e.POST("/shows", srv.CreateShowHandler)
+	e.GET("/shows", srv.GetShowsHandler)
+	e.GET("/shows/:show_id/availability", srv.GetShowAvailabilityHandler)
+	e.GET("/shows/:show_id/attendance", srv.GetShowAttendanceHandler)
+	e.PATCH("/shows/:show_id", srv.UpdateShowHandler)
+	e.POST("/shows/:show_id/cancel", srv.CancelShowHandler)
+	e.GET("/shows/:show_id/cancellation", srv.GetShowCancellationHandler)
 	e.POST("/book-tickets", srv.BookTicketsHandler)
+	e.POST("/check-in", srv.CheckInHandler)
+	e.POST("/bookings/:booking_id/cancel", srv.CancelBookingHandler)
+	e.GET("/bookings/:booking_id/cancellation", srv.GetBookingCancellationHandler)
 
 	e.GET("/ops/bookings", srv.GetBookingsHandler)
+	e.GET("/ops/bookings/stream", srv.StreamBookingsHandler)
 	e.GET("/ops/bookings/:booking_id", srv.GetBookingHandler)
+	e.GET("/ops/bookings/:booking_id/stream", srv.StreamBookingHandler)
+	e.PUT("/ops/ticket-refund/:ticket_id", srv.OpsRefundTicketHandler)
 
 	e.POST("/book-vip-bundle", srv.BookVIPBundleHandler)
 
+	e.GET("/commands/:command_id", srv.GetCommandHandler)
+
+	e.POST("/webhooks", srv.RegisterWebhookHandler)
+	e.GET("/webhooks", srv.GetWebhooksHandler)
+	e.POST("/webhooks/:subscription_id/enable", srv.EnableWebhookHandler)
+	e.GET("/webhooks/:subscription_id/deliveries", srv.GetWebhookDeliveriesHandler)
+	e.GET("/webhooks/deliveries/:delivery_id", srv.GetWebhookDeliveryHandler)
+	e.POST("/webhooks/deliveries/:delivery_id/redeliver", srv.RedeliverWebhookHandler)
+
 	e.GET("/health", func(c echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
@@ -87,6 +164,8 @@ func NewServer(
 	})
 
 	e.Use(TracingMiddleware())
+	e.Use(IdempotencyMiddleware(idempotentRequestsRepo))
+	e.Pre(RawResponseWriterMiddleware())
 
 	return srv
 }
@@ -101,6 +180,9 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Stop(ctx context.Context) error {
+	// streams never finish on their own
+	s.opsBookingUpdates.Close()
+
 	return s.e.Shutdown(ctx)
 }
 
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"tickets/internal/application/usecases/opsbookings"
+	"tickets/internal/entities"
+	"time"
+)
+
+const (
+	// sseKeepAliveInterval keeps proxies from closing streams without updates.
+	sseKeepAliveInterval = 15 * time.Second
+	// sseReplayBatchSize is how many missed updates are loaded at once when a stream is resumed.
+	sseReplayBatchSize = 100
+
+	rawResponseWriterKey = "raw_response_writer"
+)
+
+// RawResponseWriterMiddleware keeps the response writer before other middlewares wrap it,
+// it must be added with Echo.Pre.
+// The body dump middleware of the common Echo keeps the whole response in memory, which never ends for streams.
+func RawResponseWriterMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(rawResponseWriterKey, c.Response().Writer)
+			return next(c)
+		}
+	}
+}
+
+// StreamBookingsHandler streams updated bookings as Server-Sent Events, placeholders are not streamed.
+//
+// The event ID is the update sequence of the booking. Clients resuming with the Last-Event-ID header
+// (or the last_event_id query param) get the latest state of all bookings updated since then first.
+func (s *Server) StreamBookingsHandler(c echo.Context) error {
+	lastEventID, err := parseLastEventID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	ctx := c.Request().Context()
+
+	// listen before replaying, so no update is missed in between
+	listener := s.opsBookingUpdates.Listen(nil)
+	defer listener.Close()
+
+	startEventStream(c)
+
+	if lastEventID > 0 {
+		for {
+			bookings, err := s.opsBookingReadModelRepo.ListUpdatedAfter(ctx, lastEventID, sseReplayBatchSize)
+			if err != nil {
+				// the client reconnects with the last event it got
+				log.FromContext(ctx).Error("failed to list updated bookings, err:", err)
+				return nil
+			}
+
+			for _, booking := range bookings {
+				if err := writeBookingEvent(c, booking); err != nil {
+					return nil
+				}
+				lastEventID = booking.UpdateSequence
+			}
+
+			if len(bookings) < sseReplayBatchSize {
+				break
+			}
+		}
+	}
+
+	return s.streamBookingUpdates(c, listener, func(booking *entities.OpsBooking) bool {
+		return !booking.BookedAt.IsZero()
+	})
+}
+
+// StreamBookingHandler streams the booking as Server-Sent Events, the current state is sent first.
+// The event ID is the update sequence of the booking, the current state is not sent again
+// to clients resuming with the Last-Event-ID header (or the last_event_id query param) which already have it.
+func (s *Server) StreamBookingHandler(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("booking_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "booking_id is not a valid UUID")
+	}
+
+	lastEventID, err := parseLastEventID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	ctx := c.Request().Context()
+
+	// listen before reading the current state, so no update is missed in between
+	listener := s.opsBookingUpdates.Listen(&id)
+	defer listener.Close()
+
+	booking, err := s.opsBookingReadModelRepo.GetByID(ctx, id)
+	if err != nil {
+		log.FromContext(ctx).Error("failed to get booking, err:", err)
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+
+	if booking == nil {
+		return c.JSON(http.StatusNotFound, "booking not found")
+	}
+
+	startEventStream(c)
+
+	if booking.UpdateSequence > lastEventID {
+		if err := writeBookingEvent(c, *booking); err != nil {
+			return nil
+		}
+		lastEventID = booking.UpdateSequence
+	}
+
+	return s.streamBookingUpdates(c, listener, func(booking *entities.OpsBooking) bool {
+		if booking.UpdateSequence <= lastEventID {
+			return false
+		}
+		lastEventID = booking.UpdateSequence
+
+		return true
+	})
+}
+
+// streamBookingUpdates writes the bookings updated after the listener was notified, until the client disconnects.
+func (s *Server) streamBookingUpdates(
+	c echo.Context,
+	listener *opsbookings.UpdatesListener,
+	shouldSend func(booking *entities.OpsBooking) bool,
+) error {
+	ctx := c.Request().Context()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-listener.Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Response(), ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			c.Response().Flush()
+		case <-listener.Ready():
+			for _, bookingID := range listener.Take() {
+				booking, err := s.opsBookingReadModelRepo.GetByID(ctx, bookingID)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.FromContext(ctx).Error("failed to get updated booking, err:", err)
+					}
+					return nil
+				}
+
+				if booking == nil || !shouldSend(booking) {
+					continue
+				}
+
+				if err := writeBookingEvent(c, *booking); err != nil {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func parseLastEventID(c echo.Context) (int64, error) {
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		// EventSource sends the header only when it reconnects by itself
+		lastEventID = c.QueryParam("last_event_id")
+	}
+	if lastEventID == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("Last-Event-ID must be a positive number")
+	}
+
+	return id, nil
+}
+
+func startEventStream(c echo.Context) {
+	if w, ok := c.Get(rawResponseWriterKey).(http.ResponseWriter); ok {
+		c.Response().Writer = w
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "text/event-stream")
+	header.Set(echo.HeaderCacheControl, "no-cache")
+	header.Set(echo.HeaderConnection, "keep-alive")
+	// disables buffering in nginx
+	header.Set("X-Accel-Buffering", "no")
+
+	c.Response().WriteHeader(http.StatusOK)
+	c.Response().Flush()
+}
+
+func writeBookingEvent(c echo.Context, booking entities.OpsBooking) error {
+	data, err := json.Marshal(booking)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.Response(), "id: %d\ndata: %s\n\n", booking.UpdateSequence, data)
+	if err != nil {
+		return err
+	}
+	c.Response().Flush()
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tickets/internal/application/usecases/opsbookings"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBookingEvent(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/ops/bookings/stream", nil), rec)
+
+	bookingID := uuid.MustParse("6a3b5c1e-2f4d-4e8a-9b7c-1d2e3f4a5b6c")
+	booking := entities.OpsBooking{
+		BookingID: bookingID,
+		Tickets: map[string]entities.OpsTicket{
+			"ticket-1": {CustomerEmail: "customer@example.com"},
+		},
+		UpdateSequence: 42,
+	}
+
+	require.NoError(t, writeBookingEvent(c, booking))
+	require.NoError(t, writeBookingEvent(c, booking))
+
+	scanner := bufio.NewScanner(rec.Body)
+	for i := 0; i < 2; i++ {
+		require.True(t, scanner.Scan())
+		assert.Equal(t, "id: 42", scanner.Text())
+
+		require.True(t, scanner.Scan())
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		require.True(t, ok, "data line expected, got %q", scanner.Text())
+		assert.Contains(t, data, `"booking_id":"`+bookingID.String()+`"`)
+		assert.NotContains(t, data, "UpdateSequence")
+
+		// events are separated with an empty line
+		require.True(t, scanner.Scan())
+		assert.Empty(t, scanner.Text())
+	}
+	assert.False(t, scanner.Scan())
+}
+
+func TestParseLastEventID(t *testing.T) {
+	testCases := []struct {
+		name          string
+		header        string
+		query         string
+		expectedID    int64
+		expectedError bool
+	}{
+		{name: "none"},
+		{name: "header", header: "12", expectedID: 12},
+		{name: "query param", query: "7", expectedID: 7},
+		{name: "header takes precedence", header: "12", query: "7", expectedID: 12},
+		{name: "not a number", header: "abc", expectedError: true},
+		{name: "negative", query: "-1", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ops/bookings/stream?last_event_id="+tc.query, nil)
+			if tc.header != "" {
+				req.Header.Set("Last-Event-ID", tc.header)
+			}
+
+			id, err := parseLastEventID(echo.New().NewContext(req, httptest.NewRecorder()))
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedID, id)
+		})
+	}
+}
+
+func TestStreamBookingsHandler_Disconnect(t *testing.T) {
+	newStream := func(t *testing.T, broadcaster *opsbookings.UpdatesBroadcaster) (*http.Response, context.CancelFunc, <-chan struct{}) {
+		srv := &Server{opsBookingUpdates: broadcaster}
+
+		handlerDone := make(chan struct{})
+		e := echo.New()
+		e.Pre(RawResponseWriterMiddleware())
+		e.GET("/ops/bookings/stream", func(c echo.Context) error {
+			defer close(handlerDone)
+			return srv.StreamBookingsHandler(c)
+		})
+
+		server := httptest.NewServer(e)
+		t.Cleanup(server.Close)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/ops/bookings/stream", nil)
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		return resp, cancel, handlerDone
+	}
+
+	assertHandlerDone := func(t *testing.T, handlerDone <-chan struct{}) {
+		select {
+		case <-handlerDone:
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream handler is still running")
+		}
+	}
+
+	t.Run("client disconnects", func(t *testing.T) {
+		resp, cancel, handlerDone := newStream(t, opsbookings.NewUpdatesBroadcaster())
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+		assert.Equal(t, "no-cache", resp.Header.Get(echo.HeaderCacheControl))
+		assert.Equal(t, "no", resp.Header.Get("X-Accel-Buffering"))
+
+		cancel()
+		assertHandlerDone(t, handlerDone)
+	})
+
+	t.Run("server stops", func(t *testing.T) {
+		broadcaster := opsbookings.NewUpdatesBroadcaster()
+		resp, _, handlerDone := newStream(t, broadcaster)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		broadcaster.Close()
+		assertHandlerDone(t, handlerDone)
+	})
+}
@@ -9,14 +9,11 @@ import (
 )
 
 type TicketStatusRequest struct {
-	TicketId      string `json:"ticket_id"`
-	Status        string `json:"status"`
-	CustomerEmail string `json:"customer_email"`
-	Price         struct {
-		Amount   string `json:"amount"`
-		Currency string `json:"currency"`
-	} `json:"price"`
-	BookingID string `json:"booking_id"`
+	TicketId      string         `json:"ticket_id"`
+	Status        string         `json:"status"`
+	CustomerEmail string         `json:"customer_email"`
+	Price         entities.Money `json:"price"`
+	BookingID     string         `json:"booking_id"`
 }
 
 type TicketsStatusRequest struct {
@@ -45,11 +42,8 @@ func (s *Server) TicketsStatusHandler(c echo.Context) error {
 			TicketId:      ticket.TicketId,
 			Status:        ticket.Status,
 			CustomerEmail: ticket.CustomerEmail,
-			Price: entities.Money{
-				Amount:   ticket.Price.Amount,
-				Currency: ticket.Price.Currency,
-			},
-			BookingId: ticket.BookingID,
+			Price:         ticket.Price,
+			BookingId:     ticket.BookingID,
 		})
 	}
 
@@ -58,12 +52,16 @@ func (s *Server) TicketsStatusHandler(c echo.Context) error {
 		WithField("idempotency_key", idempotencyKey).
 		Info("Confirming tickets http handler")
 
-	err = s.ticketsService.ProcessTickets(ctx, tickets)
+	batch, replayed, err := s.ticketsService.ProcessTickets(ctx, tickets)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"reason": err.Error(),
 		})
 	}
 
-	return c.NoContent(http.StatusOK)
+	if replayed {
+		c.Response().Header().Set("Idempotent-Replayed", "true")
+	}
+
+	return c.JSON(http.StatusOK, batch)
 }
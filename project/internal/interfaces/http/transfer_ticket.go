@@ -0,0 +1,142 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"tickets/internal/application/usecases/transfer"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TransferTicketRequest struct {
+	CustomerEmail      string `json:"customer_email"`
+	RequiresAcceptance bool   `json:"requires_acceptance"`
+}
+
+func (s *Server) TransferTicketHandler(c echo.Context) error {
+	ticketID, err := uuid.Parse(c.Param("ticket_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "ticket_id is not a valid UUID")
+	}
+
+	var request TransferTicketRequest
+	err = c.Bind(&request)
+	if err != nil {
+		return err
+	}
+
+	if _, err := mail.ParseAddress(request.CustomerEmail); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"reason": "customer_email is not a valid email",
+		})
+	}
+
+	ticketTransfer, err := s.transferTicketUsecase.RequestTransfer(c.Request().Context(), transfer.TransferTicketReq{
+		TicketID:           ticketID,
+		ToEmail:            request.CustomerEmail,
+		RequiresAcceptance: request.RequiresAcceptance,
+		RequesterIP:        c.RealIP(),
+	})
+	if err != nil {
+		return transferErrorResponse(c, err)
+	}
+
+	status := http.StatusOK
+	if ticketTransfer.IsPending() {
+		status = http.StatusAccepted
+	}
+
+	return c.JSON(status, ticketTransfer)
+}
+
+func (s *Server) AcceptTicketTransferHandler(c echo.Context) error {
+	ticketID, transferID, err := parseTransferParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	ticketTransfer, err := s.transferTicketUsecase.AcceptTransfer(c.Request().Context(), ticketID, transferID)
+	if err != nil {
+		return transferErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, ticketTransfer)
+}
+
+func (s *Server) DeclineTicketTransferHandler(c echo.Context) error {
+	ticketID, transferID, err := parseTransferParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	ticketTransfer, err := s.transferTicketUsecase.DeclineTransfer(c.Request().Context(), ticketID, transferID)
+	if err != nil {
+		return transferErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, ticketTransfer)
+}
+
+func (s *Server) GetTicketTransfersHandler(c echo.Context) error {
+	ticketID, err := uuid.Parse(c.Param("ticket_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "ticket_id is not a valid UUID")
+	}
+
+	transfers, err := s.transferTicketUsecase.GetTransfers(c.Request().Context(), ticketID)
+	if err != nil {
+		return fmt.Errorf("get ticket transfers: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, transfers)
+}
+
+func parseTransferParams(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	ticketID, err := uuid.Parse(c.Param("ticket_id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("ticket_id is not a valid UUID")
+	}
+
+	transferID, err := uuid.Parse(c.Param("transfer_id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("transfer_id is not a valid UUID")
+	}
+
+	return ticketID, transferID, nil
+}
+
+func transferErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrTicketNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"reason": "Ticket not found",
+		})
+	case errors.Is(err, repository.ErrTicketTransferNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"reason": "Ticket transfer not found",
+		})
+	case errors.Is(err, entities.ErrTicketTransferToSameCustomer):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"reason": "Ticket already belongs to this customer",
+		})
+	case errors.Is(err, entities.ErrTicketNotTransferable):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"reason": "Ticket can't be transferred: " + err.Error(),
+		})
+	case errors.Is(err, entities.ErrTicketTransferPending):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"reason": "Ticket has a transfer waiting for acceptance",
+		})
+	case errors.Is(err, entities.ErrTicketTransferNotPending):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"reason": "Ticket transfer is not waiting for acceptance",
+		})
+	}
+
+	return fmt.Errorf("transfer ticket: %w", err)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"tickets/internal/application/usecases/shows"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+)
+
+type UpdateShowRequest struct {
+	NumberOfTickets *int       `json:"number_of_tickets"`
+	StartTime       *time.Time `json:"start_time"`
+	Title           *string    `json:"title"`
+	Venue           *string    `json:"venue"`
+}
+
+func (s *Server) UpdateShowHandler(c echo.Context) error {
+	showID, err := uuid.Parse(c.Param("show_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "show_id is not a valid UUID")
+	}
+
+	var request UpdateShowRequest
+	err = c.Bind(&request)
+	if err != nil {
+		return err
+	}
+
+	if request.NumberOfTickets != nil && *request.NumberOfTickets <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"reason": "number_of_tickets must be positive",
+		})
+	}
+
+	show, err := s.updateShowUsecase.UpdateShow(c.Request().Context(), shows.UpdateShowReq{
+		ShowID:          showID,
+		NumberOfTickets: request.NumberOfTickets,
+		StartTime:       request.StartTime,
+		Title:           request.Title,
+		Venue:           request.Venue,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrShowNotFound):
+			return c.JSON(http.StatusNotFound, "show not found")
+		case errors.Is(err, entities.ErrShowCancelled):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"reason": "Show is cancelled",
+			})
+		case errors.Is(err, entities.ErrShowCapacityBelowBookedTickets):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"reason": "number_of_tickets is lower than number of already booked tickets",
+			})
+		}
+		return fmt.Errorf("update show: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, show)
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"tickets/internal/application/usecases/webhooks"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultWebhookDeliveriesLimit = 50
+	maxWebhookDeliveriesLimit     = 500
+)
+
+type RegisterWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventNames []string `json:"event_names"`
+}
+
+type WebhookDeliveryResponse struct {
+	entities.WebhookDelivery
+	DeliveryAttempts []entities.WebhookDeliveryAttempt `json:"delivery_attempts"`
+}
+
+func (s *Server) RegisterWebhookHandler(c echo.Context) error {
+	var request RegisterWebhookRequest
+	err := c.Bind(&request)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := s.webhooksUsecase.Register(c.Request().Context(), webhooks.RegisterReq{
+		URL:        request.URL,
+		EventNames: request.EventNames,
+	})
+	if err != nil {
+		return webhookErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, subscription)
+}
+
+func (s *Server) GetWebhooksHandler(c echo.Context) error {
+	subscriptions, err := s.webhooksUsecase.GetSubscriptions(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("get webhook subscriptions: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, subscriptions)
+}
+
+func (s *Server) EnableWebhookHandler(c echo.Context) error {
+	subscriptionID, err := uuid.Parse(c.Param("subscription_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "subscription_id is not a valid UUID")
+	}
+
+	subscription, err := s.webhooksUsecase.Enable(c.Request().Context(), subscriptionID)
+	if err != nil {
+		return webhookErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+func (s *Server) GetWebhookDeliveriesHandler(c echo.Context) error {
+	subscriptionID, err := uuid.Parse(c.Param("subscription_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "subscription_id is not a valid UUID")
+	}
+
+	limit := defaultWebhookDeliveriesLimit
+	if l := c.QueryParam("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 || limit > maxWebhookDeliveriesLimit {
+			return c.JSON(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxWebhookDeliveriesLimit))
+		}
+	}
+
+	deliveries, err := s.webhooksUsecase.GetDeliveries(c.Request().Context(), subscriptionID, limit)
+	if err != nil {
+		return webhookErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+func (s *Server) GetWebhookDeliveryHandler(c echo.Context) error {
+	deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "delivery_id is not a valid UUID")
+	}
+
+	delivery, attempts, err := s.webhooksUsecase.GetDelivery(c.Request().Context(), deliveryID)
+	if err != nil {
+		return webhookErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, WebhookDeliveryResponse{
+		WebhookDelivery:  delivery,
+		DeliveryAttempts: attempts,
+	})
+}
+
+func (s *Server) RedeliverWebhookHandler(c echo.Context) error {
+	deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "delivery_id is not a valid UUID")
+	}
+
+	delivery, err := s.webhooksUsecase.Redeliver(c.Request().Context(), deliveryID)
+	if err != nil {
+		return webhookErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, delivery)
+}
+
+func webhookErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, entities.ErrInvalidWebhookSubscription):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"reason": err.Error(),
+		})
+	case errors.Is(err, repository.ErrWebhookSubscriptionNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"reason": "Webhook subscription not found",
+		})
+	case errors.Is(err, repository.ErrWebhookDeliveryNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"reason": "Webhook delivery not found",
+		})
+	case errors.Is(err, entities.ErrWebhookSubscriptionDisabled):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"reason": "Webhook subscription is disabled, enable it first",
+		})
+	case errors.Is(err, entities.ErrWebhookDeliveryPending):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"reason": "Webhook delivery is still pending",
+		})
+	}
+
+	return fmt.Errorf("webhooks: %w", err)
+}
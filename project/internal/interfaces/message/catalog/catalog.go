@@ -0,0 +1,541 @@
+// Package catalog describes the topics of the service with their messages, producers and consumers,
+// as they are registered in the router and the buses.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"tickets/contracts/public"
+	"tickets/internal/application/usecases/cancellation"
+	"tickets/internal/application/usecases/opsbookings"
+	"tickets/internal/application/usecases/webhooks"
+	internalMessage "tickets/internal/interfaces/message"
+	"tickets/internal/interfaces/message/commands"
+	"tickets/internal/interfaces/message/events"
+	"tickets/internal/repository"
+	"tickets/internal/schema"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+)
+
+//go:generate go run tickets/cmd/catalog -dir ../../../../docs/catalog
+
+const (
+	EventBusProducer   = "event_bus"
+	CommandBusProducer = "command_bus"
+)
+
+// forwardingHandlers only forward messages to other topics, they are run during Generate,
+// so the topics they publish to are in the catalog. Other handlers are never run.
+var forwardingHandlers = map[string]struct{}{
+	"events_splitter": {},
+}
+
+// generateTimeout is how long Generate waits for the messages to be handled.
+const generateTimeout = 10 * time.Second
+
+type Catalog struct {
+	Channels []Channel
+	Messages []Message
+}
+
+// Channel is a topic of the service, partitions of the topic are described by their base topic.
+type Channel struct {
+	Topic        string
+	Messages     []string
+	Partitions   int
+	PartitionKey string
+	Producers    []Operation
+	Consumers    []Operation
+}
+
+// Operation is a handler, or a bus, sending or receiving the messages on the channel.
+type Operation struct {
+	Name     string
+	Messages []string
+}
+
+// Message is identified by its ID, public events have the same names as internal ones, but different IDs.
+type Message struct {
+	ID     string
+	Name   string
+	Public bool
+	Schema *schema.Schema
+}
+
+// Generate sends all events and commands of internal/schema through the buses to the router from NewRouter
+// and records which handlers received them.
+// The router, buses and processors are configured as in the app, but with an in-memory Pub/Sub.
+func Generate(ctx context.Context, partitioning events.Partitioning) (*Catalog, error) {
+	ctx, cancel := context.WithTimeout(ctx, generateTimeout)
+	defer cancel()
+
+	logger := watermill.NopLogger{}
+	marshaler, err := schema.NewMarshaler(schema.Encodings{})
+	if err != nil {
+		return nil, err
+	}
+
+	pubSub := gochannel.NewGoChannel(gochannel.Config{}, logger)
+	defer pubSub.Close()
+
+	r := newRecorder(pubSub, marshaler)
+	routerPublisher := recordingPublisher{recorder: r}
+
+	eventProcessorConfig, err := events.NewEventProcessorConfig(nil, logger, partitioning)
+	if err != nil {
+		return nil, err
+	}
+	eventProcessorConfig.SubscriberConstructor = func(cqrs.EventProcessorSubscriberConstructorParams) (message.Subscriber, error) {
+		return recordingSubscriber{recorder: r}, nil
+	}
+
+	commandProcessorConfig, err := commands.NewCommandProcessorConfig(nil, logger)
+	if err != nil {
+		return nil, err
+	}
+	commandProcessorConfig.SubscriberConstructor = func(cqrs.CommandProcessorSubscriberConstructorParams) (message.Subscriber, error) {
+		return recordingSubscriber{recorder: r}, nil
+	}
+
+	// the handlers are never run, so they don't need dependencies
+	router, err := internalMessage.NewRouter(
+		logger,
+		recordingSubscriber{recorder: r},
+		recordingSubscriber{recorder: r},
+		routerPublisher,
+		&events.Handler{},
+		&commands.Handler{},
+		marshaler,
+		eventProcessorConfig,
+		commandProcessorConfig,
+		nil,
+		&repository.OpsBookingReadModelRepo{},
+		&opsbookings.UpdatesBroadcaster{},
+		&events.VipBundleProcessManager{},
+		&events.BookingCancellationProcessManager{},
+		&cancellation.CancelShowUsecase{},
+		&repository.ShowAvailabilityReadModelRepo{},
+		&repository.ShowAttendanceReadModelRepo{},
+		&webhooks.WebhooksUsecase{},
+		noopCommandTracker{},
+		partitioning,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create router: %w", err)
+	}
+	router.AddMiddleware(r.middleware)
+
+	eventBus, err := events.NewEventBus(
+		recordingPublisher{recorder: r, producer: EventBusProducer},
+		logger,
+		events.BusConfig{Partitioning: partitioning},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event bus: %w", err)
+	}
+
+	commandBus, err := commands.NewBus(
+		recordingPublisher{recorder: r, producer: CommandBusProducer},
+		logger,
+		noopCommandTracker{},
+		trmsqlx.DefaultCtxGetter,
+		schema.Encodings{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command bus: %w", err)
+	}
+
+	routerErr := make(chan error, 1)
+	go func() {
+		routerErr <- router.Run(ctx)
+	}()
+
+	select {
+	case <-router.Running():
+	case err := <-routerErr:
+		return nil, fmt.Errorf("router stopped: %w", err)
+	}
+
+	// stopped handlers are removed from the router
+	handlers := router.Handlers()
+
+	err = func() error {
+		for _, event := range schema.Events {
+			if err := eventBus.Publish(ctx, event); err != nil {
+				return fmt.Errorf("failed to publish %T: %w", event, err)
+			}
+		}
+		for _, command := range schema.Commands {
+			if err := commandBus.Send(ctx, command); err != nil {
+				return fmt.Errorf("failed to send %T: %w", command, err)
+			}
+		}
+		if err := r.waitUntilHandled(ctx); err != nil {
+			return err
+		}
+
+		// handlers of topics without messages from the buses, like the outbox, get a message without a name
+		if err := r.probeEmptyTopics(); err != nil {
+			return err
+		}
+		return r.waitUntilHandled(ctx)
+	}()
+
+	if closeErr := router.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close router: %w", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range handlers {
+		if !r.hasConsumer(name) {
+			return nil, fmt.Errorf("handler %s didn't receive any message", name)
+		}
+	}
+
+	return r.catalog(partitioning)
+}
+
+type delivery struct {
+	operation string
+	topic     string
+	message   string
+}
+
+// recorder records the messages published and received on the in-memory Pub/Sub.
+type recorder struct {
+	pubSub    *gochannel.GoChannel
+	marshaler cqrs.CommandEventMarshaler
+
+	lock          sync.Mutex
+	produced      []delivery
+	consumed      []delivery
+	partitioned   map[string]bool
+	published     map[string]int
+	subscriptions map[string]int
+	handled       int
+	handlerErr    error
+}
+
+func newRecorder(pubSub *gochannel.GoChannel, marshaler cqrs.CommandEventMarshaler) *recorder {
+	return &recorder{
+		pubSub:        pubSub,
+		marshaler:     marshaler,
+		partitioned:   map[string]bool{},
+		published:     map[string]int{},
+		subscriptions: map[string]int{},
+	}
+}
+
+func (r *recorder) publish(producer string, topic string, messages ...*message.Message) error {
+	baseTopic := events.BaseTopic(topic)
+
+	r.lock.Lock()
+	for _, msg := range messages {
+		if producer == "" {
+			producer = message.HandlerNameFromCtx(msg.Context())
+		}
+		r.produced = append(r.produced, delivery{
+			operation: producer,
+			topic:     baseTopic,
+			message:   r.marshaler.NameFromMessage(msg),
+		})
+	}
+	r.published[baseTopic] += len(messages)
+	if baseTopic != topic {
+		r.partitioned[baseTopic] = true
+	}
+	r.lock.Unlock()
+
+	// processors subscribe to the base topic, partitions are consumed by the partitioned subscriber
+	return r.pubSub.Publish(baseTopic, messages...)
+}
+
+func (r *recorder) subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	r.lock.Lock()
+	r.subscriptions[topic]++
+	r.lock.Unlock()
+
+	return r.pubSub.Subscribe(ctx, topic)
+}
+
+// middleware records the received message instead of handling it, only forwardingHandlers are run.
+func (r *recorder) middleware(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		handlerName := message.HandlerNameFromCtx(msg.Context())
+
+		var err error
+		if _, ok := forwardingHandlers[handlerName]; ok {
+			_, err = h(msg)
+		}
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		r.consumed = append(r.consumed, delivery{
+			operation: handlerName,
+			topic:     message.SubscribeTopicFromCtx(msg.Context()),
+			message:   r.marshaler.NameFromMessage(msg),
+		})
+		r.handled++
+		if err != nil && r.handlerErr == nil {
+			r.handlerErr = fmt.Errorf("handler %s failed: %w", handlerName, err)
+		}
+
+		// not retried, the error is returned by Generate
+		return nil, nil
+	}
+}
+
+func (r *recorder) waitUntilHandled(ctx context.Context) error {
+	for {
+		r.lock.Lock()
+		expected := 0
+		for topic, published := range r.published {
+			expected += published * r.subscriptions[topic]
+		}
+		handled, handlerErr := r.handled, r.handlerErr
+		r.lock.Unlock()
+
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if handled >= expected {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d of %d messages handled: %w", handled, expected, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (r *recorder) probeEmptyTopics() error {
+	r.lock.Lock()
+	var topics []string
+	for topic := range r.subscriptions {
+		if r.published[topic] == 0 {
+			topics = append(topics, topic)
+		}
+	}
+	for _, topic := range topics {
+		r.published[topic]++
+	}
+	r.lock.Unlock()
+
+	for _, topic := range topics {
+		if err := r.pubSub.Publish(topic, message.NewMessage(uuid.NewString(), nil)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *recorder) hasConsumer(name string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, d := range r.consumed {
+		if d.operation == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *recorder) catalog(partitioning events.Partitioning) (*Catalog, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	publicTypes := map[string]any{}
+	for _, event := range public.Events {
+		publicTypes[reflect.TypeOf(event).Name()] = event
+	}
+
+	registry, err := schema.Embedded()
+	if err != nil {
+		return nil, err
+	}
+
+	channels := map[string]*Channel{}
+	messages := map[string]Message{}
+
+	add := func(deliveries []delivery, consumed bool) error {
+		// operation -> topic -> message IDs
+		operations := map[string]map[string]map[string]struct{}{}
+
+		for _, d := range deliveries {
+			channel, ok := channels[d.topic]
+			if !ok {
+				channel = &Channel{Topic: d.topic}
+				channels[d.topic] = channel
+			}
+
+			if operations[d.operation] == nil {
+				operations[d.operation] = map[string]map[string]struct{}{}
+			}
+			if operations[d.operation][d.topic] == nil {
+				operations[d.operation][d.topic] = map[string]struct{}{}
+			}
+
+			if d.message == "" {
+				continue
+			}
+
+			msg, err := newMessage(d, registry, publicTypes)
+			if err != nil {
+				return err
+			}
+			messages[msg.ID] = msg
+			operations[d.operation][d.topic][msg.ID] = struct{}{}
+
+			if !contains(channel.Messages, msg.ID) {
+				channel.Messages = append(channel.Messages, msg.ID)
+			}
+
+			if config, ok := partitioning[d.message]; ok && r.partitioned[d.topic] {
+				channel.Partitions = config.Partitions
+				channel.PartitionKey = config.Key
+			}
+		}
+
+		for name, topics := range operations {
+			for topic, ids := range topics {
+				operation := Operation{Name: name, Messages: sortedKeys(ids)}
+				if consumed {
+					channels[topic].Consumers = append(channels[topic].Consumers, operation)
+				} else {
+					channels[topic].Producers = append(channels[topic].Producers, operation)
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if err := add(r.produced, false); err != nil {
+		return nil, err
+	}
+	if err := add(r.consumed, true); err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{}
+	for _, channel := range channels {
+		sort.Strings(channel.Messages)
+		sortOperations(channel.Producers)
+		sortOperations(channel.Consumers)
+		c.Channels = append(c.Channels, *channel)
+	}
+	sort.Slice(c.Channels, func(i, j int) bool {
+		return c.Channels[i].Topic < c.Channels[j].Topic
+	})
+
+	for _, msg := range messages {
+		c.Messages = append(c.Messages, msg)
+	}
+	sort.Slice(c.Messages, func(i, j int) bool {
+		return c.Messages[i].ID < c.Messages[j].ID
+	})
+
+	return c, nil
+}
+
+func newMessage(d delivery, registry *schema.Registry, publicTypes map[string]any) (Message, error) {
+	if strings.HasPrefix(d.topic, public.TopicPrefix) {
+		event, ok := publicTypes[d.message]
+		if !ok {
+			return Message{}, fmt.Errorf("%s on %s is not in public.Events", d.message, d.topic)
+		}
+
+		s, err := schema.Generate(event)
+		if err != nil {
+			return Message{}, err
+		}
+
+		return Message{ID: "public." + d.message, Name: d.message, Public: true, Schema: s}, nil
+	}
+
+	s, ok := registry.Get(d.message)
+	if !ok {
+		return Message{}, fmt.Errorf("%w of %s", schema.ErrNoSchema, d.message)
+	}
+
+	return Message{ID: d.message, Name: d.message, Schema: s}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortOperations(operations []Operation) {
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].Name < operations[j].Name
+	})
+}
+
+// recordingPublisher publishes as the producer, or as the handler from the message context when it's empty.
+type recordingPublisher struct {
+	recorder *recorder
+	producer string
+}
+
+func (p recordingPublisher) Publish(topic string, messages ...*message.Message) error {
+	return p.recorder.publish(p.producer, topic, messages...)
+}
+
+func (p recordingPublisher) Close() error {
+	return nil
+}
+
+type recordingSubscriber struct {
+	recorder *recorder
+}
+
+func (s recordingSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	return s.recorder.subscribe(ctx, topic)
+}
+
+func (s recordingSubscriber) Close() error {
+	return nil
+}
+
+type noopCommandTracker struct{}
+
+func (noopCommandTracker) Accept(context.Context, uuid.UUID, string) error { return nil }
+
+func (noopCommandTracker) MarkProcessing(context.Context, uuid.UUID) error { return nil }
+
+func (noopCommandTracker) MarkSucceeded(context.Context, uuid.UUID) error { return nil }
+
+func (noopCommandTracker) MarkFailed(context.Context, uuid.UUID, string) error { return nil }
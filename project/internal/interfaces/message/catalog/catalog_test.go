@@ -0,0 +1,46 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"tickets/internal/app"
+	"tickets/internal/interfaces/message/outbox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const catalogDir = "../../../../docs/catalog"
+
+func TestCatalog_UpToDate(t *testing.T) {
+	c, err := Generate(context.Background(), app.EventPartitioning)
+	require.NoError(t, err)
+
+	files, err := Render(c)
+	require.NoError(t, err)
+
+	for name, content := range files {
+		recorded, err := os.ReadFile(filepath.Join(catalogDir, name))
+		require.NoError(t, err)
+
+		assert.Equal(t, string(recorded), string(content), "%s is out of date, run go generate ./internal/interfaces/message/catalog", name)
+	}
+}
+
+func TestCatalog_EveryConsumedTopicIsProduced(t *testing.T) {
+	c, err := Generate(context.Background(), app.EventPartitioning)
+	require.NoError(t, err)
+
+	for _, channel := range c.Channels {
+		if channel.Topic == outbox.Topic {
+			// the outbox is written in the transactions of the repositories, not by the buses
+			continue
+		}
+
+		if len(channel.Consumers) > 0 {
+			assert.NotEmpty(t, channel.Producers, "nothing publishes to %s, consumed by %v", channel.Topic, channel.Consumers)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	AsyncAPIFile = "asyncapi.json"
+	MarkdownFile = "catalog.md"
+	GraphvizFile = "catalog.dot"
+)
+
+const generatedNote = "Generated by `go generate ./internal/interfaces/message/catalog` from the router registrations, don't edit."
+
+// Render returns the catalog files by their names.
+func Render(c *Catalog) (map[string][]byte, error) {
+	asyncAPI, err := AsyncAPI(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		AsyncAPIFile: asyncAPI,
+		MarkdownFile: Markdown(c),
+		GraphvizFile: Graphviz(c),
+	}, nil
+}
+
+// AsyncAPI returns the AsyncAPI 3.0 document of the catalog.
+func AsyncAPI(c *Catalog) ([]byte, error) {
+	channels := map[string]any{}
+	operations := map[string]any{}
+
+	for _, channel := range c.Channels {
+		channelID := id(channel.Topic)
+		channelRef := map[string]string{"$ref": "#/channels/" + channelID}
+
+		messages := map[string]any{}
+		for _, msg := range channel.Messages {
+			messages[id(msg)] = map[string]string{"$ref": "#/components/messages/" + id(msg)}
+		}
+
+		ch := map[string]any{
+			"address":  channel.Topic,
+			"messages": messages,
+		}
+		if channel.Partitions > 0 {
+			ch["description"] = partitionsDescription(channel)
+		}
+		channels[channelID] = ch
+
+		addOperations := func(action string, ops []Operation) {
+			for _, op := range ops {
+				var refs []map[string]string
+				for _, msg := range op.Messages {
+					refs = append(refs, map[string]string{"$ref": "#/channels/" + channelID + "/messages/" + id(msg)})
+				}
+
+				operationID := id(op.Name)
+				if action == "send" {
+					operationID = id(op.Name + ".send." + channel.Topic)
+				}
+
+				operation := map[string]any{
+					"action":  action,
+					"title":   op.Name,
+					"channel": channelRef,
+				}
+				if len(refs) > 0 {
+					operation["messages"] = refs
+				}
+				operations[operationID] = operation
+			}
+		}
+		addOperations("send", channel.Producers)
+		addOperations("receive", channel.Consumers)
+	}
+
+	messages := map[string]any{}
+	schemas := map[string]any{}
+	for _, msg := range c.Messages {
+		messages[id(msg.ID)] = map[string]any{
+			"name":        msg.Name,
+			"title":       msg.ID,
+			"contentType": "application/json",
+			"payload":     map[string]string{"$ref": "#/components/schemas/" + id(msg.ID)},
+		}
+		schemas[id(msg.ID)] = msg.Schema
+	}
+
+	doc := map[string]any{
+		"asyncapi": "3.0.0",
+		"info": map[string]any{
+			"title":       "svc-tickets",
+			"version":     "1.0.0",
+			"description": generatedNote,
+		},
+		"defaultContentType": "application/json",
+		"channels":           channels,
+		"operations":         operations,
+		"components": map[string]any{
+			"messages": messages,
+			"schemas":  schemas,
+		},
+	}
+
+	content, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal AsyncAPI document: %w", err)
+	}
+
+	return append(content, '\n'), nil
+}
+
+// Markdown returns the table of the channels with their producers and consumers.
+func Markdown(c *Catalog) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("# Message catalog\n\n")
+	b.WriteString(generatedNote + "\n\n")
+	b.WriteString("| Topic | Messages | Producers | Consumers |\n")
+	b.WriteString("|---|---|---|---|\n")
+
+	for _, channel := range c.Channels {
+		topic := "`" + channel.Topic + "`"
+		if channel.Partitions > 0 {
+			topic += "<br>" + partitionsDescription(channel)
+		}
+
+		fmt.Fprintf(
+			&b,
+			"| %s | %s | %s | %s |\n",
+			topic,
+			strings.Join(channel.Messages, "<br>"),
+			operationNames(channel.Producers),
+			operationNames(channel.Consumers),
+		)
+	}
+
+	return b.Bytes()
+}
+
+// Graphviz returns the graph of producers, topics and consumers, render it with dot -Tsvg.
+func Graphviz(c *Catalog) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("// " + generatedNote + "\n")
+	b.WriteString("digraph catalog {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\", fontsize=10];\n\n")
+
+	operations := map[string]struct{}{}
+	for _, channel := range c.Channels {
+		label := channel.Topic
+		if channel.Partitions > 0 {
+			label += fmt.Sprintf("\n(%d partitions)", channel.Partitions)
+		}
+		fmt.Fprintf(&b, "  %q [shape=cylinder, label=%q];\n", "topic:"+channel.Topic, label)
+
+		for _, op := range channel.Producers {
+			operations[op.Name] = struct{}{}
+		}
+		for _, op := range channel.Consumers {
+			operations[op.Name] = struct{}{}
+		}
+	}
+	b.WriteString("\n")
+
+	for _, name := range sortedKeys(operations) {
+		fmt.Fprintf(&b, "  %q [shape=box, label=%q];\n", "handler:"+name, name)
+	}
+	b.WriteString("\n")
+
+	for _, channel := range c.Channels {
+		for _, op := range channel.Producers {
+			fmt.Fprintf(&b, "  %q -> %q;\n", "handler:"+op.Name, "topic:"+channel.Topic)
+		}
+		for _, op := range channel.Consumers {
+			fmt.Fprintf(&b, "  %q -> %q;\n", "topic:"+channel.Topic, "handler:"+op.Name)
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
+
+func partitionsDescription(channel Channel) string {
+	return fmt.Sprintf("%d partitions by `%s`", channel.Partitions, channel.PartitionKey)
+}
+
+func operationNames(operations []Operation) string {
+	names := make([]string, 0, len(operations))
+	for _, op := range operations {
+		names = append(names, op.Name)
+	}
+
+	return strings.Join(names, "<br>")
+}
+
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9_\-]`)
+
+// id returns the AsyncAPI identifier of the topic, handler or message.
+func id(name string) string {
+	return invalidIDChars.ReplaceAllString(name, "_")
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
@@ -22,6 +23,10 @@ func (h *Handler) BookShowTicketsHandler() cqrs.CommandHandler {
 					NumberOfTickets: command.NumberOfTickets,
 					CustomerEmail:   command.CustomerEmail,
 				})
+			if errors.Is(err, entities.ErrShowCancelled) {
+				// BookingFailed_v1 was already published, retrying won't help
+				return nil
+			}
 			if err != nil {
 				return fmt.Errorf("book tickets: %w", err)
 			}
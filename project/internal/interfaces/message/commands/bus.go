@@ -1,25 +1,83 @@
 package commands
 
 import (
+	"fmt"
+	"tickets/internal/interfaces/message/outbox"
+	"tickets/internal/schema"
+
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/ThreeDotsLabs/watermill/message"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 )
 
+// NewBus creates the command bus. Every sent command is stored as accepted by the tracker,
+// its ID is sent in the command_id metadata.
+//
+// Commands sent with a transaction in the context are stored in the outbox and forwarded after the commit,
+// so they are sent only when the state changed in the same transaction is stored.
+// Don't wait for a reply (requestreply.SendWithReply) inside a transaction, the command is not sent until it's committed.
+//
+// The encodings select JSON or protobuf payloads by command name, the command processor reads both.
 func NewBus(
 	publisher message.Publisher,
 	watermillLogger watermill.LoggerAdapter,
+	tracker CommandTracker,
+	trGetter *trmsqlx.CtxGetter,
+	encodings schema.Encodings,
 ) (*cqrs.CommandBus, error) {
+	marshaler, err := schema.NewMarshaler(encodings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create marshaler: %w", err)
+	}
+
 	return cqrs.NewCommandBusWithConfig(
-		publisher,
+		outboxAwarePublisher{
+			publisher:       publisher,
+			trGetter:        trGetter,
+			watermillLogger: watermillLogger,
+		},
 		cqrs.CommandBusConfig{
 			GeneratePublishTopic: func(params cqrs.CommandBusGeneratePublishTopicParams) (string, error) {
 				return "commands." + params.CommandName, nil
 			},
-			Marshaler: cqrs.JSONMarshaler{
-				GenerateName: cqrs.StructName,
-			},
-			Logger: watermillLogger,
+			Marshaler: marshaler,
+			OnSend:    trackOnSend(tracker),
+			Logger:    watermillLogger,
 		},
 	)
 }
+
+// outboxAwarePublisher publishes to the outbox when the message context has a transaction.
+type outboxAwarePublisher struct {
+	publisher       message.Publisher
+	trGetter        *trmsqlx.CtxGetter
+	watermillLogger watermill.LoggerAdapter
+}
+
+func (p outboxAwarePublisher) Publish(topic string, messages ...*message.Message) error {
+	for _, msg := range messages {
+		tr := p.trGetter.DefaultTrOrDB(msg.Context(), nil)
+		if tr == nil {
+			if err := p.publisher.Publish(topic, msg); err != nil {
+				return err
+			}
+			continue
+		}
+
+		outboxPublisher, err := outbox.NewPublisher(tr, p.watermillLogger)
+		if err != nil {
+			return fmt.Errorf("failed to create outbox publisher: %w", err)
+		}
+
+		if err := outboxPublisher.Publish(topic, msg); err != nil {
+			return fmt.Errorf("failed to publish command to outbox: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p outboxAwarePublisher) Close() error {
+	return p.publisher.Close()
+}
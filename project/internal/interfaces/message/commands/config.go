@@ -1,17 +1,24 @@
 package commands
 
 import (
+	"fmt"
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/redis/go-redis/v9"
+	"tickets/internal/schema"
 )
 
 func NewCommandProcessorConfig(
 	redisClient *redis.Client,
 	watermillLogger watermill.LoggerAdapter,
-) cqrs.CommandProcessorConfig {
+) (cqrs.CommandProcessorConfig, error) {
+	marshaler, err := schema.NewMarshaler(schema.Encodings{})
+	if err != nil {
+		return cqrs.CommandProcessorConfig{}, fmt.Errorf("failed to create marshaler: %w", err)
+	}
+
 	return cqrs.CommandProcessorConfig{
 		GenerateSubscribeTopic: func(params cqrs.CommandProcessorGenerateSubscribeTopicParams) (string, error) {
 			return "commands." + params.CommandName, nil
@@ -22,9 +29,7 @@ func NewCommandProcessorConfig(
 				ConsumerGroup: "svc-tickets.commands",
 			}, watermillLogger)
 		},
-		Marshaler: cqrs.JSONMarshaler{
-			GenerateName: cqrs.StructName,
-		},
-		Logger: watermillLogger,
-	}
+		Marshaler: marshaler,
+		Logger:    watermillLogger,
+	}, nil
 }
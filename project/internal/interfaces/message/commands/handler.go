@@ -3,14 +3,16 @@ package commands
 import (
 	"context"
 	"tickets/internal/application/usecases/booking"
+	"tickets/internal/entities"
 	"tickets/internal/infrastructure/clients"
 
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/components/requestreply"
 	"github.com/google/uuid"
 )
 
 type PaymentsService interface {
-	Refund(ctx context.Context, ticketID, idempotencyKey string) error
+	Refund(ctx context.Context, ticketID, reason, idempotencyKey string) error
 }
 
 type ReceiptsService interface {
@@ -29,6 +31,7 @@ type Handler struct {
 	receiptsService      ReceiptsService
 	bookTicketsUsecase   *booking.BookTicketsUsecase
 	transportationClient TransportationBooker
+	refundReplies        requestreply.Backend[entities.RefundTicketResult]
 }
 
 func NewHandler(
@@ -37,6 +40,7 @@ func NewHandler(
 	receiptsService ReceiptsService,
 	bookTicketsUsecase *booking.BookTicketsUsecase,
 	transportationClient TransportationBooker,
+	refundReplies requestreply.Backend[entities.RefundTicketResult],
 ) *Handler {
 	return &Handler{
 		eb:                   eb,
@@ -44,5 +48,6 @@ func NewHandler(
 		receiptsService:      receiptsService,
 		bookTicketsUsecase:   bookTicketsUsecase,
 		transportationClient: transportationClient,
+		refundReplies:        refundReplies,
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/redis/go-redis/v9"
+	"tickets/internal/schema"
 )
 
 func NewCommandsProcessor(
@@ -19,6 +20,11 @@ func NewCommandsProcessor(
 		ConsumerGroup: "svc-tickets.commands",
 	}, watermillLogger)
 
+	marshaler, err := schema.NewMarshaler(schema.Encodings{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create marshaler: %w", err)
+	}
+
 	commandProcessor, err := cqrs.NewCommandProcessorWithConfig(
 		router,
 		cqrs.CommandProcessorConfig{
@@ -28,10 +34,8 @@ func NewCommandsProcessor(
 			SubscriberConstructor: func(params cqrs.CommandProcessorSubscriberConstructorParams) (message.Subscriber, error) {
 				return sub, nil
 			},
-			Marshaler: cqrs.JSONMarshaler{
-				GenerateName: cqrs.StructName,
-			},
-			Logger: watermillLogger,
+			Marshaler: marshaler,
+			Logger:    watermillLogger,
 		},
 	)
 	if err != nil {
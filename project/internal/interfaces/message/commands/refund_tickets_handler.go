@@ -9,32 +9,38 @@ import (
 )
 
 func (h *Handler) RefundTicketsHandler() cqrs.CommandHandler {
-	return cqrs.NewCommandHandler(
+	return newCommandHandlerWithReply(
 		"refund_tickets",
-		func(ctx context.Context, command *entities.RefundTicket) error {
+		h.refundReplies,
+		func(ctx context.Context, command *entities.RefundTicket) (entities.RefundTicketResult, error) {
 			log.FromContext(ctx).Info("Refunding ticket: ", command.TicketID)
 
-			err := h.paymentService.Refund(ctx, command.TicketID, command.Header.IdempotencyKey)
+			// the amount was decided by the refund policy when the refund was requested
+			err := h.paymentService.Refund(ctx, command.TicketID, command.Reason, command.Header.IdempotencyKey)
 			if err != nil {
-				return fmt.Errorf("error refunding tickets: %w", err)
+				return entities.RefundTicketResult{}, fmt.Errorf("error refunding tickets: %w", err)
 			}
 			log.FromContext(ctx).Info("Payment refunded")
 
 			err = h.receiptsService.VoidReceipt(ctx, command.TicketID, command.Header.IdempotencyKey)
 			if err != nil {
-				return fmt.Errorf("error voiding receipt: %w", err)
+				return entities.RefundTicketResult{}, fmt.Errorf("error voiding receipt: %w", err)
 			}
 			log.FromContext(ctx).Info("Receipt voided")
 
 			err = h.eb.Publish(ctx, &entities.TicketRefunded_v1{
-				Header:   command.Header,
-				TicketID: command.TicketID,
+				Header:         command.Header,
+				TicketID:       command.TicketID,
+				RefundedAmount: command.RefundedAmount,
 			})
 			if err != nil {
-				return fmt.Errorf("error publishing TicketRefunded_v1 event: %w", err)
+				return entities.RefundTicketResult{}, fmt.Errorf("error publishing TicketRefunded_v1 event: %w", err)
 			}
 
-			return nil
+			return entities.RefundTicketResult{
+				TicketID:       command.TicketID,
+				RefundedAmount: command.RefundedAmount,
+			}, nil
 		},
 	)
 }
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/components/requestreply"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+// replyStreamTTL removes reply streams of senders which stopped waiting before the reply was published.
+const replyStreamTTL = time.Hour
+
+func replyTopic(operationID requestreply.OperationID) string {
+	return "commands_replies." + string(operationID)
+}
+
+// NewReplyBackend creates the backend for sending commands with requestreply.SendWithReply.
+// Each request gets its own reply stream, which is deleted when the sender stops waiting.
+func NewReplyBackend[Result any](
+	redisClient *redis.Client,
+	publisher message.Publisher,
+	watermillLogger watermill.LoggerAdapter,
+	timeout time.Duration,
+) (*requestreply.PubSubBackend[Result], error) {
+	return requestreply.NewPubSubBackend[Result](
+		requestreply.PubSubBackendConfig{
+			Publisher: expiringReplyPublisher{
+				Publisher:   publisher,
+				redisClient: redisClient,
+			},
+			SubscriberConstructor: func(params requestreply.PubSubBackendSubscribeParams) (message.Subscriber, error) {
+				return redisstream.NewSubscriber(redisstream.SubscriberConfig{
+					Client: redisClient,
+					// the stream is created for this request only, so the reply can't be missed
+					// even when it's published before the subscriber starts reading
+					FanOutOldestId: "0",
+				}, watermillLogger)
+			},
+			GenerateSubscribeTopic: func(params requestreply.PubSubBackendSubscribeParams) (string, error) {
+				return replyTopic(params.OperationID), nil
+			},
+			GeneratePublishTopic: func(params requestreply.PubSubBackendPublishParams) (string, error) {
+				return replyTopic(params.OperationID), nil
+			},
+			OnListenForReplyFinished: func(ctx context.Context, params requestreply.PubSubBackendSubscribeParams) {
+				// ctx is already cancelled here
+				err := redisClient.Del(context.Background(), replyTopic(params.OperationID)).Err()
+				if err != nil {
+					watermillLogger.Error("Failed to delete reply stream", err, watermill.LogFields{
+						"operation_id": params.OperationID,
+					})
+				}
+			},
+			Logger:                watermillLogger,
+			ListenForReplyTimeout: &timeout,
+		},
+		requestreply.BackendPubsubJSONMarshaler[Result]{},
+	)
+}
+
+type expiringReplyPublisher struct {
+	message.Publisher
+	redisClient *redis.Client
+}
+
+func (p expiringReplyPublisher) Publish(topic string, messages ...*message.Message) error {
+	err := p.Publisher.Publish(topic, messages...)
+	if err != nil {
+		return err
+	}
+
+	return p.redisClient.Expire(context.Background(), topic, replyStreamTTL).Err()
+}
+
+// newCommandHandlerWithReply publishes the handler result to the sender waiting with requestreply.SendWithReply.
+// Commands sent with Send are handled the same way, just without the reply.
+//
+// Failed commands are retried, so the sender may get the error of the first attempt
+// while a later attempt succeeds. The command status tells the final result.
+func newCommandHandlerWithReply[Command any, Result any](
+	handlerName string,
+	backend requestreply.Backend[Result],
+	handleFunc func(ctx context.Context, cmd *Command) (Result, error),
+) cqrs.CommandHandler {
+	withReply := requestreply.NewCommandHandlerWithResult(handlerName, backend, handleFunc)
+
+	return cqrs.NewCommandHandler(handlerName, func(ctx context.Context, cmd *Command) error {
+		msg := cqrs.OriginalMessageFromCtx(ctx)
+		if msg == nil || msg.Metadata.Get(requestreply.OperationIDMetadataKey) == "" {
+			_, err := handleFunc(ctx, cmd)
+			return err
+		}
+
+		err := withReply.Handle(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("handle %s with reply: %w", handlerName, err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+)
+
+const commandIDMetadataKey = "command_id"
+
+// CommandTracker stores the status of every command sent through the bus.
+type CommandTracker interface {
+	Accept(ctx context.Context, commandID uuid.UUID, commandName string) error
+	MarkProcessing(ctx context.Context, commandID uuid.UUID) error
+	MarkSucceeded(ctx context.Context, commandID uuid.UUID) error
+	MarkFailed(ctx context.Context, commandID uuid.UUID, reason string) error
+}
+
+type commandIDKey struct{}
+
+// ContextWithCommandID sets the ID of the next command sent with ctx, so the caller can return it for polling.
+// Commands sent without it get a random ID.
+func ContextWithCommandID(ctx context.Context, commandID uuid.UUID) context.Context {
+	return context.WithValue(ctx, commandIDKey{}, commandID)
+}
+
+func commandIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	commandID, ok := ctx.Value(commandIDKey{}).(uuid.UUID)
+	return commandID, ok
+}
+
+func trackOnSend(tracker CommandTracker) cqrs.CommandBusOnSendFn {
+	return func(params cqrs.CommandBusOnSendParams) error {
+		commandID, ok := commandIDFromContext(params.Message.Context())
+		if !ok {
+			commandID = uuid.New()
+		}
+
+		params.Message.Metadata.Set(commandIDMetadataKey, commandID.String())
+
+		err := tracker.Accept(params.Message.Context(), commandID, params.CommandName)
+		if err != nil {
+			return fmt.Errorf("accept command %s: %w", params.CommandName, err)
+		}
+
+		return nil
+	}
+}
+
+// StatusTrackingMiddleware updates the status of commands as they are handled.
+// It has to be added before the retry middleware, so the command is marked as failed only when all retries failed.
+func StatusTrackingMiddleware(tracker CommandTracker) message.HandlerMiddleware {
+	return func(next message.HandlerFunc) message.HandlerFunc {
+		return func(msg *message.Message) ([]*message.Message, error) {
+			if !strings.HasPrefix(message.SubscribeTopicFromCtx(msg.Context()), "commands.") {
+				return next(msg)
+			}
+
+			commandID, err := uuid.Parse(msg.Metadata.Get(commandIDMetadataKey))
+			if err != nil {
+				// sent before the tracking was introduced
+				return next(msg)
+			}
+
+			if err := tracker.MarkProcessing(msg.Context(), commandID); err != nil {
+				return nil, err
+			}
+
+			msgs, handlerErr := next(msg)
+			if handlerErr != nil {
+				if err := tracker.MarkFailed(msg.Context(), commandID, handlerErr.Error()); err != nil {
+					log.FromContext(msg.Context()).WithField("error", err).Error("Failed to mark command as failed")
+				}
+				return msgs, handlerErr
+			}
+
+			if err := tracker.MarkSucceeded(msg.Context(), commandID); err != nil {
+				return nil, err
+			}
+
+			return msgs, nil
+		}
+	}
+}
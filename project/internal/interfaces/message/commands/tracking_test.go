@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trackerStub records the status changes in the order they happened.
+type trackerStub struct {
+	mu       sync.Mutex
+	statuses map[uuid.UUID][]string
+	names    map[uuid.UUID]string
+	failures map[uuid.UUID]string
+}
+
+func newTrackerStub() *trackerStub {
+	return &trackerStub{
+		statuses: map[uuid.UUID][]string{},
+		names:    map[uuid.UUID]string{},
+		failures: map[uuid.UUID]string{},
+	}
+}
+
+func (s *trackerStub) record(commandID uuid.UUID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[commandID] = append(s.statuses[commandID], status)
+}
+
+func (s *trackerStub) Accept(_ context.Context, commandID uuid.UUID, commandName string) error {
+	s.mu.Lock()
+	s.names[commandID] = commandName
+	s.mu.Unlock()
+
+	s.record(commandID, "accepted")
+	return nil
+}
+
+func (s *trackerStub) MarkProcessing(_ context.Context, commandID uuid.UUID) error {
+	s.record(commandID, "processing")
+	return nil
+}
+
+func (s *trackerStub) MarkSucceeded(_ context.Context, commandID uuid.UUID) error {
+	s.record(commandID, "succeeded")
+	return nil
+}
+
+func (s *trackerStub) MarkFailed(_ context.Context, commandID uuid.UUID, reason string) error {
+	s.mu.Lock()
+	s.failures[commandID] = reason
+	s.mu.Unlock()
+
+	s.record(commandID, "failed")
+	return nil
+}
+
+func (s *trackerStub) get(commandID uuid.UUID) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.statuses[commandID]...)
+}
+
+func TestTrackOnSend(t *testing.T) {
+	tracker := newTrackerStub()
+	onSend := trackOnSend(tracker)
+
+	t.Run("command ID from context", func(t *testing.T) {
+		commandID := uuid.New()
+		msg := message.NewMessage(watermill.NewUUID(), nil)
+		msg.SetContext(ContextWithCommandID(context.Background(), commandID))
+
+		require.NoError(t, onSend(cqrs.CommandBusOnSendParams{CommandName: "RefundTicket", Message: msg}))
+
+		assert.Equal(t, commandID.String(), msg.Metadata.Get(commandIDMetadataKey))
+		assert.Equal(t, []string{"accepted"}, tracker.get(commandID))
+		assert.Equal(t, "RefundTicket", tracker.names[commandID])
+	})
+
+	t.Run("random command ID", func(t *testing.T) {
+		msg := message.NewMessage(watermill.NewUUID(), nil)
+
+		require.NoError(t, onSend(cqrs.CommandBusOnSendParams{CommandName: "RefundTicket", Message: msg}))
+
+		commandID, err := uuid.Parse(msg.Metadata.Get(commandIDMetadataKey))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"accepted"}, tracker.get(commandID))
+	})
+}
+
+func TestStatusTrackingMiddleware(t *testing.T) {
+	tracker := newTrackerStub()
+	pubSub := gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+
+	router, err := message.NewRouter(message.RouterConfig{}, watermill.NopLogger{})
+	require.NoError(t, err)
+	router.AddMiddleware(StatusTrackingMiddleware(tracker))
+
+	handled := make(chan *message.Message, 10)
+	var mu sync.Mutex
+	failuresLeft := map[string]int{}
+
+	handler := func(msg *message.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failuresLeft[msg.UUID] > 0 {
+			failuresLeft[msg.UUID]--
+			return errors.New("payment provider unavailable")
+		}
+
+		handled <- msg
+		return nil
+	}
+	router.AddNoPublisherHandler("refund_tickets", "commands.RefundTicket", pubSub, handler)
+	router.AddNoPublisherHandler("events_handler", "events.TicketRefunded_v1", pubSub, handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = router.Run(ctx)
+	}()
+	<-router.Running()
+
+	publish := func(topic string, commandID string, failures int) {
+		msg := message.NewMessage(watermill.NewUUID(), []byte("{}"))
+		if commandID != "" {
+			msg.Metadata.Set(commandIDMetadataKey, commandID)
+		}
+
+		mu.Lock()
+		failuresLeft[msg.UUID] = failures
+		mu.Unlock()
+
+		require.NoError(t, pubSub.Publish(topic, msg))
+
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("message was not handled")
+		}
+	}
+
+	t.Run("succeeded", func(t *testing.T) {
+		commandID := uuid.New()
+		publish("commands.RefundTicket", commandID.String(), 0)
+
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"processing", "succeeded"}, tracker.get(commandID))
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("failed and re-delivered", func(t *testing.T) {
+		commandID := uuid.New()
+		publish("commands.RefundTicket", commandID.String(), 1)
+
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"processing", "failed", "processing", "succeeded"}, tracker.get(commandID))
+		}, time.Second, 10*time.Millisecond)
+
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		assert.Equal(t, "payment provider unavailable", tracker.failures[commandID])
+	})
+
+	t.Run("sent before the tracking was introduced", func(t *testing.T) {
+		tracker.mu.Lock()
+		tracked := len(tracker.statuses)
+		tracker.mu.Unlock()
+
+		publish("commands.RefundTicket", "", 0)
+
+		tracker.mu.Lock()
+		defer tracker.mu.Unlock()
+		assert.Len(t, tracker.statuses, tracked)
+	})
+
+	t.Run("not a command", func(t *testing.T) {
+		commandID := uuid.New()
+		publish("events.TicketRefunded_v1", commandID.String(), 0)
+
+		assert.Empty(t, tracker.get(commandID))
+	})
+}
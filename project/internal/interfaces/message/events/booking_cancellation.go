@@ -0,0 +1,236 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+	"tickets/internal/repository"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+)
+
+type BookingCancellationRepository interface {
+	Get(ctx context.Context, bookingID uuid.UUID) (entities.BookingCancellation, error)
+	GetByTicketID(ctx context.Context, ticketID string) (entities.BookingCancellation, error)
+
+	Update(
+		ctx context.Context,
+		bookingID uuid.UUID,
+		updateFn func(cancellation entities.BookingCancellation) (entities.BookingCancellation, error),
+	) (entities.BookingCancellation, error)
+}
+
+// RefundRequester evaluates the refund policy and sends RefundTicket when the refund is allowed.
+type RefundRequester interface {
+	RequestRefund(ctx context.Context, req entities.RefundRequest) (entities.RefundDecision, error)
+}
+
+type BookingSeatsReleaser interface {
+	ReleaseTickets(ctx context.Context, bookingID uuid.UUID, numberOfTickets int) error
+}
+
+// BookingCancellationProcessManager refunds all tickets of a cancelled booking.
+// When every ticket is refunded (or its refund was denied by the refund policy),
+// it gives the seats back to the show and emits BookingCancelled_v1.
+type BookingCancellationProcessManager struct {
+	refundRequester RefundRequester
+	repository      BookingCancellationRepository
+	bookingsRepo    BookingSeatsReleaser
+	trManager       *trmanager.Manager
+	outboxBus       *OutboxBusFactory
+}
+
+func NewBookingCancellationProcessManager(
+	refundRequester RefundRequester,
+	repository BookingCancellationRepository,
+	bookingsRepo BookingSeatsReleaser,
+	trManager *trmanager.Manager,
+	outboxBus *OutboxBusFactory,
+) *BookingCancellationProcessManager {
+	return &BookingCancellationProcessManager{
+		refundRequester: refundRequester,
+		repository:      repository,
+		bookingsRepo:    bookingsRepo,
+		trManager:       trManager,
+		outboxBus:       outboxBus,
+	}
+}
+
+func (p BookingCancellationProcessManager) OnBookingCancellationInitialized(
+	ctx context.Context,
+	event *entities.BookingCancellationInitialized_v1,
+) error {
+	cancellation, err := p.repository.Get(ctx, event.BookingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookingCancellationNotFound) {
+			return nil
+		}
+		return fmt.Errorf("OnBookingCancellationInitialized: get booking cancellation: %w", err)
+	}
+
+	if cancellation.IsFinalized {
+		return nil
+	}
+
+	if cancellation.AllTicketsRefunded() {
+		// all tickets were refunded before, but finalizing didn't happen yet
+		return p.finalize(ctx, cancellation.BookingID)
+	}
+
+	pendingTicketIDs := cancellation.PendingTicketIDs()
+	deniedTicketIDs := make(map[string]bool)
+	for _, ticketID := range pendingTicketIDs {
+		// evaluated for the time of the cancellation, so retries get the same decision
+		_, err := p.refundRequester.RequestRefund(ctx, entities.RefundRequest{
+			TicketID:       ticketID,
+			Initiator:      cancellation.RefundInitiator(),
+			RequestedAt:    cancellation.RequestedAt,
+			IdempotencyKey: cancellation.RefundIdempotencyKey(ticketID),
+		})
+		if errors.Is(err, entities.ErrRefundDenied) {
+			deniedTicketIDs[ticketID] = true
+			continue
+		}
+		if errors.Is(err, entities.ErrRefundInProgress) || errors.Is(err, entities.ErrTicketAlreadyRefunded) {
+			// refund requested on its own, its TicketRefunded_v1 marks the ticket as refunded
+			continue
+		}
+		if errors.Is(err, entities.ErrTicketNotRefundable) {
+			// e.g. cancelled by the provider after the booking cancellation was initialized
+			deniedTicketIDs[ticketID] = true
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("OnBookingCancellationInitialized: request refund: %w", err)
+		}
+	}
+
+	log.FromContext(ctx).Info(
+		"Refunds requested for booking ", cancellation.BookingID,
+		", tickets: ", len(pendingTicketIDs)-len(deniedTicketIDs),
+		", denied: ", len(deniedTicketIDs),
+	)
+
+	err = p.trManager.Do(ctx, func(ctx context.Context) error {
+		cancellation, err = p.repository.Update(ctx, cancellation.BookingID, func(c entities.BookingCancellation) (entities.BookingCancellation, error) {
+			now := time.Now().UTC()
+			for _, ticketID := range pendingTicketIDs {
+				ticket := c.Tickets[ticketID]
+				if ticket.Status != entities.BookingCancellationTicketPending {
+					continue
+				}
+				if deniedTicketIDs[ticketID] {
+					ticket.Status = entities.BookingCancellationTicketRefundDenied
+				} else {
+					ticket.RefundRequestedAt = &now
+				}
+				c.Tickets[ticketID] = ticket
+			}
+			return c, nil
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("OnBookingCancellationInitialized: update booking cancellation: %w", err)
+	}
+
+	if len(deniedTicketIDs) > 0 && cancellation.AllTicketsRefunded() {
+		// no TicketRefunded_v1 is coming for the last tickets
+		return p.finalize(ctx, cancellation.BookingID)
+	}
+
+	return nil
+}
+
+func (p BookingCancellationProcessManager) OnTicketRefunded(ctx context.Context, event *entities.TicketRefunded_v1) error {
+	cancellation, err := p.repository.GetByTicketID(ctx, event.TicketID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookingCancellationNotFound) {
+			// refund of a single ticket, not a part of booking cancellation
+			return nil
+		}
+		return fmt.Errorf("OnTicketRefunded: get booking cancellation: %w", err)
+	}
+
+	return p.trManager.Do(ctx, func(ctx context.Context) error {
+		cancellation, err = p.repository.Update(ctx, cancellation.BookingID, func(c entities.BookingCancellation) (entities.BookingCancellation, error) {
+			ticket := c.Tickets[event.TicketID]
+			if ticket.Status == entities.BookingCancellationTicketRefunded {
+				// re-delivery
+				return c, nil
+			}
+
+			refundedAt := event.Header.PublishedAt
+			ticket.Status = entities.BookingCancellationTicketRefunded
+			ticket.RefundedAt = &refundedAt
+			ticket.RefundedAmount = event.RefundedAmount
+			c.Tickets[event.TicketID] = ticket
+
+			return c, nil
+		})
+		if err != nil {
+			return fmt.Errorf("OnTicketRefunded: update booking cancellation: %w", err)
+		}
+
+		if cancellation.IsFinalized || !cancellation.AllTicketsRefunded() {
+			return nil
+		}
+
+		return p.finalize(ctx, cancellation.BookingID)
+	})
+}
+
+func (p BookingCancellationProcessManager) finalize(ctx context.Context, bookingID uuid.UUID) error {
+	return p.trManager.Do(ctx, func(ctx context.Context) error {
+		var alreadyFinalized bool
+
+		cancellation, err := p.repository.Update(ctx, bookingID, func(c entities.BookingCancellation) (entities.BookingCancellation, error) {
+			if c.IsFinalized {
+				alreadyFinalized = true
+				return c, nil
+			}
+
+			cancelledAt := time.Now().UTC()
+			c.CancelledAt = &cancelledAt
+			c.IsFinalized = true
+
+			return c, nil
+		})
+		if err != nil {
+			return fmt.Errorf("finalize: update booking cancellation: %w", err)
+		}
+
+		if alreadyFinalized {
+			return nil
+		}
+
+		err = p.bookingsRepo.ReleaseTickets(ctx, bookingID, cancellation.NumberOfTickets)
+		if err != nil {
+			return fmt.Errorf("finalize: release tickets: %w", err)
+		}
+
+		eb, err := p.outboxBus.EventBus(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = eb.Publish(ctx, entities.BookingCancelled_v1{
+			Header:          entities.NewEventHeader(),
+			BookingID:       cancellation.BookingID,
+			ShowID:          cancellation.ShowID,
+			NumberOfTickets: cancellation.NumberOfTickets,
+			CustomerEmail:   cancellation.CustomerEmail,
+			Reason:          cancellation.Reason,
+			CancelledAt:     *cancellation.CancelledAt,
+		})
+		if err != nil {
+			return fmt.Errorf("finalize: publish booking cancelled event: %w", err)
+		}
+
+		return nil
+	})
+}
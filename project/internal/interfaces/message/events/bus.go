@@ -1,17 +1,51 @@
 package events
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"tickets/internal/entities"
+	"tickets/internal/schema"
 )
 
+// BusConfig configures how the event bus publishes events.
+type BusConfig struct {
+	// Partitioning must be the same as the partitioning of the event processor.
+	Partitioning Partitioning
+	// Encodings select JSON or protobuf payloads by event name, all consumers read both.
+	Encodings schema.Encodings
+	// CloudEvents binds the published events to CloudEvents, it's disabled when its Mode is empty.
+	CloudEvents CloudEventsConfig
+}
+
+func (c BusConfig) marshaler() (cqrs.CommandEventMarshaler, error) {
+	marshaler, err := schema.NewMarshaler(c.Encodings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create marshaler: %w", err)
+	}
+	if c.CloudEvents.Mode == "" {
+		return marshaler, nil
+	}
+
+	return cloudEventsMarshaler{Marshaler: marshaler, config: c.CloudEvents}, nil
+}
+
+// NewEventBus publishes events from the partitioning to their partitions,
+// the event processor must be configured with the same partitioning.
 func NewEventBus(
 	pub message.Publisher,
 	logger watermill.LoggerAdapter,
+	config BusConfig,
 ) (*cqrs.EventBus, error) {
+	partitioning := config.Partitioning
+
+	marshaler, err := config.marshaler()
+	if err != nil {
+		return nil, err
+	}
+
 	return cqrs.NewEventBusWithConfig(
 		pub,
 		cqrs.EventBusConfig{
@@ -23,17 +57,25 @@ func NewEventBus(
 
 				if event.IsInternal() {
 					// Publish directly to the per-event topic
-					return "internal-events.svc-tickets." + params.EventName, nil
+					topic := "internal-events.svc-tickets." + params.EventName
+					if _, ok := partitioning[params.EventName]; !ok {
+						return topic, nil
+					}
+
+					payload, err := json.Marshal(params.Event)
+					if err != nil {
+						return "", fmt.Errorf("failed to marshal %s: %w", params.EventName, err)
+					}
+
+					return partitioning.Topic(topic, params.EventName, payload)
 				} else {
 					// Publish to the "events" topic, so it will be stored to the data lake and forwarded to the
-					// per-event topic
+					// per-event topic, partitioned by events_splitter
 					return "events", nil
 				}
 			},
-			Marshaler: cqrs.JSONMarshaler{
-				GenerateName: cqrs.StructName,
-			},
-			Logger: logger,
+			Marshaler: marshaler,
+			Logger:    logger,
 		},
 	)
 }
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"tickets/internal/entities"
+	"tickets/internal/schema"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// CloudEventsConfig publishes the events as CloudEvents 1.0, so tooling of other teams can consume them.
+// The id and time are from the EventHeader, the type is the event name.
+type CloudEventsConfig struct {
+	// Mode is empty when the events are published without the CloudEvents attributes.
+	Mode schema.CloudEventsMode
+	// Source identifies the service in all events, for example "/svc-tickets".
+	Source string
+	// SubjectKeys are top-level JSON fields of the events, for example "booking_id",
+	// the first one the event has is its subject.
+	SubjectKeys []string
+}
+
+func (c CloudEventsConfig) Validate() error {
+	if err := c.Mode.Validate(); err != nil {
+		return err
+	}
+	if c.Mode != "" && c.Source == "" {
+		return fmt.Errorf("missing CloudEvents source")
+	}
+
+	return nil
+}
+
+// cloudEventsMarshaler binds the marshaled events to CloudEvents,
+// consumers read them with schema.Marshaler.
+type cloudEventsMarshaler struct {
+	schema.Marshaler
+	config CloudEventsConfig
+}
+
+func (m cloudEventsMarshaler) Marshal(v any) (*message.Message, error) {
+	msg, err := m.Marshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes, err := m.attributes(v)
+	if err != nil {
+		return nil, err
+	}
+	if attributes.ID == "" {
+		attributes.ID = msg.UUID
+	}
+
+	if err := schema.ToCloudEvent(msg, m.config.Mode, attributes); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (m cloudEventsMarshaler) attributes(v any) (schema.CloudEventAttributes, error) {
+	name := m.Name(v)
+
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return schema.CloudEventAttributes{}, fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return schema.CloudEventAttributes{}, fmt.Errorf("failed to unmarshal %s to get the CloudEvents attributes: %w", name, err)
+	}
+
+	var header entities.EventHeader
+	if raw, ok := fields["header"]; ok {
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return schema.CloudEventAttributes{}, fmt.Errorf("failed to get header of %s: %w", name, err)
+		}
+	}
+
+	attributes := schema.CloudEventAttributes{
+		ID:     header.Id,
+		Source: m.config.Source,
+		Type:   name,
+		Time:   header.PublishedAt,
+	}
+
+	for _, key := range m.config.SubjectKeys {
+		// subjects are IDs, fields of other types are skipped
+		var subject string
+		if err := json.Unmarshal(fields[key], &subject); err == nil && subject != "" {
+			attributes.Subject = subject
+			break
+		}
+	}
+
+	return attributes, nil
+}
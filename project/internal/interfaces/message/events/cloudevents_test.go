@@ -0,0 +1,106 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"tickets/internal/entities"
+	"tickets/internal/schema"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudEvents(t *testing.T) {
+	event := entities.BookingMade_v1{
+		Header:          entities.NewEventHeader(),
+		NumberOfTickets: 2,
+		BookingID:       uuid.New(),
+		CustomerEmail:   "customer@example.com",
+		ShowID:          uuid.New(),
+	}
+	event.Header.PublishedAt = time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	marshaler, err := receivingMarshaler()
+	require.NoError(t, err)
+
+	cloudEvents := CloudEventsConfig{
+		Source:      "/svc-tickets",
+		SubjectKeys: []string{"vip_bundle_id", "booking_id"},
+	}
+
+	t.Run("binary", func(t *testing.T) {
+		cloudEvents := cloudEvents
+		cloudEvents.Mode = schema.CloudEventsBinary
+
+		busMarshaler, err := BusConfig{CloudEvents: cloudEvents}.marshaler()
+		require.NoError(t, err)
+
+		msg, err := busMarshaler.Marshal(event)
+		require.NoError(t, err)
+
+		assert.Equal(t, "1.0", msg.Metadata.Get("ce_specversion"))
+		assert.Equal(t, event.Header.Id, msg.Metadata.Get("ce_id"))
+		assert.Equal(t, "/svc-tickets", msg.Metadata.Get("ce_source"))
+		assert.Equal(t, "BookingMade_v1", msg.Metadata.Get("ce_type"))
+		assert.Equal(t, event.BookingID.String(), msg.Metadata.Get("ce_subject"))
+		assert.Equal(t, "2024-06-01T12:30:00Z", msg.Metadata.Get("ce_time"))
+		assert.Equal(t, schema.ContentTypeJSON, msg.Metadata.Get(schema.ContentTypeMetadataKey))
+
+		var received entities.BookingMade_v1
+		require.NoError(t, marshaler.Unmarshal(msg, &received))
+		assert.Equal(t, event, received)
+	})
+
+	for name, encoding := range map[string]schema.Encoding{
+		"structured json":     schema.EncodingJSON,
+		"structured protobuf": schema.EncodingProtobuf,
+	} {
+		t.Run(name, func(t *testing.T) {
+			cloudEvents := cloudEvents
+			cloudEvents.Mode = schema.CloudEventsStructured
+
+			config := BusConfig{
+				Encodings:   schema.Encodings{Default: encoding},
+				CloudEvents: cloudEvents,
+			}
+			busMarshaler, err := config.marshaler()
+			require.NoError(t, err)
+
+			msg, err := busMarshaler.Marshal(event)
+			require.NoError(t, err)
+			assert.Equal(t, schema.ContentTypeCloudEvents, msg.Metadata.Get(schema.ContentTypeMetadataKey))
+
+			var envelope map[string]any
+			require.NoError(t, json.Unmarshal(msg.Payload, &envelope))
+			assert.Equal(t, "1.0", envelope["specversion"])
+			assert.Equal(t, event.Header.Id, envelope["id"])
+			assert.Equal(t, "/svc-tickets", envelope["source"])
+			assert.Equal(t, "BookingMade_v1", envelope["type"])
+			assert.Equal(t, event.BookingID.String(), envelope["subject"])
+			assert.Equal(t, "2024-06-01T12:30:00Z", envelope["time"])
+
+			var received entities.BookingMade_v1
+			require.NoError(t, marshaler.Unmarshal(msg, &received))
+			assert.Equal(t, event, received)
+
+			expectedPayload, err := json.Marshal(event)
+			require.NoError(t, err)
+			payload, err := marshaler.JSONPayload(msg)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expectedPayload), string(payload))
+
+			publicMsg, ok, err := ToPublicMessage(msg, "BookingMade_v1")
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, schema.ContentTypeJSON, publicMsg.Metadata.Get(schema.ContentTypeMetadataKey))
+		})
+	}
+
+	t.Run("invalid config", func(t *testing.T) {
+		assert.NoError(t, CloudEventsConfig{}.Validate())
+		assert.Error(t, CloudEventsConfig{Mode: "xml"}.Validate())
+		assert.Error(t, CloudEventsConfig{Mode: schema.CloudEventsBinary}.Validate())
+	})
+}
@@ -7,17 +7,34 @@ import (
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/redis/go-redis/v9"
+	"sync"
 	"tickets/internal/entities"
+	"tickets/internal/schema"
 )
 
-var marshaler = cqrs.JSONMarshaler{
+// receivingMarshaler reads JSON and protobuf payloads, it validates them with the schemas from internal/schema.
+var receivingMarshaler = sync.OnceValues(func() (schema.Marshaler, error) {
+	return schema.NewMarshaler(schema.Encodings{})
+})
+
+// publicMarshaler marshals the public contracts, which have the same names as the internal events
+// but not their schemas.
+var publicMarshaler = cqrs.JSONMarshaler{
 	GenerateName: cqrs.StructName,
 }
 
+// NewEventProcessorConfig subscribes to all partitions of the events from the partitioning,
+// it must be the same as the partitioning of the event bus.
 func NewEventProcessorConfig(
 	redisClient *redis.Client,
 	watemillLogger watermill.LoggerAdapter,
-) cqrs.EventProcessorConfig {
+	partitioning Partitioning,
+) (cqrs.EventProcessorConfig, error) {
+	marshaler, err := receivingMarshaler()
+	if err != nil {
+		return cqrs.EventProcessorConfig{}, fmt.Errorf("failed to create marshaler: %w", err)
+	}
+
 	return cqrs.EventProcessorConfig{
 		GenerateSubscribeTopic: func(params cqrs.EventProcessorGenerateSubscribeTopicParams) (string, error) {
 			handlerEvent := params.EventHandler.NewEvent()
@@ -36,6 +53,14 @@ func NewEventProcessorConfig(
 			return fmt.Sprintf(prefix + params.EventName), nil
 		},
 		SubscriberConstructor: func(params cqrs.EventProcessorSubscriberConstructorParams) (message.Subscriber, error) {
+			eventName := marshaler.Name(params.EventHandler.NewEvent())
+			if config, ok := partitioning[eventName]; ok {
+				return NewPartitionedSubscriber(redisClient, PartitionedSubscriberConfig{
+					ConsumerGroup: "svc-tickets." + params.HandlerName,
+					Partitions:    config.Partitions,
+				}, watemillLogger)
+			}
+
 			return redisstream.NewSubscriber(redisstream.SubscriberConfig{
 				Client:        redisClient,
 				ConsumerGroup: "svc-tickets." + params.HandlerName,
@@ -43,5 +68,5 @@ func NewEventProcessorConfig(
 		},
 		Marshaler: marshaler,
 		Logger:    watemillLogger,
-	}
+	}, nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"tickets/internal/entities"
 	"tickets/internal/infrastructure/clients"
+	"tickets/internal/infrastructure/rendering"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/google/uuid"
@@ -32,7 +34,8 @@ type FileStorageService interface {
 //go:generate mockgen -destination=mocks/tickets_repository_mock.go -package=mocks . TicketsRepository
 type TicketsRepository interface {
 	Create(ctx context.Context, t *entities.Ticket) error
-	Delete(ctx context.Context, ticketID uuid.UUID) error
+	Get(ctx context.Context, ticketID uuid.UUID) (*entities.Ticket, error)
+	UpdateStatus(ctx context.Context, ticketID uuid.UUID, status entities.TicketStatus, changedAt time.Time) error
 }
 
 //go:generate mockgen -destination=mocks/shows_repository_mock.go -package=mocks . ShowsRepository
@@ -40,6 +43,20 @@ type ShowsRepository interface {
 	GetShow(ctx context.Context, id uuid.UUID) (*entities.Show, error)
 }
 
+//go:generate mockgen -destination=mocks/bookings_repository_mock.go -package=mocks . BookingsRepository
+type BookingsRepository interface {
+	GetBooking(ctx context.Context, bookingID uuid.UUID) (*entities.Booking, error)
+}
+
+//go:generate mockgen -destination=mocks/ticket_renderer_mock.go -package=mocks . TicketRenderer
+type TicketRenderer interface {
+	Render(ticket rendering.Ticket) ([]byte, error)
+}
+
+type TicketTokenSigner interface {
+	Sign(ticketID string, version int) string
+}
+
 type EventRepository interface {
 	SaveEvent(ctx context.Context, event entities.DatalakeEvent) error
 }
@@ -52,6 +69,9 @@ type Handler struct {
 	deadNationClient   DeadNationService
 	ticketsRepository  TicketsRepository
 	showsRepository    ShowsRepository
+	bookingsRepository BookingsRepository
+	ticketSigner       TicketTokenSigner
+	ticketRenderer     TicketRenderer
 }
 
 func NewHandler(
@@ -62,6 +82,9 @@ func NewHandler(
 	deadNationClient DeadNationService,
 	ticketsRepository TicketsRepository,
 	showsRepository ShowsRepository,
+	bookingsRepository BookingsRepository,
+	ticketSigner TicketTokenSigner,
+	ticketRenderer TicketRenderer,
 ) *Handler {
 	return &Handler{
 		eb:                 eb,
@@ -71,5 +94,8 @@ func NewHandler(
 		deadNationClient:   deadNationClient,
 		ticketsRepository:  ticketsRepository,
 		showsRepository:    showsRepository,
+		bookingsRepository: bookingsRepository,
+		ticketSigner:       ticketSigner,
+		ticketRenderer:     ticketRenderer,
 	}
 }
@@ -0,0 +1,52 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: tickets/internal/interfaces/message/events (interfaces: BookingsRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	entities "tickets/internal/entities"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockBookingsRepository is a mock of BookingsRepository interface.
+type MockBookingsRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockBookingsRepositoryMockRecorder
+}
+
+// MockBookingsRepositoryMockRecorder is the mock recorder for MockBookingsRepository.
+type MockBookingsRepositoryMockRecorder struct {
+	mock *MockBookingsRepository
+}
+
+// NewMockBookingsRepository creates a new mock instance.
+func NewMockBookingsRepository(ctrl *gomock.Controller) *MockBookingsRepository {
+	mock := &MockBookingsRepository{ctrl: ctrl}
+	mock.recorder = &MockBookingsRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBookingsRepository) EXPECT() *MockBookingsRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetBooking mocks base method.
+func (m *MockBookingsRepository) GetBooking(arg0 context.Context, arg1 uuid.UUID) (*entities.Booking, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBooking", arg0, arg1)
+	ret0, _ := ret[0].(*entities.Booking)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBooking indicates an expected call of GetBooking.
+func (mr *MockBookingsRepositoryMockRecorder) GetBooking(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBooking", reflect.TypeOf((*MockBookingsRepository)(nil).GetBooking), arg0, arg1)
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: tickets/internal/interfaces/message/events (interfaces: TicketRenderer)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	rendering "tickets/internal/infrastructure/rendering"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTicketRenderer is a mock of TicketRenderer interface.
+type MockTicketRenderer struct {
+	ctrl     *gomock.Controller
+	recorder *MockTicketRendererMockRecorder
+}
+
+// MockTicketRendererMockRecorder is the mock recorder for MockTicketRenderer.
+type MockTicketRendererMockRecorder struct {
+	mock *MockTicketRenderer
+}
+
+// NewMockTicketRenderer creates a new mock instance.
+func NewMockTicketRenderer(ctrl *gomock.Controller) *MockTicketRenderer {
+	mock := &MockTicketRenderer{ctrl: ctrl}
+	mock.recorder = &MockTicketRendererMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTicketRenderer) EXPECT() *MockTicketRendererMockRecorder {
+	return m.recorder
+}
+
+// Render mocks base method.
+func (m *MockTicketRenderer) Render(arg0 rendering.Ticket) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Render", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Render indicates an expected call of Render.
+func (mr *MockTicketRendererMockRecorder) Render(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*MockTicketRenderer)(nil).Render), arg0)
+}
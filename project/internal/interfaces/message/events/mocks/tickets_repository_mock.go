@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: tickets/internal/interfaces/events (interfaces: TicketsRepository)
+// Source: tickets/internal/interfaces/message/events (interfaces: TicketsRepository)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 	entities "tickets/internal/entities"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
@@ -50,16 +51,31 @@ func (mr *MockTicketsRepositoryMockRecorder) Create(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTicketsRepository)(nil).Create), arg0, arg1)
 }
 
-// Delete mocks base method.
-func (m *MockTicketsRepository) Delete(arg0 context.Context, arg1 uuid.UUID) error {
+// Get mocks base method.
+func (m *MockTicketsRepository) Get(arg0 context.Context, arg1 uuid.UUID) (*entities.Ticket, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(*entities.Ticket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockTicketsRepositoryMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockTicketsRepository)(nil).Get), arg0, arg1)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockTicketsRepository) UpdateStatus(arg0 context.Context, arg1 uuid.UUID, arg2 entities.TicketStatus, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Delete indicates an expected call of Delete.
-func (mr *MockTicketsRepositoryMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockTicketsRepositoryMockRecorder) UpdateStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTicketsRepository)(nil).Delete), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockTicketsRepository)(nil).UpdateStatus), arg0, arg1, arg2, arg3)
 }
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"tickets/internal/interfaces/message/outbox"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+)
+
+// OutboxBusFactory creates event buses publishing to the outbox in the transaction from the context,
+// so the events are forwarded only when the transaction is committed.
+// It's created once with the config of the event bus and shared by all usecases publishing through the outbox.
+type OutboxBusFactory struct {
+	trGetter        *trmsqlx.CtxGetter
+	watermillLogger watermill.LoggerAdapter
+	config          BusConfig
+}
+
+func NewOutboxBusFactory(
+	trGetter *trmsqlx.CtxGetter,
+	watermillLogger watermill.LoggerAdapter,
+	config BusConfig,
+) *OutboxBusFactory {
+	return &OutboxBusFactory{
+		trGetter:        trGetter,
+		watermillLogger: watermillLogger,
+		config:          config,
+	}
+}
+
+// EventBus returns the event bus of the transaction from ctx, it fails when ctx has no transaction.
+func (f *OutboxBusFactory) EventBus(ctx context.Context) (*cqrs.EventBus, error) {
+	tr := f.trGetter.DefaultTrOrDB(ctx, nil)
+	if tr == nil {
+		return nil, fmt.Errorf("failed to get transaction from context")
+	}
+
+	publisher, err := outbox.NewPublisher(tr, f.watermillLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event publisher: %w", err)
+	}
+
+	eb, err := NewEventBus(publisher, f.watermillLogger, f.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event bus: %w", err)
+	}
+
+	return eb, nil
+}
+
+// Publish publishes the event to the outbox in the transaction from ctx.
+func (f *OutboxBusFactory) Publish(ctx context.Context, event any) error {
+	eb, err := f.EventBus(ctx)
+	if err != nil {
+		return err
+	}
+
+	return eb.Publish(ctx, event)
+}
@@ -0,0 +1,288 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultPartitionLeaseTTL is how long a partition stays assigned to a consumer which stopped renewing its lease.
+	defaultPartitionLeaseTTL = 30 * time.Second
+	// drainCheckInterval is how often the unpartitioned topic is checked until it's drained.
+	drainCheckInterval = time.Second
+)
+
+var (
+	acquirePartitionLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0`)
+
+	releasePartitionLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// PartitionedSubscriberConfig configures the PartitionedSubscriber of one consumer group.
+type PartitionedSubscriberConfig struct {
+	ConsumerGroup string
+	Partitions    int
+	// LeaseTTL is how long a partition stays assigned to a consumer which stopped renewing its lease,
+	// 30 seconds by default. Messages left pending by the consumer are claimed after it too.
+	LeaseTTL time.Duration
+}
+
+// PartitionedSubscriber consumes all partitions of the topic.
+//
+// Consumers of the same consumer group take partitions with leases stored in Redis: a partition
+// is consumed only by the consumer holding its lease, so the messages of the partition are handled one by one.
+// Leases of consumers which are gone expire after the LeaseTTL and are taken by other consumers.
+//
+// The unpartitioned topic, to which the events were published before they were partitioned, is consumed
+// as one more partition. The partitions are consumed only after it's drained (all its messages were
+// delivered to the consumer group and acked), so the older events of a key are handled before the newer ones.
+type PartitionedSubscriber struct {
+	redisClient   *redis.Client
+	subscriber    *redisstream.Subscriber
+	consumerGroup string
+	consumer      string
+	partitions    int
+	leaseTTL      time.Duration
+	logger        watermill.LoggerAdapter
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewPartitionedSubscriber(
+	redisClient *redis.Client,
+	config PartitionedSubscriberConfig,
+	logger watermill.LoggerAdapter,
+) (*PartitionedSubscriber, error) {
+	if config.LeaseTTL == 0 {
+		config.LeaseTTL = defaultPartitionLeaseTTL
+	}
+
+	consumer := watermill.NewShortUUID()
+
+	subscriber, err := redisstream.NewSubscriber(redisstream.SubscriberConfig{
+		Client:        redisClient,
+		ConsumerGroup: config.ConsumerGroup,
+		Consumer:      consumer,
+		// messages left pending by the previous owner of the partition are older than its lease,
+		// they are claimed before reading new messages
+		MaxIdleTime: config.LeaseTTL,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PartitionedSubscriber{
+		redisClient:   redisClient,
+		subscriber:    subscriber,
+		consumerGroup: config.ConsumerGroup,
+		consumer:      consumer,
+		partitions:    config.Partitions,
+		leaseTTL:      config.LeaseTTL,
+		logger:        logger,
+		closing:       make(chan struct{}),
+	}, nil
+}
+
+func (s *PartitionedSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	output := make(chan *message.Message)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go s.consumePartition(ctx, topic, output, wg)
+	go func() {
+		defer wg.Done()
+
+		if !s.waitUntilDrained(ctx, topic) {
+			return
+		}
+
+		for i := 0; i < s.partitions; i++ {
+			wg.Add(1)
+			go s.consumePartition(ctx, PartitionTopic(topic, i), output, wg)
+		}
+	}()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		wg.Wait()
+		close(output)
+	}()
+
+	return output, nil
+}
+
+// consumePartition consumes the stream while holding its lease.
+func (s *PartitionedSubscriber) consumePartition(ctx context.Context, stream string, output chan<- *message.Message, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	lease := fmt.Sprintf("partition_leases.%s.%s", s.consumerGroup, stream)
+	logFields := watermill.LogFields{"stream": stream, "consumer_group": s.consumerGroup, "consumer": s.consumer}
+
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	defer ticker.Stop()
+
+	var stopConsuming context.CancelFunc
+	consumingWg := &sync.WaitGroup{}
+	defer func() {
+		if stopConsuming != nil {
+			stopConsuming()
+		}
+		consumingWg.Wait()
+
+		// let other consumers take the partition right away, ctx is already cancelled here
+		err := releasePartitionLease.Run(context.Background(), s.redisClient, []string{lease}, s.consumer).Err()
+		if err != nil {
+			s.logger.Error("Failed to release partition lease", err, logFields)
+		}
+	}()
+
+	for {
+		owned, err := acquirePartitionLease.Run(
+			ctx, s.redisClient, []string{lease}, s.consumer, s.leaseTTL.Milliseconds(),
+		).Bool()
+		if err != nil {
+			s.logger.Error("Failed to acquire partition lease", err, logFields)
+			// we can't tell if the lease is still ours
+			owned = false
+		}
+
+		if owned && stopConsuming == nil {
+			s.logger.Info("Partition assigned", logFields)
+
+			var partitionCtx context.Context
+			partitionCtx, stopConsuming = context.WithCancel(ctx)
+
+			messages, err := s.subscriber.Subscribe(partitionCtx, stream)
+			if err != nil {
+				s.logger.Error("Failed to subscribe to partition", err, logFields)
+				stopConsuming()
+				stopConsuming = nil
+			} else {
+				consumingWg.Add(1)
+				go func() {
+					defer consumingWg.Done()
+					forwardMessages(partitionCtx, messages, output)
+				}()
+			}
+		}
+
+		if !owned && stopConsuming != nil {
+			s.logger.Info("Partition lease lost", logFields)
+			stopConsuming()
+			stopConsuming = nil
+			consumingWg.Wait()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitUntilDrained waits until the unpartitioned topic is drained, it returns false when the subscriber is closed before.
+// Events published by the instances which don't partition them yet (during a rolling deployment)
+// are still consumed from it afterwards.
+func (s *PartitionedSubscriber) waitUntilDrained(ctx context.Context, topic string) bool {
+	logFields := watermill.LogFields{"topic": topic, "consumer_group": s.consumerGroup}
+
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+
+	logged := false
+	for {
+		drained, err := s.isDrained(ctx, topic)
+		if err != nil {
+			s.logger.Error("Failed to check if unpartitioned topic is drained", err, logFields)
+		}
+		if drained {
+			return true
+		}
+
+		if !logged {
+			s.logger.Info("Waiting for unpartitioned topic to drain before consuming partitions", logFields)
+			logged = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.closing:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// isDrained checks if all messages of the stream were delivered to the consumer group and acked.
+func (s *PartitionedSubscriber) isDrained(ctx context.Context, stream string) (bool, error) {
+	exists, err := s.redisClient.Exists(ctx, stream).Result()
+	if err != nil {
+		return false, err
+	}
+	if exists == 0 {
+		return true, nil
+	}
+
+	info, err := s.redisClient.XInfoStream(ctx, stream).Result()
+	if err != nil {
+		return false, err
+	}
+
+	groups, err := s.redisClient.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return false, err
+	}
+
+	for _, group := range groups {
+		if group.Name == s.consumerGroup {
+			return group.Pending == 0 && group.LastDeliveredID == info.LastGeneratedID, nil
+		}
+	}
+
+	// the group is created from the first message when the topic is consumed
+	return info.Length == 0, nil
+}
+
+func forwardMessages(ctx context.Context, messages <-chan *message.Message, output chan<- *message.Message) {
+	for msg := range messages {
+		select {
+		case output <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *PartitionedSubscriber) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closing)
+	})
+	s.wg.Wait()
+
+	return s.subscriber.Close()
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// EventPartitioning splits the topic of the event into partitions.
+// Events with the same key always go to the same partition, and each partition is handled
+// by one consumer at a time, so they are handled in the order they were published.
+type EventPartitioning struct {
+	// Key is the top-level JSON field of the event, for example "booking_id".
+	Key        string
+	Partitions int
+}
+
+// Partitioning by event name, events which are not in it use a single topic.
+type Partitioning map[string]EventPartitioning
+
+// PartitionTopic is the topic of the partition of the base topic.
+func PartitionTopic(topic string, partition int) string {
+	return fmt.Sprintf("%s.partition-%d", topic, partition)
+}
+
+// BaseTopic returns the topic of which the topic is a partition, or the topic itself when it's not a partition.
+func BaseTopic(topic string) string {
+	i := strings.LastIndex(topic, ".partition-")
+	if i < 0 {
+		return topic
+	}
+	if _, err := strconv.Atoi(topic[i+len(".partition-"):]); err != nil {
+		return topic
+	}
+
+	return topic[:i]
+}
+
+// Topic returns the topic to which the event with the payload is published.
+func (p Partitioning) Topic(topic string, eventName string, payload []byte) (string, error) {
+	config, ok := p[eventName]
+	if !ok {
+		return topic, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return "", fmt.Errorf("failed to unmarshal %s to get the partition key: %w", eventName, err)
+	}
+
+	// the key is a string in all events, like IDs
+	var key string
+	if err := json.Unmarshal(fields[config.Key], &key); err != nil {
+		return "", fmt.Errorf("failed to get partition key %s of %s: %w", config.Key, eventName, err)
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return PartitionTopic(topic, int(h.Sum32()%uint32(config.Partitions))), nil
+}
+
+func (p Partitioning) Validate() error {
+	for eventName, config := range p {
+		if config.Key == "" {
+			return fmt.Errorf("missing partition key of %s", eventName)
+		}
+		if config.Partitions < 1 {
+			return fmt.Errorf("%s must have at least one partition", eventName)
+		}
+	}
+
+	return nil
+}
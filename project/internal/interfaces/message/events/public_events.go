@@ -0,0 +1,190 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"tickets/contracts/public"
+	"tickets/internal/entities"
+	"tickets/internal/schema"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// publicEventTranslators map the internal events to their public contracts,
+// internal events without a translator are not published outside.
+var publicEventTranslators = map[string]func(payload []byte) (any, error){
+	"BookingMade_v1": translate(func(e entities.BookingMade_v1) any {
+		return public.BookingMade_v1{
+			Header:          publicHeader(e.Header),
+			BookingID:       e.BookingID.String(),
+			ShowID:          e.ShowID.String(),
+			NumberOfTickets: e.NumberOfTickets,
+			CustomerEmail:   e.CustomerEmail,
+			BookedAt:        e.BookedAt,
+		}
+	}),
+	"BookingCancelled_v1": translate(func(e entities.BookingCancelled_v1) any {
+		return public.BookingCancelled_v1{
+			Header:          publicHeader(e.Header),
+			BookingID:       e.BookingID.String(),
+			ShowID:          e.ShowID.String(),
+			NumberOfTickets: e.NumberOfTickets,
+			CustomerEmail:   e.CustomerEmail,
+			Reason:          e.Reason,
+			CancelledAt:     e.CancelledAt,
+		}
+	}),
+	"TicketBookingConfirmed_v1": translate(func(e entities.TicketBookingConfirmed_v1) any {
+		return public.TicketBookingConfirmed_v1{
+			Header:        publicHeader(e.Header),
+			TicketID:      e.TicketID,
+			BookingID:     e.BookingID,
+			CustomerEmail: e.CustomerEmail,
+			Price:         publicMoney(e.Price),
+		}
+	}),
+	"TicketBookingCanceled_v1": translate(func(e entities.TicketBookingCanceled_v1) any {
+		return public.TicketBookingCanceled_v1{
+			Header:        publicHeader(e.Header),
+			TicketID:      e.TicketId,
+			BookingID:     e.BookingId,
+			CustomerEmail: e.CustomerEmail,
+			Price:         publicMoney(e.Price),
+		}
+	}),
+	"TicketReceiptIssued_v1": translate(func(e entities.TicketReceiptIssued_v1) any {
+		return public.TicketReceiptIssued_v1{
+			Header:        publicHeader(e.Header),
+			TicketID:      e.TicketId,
+			BookingID:     e.BookingId,
+			ReceiptNumber: e.ReceiptNumber,
+			IssuedAt:      e.IssuedAt,
+		}
+	}),
+	"TicketPrinted_v1": translate(func(e entities.TicketPrinted_v1) any {
+		return public.TicketPrinted_v1{
+			Header:    publicHeader(e.Header),
+			TicketID:  e.TicketID,
+			BookingID: e.BookingID,
+			FileName:  e.FileName,
+			PrintedAt: e.PrintedAt,
+		}
+	}),
+	"TicketRefunded_v1": translate(func(e entities.TicketRefunded_v1) any {
+		event := public.TicketRefunded_v1{
+			Header:   publicHeader(e.Header),
+			TicketID: e.TicketID,
+		}
+		if e.RefundedAmount != nil {
+			amount := publicMoney(*e.RefundedAmount)
+			event.RefundedAmount = &amount
+		}
+		return event
+	}),
+	"TicketCheckedIn_v1": translate(func(e entities.TicketCheckedIn_v1) any {
+		return public.TicketCheckedIn_v1{
+			Header:      publicHeader(e.Header),
+			TicketID:    e.TicketID,
+			BookingID:   e.BookingID,
+			ShowID:      e.ShowID,
+			CheckedInAt: e.CheckedInAt,
+		}
+	}),
+	"TicketTransferred_v1": translate(func(e entities.TicketTransferred_v1) any {
+		return public.TicketTransferred_v1{
+			Header:        publicHeader(e.Header),
+			TransferID:    e.TransferID.String(),
+			TicketID:      e.TicketID,
+			BookingID:     e.BookingID,
+			ShowID:        e.ShowID,
+			FromEmail:     e.FromEmail,
+			ToEmail:       e.ToEmail,
+			TransferredAt: e.TransferredAt,
+		}
+	}),
+	"ShowCancelled_v1": translate(func(e entities.ShowCancelled_v1) any {
+		return public.ShowCancelled_v1{
+			Header:      publicHeader(e.Header),
+			ShowID:      e.ShowID.String(),
+			Reason:      e.Reason,
+			CancelledAt: e.CancelledAt,
+		}
+	}),
+}
+
+// ToPublicEvent returns the public contract of the internal event, or false when the event is not public.
+func ToPublicEvent(eventName string, payload []byte) (any, bool, error) {
+	translator, ok := publicEventTranslators[eventName]
+	if !ok {
+		return nil, false, nil
+	}
+
+	event, err := translator(payload)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return event, true, nil
+}
+
+// ToPublicMessage translates the message of the internal event to the message of its public contract,
+// or returns false when the event is not public.
+// The public message keeps the UUID and metadata of the internal one, so redelivered events can be deduplicated.
+// Public contracts are always JSON, whatever the encoding of the internal event.
+func ToPublicMessage(msg *message.Message, eventName string) (*message.Message, bool, error) {
+	marshaler, err := receivingMarshaler()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create marshaler: %w", err)
+	}
+
+	payload, err := marshaler.JSONPayload(msg)
+	if err != nil {
+		return nil, false, err
+	}
+
+	event, ok, err := ToPublicEvent(eventName, payload)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	publicMsg, err := publicMarshaler.Marshal(event)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal public %s: %w", eventName, err)
+	}
+	publicMsg.Metadata.Set(schema.ContentTypeMetadataKey, schema.ContentTypeJSON)
+
+	for key, value := range msg.Metadata {
+		if _, ok := publicMsg.Metadata[key]; !ok {
+			publicMsg.Metadata.Set(key, value)
+		}
+	}
+	publicMsg.UUID = msg.UUID
+	publicMsg.SetContext(msg.Context())
+
+	return publicMsg, true, nil
+}
+
+func translate[T any](toPublic func(event T) any) func(payload []byte) (any, error) {
+	return func(payload []byte) (any, error) {
+		var event T
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrJsonUnmarshal, err)
+		}
+
+		return toPublic(event), nil
+	}
+}
+
+func publicHeader(header entities.EventHeader) public.Header {
+	return public.Header{
+		EventID:     header.Id,
+		PublishedAt: header.PublishedAt,
+	}
+}
+
+func publicMoney(m entities.Money) public.Money {
+	return public.Money{
+		Amount:   m.AmountString(),
+		Currency: m.Currency,
+	}
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"tickets/contracts/public"
+	"tickets/internal/entities"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicEventTranslators(t *testing.T) {
+	publicNames := map[string]struct{}{}
+	for _, event := range public.Events {
+		publicNames[reflect.TypeOf(event).Name()] = struct{}{}
+	}
+
+	for eventName, translator := range publicEventTranslators {
+		_, ok := publicNames[eventName]
+		assert.True(t, ok, "%s is not in public.Events", eventName)
+
+		// the public event keeps the name of the internal one, so the public topic matches it
+		event, err := translator([]byte(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, eventName, reflect.TypeOf(event).Name())
+	}
+
+	for name := range publicNames {
+		_, ok := publicEventTranslators[name]
+		assert.True(t, ok, "public %s has no translator", name)
+	}
+}
+
+func TestToPublicMessage(t *testing.T) {
+	refundedAmount := entities.MustNewMoney("12.5", "EUR")
+	internal := entities.TicketRefunded_v1{
+		Header:         entities.NewEventHeader(),
+		TicketID:       "a0d1c5e4-6a5f-4a39-9e1b-0c4f2b5d7e81",
+		RefundedAmount: &refundedAmount,
+	}
+
+	marshaler, err := receivingMarshaler()
+	require.NoError(t, err)
+
+	msg, err := marshaler.Marshal(internal)
+	require.NoError(t, err)
+	msg.Metadata.Set("correlation_id", "test-correlation-id")
+
+	publicMsg, ok, err := ToPublicMessage(msg, "TicketRefunded_v1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	assert.Equal(t, msg.UUID, publicMsg.UUID)
+	assert.Equal(t, "test-correlation-id", publicMsg.Metadata.Get("correlation_id"))
+	assert.Equal(t, "TicketRefunded_v1", marshaler.NameFromMessage(publicMsg))
+
+	var event public.TicketRefunded_v1
+	require.NoError(t, json.Unmarshal(publicMsg.Payload, &event))
+	assert.Equal(t, public.TicketRefunded_v1{
+		Header: public.Header{
+			EventID:     internal.Header.Id,
+			PublishedAt: event.Header.PublishedAt,
+		},
+		TicketID:       internal.TicketID,
+		RefundedAmount: &public.Money{Amount: "12.50", Currency: "EUR"},
+	}, event)
+	assert.True(t, internal.Header.PublishedAt.Equal(event.Header.PublishedAt))
+
+	_, ok, err = ToPublicMessage(message.NewMessage("1", []byte(`{}`)), "BookingCancellationInitialized_v1")
+	require.NoError(t, err)
+	assert.False(t, ok, "internal-only events are not published")
+
+	_, _, err = ToPublicMessage(message.NewMessage("1", []byte(`not json`)), "TicketRefunded_v1")
+	assert.ErrorIs(t, err, ErrJsonUnmarshal)
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"tickets/internal/entities"
+)
+
+// NotifyAboutShowCancellationHandler appends the email for the customer to the customer-emails spreadsheet
+// once all tickets of their booking were refunded because the show was cancelled.
+func (h *Handler) NotifyAboutShowCancellationHandler() cqrs.EventHandler {
+	return cqrs.NewEventHandler(
+		"notify_about_show_cancellation_handler",
+		func(ctx context.Context, event *entities.BookingCancelled_v1) error {
+			show, err := h.showsRepository.GetShow(ctx, event.ShowID)
+			if err != nil {
+				return fmt.Errorf("failed to get show: %w", err)
+			}
+
+			if !show.IsCancelled() {
+				// booking cancelled by the customer
+				return nil
+			}
+
+			log.FromContext(ctx).Info("Notifying customer about show cancellation")
+
+			return h.spreadsheetsClient.AppendRow(
+				ctx,
+				entities.AppendToTrackerRequest{
+					SpreadsheetName: "customer-emails",
+					Rows: []string{
+						event.CustomerEmail,
+						fmt.Sprintf("%s has been cancelled", show.Title),
+						fmt.Sprintf(
+							"Unfortunately %s at %s on %s has been cancelled (%s). All %d tickets of your booking %s have been refunded.",
+							show.Title,
+							show.Venue,
+							show.StartTime.Format("2006-01-02 15:04 MST"),
+							event.Reason,
+							event.NumberOfTickets,
+							event.BookingID,
+						),
+					},
+				},
+			)
+		},
+	)
+}
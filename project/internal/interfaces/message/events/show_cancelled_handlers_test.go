@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tickets/internal/entities"
+	"tickets/internal/interfaces/message/events/mocks"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyAboutShowCancellationHandler(t *testing.T) {
+	cancelledAt := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	event := &entities.BookingCancelled_v1{
+		Header:          entities.NewEventHeader(),
+		BookingID:       uuid.New(),
+		ShowID:          uuid.New(),
+		NumberOfTickets: 2,
+		CustomerEmail:   "customer@example.com",
+		Reason:          "venue flooded",
+		CancelledAt:     cancelledAt,
+	}
+	show := entities.Show{
+		Id:              event.ShowID,
+		NumberOfTickets: 100,
+		StartTime:       time.Date(2024, 6, 10, 20, 0, 0, 0, time.UTC),
+		Title:           "Test Show",
+		Venue:           "Test Venue",
+	}
+
+	testCases := []struct {
+		name        string
+		cancelledAt *time.Time
+		getShowErr  error
+		expectRow   bool
+		expectErr   bool
+	}{
+		{
+			name:        "show cancelled",
+			cancelledAt: &cancelledAt,
+			expectRow:   true,
+		},
+		{
+			name: "booking cancelled by the customer",
+		},
+		{
+			name:       "show not found",
+			getShowErr: errors.New("not found"),
+			expectErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			showsRepo := mocks.NewMockShowsRepository(ctrl)
+			spreadsheets := mocks.NewMockSpreadsheetsService(ctrl)
+
+			show := show
+			show.CancelledAt = tc.cancelledAt
+			showsRepo.EXPECT().GetShow(gomock.Any(), event.ShowID).Return(&show, tc.getShowErr)
+
+			if tc.expectRow {
+				spreadsheets.EXPECT().
+					AppendRow(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, req entities.AppendToTrackerRequest) error {
+						assert.Equal(t, "customer-emails", req.SpreadsheetName)
+						require.Len(t, req.Rows, 3)
+						assert.Equal(t, event.CustomerEmail, req.Rows[0])
+						assert.Equal(t, "Test Show has been cancelled", req.Rows[1])
+						assert.Contains(t, req.Rows[2], "venue flooded")
+						assert.Contains(t, req.Rows[2], event.BookingID.String())
+						return nil
+					})
+			}
+
+			h := NewHandler(nil, spreadsheets, nil, nil, nil, nil, showsRepo, nil, nil, nil)
+			err := h.NotifyAboutShowCancellationHandler().Handle(context.Background(), event)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -15,9 +15,6 @@ func (h *Handler) RefundTicketHandler() cqrs.EventHandler {
 		func(ctx context.Context, payload *entities.TicketBookingCanceled_v1) error {
 			log.FromContext(ctx).Info("Refunding ticket")
 
-			if payload.Price.Currency == "" {
-				payload.Price.Currency = "USD"
-			}
 			return h.spreadsheetsClient.AppendRow(
 				ctx,
 				entities.AppendToTrackerRequest{
@@ -25,7 +22,7 @@ func (h *Handler) RefundTicketHandler() cqrs.EventHandler {
 					Rows: []string{
 						payload.TicketId,
 						payload.CustomerEmail,
-						payload.Price.Amount,
+						payload.Price.AmountString(),
 						payload.Price.Currency,
 					},
 				},
@@ -45,7 +42,7 @@ func (h *Handler) RemoveTicketsHandler() cqrs.EventHandler {
 				return fmt.Errorf("failed to parse ticket id: %w", err)
 			}
 
-			return h.ticketsRepository.Delete(ctx, id)
+			return h.ticketsRepository.UpdateStatus(ctx, id, entities.TicketStatusCancelled, payload.Header.PublishedAt)
 		},
 	)
 }
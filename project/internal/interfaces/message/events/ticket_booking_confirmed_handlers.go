@@ -2,12 +2,16 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"tickets/internal/entities"
+	"tickets/internal/infrastructure/rendering"
+	"tickets/internal/repository"
 	"time"
 
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/google/uuid"
 )
 
 func (h *Handler) TicketsToPrintHandler() cqrs.EventHandler {
@@ -16,16 +20,13 @@ func (h *Handler) TicketsToPrintHandler() cqrs.EventHandler {
 		func(ctx context.Context, payload *entities.TicketBookingConfirmed_v1) error {
 			log.FromContext(ctx).Info("Adding ticket to print")
 
-			if payload.Price.Currency == "" {
-				payload.Price.Currency = "USD"
-			}
 			return h.spreadsheetsClient.AppendRow(
 				ctx, entities.AppendToTrackerRequest{
 					SpreadsheetName: "tickets-to-print",
 					Rows: []string{
 						payload.TicketID,
 						payload.CustomerEmail,
-						payload.Price.Amount,
+						payload.Price.AmountString(),
 						payload.Price.Currency,
 					},
 				})
@@ -40,28 +41,15 @@ func (h *Handler) PrepareTicketsHandler() cqrs.EventHandler {
 			log.FromContext(ctx).Info("Preparing ticket. Generate ticket file HTML")
 			log.FromContext(ctx).Info("Ticket BookingID: ", payload.TicketID, " Booking BookingID: ", payload.BookingID)
 
-			if payload.Price.Currency == "" {
-				payload.Price.Currency = "USD"
-			}
 
-			fileID := fmt.Sprintf("%s-ticket.html", payload.TicketID)
-			content := []byte(fmt.Sprintf(`
-<!DOCTYPE html>
-	<html>
-		<head>
-			<title>Ticket</title>
-		</head>
-		<body>
-			<h1>Ticket</h1>
-			<p>Ticket BookingID: %s</p>
-			<p>Price: %s %s</p>
-		</body>	
-	</html>
-		`, payload.TicketID, payload.Price.Amount, payload.Price.Currency))
-
-			err := h.fileStorage.Upload(ctx, fileID, content)
+			fileID, err := h.printTicket(ctx, entities.Ticket{
+				TicketId:      payload.TicketID,
+				CustomerEmail: payload.CustomerEmail,
+				Price:         payload.Price,
+				BookingId:     payload.BookingID,
+			})
 			if err != nil {
-				return fmt.Errorf("failed to upload ticket: %w", err)
+				return err
 			}
 
 			log.FromContext(ctx).Info("Publishing TicketPrinted_v1 with ticket_id: ", payload.TicketID, " and booking_id: ", payload.BookingID)
@@ -78,15 +66,79 @@ func (h *Handler) PrepareTicketsHandler() cqrs.EventHandler {
 	)
 }
 
+// printTicket renders the ticket and uploads it, returning the name of the uploaded file.
+// Each token version gets its own file, so the file printed for the previous holder of a transferred ticket
+// is not overwritten but its check-in code no longer works.
+func (h *Handler) printTicket(ctx context.Context, ticket entities.Ticket) (string, error) {
+	printable, err := h.printableTicket(ctx, ticket)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := h.ticketRenderer.Render(printable)
+	if err != nil {
+		return "", fmt.Errorf("failed to render ticket: %w", err)
+	}
+
+	fileID := fmt.Sprintf("%s-ticket.html", ticket.TicketId)
+	if ticket.TokenVersion > 0 {
+		fileID = fmt.Sprintf("%s-ticket-v%d.html", ticket.TicketId, ticket.TokenVersion)
+	}
+
+	err = h.fileStorage.Upload(ctx, fileID, content)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload ticket: %w", err)
+	}
+
+	return fileID, nil
+}
+
+// printableTicket collects the show details of the ticket.
+// Tickets of bookings which are not known (e.g. booked outside of our system) are printed without them.
+func (h *Handler) printableTicket(ctx context.Context, t entities.Ticket) (rendering.Ticket, error) {
+	ticket := rendering.Ticket{
+		TicketID:      t.TicketId,
+		BookingID:     t.BookingId,
+		CustomerEmail: t.CustomerEmail,
+		Price:         t.Price,
+		CheckInCode:   h.ticketSigner.Sign(t.TicketId, t.TokenVersion),
+	}
+
+	bookingID, err := uuid.Parse(t.BookingId)
+	if err != nil {
+		log.FromContext(ctx).Warn("Ticket ", t.TicketId, " has invalid booking id, printing without show details")
+		return ticket, nil
+	}
+	booking, err := h.bookingsRepository.GetBooking(ctx, bookingID)
+	if errors.Is(err, repository.ErrBookingNotFound) {
+		log.FromContext(ctx).Warn("Booking ", bookingID, " not found, printing ticket without show details")
+		return ticket, nil
+	}
+	if err != nil {
+		return rendering.Ticket{}, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	show, err := h.showsRepository.GetShow(ctx, booking.ShowId)
+	if err != nil {
+		return rendering.Ticket{}, fmt.Errorf("failed to get show: %w", err)
+	}
+
+	ticket.Locale = booking.Locale
+	ticket.ShowID = show.Id
+	ticket.OrganizerID = show.DeadNationId
+	ticket.ShowTitle = show.Title
+	ticket.Venue = show.Venue
+	ticket.StartTime = show.StartTime
+
+	return ticket, nil
+}
+
 func (h *Handler) IssueReceiptHandler() cqrs.EventHandler {
 	return cqrs.NewEventHandler(
 		"issue_receipt_handler",
 		func(ctx context.Context, payload *entities.TicketBookingConfirmed_v1) error {
 			log.FromContext(ctx).Info("Issuing receipt with ticket_id: ", payload.TicketID)
 
-			if payload.Price.Currency == "" {
-				payload.Price.Currency = "USD"
-			}
 			resp, err := h.receiptsClient.IssueReceipt(
 				ctx,
 				entities.IssueReceiptRequest{
@@ -125,9 +177,10 @@ func (h *Handler) StoreTicketsHandler() cqrs.EventHandler {
 
 			return h.ticketsRepository.Create(ctx, &entities.Ticket{
 				TicketId:      payload.TicketID,
-				Status:        "confirmed",
+				Status:        string(entities.TicketStatusConfirmed),
 				CustomerEmail: payload.CustomerEmail,
 				Price:         payload.Price,
+				BookingId:     payload.BookingID,
 			})
 		},
 	)
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/google/uuid"
+	"tickets/internal/entities"
+	"time"
+)
+
+func (h *Handler) TicketPrintedStatusHandler() cqrs.EventHandler {
+	return cqrs.NewEventHandler(
+		"ticket_printed_status_handler",
+		func(ctx context.Context, payload *entities.TicketPrinted_v1) error {
+			log.FromContext(ctx).Info("Marking ticket as printed")
+
+			return h.updateTicketStatus(ctx, payload.TicketID, entities.TicketStatusPrinted, payload.PrintedAt)
+		},
+	)
+}
+
+func (h *Handler) TicketReceiptIssuedStatusHandler() cqrs.EventHandler {
+	return cqrs.NewEventHandler(
+		"ticket_receipt_issued_status_handler",
+		func(ctx context.Context, payload *entities.TicketReceiptIssued_v1) error {
+			log.FromContext(ctx).Info("Marking ticket receipt as issued")
+
+			return h.updateTicketStatus(ctx, payload.TicketId, entities.TicketStatusReceiptIssued, payload.IssuedAt)
+		},
+	)
+}
+
+func (h *Handler) TicketRefundedStatusHandler() cqrs.EventHandler {
+	return cqrs.NewEventHandler(
+		"ticket_refunded_status_handler",
+		func(ctx context.Context, payload *entities.TicketRefunded_v1) error {
+			log.FromContext(ctx).Info("Marking ticket as refunded")
+
+			return h.updateTicketStatus(ctx, payload.TicketID, entities.TicketStatusRefunded, payload.Header.PublishedAt)
+		},
+	)
+}
+
+// updateTicketStatus fails when the ticket is not stored yet,
+// so the event is retried after StoreTicketsHandler stores it.
+func (h *Handler) updateTicketStatus(ctx context.Context, ticketID string, status entities.TicketStatus, changedAt time.Time) error {
+	id, err := uuid.Parse(ticketID)
+	if err != nil {
+		return fmt.Errorf("failed to parse ticket id: %w", err)
+	}
+
+	err = h.ticketsRepository.UpdateStatus(ctx, id, status, changedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update ticket status to %s: %w", status, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) NotifyAboutTicketTransferRequestHandler() cqrs.EventHandler {
+	return cqrs.NewEventHandler(
+		"notify_about_ticket_transfer_request_handler",
+		func(ctx context.Context, payload *entities.TicketTransferRequested_v1) error {
+			log.FromContext(ctx).Info("Notifying ", payload.ToEmail, " about transfer of ticket ", payload.TicketID)
+
+			return h.spreadsheetsClient.AppendRow(
+				ctx, entities.AppendToTrackerRequest{
+					SpreadsheetName: "ticket-transfer-requests",
+					Rows: []string{
+						payload.TransferID.String(),
+						payload.TicketID,
+						payload.FromEmail,
+						payload.ToEmail,
+					},
+				})
+		},
+	)
+}
+
+// ReprintTransferredTicketHandler prints the ticket for its new holder.
+// The ticket is always printed in its current state, so re-delivered events don't print outdated tickets.
+func (h *Handler) ReprintTransferredTicketHandler() cqrs.EventHandler {
+	return cqrs.NewEventHandler(
+		"reprint_transferred_ticket_handler",
+		func(ctx context.Context, payload *entities.TicketTransferred_v1) error {
+			log.FromContext(ctx).Info("Reprinting transferred ticket ", payload.TicketID)
+
+			ticketID, err := uuid.Parse(payload.TicketID)
+			if err != nil {
+				return fmt.Errorf("invalid ticket id %s: %w", payload.TicketID, err)
+			}
+
+			ticket, err := h.ticketsRepository.Get(ctx, ticketID)
+			if err != nil {
+				return fmt.Errorf("failed to get ticket: %w", err)
+			}
+
+			fileID, err := h.printTicket(ctx, *ticket)
+			if err != nil {
+				return err
+			}
+
+			return h.eb.Publish(
+				ctx,
+				entities.TicketPrinted_v1{
+					Header:    entities.NewEventHeader(),
+					TicketID:  ticket.TicketId,
+					BookingID: ticket.BookingId,
+					FileName:  fileID,
+					PrintedAt: time.Now().UTC(),
+				})
+		},
+	)
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"tickets/internal/entities"
 	"tickets/internal/repository"
+	"time"
 
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 	"github.com/google/uuid"
 )
 
@@ -266,21 +268,30 @@ type VipBundleRepository interface {
 // 	return nil
 // }
 
+// VipBundleProcessManager books the show tickets, both flights and the taxi of a VIP bundle, one after another.
+// The bundle is updated in the same transaction in which the next command is sent (through the outbox),
+// so a command is never sent for a state that was not stored, and a stored state never misses its command.
 type VipBundleProcessManager struct {
-	commandBus CommandBus
-	eventBus   EventBus
-	repository VipBundleRepository
+	commandBus      CommandBus
+	eventBus        EventBus
+	repository      VipBundleRepository
+	refundRequester RefundRequester
+	trManager       *trmanager.Manager
 }
 
 func NewVipBundleProcessManager(
 	commandBus CommandBus,
 	eventBus EventBus,
 	repository VipBundleRepository,
+	refundRequester RefundRequester,
+	trManager *trmanager.Manager,
 ) *VipBundleProcessManager {
 	return &VipBundleProcessManager{
-		commandBus: commandBus,
-		eventBus:   eventBus,
-		repository: repository,
+		commandBus:      commandBus,
+		eventBus:        eventBus,
+		repository:      repository,
+		refundRequester: refundRequester,
+		trManager:       trManager,
 	}
 }
 
@@ -304,30 +315,32 @@ func (v VipBundleProcessManager) OnVipBundleInitialized(ctx context.Context, eve
 }
 
 func (v VipBundleProcessManager) OnBookingMade(ctx context.Context, event *entities.BookingMade_v1) error {
-	vpBundle, err := v.repository.UpdateByBookingID(ctx, event.BookingID, func(vipBundle entities.VipBundle) (entities.VipBundle, error) {
-		vipBundle.BookingMadeAt = &event.Header.PublishedAt
-		return vipBundle, nil
-	})
-	if err != nil {
-		// if errors.Is(err, repository.ErrVipBundleSkipped) {
-		// 	return nil
-		// }
-		return fmt.Errorf("OnBookingMade: update vip bundle: %w", err)
-	}
+	return v.trManager.Do(ctx, func(ctx context.Context) error {
+		vpBundle, err := v.repository.UpdateByBookingID(ctx, event.BookingID, func(vipBundle entities.VipBundle) (entities.VipBundle, error) {
+			vipBundle.BookingMadeAt = &event.Header.PublishedAt
+			return vipBundle, nil
+		})
+		if err != nil {
+			// if errors.Is(err, repository.ErrVipBundleSkipped) {
+			// 	return nil
+			// }
+			return fmt.Errorf("OnBookingMade: update vip bundle: %w", err)
+		}
 
-	// book inbound flight
-	err = v.commandBus.Send(ctx, entities.BookFlight{
-		CustomerEmail:  vpBundle.CustomerEmail,
-		FlightID:       vpBundle.InboundFlightID,
-		Passengers:     vpBundle.Passengers,
-		ReferenceID:    vpBundle.VipBundleID.String(),
-		IdempotencyKey: uuid.New().String(),
-	})
-	if err != nil {
-		return fmt.Errorf("OnBookingMade: sending book flight: %w", err)
-	}
+		// book inbound flight
+		err = v.commandBus.Send(ctx, entities.BookFlight{
+			CustomerEmail:  vpBundle.CustomerEmail,
+			FlightID:       vpBundle.InboundFlightID,
+			Passengers:     vpBundle.Passengers,
+			ReferenceID:    vpBundle.VipBundleID.String(),
+			IdempotencyKey: uuid.New().String(),
+		})
+		if err != nil {
+			return fmt.Errorf("OnBookingMade: sending book flight: %w", err)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func (v VipBundleProcessManager) OnTicketBookingConfirmed(ctx context.Context, event *entities.TicketBookingConfirmed_v1) error {
@@ -361,50 +374,52 @@ func (v VipBundleProcessManager) OnTicketBookingConfirmed(ctx context.Context, e
 }
 
 func (v VipBundleProcessManager) OnFlightBooked(ctx context.Context, event *entities.FlightBooked_v1) error {
-	vb, err := v.repository.UpdateByID(
-		ctx,
-		uuid.MustParse(event.ReferenceID),
-		func(vipBundle entities.VipBundle) (entities.VipBundle, error) {
-			if vipBundle.InboundFlightID == event.FlightID {
-				vipBundle.InboundFlightBookedAt = &event.Header.PublishedAt
-				vipBundle.InboundFlightTicketsIDs = event.TicketIDs
-			}
-			if vipBundle.ReturnFlightID == event.FlightID {
-				vipBundle.ReturnFlightBookedAt = &event.Header.PublishedAt
-				vipBundle.ReturnFlightTicketsIDs = event.TicketIDs
-			}
-
-			return vipBundle, nil
-		},
-	)
-	if err != nil {
-		return err
-	}
-
-	switch {
-	case vb.InboundFlightBookedAt != nil && vb.ReturnFlightBookedAt == nil:
-		return v.commandBus.Send(ctx, entities.BookFlight{
-			CustomerEmail:  vb.CustomerEmail,
-			FlightID:       vb.ReturnFlightID,
-			Passengers:     vb.Passengers,
-			ReferenceID:    vb.VipBundleID.String(),
-			IdempotencyKey: uuid.NewString(),
-		})
-	case vb.InboundFlightBookedAt != nil && vb.ReturnFlightBookedAt != nil:
-		return v.commandBus.Send(ctx, entities.BookTaxi{
-			CustomerEmail:      vb.CustomerEmail,
-			CustomerName:       vb.Passengers[0],
-			NumberOfPassengers: vb.NumberOfTickets,
-			ReferenceID:        vb.VipBundleID.String(),
-			IdempotencyKey:     uuid.NewString(),
-		})
-	default:
-		return fmt.Errorf(
-			"unsupported state: InboundFlightBookedAt: %v, ReturnFlightBookedAt: %v",
-			vb.InboundFlightBookedAt,
-			vb.ReturnFlightBookedAt,
+	return v.trManager.Do(ctx, func(ctx context.Context) error {
+		vb, err := v.repository.UpdateByID(
+			ctx,
+			uuid.MustParse(event.ReferenceID),
+			func(vipBundle entities.VipBundle) (entities.VipBundle, error) {
+				if vipBundle.InboundFlightID == event.FlightID {
+					vipBundle.InboundFlightBookedAt = &event.Header.PublishedAt
+					vipBundle.InboundFlightTicketsIDs = event.TicketIDs
+				}
+				if vipBundle.ReturnFlightID == event.FlightID {
+					vipBundle.ReturnFlightBookedAt = &event.Header.PublishedAt
+					vipBundle.ReturnFlightTicketsIDs = event.TicketIDs
+				}
+
+				return vipBundle, nil
+			},
 		)
-	}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case vb.InboundFlightBookedAt != nil && vb.ReturnFlightBookedAt == nil:
+			return v.commandBus.Send(ctx, entities.BookFlight{
+				CustomerEmail:  vb.CustomerEmail,
+				FlightID:       vb.ReturnFlightID,
+				Passengers:     vb.Passengers,
+				ReferenceID:    vb.VipBundleID.String(),
+				IdempotencyKey: uuid.NewString(),
+			})
+		case vb.InboundFlightBookedAt != nil && vb.ReturnFlightBookedAt != nil:
+			return v.commandBus.Send(ctx, entities.BookTaxi{
+				CustomerEmail:      vb.CustomerEmail,
+				CustomerName:       vb.Passengers[0],
+				NumberOfPassengers: vb.NumberOfTickets,
+				ReferenceID:        vb.VipBundleID.String(),
+				IdempotencyKey:     uuid.NewString(),
+			})
+		default:
+			return fmt.Errorf(
+				"unsupported state: InboundFlightBookedAt: %v, ReturnFlightBookedAt: %v",
+				vb.InboundFlightBookedAt,
+				vb.ReturnFlightBookedAt,
+			)
+		}
+	})
 }
 
 func (v VipBundleProcessManager) OnTaxiBooked(ctx context.Context, event *entities.TaxiBooked_v1) error {
@@ -501,46 +516,54 @@ func (v VipBundleProcessManager) rollback(ctx context.Context, vpBundle entities
 
 	log.FromContext(ctx).Info("Rollback: all tickets received")
 
-	for _, ticketID := range vpBundle.TicketIDs {
-		err := v.commandBus.Send(ctx, entities.RefundTicket{
-			Header:   entities.NewEventHeader(),
-			TicketID: ticketID.String(),
-		})
-		if err != nil {
-			return fmt.Errorf("rollback: sending refund ticket: %w", err)
+	return v.trManager.Do(ctx, func(ctx context.Context) error {
+		for _, ticketID := range vpBundle.TicketIDs {
+			_, err := v.refundRequester.RequestRefund(ctx, entities.RefundRequest{
+				TicketID:       ticketID.String(),
+				Initiator:      entities.RefundInitiatorSystem,
+				RequestedAt:    time.Now().UTC(),
+				IdempotencyKey: "vip-bundle-rollback-" + vpBundle.VipBundleID.String() + "-" + ticketID.String(),
+			})
+			if errors.Is(err, entities.ErrTicketNotRefundable) || errors.Is(err, entities.ErrRefundInProgress) {
+				// refunded on its own or cancelled, there is nothing to roll back
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("rollback: request refund: %w", err)
+			}
 		}
-	}
 
-	if vpBundle.InboundFlightTicketsIDs != nil {
-		// rollback inbound flight
-		err := v.commandBus.Send(ctx, entities.CancelFlightTickets{
-			FlightTicketIDs: vpBundle.InboundFlightTicketsIDs,
-		})
-		if err != nil {
-			return fmt.Errorf("rollback: sending cancel inbound flight tickets: %w", err)
+		if vpBundle.InboundFlightTicketsIDs != nil {
+			// rollback inbound flight
+			err := v.commandBus.Send(ctx, entities.CancelFlightTickets{
+				FlightTicketIDs: vpBundle.InboundFlightTicketsIDs,
+			})
+			if err != nil {
+				return fmt.Errorf("rollback: sending cancel inbound flight tickets: %w", err)
+			}
 		}
-	}
 
-	if vpBundle.ReturnFlightTicketsIDs != nil {
-		// rollback return flight
-		err := v.commandBus.Send(ctx, entities.CancelFlightTickets{
-			FlightTicketIDs: vpBundle.ReturnFlightTicketsIDs,
+		if vpBundle.ReturnFlightTicketsIDs != nil {
+			// rollback return flight
+			err := v.commandBus.Send(ctx, entities.CancelFlightTickets{
+				FlightTicketIDs: vpBundle.ReturnFlightTicketsIDs,
+			})
+			if err != nil {
+				return fmt.Errorf("rollback: sending cancel return flight tickets: %w", err)
+			}
+		}
+
+		_, err := v.repository.UpdateByBookingID(ctx, vpBundle.BookingID, func(vipBundle entities.VipBundle) (entities.VipBundle, error) {
+			vipBundle.Failed = true
+			vipBundle.IsFinalized = true
+			return vipBundle, nil
 		})
 		if err != nil {
-			return fmt.Errorf("rollback: sending cancel return flight tickets: %w", err)
+			// if errors.Is(err, repository.ErrVipBundleSkipped) {
+			// 	return nil
+			// }
+			return fmt.Errorf("rollback: update vip bundle: %w", err)
 		}
-	}
-
-	_, err := v.repository.UpdateByBookingID(ctx, vpBundle.BookingID, func(vipBundle entities.VipBundle) (entities.VipBundle, error) {
-		vipBundle.Failed = true
-		vipBundle.IsFinalized = true
-		return vipBundle, nil
+		return nil
 	})
-	if err != nil {
-		// if errors.Is(err, repository.ErrVipBundleSkipped) {
-		// 	return nil
-		// }
-		return fmt.Errorf("rollback: update vip bundle: %w", err)
-	}
-	return nil
 }
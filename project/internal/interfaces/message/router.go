@@ -1,12 +1,19 @@
 package message
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"tickets/contracts/public"
+	"tickets/internal/application/usecases/cancellation"
+	"tickets/internal/application/usecases/opsbookings"
+	"tickets/internal/application/usecases/webhooks"
 	"tickets/internal/entities"
 	"tickets/internal/interfaces/message/commands"
 	"tickets/internal/interfaces/message/events"
 	"tickets/internal/interfaces/message/outbox"
 	"tickets/internal/repository"
+	"tickets/internal/schema"
 	"time"
 
 	"github.com/ThreeDotsLabs/watermill"
@@ -16,6 +23,10 @@ import (
 	"github.com/google/uuid"
 )
 
+// PoisonQueueTopic has the messages which don't match their schemas,
+// the reason is in the reason_poisoned metadata.
+const PoisonQueueTopic = "svc-tickets.poison_queue"
+
 func NewRouter(
 	watermillLogger watermill.LoggerAdapter,
 	postgresSubscriber message.Subscriber,
@@ -25,13 +36,21 @@ func NewRouter(
 	eventHandler *events.Handler,
 	commandsHandler *commands.Handler,
 
-	marshaller cqrs.CommandEventMarshaler,
+	marshaller schema.Marshaler,
 	eventProcessorConfig cqrs.EventProcessorConfig,
 	commandProcessorConfig cqrs.CommandProcessorConfig,
 
 	eventsRepo events.EventRepository,
 	opsBookingReadModelRepo *repository.OpsBookingReadModelRepo,
+	opsBookingUpdates *opsbookings.UpdatesBroadcaster,
 	vipBundleProcessManager *events.VipBundleProcessManager,
+	bookingCancellationProcessManager *events.BookingCancellationProcessManager,
+	cancelShowUsecase *cancellation.CancelShowUsecase,
+	showAvailabilityReadModelRepo *repository.ShowAvailabilityReadModelRepo,
+	showAttendanceReadModelRepo *repository.ShowAttendanceReadModelRepo,
+	webhooksUsecase *webhooks.WebhooksUsecase,
+	commandTracker commands.CommandTracker,
+	eventPartitioning events.Partitioning,
 ) (*message.Router, error) {
 
 	router, err := message.NewRouter(message.RouterConfig{}, watermillLogger)
@@ -39,7 +58,10 @@ func NewRouter(
 		return nil, err
 	}
 
-	initMiddlewares(watermillLogger, router)
+	err = initMiddlewares(watermillLogger, router, redisPublisher, commandTracker)
+	if err != nil {
+		return nil, err
+	}
 
 	outbox.AddForwarderHandler(
 		postgresSubscriber,
@@ -64,6 +86,11 @@ func NewRouter(
 		eventHandler.RefundTicketHandler(),
 		eventHandler.RemoveTicketsHandler(),
 
+		// Ticket lifecycle handlers
+		eventHandler.TicketPrintedStatusHandler(),
+		eventHandler.TicketReceiptIssuedStatusHandler(),
+		eventHandler.TicketRefundedStatusHandler(),
+
 		// BookingMade handlers
 		eventHandler.TicketBookingHandler(),
 
@@ -101,6 +128,31 @@ func NewRouter(
 			vipBundleProcessManager.OnTaxiBookingFailed,
 		),
 
+		// Booking cancellation handlers
+		cqrs.NewEventHandler(
+			"booking_cancellation_process_manager.on_booking_cancellation_initialized",
+			bookingCancellationProcessManager.OnBookingCancellationInitialized,
+		),
+		cqrs.NewEventHandler(
+			"booking_cancellation_process_manager.on_ticket_refunded",
+			bookingCancellationProcessManager.OnTicketRefunded,
+		),
+
+		// Show cancellation handlers
+		cqrs.NewEventHandler(
+			"show_cancellation.on_show_cancelled",
+			cancelShowUsecase.OnShowCancelled,
+		),
+		cqrs.NewEventHandler(
+			"show_cancellation.on_ticket_booking_confirmed",
+			cancelShowUsecase.OnTicketBookingConfirmed,
+		),
+		eventHandler.NotifyAboutShowCancellationHandler(),
+
+		// Ticket transfer handlers
+		eventHandler.NotifyAboutTicketTransferRequestHandler(),
+		eventHandler.ReprintTransferredTicketHandler(),
+
 		// Read model handlers
 		cqrs.NewEventHandler(
 			"ops_booking_read_model.on_booking_made",
@@ -117,6 +169,21 @@ func NewRouter(
 		cqrs.NewEventHandler(
 			"ops_booking_read_model.on_ticket_removed",
 			opsBookingReadModelRepo.OnTicketRefundedEvent),
+		cqrs.NewEventHandler(
+			"ops_booking_read_model.on_ticket_transferred",
+			opsBookingReadModelRepo.OnTicketTransferredEvent),
+		cqrs.NewEventHandler(
+			"show_availability_read_model.on_booking_made",
+			showAvailabilityReadModelRepo.OnBookingMadeEvent),
+		cqrs.NewEventHandler(
+			"show_availability_read_model.on_booking_cancelled",
+			showAvailabilityReadModelRepo.OnBookingCancelledEvent),
+		cqrs.NewEventHandler(
+			"show_availability_read_model.on_show_cancelled",
+			showAvailabilityReadModelRepo.OnShowCancelledEvent),
+		cqrs.NewEventHandler(
+			"show_attendance_read_model.on_ticket_checked_in",
+			showAttendanceReadModelRepo.OnTicketCheckedInEvent),
 	)
 
 	commandsProcessor, err := cqrs.NewCommandProcessorWithConfig(router, commandProcessorConfig)
@@ -144,7 +211,80 @@ func NewRouter(
 				return fmt.Errorf("cannot get event name from message")
 			}
 
-			return redisPublisher.Publish("events."+eventName, msg)
+			// the partition keys are read from the JSON payload
+			payload, err := marshaller.JSONPayload(msg)
+			if err != nil {
+				return err
+			}
+
+			topic, err := eventPartitioning.Topic("events."+eventName, eventName, payload)
+			if err != nil {
+				return err
+			}
+
+			err = redisPublisher.Publish(topic, msg)
+			if err != nil {
+				return err
+			}
+
+			// consumers outside the service get the public contract of the event, never the internal one
+			publicMsg, ok, err := events.ToPublicMessage(msg, eventName)
+			if err != nil || !ok {
+				return err
+			}
+
+			return redisPublisher.Publish(public.Topic(eventName), publicMsg)
+		},
+	)
+
+	// redisSubscriber has no consumer group, so each instance gets all updates for its streaming clients
+	router.AddNoPublisherHandler(
+		"ops_booking_updates_broadcaster",
+		"internal-events.svc-tickets.InternalOpsReadModelUpdated",
+		redisSubscriber,
+		func(msg *message.Message) error {
+			var event entities.InternalOpsReadModelUpdated
+			err := marshaller.Unmarshal(msg, &event)
+			if err != nil {
+				return err
+			}
+
+			return opsBookingUpdates.OnOpsReadModelUpdated(msg.Context(), &event)
+		},
+	)
+
+	// every instance queues the deliveries, it's idempotent for each subscription and event
+	router.AddNoPublisherHandler(
+		"webhooks_enqueuer",
+		"events",
+		redisSubscriber,
+		func(msg *message.Message) error {
+			eventName := marshaller.NameFromMessage(msg)
+			if eventName == "" {
+				return fmt.Errorf("cannot get event name from message")
+			}
+
+			// partners get only the public events
+			publicMsg, ok, err := events.ToPublicMessage(msg, eventName)
+			if err != nil || !ok {
+				return err
+			}
+
+			// the public event has the name, but not the schema of the internal one
+			var event struct {
+				Header public.Header `json:"header"`
+			}
+			err = json.Unmarshal(publicMsg.Payload, &event)
+			if err != nil {
+				return fmt.Errorf("%w: %w", events.ErrJsonUnmarshal, err)
+			}
+
+			id, err := uuid.Parse(event.Header.EventID)
+			if err != nil {
+				return fmt.Errorf("failed to parse event ID: %w", err)
+			}
+
+			return webhooksUsecase.Enqueue(msg.Context(), id, eventName, publicMsg.Payload)
 		},
 	)
 
@@ -173,13 +313,19 @@ func NewRouter(
 				return fmt.Errorf("failed to parse BookingID: %w", err)
 			}
 
+			// the data lake keeps JSON, whatever the encoding of the event
+			payload, err := marshaller.JSONPayload(msg)
+			if err != nil {
+				return err
+			}
+
 			err = eventsRepo.SaveEvent(
 				msg.Context(),
 				entities.DatalakeEvent{
 					Id:          id,
 					PublishedAt: event.Header.PublishedAt,
 					EventName:   eventName,
-					Payload:     msg.Payload,
+					Payload:     payload,
 				},
 			)
 			if err != nil {
@@ -193,12 +339,20 @@ func NewRouter(
 	return router, nil
 }
 
-func initMiddlewares(watermillLogger watermill.LoggerAdapter, router *message.Router) {
+func initMiddlewares(
+	watermillLogger watermill.LoggerAdapter,
+	router *message.Router,
+	publisher message.Publisher,
+	commandTracker commands.CommandTracker,
+) error {
 	router.AddMiddleware(events.TracingMiddleware)
 	router.AddMiddleware(middleware.Recoverer)
 	router.AddMiddleware(events.CorrelationIDMiddleware)
 	router.AddMiddleware(events.LoggingMiddleware)
 
+	// outside of retries, so the command is failed only when all retries failed
+	router.AddMiddleware(commands.StatusTrackingMiddleware(commandTracker))
+
 	router.AddMiddleware(middleware.Retry{
 		MaxRetries:      10,
 		InitialInterval: time.Millisecond * 100,
@@ -207,7 +361,18 @@ func initMiddlewares(watermillLogger watermill.LoggerAdapter, router *message.Ro
 		Logger:          watermillLogger,
 	}.Middleware)
 
+	// messages not matching their schemas never succeed, they are kept in the poison queue instead of retrying
+	poisonQueue, err := middleware.PoisonQueueWithFilter(publisher, PoisonQueueTopic, func(err error) bool {
+		return errors.Is(err, schema.ErrInvalidPayload)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create poison queue middleware: %w", err)
+	}
+	router.AddMiddleware(poisonQueue)
+
 	// skip marshalling errors before retrying
 	router.AddMiddleware(events.SkipMarshallingErrorsMiddleware)
 	router.AddMiddleware(events.MetricsMiddleware)
+
+	return nil
 }
@@ -0,0 +1,206 @@
+package protobuf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var scalarTypes = map[descriptorpb.FieldDescriptorProto_Type]string{
+	descriptorpb.FieldDescriptorProto_TYPE_STRING: "string",
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL:   "bool",
+	descriptorpb.FieldDescriptorProto_TYPE_INT64:  "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_INT32:  "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64: "uint64",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32: "uint32",
+	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE: "double",
+	descriptorpb.FieldDescriptorProto_TYPE_FLOAT:  "float",
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES:  "bytes",
+}
+
+// Proto returns the .proto file of the messages, for consumers in other languages.
+func (f *File) Proto() []byte {
+	var b bytes.Buffer
+
+	b.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&b, "package %s;\n", f.descriptor.GetPackage())
+
+	if len(f.descriptor.Dependency) > 0 {
+		b.WriteString("\n")
+		for _, dependency := range f.descriptor.Dependency {
+			fmt.Fprintf(&b, "import %q;\n", dependency)
+		}
+	}
+
+	for _, msg := range f.descriptor.MessageType {
+		fmt.Fprintf(&b, "\nmessage %s {\n", msg.GetName())
+		for _, field := range msg.Field {
+			label := ""
+			if field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+				label = "repeated "
+			}
+			fmt.Fprintf(&b, "  %s%s %s = %d;\n", label, f.typeName(field), field.GetName(), field.GetNumber())
+		}
+		b.WriteString("}\n")
+	}
+
+	return b.Bytes()
+}
+
+func (f *File) typeName(field *descriptorpb.FieldDescriptorProto) string {
+	if name, ok := scalarTypes[field.GetType()]; ok {
+		return name
+	}
+
+	return strings.TrimPrefix(strings.TrimPrefix(field.GetTypeName(), "."), f.descriptor.GetPackage()+".")
+}
+
+// Field of a message in a .proto file printed by Proto.
+type Field struct {
+	Number   int
+	Type     string
+	Repeated bool
+}
+
+var (
+	messageLine = regexp.MustCompile(`^message (\w+) \{$`)
+	fieldLine   = regexp.MustCompile(`^\s+(repeated )?([\w.]+) (\w+) = (\d+);$`)
+)
+
+// Fields returns the fields of the .proto file printed by Proto by message and field names.
+func Fields(proto []byte) (map[string]map[string]Field, error) {
+	messages := map[string]map[string]Field{}
+
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(proto))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+
+		if m := messageLine.FindStringSubmatch(text); m != nil {
+			current = m[1]
+			messages[current] = map[string]Field{}
+			continue
+		}
+		if text == "}" {
+			current = ""
+			continue
+		}
+
+		m := fieldLine.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		if current == "" {
+			return nil, fmt.Errorf("line %d: field outside of a message", line)
+		}
+
+		number, err := strconv.Atoi(m[4])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		messages[current][m[3]] = Field{Number: number, Type: m[2], Repeated: m[1] != ""}
+	}
+
+	return messages, scanner.Err()
+}
+
+// BreakingChanges returns the changes of the fields of the old .proto file
+// which make messages encoded with it unreadable with the new one.
+// Removed fields are breaking too: their numbers could be reused by new fields.
+func BreakingChanges(old, new []byte) ([]string, error) {
+	oldMessages, err := Fields(old)
+	if err != nil {
+		return nil, fmt.Errorf("invalid old .proto file: %w", err)
+	}
+	newMessages, err := Fields(new)
+	if err != nil {
+		return nil, fmt.Errorf("invalid new .proto file: %w", err)
+	}
+
+	var changes []string
+	for msgName, oldFields := range oldMessages {
+		newFields, ok := newMessages[msgName]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s: removed", msgName))
+			continue
+		}
+
+		for fieldName, oldField := range oldFields {
+			path := msgName + "." + fieldName
+
+			newField, ok := newFields[fieldName]
+			switch {
+			case !ok:
+				changes = append(changes, fmt.Sprintf("%s: removed", path))
+			case newField.Number != oldField.Number:
+				changes = append(changes, fmt.Sprintf("%s: number changed from %d to %d", path, oldField.Number, newField.Number))
+			case newField.Type != oldField.Type || newField.Repeated != oldField.Repeated:
+				changes = append(changes, fmt.Sprintf("%s: type changed from %s to %s", path, oldField.typeName(), newField.typeName()))
+			}
+		}
+	}
+
+	sort.Strings(changes)
+	return changes, nil
+}
+
+func (f Field) typeName() string {
+	if f.Repeated {
+		return "repeated " + f.Type
+	}
+	return f.Type
+}
+
+// FieldNumbers are the numbers of the fields by message and field names.
+// Unlike the .proto file, recorded field numbers keep the removed fields, so their numbers are never reused.
+type FieldNumbers map[string]map[string]int
+
+// Record returns the numbers with the new fields of the .proto file printed by Proto added,
+// and the conflicts of its fields with the recorded numbers: changed numbers and numbers reused by other fields.
+// The recorded numbers are never changed or removed.
+func (n FieldNumbers) Record(proto []byte) (FieldNumbers, []string, error) {
+	messages, err := Fields(proto)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid .proto file: %w", err)
+	}
+
+	recorded := FieldNumbers{}
+	for msgName, fields := range n {
+		recorded[msgName] = maps.Clone(fields)
+	}
+
+	var conflicts []string
+	for msgName, fields := range messages {
+		if recorded[msgName] == nil {
+			recorded[msgName] = map[string]int{}
+		}
+
+		for fieldName, field := range fields {
+			path := msgName + "." + fieldName
+
+			if number, ok := n[msgName][fieldName]; ok {
+				if number != field.Number {
+					conflicts = append(conflicts, fmt.Sprintf("%s: number changed from %d to %d", path, number, field.Number))
+				}
+				continue
+			}
+
+			for otherName, number := range n[msgName] {
+				if number == field.Number {
+					conflicts = append(conflicts, fmt.Sprintf("%s: number %d was used by %s.%s", path, number, msgName, otherName))
+				}
+			}
+			recorded[msgName][fieldName] = field.Number
+		}
+	}
+
+	sort.Strings(conflicts)
+	return recorded, conflicts, nil
+}
@@ -0,0 +1,495 @@
+// Package protobuf encodes Go structs as protobuf messages without generated code.
+//
+// The messages are defined by the structs: every field has the protobuf tag with its field number,
+// and the name from the json tag (or the lowercase Go name), so both encodings have the same fields:
+//
+//	BookingID uuid.UUID `json:"booking_id" protobuf:"2"`
+//
+// Field numbers must never change or be reused, otherwise messages sent before the change are read wrong.
+//
+// Types are mapped to:
+//   - time.Time: google.protobuf.Timestamp,
+//   - types implementing encoding.TextMarshaler, like uuid.UUID and decimal.Decimal: string,
+//   - structs and pointers to structs: messages named as the struct,
+//   - slices: repeated fields,
+//   - strings, booleans, integers and floats: their protobuf scalar types.
+package protobuf
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+var timestampFile = timestamppb.File_google_protobuf_timestamp_proto
+
+// File has the protobuf messages of the structs and all structs used by their fields.
+type File struct {
+	descriptor *descriptorpb.FileDescriptorProto
+	file       protoreflect.FileDescriptor
+}
+
+// NewFile defines the messages of the structs in the protobuf package.
+func NewFile(pkg string, structs []any) (*File, error) {
+	b := builder{
+		pkg:      pkg,
+		messages: map[string]*descriptorpb.DescriptorProto{},
+		types:    map[string]reflect.Type{},
+	}
+
+	for _, s := range structs {
+		t := reflect.TypeOf(s)
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%T is not a struct", s)
+		}
+		if _, err := b.message(t); err != nil {
+			return nil, err
+		}
+	}
+
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String(pkg + ".proto"),
+		Package: proto.String(pkg),
+		Syntax:  proto.String("proto3"),
+	}
+	if b.usesTimestamp {
+		fd.Dependency = []string{timestampFile.Path()}
+	}
+
+	names := make([]string, 0, len(b.messages))
+	for name := range b.messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fd.MessageType = append(fd.MessageType, b.messages[name])
+	}
+
+	file, err := protodesc.NewFile(fd, protoregistry.GlobalFiles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf definitions: %w", err)
+	}
+
+	return &File{descriptor: fd, file: file}, nil
+}
+
+// Marshal encodes the struct as the message of its type.
+func (f *File) Marshal(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+
+	md := f.file.Messages().ByName(protoreflect.Name(rv.Type().Name()))
+	if md == nil {
+		return nil, fmt.Errorf("no protobuf message %s", rv.Type().Name())
+	}
+
+	m := dynamicpb.NewMessage(md)
+	if err := encodeMessage(rv, m); err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", md.Name(), err)
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+}
+
+// Unmarshal decodes the message with the name to v, which must be a pointer to a struct.
+// The fields are matched by their names, so v can have only some fields of the message,
+// fields of v which are not in the message are left unchanged, like with encoding/json.
+func (f *File) Unmarshal(name string, data []byte, v any) error {
+	md := f.file.Messages().ByName(protoreflect.Name(name))
+	if md == nil {
+		return fmt.Errorf("no protobuf message %s", name)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode %s to %T, it's not a pointer to a struct", name, v)
+	}
+
+	m := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", name, err)
+	}
+
+	if err := decodeMessage(m, rv.Elem()); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+
+	return nil
+}
+
+type builder struct {
+	pkg           string
+	messages      map[string]*descriptorpb.DescriptorProto
+	types         map[string]reflect.Type
+	usesTimestamp bool
+}
+
+func (b *builder) message(t reflect.Type) (string, error) {
+	name := t.Name()
+	if name == "" {
+		return "", fmt.Errorf("anonymous struct %s can't be a protobuf message", t)
+	}
+
+	if existing, ok := b.types[name]; ok {
+		if existing != t {
+			return "", fmt.Errorf("%s and %s have the same protobuf message name", existing, t)
+		}
+		return name, nil
+	}
+
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	b.types[name] = t
+	b.messages[name] = msg
+
+	numbers := map[int32]string{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		fieldName, ok := protoName(sf)
+		if !ok {
+			continue
+		}
+
+		tag, ok := sf.Tag.Lookup("protobuf")
+		if !ok {
+			return "", fmt.Errorf("%s.%s has no protobuf tag with the field number", name, sf.Name)
+		}
+		number, err := strconv.ParseInt(tag, 10, 32)
+		if err != nil || number < 1 {
+			return "", fmt.Errorf("invalid protobuf field number %q of %s.%s", tag, name, sf.Name)
+		}
+		if other, ok := numbers[int32(number)]; ok {
+			return "", fmt.Errorf("%s.%s and %s.%s have the same protobuf field number %d", name, other, name, sf.Name, number)
+		}
+		numbers[int32(number)] = sf.Name
+
+		field, err := b.field(sf.Type)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", name, sf.Name, err)
+		}
+		field.Name = proto.String(fieldName)
+		field.Number = proto.Int32(int32(number))
+
+		msg.Field = append(msg.Field, field)
+	}
+
+	sort.Slice(msg.Field, func(i, j int) bool {
+		return msg.Field[i].GetNumber() < msg.Field[j].GetNumber()
+	})
+
+	return name, nil
+}
+
+func (b *builder) field(t reflect.Type) (*descriptorpb.FieldDescriptorProto, error) {
+	field := &descriptorpb.FieldDescriptorProto{
+		Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		t = t.Elem()
+	}
+
+	pointer := t.Kind() == reflect.Pointer
+	if pointer {
+		t = t.Elem()
+	}
+
+	typ, typeName, err := b.fieldType(t)
+	if err != nil {
+		return nil, err
+	}
+	if pointer && typ != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+		return nil, fmt.Errorf("pointers are supported only to messages, not to %s", t)
+	}
+
+	field.Type = typ.Enum()
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+
+	return field, nil
+}
+
+func (b *builder) fieldType(t reflect.Type) (descriptorpb.FieldDescriptorProto_Type, string, error) {
+	if t == timeType {
+		b.usesTimestamp = true
+		return descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "." + string((&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()), nil
+	}
+	if isText(t) {
+		return descriptorpb.FieldDescriptorProto_TYPE_STRING, "", nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return descriptorpb.FieldDescriptorProto_TYPE_STRING, "", nil
+	case reflect.Bool:
+		return descriptorpb.FieldDescriptorProto_TYPE_BOOL, "", nil
+	case reflect.Int, reflect.Int64:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT64, "", nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT32, "", nil
+	case reflect.Uint, reflect.Uint64:
+		return descriptorpb.FieldDescriptorProto_TYPE_UINT64, "", nil
+	case reflect.Uint16, reflect.Uint32:
+		return descriptorpb.FieldDescriptorProto_TYPE_UINT32, "", nil
+	case reflect.Float64:
+		return descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "", nil
+	case reflect.Float32:
+		return descriptorpb.FieldDescriptorProto_TYPE_FLOAT, "", nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return descriptorpb.FieldDescriptorProto_TYPE_BYTES, "", nil
+		}
+	case reflect.Struct:
+		name, err := b.message(t)
+		if err != nil {
+			return 0, "", err
+		}
+		return descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "." + b.pkg + "." + name, nil
+	}
+
+	return 0, "", fmt.Errorf("type %s is not supported", t)
+}
+
+// protoName returns the protobuf name of the field, or false when the field is not encoded.
+func protoName(sf reflect.StructField) (string, bool) {
+	if !sf.IsExported() {
+		return "", false
+	}
+
+	jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	switch jsonName {
+	case "-":
+		return "", false
+	case "":
+		return strings.ToLower(sf.Name), true
+	default:
+		return jsonName, true
+	}
+}
+
+func isText(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) && reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func encodeMessage(v reflect.Value, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		fieldName, ok := protoName(sf)
+		if !ok {
+			continue
+		}
+
+		fd := fields.ByName(protoreflect.Name(fieldName))
+		if fd == nil {
+			return fmt.Errorf("%s has no field %s", m.Descriptor().Name(), fieldName)
+		}
+
+		fv := v.Field(i)
+		if fd.IsList() {
+			if fv.Len() == 0 {
+				continue
+			}
+
+			list := m.Mutable(fd).List()
+			for j := 0; j < fv.Len(); j++ {
+				value, err := encodeValue(fd, fv.Index(j), list.NewElement)
+				if err != nil {
+					return fmt.Errorf("%s[%d]: %w", fieldName, j, err)
+				}
+				list.Append(value)
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Pointer && fv.IsNil() {
+			continue
+		}
+
+		value, err := encodeValue(fd, fv, func() protoreflect.Value { return m.NewField(fd) })
+		if err != nil {
+			return fmt.Errorf("%s: %w", fieldName, err)
+		}
+		m.Set(fd, value)
+	}
+
+	return nil
+}
+
+func encodeValue(fd protoreflect.FieldDescriptor, v reflect.Value, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	switch fd.Kind() {
+	case protoreflect.MessageKind:
+		value := newValue()
+		m := value.Message()
+
+		if v.Type() == timeType {
+			t := v.Interface().(time.Time)
+			m.Set(m.Descriptor().Fields().ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+			m.Set(m.Descriptor().Fields().ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+			return value, nil
+		}
+
+		return value, encodeMessage(v, m)
+	case protoreflect.StringKind:
+		if isText(v.Type()) {
+			text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfString(string(text)), nil
+		}
+		return protoreflect.ValueOfString(v.String()), nil
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(v.Bool()), nil
+	case protoreflect.Int64Kind:
+		return protoreflect.ValueOfInt64(v.Int()), nil
+	case protoreflect.Int32Kind:
+		return protoreflect.ValueOfInt32(int32(v.Int())), nil
+	case protoreflect.Uint64Kind:
+		return protoreflect.ValueOfUint64(v.Uint()), nil
+	case protoreflect.Uint32Kind:
+		return protoreflect.ValueOfUint32(uint32(v.Uint())), nil
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(v.Float()), nil
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(v.Float())), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(v.Bytes()), nil
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("unsupported protobuf kind %s", fd.Kind())
+}
+
+func decodeMessage(m protoreflect.Message, v reflect.Value) error {
+	fields := m.Descriptor().Fields()
+
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		fieldName, ok := protoName(sf)
+		if !ok {
+			continue
+		}
+
+		fd := fields.ByName(protoreflect.Name(fieldName))
+		if fd == nil || !m.Has(fd) {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fd.IsList() {
+			if fv.Kind() != reflect.Slice {
+				return fmt.Errorf("%s: cannot decode repeated field to %s", fieldName, fv.Type())
+			}
+
+			list := m.Get(fd).List()
+			slice := reflect.MakeSlice(fv.Type(), list.Len(), list.Len())
+			for j := 0; j < list.Len(); j++ {
+				if err := decodeValue(fd, list.Get(j), slice.Index(j)); err != nil {
+					return fmt.Errorf("%s[%d]: %w", fieldName, j, err)
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+
+		if err := decodeValue(fd, m.Get(fd), fv); err != nil {
+			return fmt.Errorf("%s: %w", fieldName, err)
+		}
+	}
+
+	return nil
+}
+
+func decodeValue(fd protoreflect.FieldDescriptor, value protoreflect.Value, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cannot decode %s to %s", fd.Kind(), v.Type())
+	}
+
+	switch fd.Kind() {
+	case protoreflect.MessageKind:
+		m := value.Message()
+
+		if v.Type() == timeType {
+			seconds := m.Get(m.Descriptor().Fields().ByName("seconds")).Int()
+			nanos := m.Get(m.Descriptor().Fields().ByName("nanos")).Int()
+			v.Set(reflect.ValueOf(time.Unix(seconds, nanos).UTC()))
+			return nil
+		}
+		if v.Kind() != reflect.Struct {
+			return mismatch()
+		}
+
+		return decodeMessage(m, v)
+	case protoreflect.StringKind:
+		if isText(v.Type()) {
+			return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value.String()))
+		}
+		if v.Kind() != reflect.String {
+			return mismatch()
+		}
+		v.SetString(value.String())
+	case protoreflect.BoolKind:
+		if v.Kind() != reflect.Bool {
+			return mismatch()
+		}
+		v.SetBool(value.Bool())
+	case protoreflect.Int64Kind, protoreflect.Int32Kind:
+		if !v.CanInt() || v.OverflowInt(value.Int()) {
+			return mismatch()
+		}
+		v.SetInt(value.Int())
+	case protoreflect.Uint64Kind, protoreflect.Uint32Kind:
+		if !v.CanUint() || v.OverflowUint(value.Uint()) {
+			return mismatch()
+		}
+		v.SetUint(value.Uint())
+	case protoreflect.DoubleKind, protoreflect.FloatKind:
+		if !v.CanFloat() {
+			return mismatch()
+		}
+		v.SetFloat(value.Float())
+	case protoreflect.BytesKind:
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+			return mismatch()
+		}
+		v.SetBytes(value.Bytes())
+	default:
+		return fmt.Errorf("unsupported protobuf kind %s", fd.Kind())
+	}
+
+	return nil
+}
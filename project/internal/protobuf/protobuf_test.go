@@ -0,0 +1,236 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Header struct {
+	ID          string    `json:"id" protobuf:"1"`
+	PublishedAt time.Time `json:"published_at" protobuf:"2"`
+}
+
+type Item struct {
+	Name     string `protobuf:"1"`
+	Quantity int    `protobuf:"2"`
+}
+
+type Order_v1 struct {
+	Header   Header    `json:"header" protobuf:"1"`
+	OrderID  uuid.UUID `json:"order_id" protobuf:"2"`
+	Items    []Item    `json:"items" protobuf:"3"`
+	Gift     *Item     `json:"gift,omitempty" protobuf:"4"`
+	Tags     []string  `json:"tags" protobuf:"5"`
+	Paid     bool      `json:"paid" protobuf:"6"`
+	Total    float64   `json:"total" protobuf:"7"`
+	internal string
+}
+
+func TestFile_RoundTrip(t *testing.T) {
+	file, err := NewFile("test", []any{Order_v1{}})
+	require.NoError(t, err)
+
+	order := Order_v1{
+		Header: Header{
+			ID:          uuid.NewString(),
+			PublishedAt: time.Date(2024, 6, 1, 12, 30, 0, 123, time.UTC),
+		},
+		OrderID: uuid.New(),
+		Items: []Item{
+			{Name: "ticket", Quantity: 2},
+			{Name: "poster", Quantity: 1},
+		},
+		Gift:  &Item{Name: "pin", Quantity: 1},
+		Tags:  []string{"vip", "early"},
+		Paid:  true,
+		Total: 12.5,
+	}
+
+	data, err := file.Marshal(order)
+	require.NoError(t, err)
+
+	var decoded Order_v1
+	require.NoError(t, file.Unmarshal("Order_v1", data, &decoded))
+	assert.Equal(t, order, decoded)
+
+	t.Run("zero value", func(t *testing.T) {
+		data, err := file.Marshal(&Order_v1{})
+		require.NoError(t, err)
+
+		var decoded Order_v1
+		require.NoError(t, file.Unmarshal("Order_v1", data, &decoded))
+		assert.Equal(t, Order_v1{}, decoded)
+	})
+
+	t.Run("partial struct", func(t *testing.T) {
+		var partial struct {
+			Header struct {
+				ID string `json:"id"`
+			} `json:"header"`
+			Paid bool `json:"paid"`
+		}
+		require.NoError(t, file.Unmarshal("Order_v1", data, &partial))
+		assert.Equal(t, order.Header.ID, partial.Header.ID)
+		assert.True(t, partial.Paid)
+	})
+
+	t.Run("unknown message", func(t *testing.T) {
+		assert.Error(t, file.Unmarshal("Unknown_v1", data, &decoded))
+	})
+
+	t.Run("not a pointer", func(t *testing.T) {
+		assert.Error(t, file.Unmarshal("Order_v1", data, decoded))
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		assert.Error(t, file.Unmarshal("Order_v1", []byte{0xff, 0xff}, &decoded))
+	})
+}
+
+func TestNewFile_InvalidStructs(t *testing.T) {
+	type WithoutNumber struct {
+		Name string `json:"name"`
+	}
+	type DuplicatedNumber struct {
+		Name  string `protobuf:"1"`
+		Email string `protobuf:"1"`
+	}
+
+	for name, s := range map[string]any{
+		"not a struct":      "order",
+		"without number":    WithoutNumber{},
+		"duplicated number": DuplicatedNumber{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewFile("test", []any{s})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFile_Proto(t *testing.T) {
+	file, err := NewFile("test", []any{Order_v1{}})
+	require.NoError(t, err)
+
+	assert.Equal(t, `syntax = "proto3";
+
+package test;
+
+import "google/protobuf/timestamp.proto";
+
+message Header {
+  string id = 1;
+  google.protobuf.Timestamp published_at = 2;
+}
+
+message Item {
+  string name = 1;
+  int64 quantity = 2;
+}
+
+message Order_v1 {
+  Header header = 1;
+  string order_id = 2;
+  repeated Item items = 3;
+  Item gift = 4;
+  repeated string tags = 5;
+  bool paid = 6;
+  double total = 7;
+}
+`, string(file.Proto()))
+}
+
+func TestBreakingChanges(t *testing.T) {
+	old := []byte(`syntax = "proto3";
+
+package test;
+
+message Order_v1 {
+  string order_id = 1;
+  repeated string tags = 2;
+  int64 quantity = 3;
+  bool paid = 4;
+}
+
+message Removed_v1 {
+  string id = 1;
+}
+`)
+
+	new := []byte(`syntax = "proto3";
+
+package test;
+
+message Order_v1 {
+  string order_id = 1;
+  string tags = 2;
+  int64 quantity = 4;
+  string email = 5;
+}
+`)
+
+	changes, err := BreakingChanges(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"Order_v1.paid: removed",
+		"Order_v1.quantity: number changed from 3 to 4",
+		"Order_v1.tags: type changed from repeated string to string",
+		"Removed_v1: removed",
+	}, changes)
+
+	changes, err = BreakingChanges(old, old)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestFieldNumbers_Record(t *testing.T) {
+	recorded := FieldNumbers{
+		"Order_v1": {"order_id": 1, "tags": 2, "quantity": 3, "paid": 4},
+	}
+
+	t.Run("new fields", func(t *testing.T) {
+		proto := []byte(`message Order_v1 {
+  string order_id = 1;
+  repeated string tags = 2;
+  int64 quantity = 3;
+  string email = 5;
+}
+
+message Shipment_v1 {
+  string id = 1;
+}
+`)
+
+		numbers, conflicts, err := recorded.Record(proto)
+		require.NoError(t, err)
+		assert.Empty(t, conflicts)
+		assert.Equal(t, FieldNumbers{
+			// the removed paid field is kept
+			"Order_v1":    {"order_id": 1, "tags": 2, "quantity": 3, "paid": 4, "email": 5},
+			"Shipment_v1": {"id": 1},
+		}, numbers)
+		assert.Equal(t, 4, len(recorded["Order_v1"]), "recorded numbers must not be modified")
+	})
+
+	t.Run("changed and reused numbers", func(t *testing.T) {
+		// paid was removed in an earlier version, so the .proto file doesn't have it anymore
+		proto := []byte(`message Order_v1 {
+  string order_id = 1;
+  repeated string tags = 2;
+  int64 quantity = 5;
+  string email = 4;
+}
+`)
+
+		_, conflicts, err := recorded.Record(proto)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"Order_v1.email: number 4 was used by Order_v1.paid",
+			"Order_v1.quantity: number changed from 3 to 5",
+		}, conflicts)
+	})
+}
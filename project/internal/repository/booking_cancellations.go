@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrBookingCancellationNotFound = fmt.Errorf("booking cancellation not found")
+
+type BookingCancellationsRepo struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewBookingCancellationsRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+) *BookingCancellationsRepo {
+	return &BookingCancellationsRepo{
+		db:     db,
+		getter: getter,
+	}
+}
+
+func (r *BookingCancellationsRepo) Add(ctx context.Context, cancellation entities.BookingCancellation) error {
+	payload, err := json.Marshal(cancellation)
+	if err != nil {
+		return fmt.Errorf("marshal booking cancellation: %w", err)
+	}
+
+	_, err = r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO booking_cancellations (booking_id, payload)
+		VALUES ($1, $2)
+	`, cancellation.BookingID, payload)
+	if err != nil {
+		return fmt.Errorf("insert booking cancellation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *BookingCancellationsRepo) Get(ctx context.Context, bookingID uuid.UUID) (entities.BookingCancellation, error) {
+	return r.get(ctx, `
+		SELECT payload
+		FROM booking_cancellations
+		WHERE booking_id = $1
+	`, bookingID)
+}
+
+func (r *BookingCancellationsRepo) GetByTicketID(ctx context.Context, ticketID string) (entities.BookingCancellation, error) {
+	return r.get(ctx, `
+		SELECT payload
+		FROM booking_cancellations
+		WHERE payload -> 'tickets' ? $1
+	`, ticketID)
+}
+
+func (r *BookingCancellationsRepo) GetByShowID(ctx context.Context, showID uuid.UUID) ([]entities.BookingCancellation, error) {
+	var payloads [][]byte
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &payloads, `
+		SELECT payload
+		FROM booking_cancellations
+		WHERE payload ->> 'show_id' = $1
+	`, showID.String())
+	if err != nil {
+		return nil, fmt.Errorf("select booking cancellations: %w", err)
+	}
+
+	cancellations := make([]entities.BookingCancellation, 0, len(payloads))
+	for _, payload := range payloads {
+		var cancellation entities.BookingCancellation
+		err := json.Unmarshal(payload, &cancellation)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal booking cancellation: %w", err)
+		}
+		cancellations = append(cancellations, cancellation)
+	}
+
+	return cancellations, nil
+}
+
+// Update locks the cancellation row for the duration of the transaction from ctx,
+// so concurrent TicketRefunded_v1 events of one booking don't overwrite each other.
+func (r *BookingCancellationsRepo) Update(
+	ctx context.Context,
+	bookingID uuid.UUID,
+	updateFn func(cancellation entities.BookingCancellation) (entities.BookingCancellation, error),
+) (entities.BookingCancellation, error) {
+	cancellation, err := r.get(ctx, `
+		SELECT payload
+		FROM booking_cancellations
+		WHERE booking_id = $1
+		FOR UPDATE
+	`, bookingID)
+	if err != nil {
+		return entities.BookingCancellation{}, err
+	}
+
+	cancellation, err = updateFn(cancellation)
+	if err != nil {
+		return entities.BookingCancellation{}, fmt.Errorf("update booking cancellation: %w", err)
+	}
+
+	payload, err := json.Marshal(cancellation)
+	if err != nil {
+		return entities.BookingCancellation{}, fmt.Errorf("marshal booking cancellation: %w", err)
+	}
+
+	_, err = r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE booking_cancellations
+		SET payload = $1
+		WHERE booking_id = $2
+	`, payload, bookingID)
+	if err != nil {
+		return entities.BookingCancellation{}, fmt.Errorf("update booking cancellation: %w", err)
+	}
+
+	return cancellation, nil
+}
+
+func (r *BookingCancellationsRepo) get(ctx context.Context, query string, arg any) (entities.BookingCancellation, error) {
+	var cancellation entities.BookingCancellation
+	var payload []byte
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).QueryRowxContext(ctx, query, arg).Scan(&payload)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return cancellation, ErrBookingCancellationNotFound
+		}
+		return cancellation, fmt.Errorf("select booking cancellation: %w", err)
+	}
+
+	err = json.Unmarshal(payload, &cancellation)
+	if err != nil {
+		return cancellation, fmt.Errorf("unmarshal booking cancellation: %w", err)
+	}
+
+	return cancellation, nil
+}
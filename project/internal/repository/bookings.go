@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
@@ -37,12 +38,14 @@ func isErrorUniqueViolation(err error) bool {
 
 var ErrBookingAlreadyExists = errors.New("booking already exists")
 
+var ErrBookingNotFound = errors.New("booking not found")
+
 func (r *BookingsRepo) CreateBooking(ctx context.Context, booking entities.Booking) (uuid.UUID, error) {
 	query := `
 		INSERT INTO bookings (
-			id, show_id, number_of_tickets, customer_email
+			id, show_id, number_of_tickets, customer_email, locale
 		) VALUES (
-			$1, $2, $3, $4
+			$1, $2, $3, $4, $5
 		)`
 
 	res, err := r.getter.DefaultTrOrDB(ctx, r.db).
@@ -51,6 +54,7 @@ func (r *BookingsRepo) CreateBooking(ctx context.Context, booking entities.Booki
 			booking.ShowId,
 			booking.NumberOfTickets,
 			booking.CustomerEmail,
+			booking.Locale,
 		)
 
 	if err != nil {
@@ -89,3 +93,78 @@ func (r *BookingsRepo) GetBookingsCountByShowID(ctx context.Context, showID uuid
 
 	return count, nil
 }
+
+func (r *BookingsRepo) GetBooking(ctx context.Context, bookingID uuid.UUID) (*entities.Booking, error) {
+	var booking entities.Booking
+
+	query := `
+		SELECT id, show_id, number_of_tickets, customer_email, locale
+		FROM bookings
+		WHERE id = $1`
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).
+		QueryRowContext(ctx, query, bookingID).
+		Scan(&booking.Id, &booking.ShowId, &booking.NumberOfTickets, &booking.CustomerEmail, &booking.Locale)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	return &booking, nil
+}
+
+func (r *BookingsRepo) GetBookingsByShowID(ctx context.Context, showID uuid.UUID) ([]entities.Booking, error) {
+	var bookings []entities.Booking
+
+	query := `
+		SELECT id, show_id, number_of_tickets, customer_email, locale
+		FROM bookings
+		WHERE show_id = $1`
+
+	rows, err := r.getter.DefaultTrOrDB(ctx, r.db).QueryContext(ctx, query, showID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var booking entities.Booking
+		err := rows.Scan(&booking.Id, &booking.ShowId, &booking.NumberOfTickets, &booking.CustomerEmail, &booking.Locale)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, booking)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate bookings: %w", err)
+	}
+
+	return bookings, nil
+}
+
+// ReleaseTickets gives the seats of a booking back to the show,
+// so they are no longer counted by GetBookingsCountByShowID.
+func (r *BookingsRepo) ReleaseTickets(ctx context.Context, bookingID uuid.UUID, numberOfTickets int) error {
+	query := `
+		UPDATE bookings
+		SET number_of_tickets = GREATEST(number_of_tickets - $2, 0)
+		WHERE id = $1`
+
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).
+		ExecContext(ctx, query, bookingID, numberOfTickets)
+	if err != nil {
+		return fmt.Errorf("release booking tickets: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return ErrBookingNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrCommandNotFound = fmt.Errorf("command not found")
+
+type trackedCommand struct {
+	CommandID     uuid.UUID `db:"command_id"`
+	CommandName   string    `db:"command_name"`
+	Status        string    `db:"status"`
+	FailureReason string    `db:"failure_reason"`
+	Attempts      int       `db:"attempts"`
+	AcceptedAt    time.Time `db:"accepted_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// CommandsRepo stores statuses of commands sent through the command bus.
+// Commands sent before the tracking was introduced are not stored, updates of their status are ignored.
+type CommandsRepo struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewCommandsRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+) *CommandsRepo {
+	return &CommandsRepo{
+		db:     db,
+		getter: getter,
+	}
+}
+
+func (r *CommandsRepo) Accept(ctx context.Context, commandID uuid.UUID, commandName string) error {
+	now := time.Now().UTC()
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO commands (command_id, command_name, status, accepted_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (command_id) DO NOTHING`,
+		commandID, commandName, entities.CommandStatusAccepted, now,
+	)
+	if err != nil {
+		return fmt.Errorf("insert command: %w", err)
+	}
+
+	return nil
+}
+
+// MarkProcessing is called on every delivery of the command. Re-delivered commands which already succeeded stay succeeded.
+func (r *CommandsRepo) MarkProcessing(ctx context.Context, commandID uuid.UUID) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE commands
+		SET status = $1, attempts = attempts + 1, updated_at = $2
+		WHERE command_id = $3 AND status <> $4`,
+		entities.CommandStatusProcessing, time.Now().UTC(), commandID, entities.CommandStatusSucceeded,
+	)
+	if err != nil {
+		return fmt.Errorf("update command status: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CommandsRepo) MarkSucceeded(ctx context.Context, commandID uuid.UUID) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE commands
+		SET status = $1, failure_reason = '', updated_at = $2
+		WHERE command_id = $3`,
+		entities.CommandStatusSucceeded, time.Now().UTC(), commandID,
+	)
+	if err != nil {
+		return fmt.Errorf("update command status: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CommandsRepo) MarkFailed(ctx context.Context, commandID uuid.UUID, reason string) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE commands
+		SET status = $1, failure_reason = $2, updated_at = $3
+		WHERE command_id = $4 AND status <> $5`,
+		entities.CommandStatusFailed, reason, time.Now().UTC(), commandID, entities.CommandStatusSucceeded,
+	)
+	if err != nil {
+		return fmt.Errorf("update command status: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CommandsRepo) Get(ctx context.Context, commandID uuid.UUID) (entities.TrackedCommand, error) {
+	var command trackedCommand
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &command, `
+		SELECT command_id, command_name, status, failure_reason, attempts, accepted_at, updated_at
+		FROM commands
+		WHERE command_id = $1`, commandID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.TrackedCommand{}, ErrCommandNotFound
+		}
+		return entities.TrackedCommand{}, fmt.Errorf("get command: %w", err)
+	}
+
+	return entities.TrackedCommand{
+		CommandID:     command.CommandID,
+		CommandName:   command.CommandName,
+		Status:        entities.CommandStatus(command.Status),
+		FailureReason: command.FailureReason,
+		Attempts:      command.Attempts,
+		AcceptedAt:    command.AcceptedAt,
+		UpdatedAt:     command.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+type idempotentRequest struct {
+	Key         string         `db:"idempotency_key"`
+	Fingerprint string         `db:"fingerprint"`
+	StatusCode  sql.NullInt64  `db:"status_code"`
+	ContentType sql.NullString `db:"content_type"`
+	Headers     []byte         `db:"response_headers"`
+	Body        []byte         `db:"response_body"`
+	LockedAt    time.Time      `db:"locked_at"`
+}
+
+type IdempotentRequestsRepo struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+	// lockTimeout releases keys of requests which were never completed, for example because the service crashed
+	lockTimeout time.Duration
+}
+
+func NewIdempotentRequestsRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+	lockTimeout time.Duration,
+) *IdempotentRequestsRepo {
+	return &IdempotentRequestsRepo{
+		db:          db,
+		getter:      getter,
+		lockTimeout: lockTimeout,
+	}
+}
+
+// Lock stores the request when it's the first one with the key, or takes over a request whose lock timed out.
+// When locked is false, the request stored before is returned and must not be handled again.
+func (r *IdempotentRequestsRepo) Lock(
+	ctx context.Context,
+	key string,
+	fingerprint string,
+) (stored entities.IdempotentRequest, locked bool, err error) {
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+	now := time.Now().UTC()
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO idempotent_requests (idempotency_key, fingerprint, locked_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (idempotency_key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, locked_at = EXCLUDED.locked_at
+		WHERE idempotent_requests.status_code IS NULL
+			AND idempotent_requests.fingerprint = EXCLUDED.fingerprint
+			AND idempotent_requests.locked_at < $4`,
+		key, fingerprint, now, now.Add(-r.lockTimeout),
+	)
+	if err != nil {
+		return entities.IdempotentRequest{}, false, fmt.Errorf("lock idempotent request: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return entities.IdempotentRequest{}, false, fmt.Errorf("get affected rows: %w", err)
+	}
+
+	if affected > 0 {
+		return entities.IdempotentRequest{
+			Key:         key,
+			Fingerprint: fingerprint,
+			LockedAt:    now,
+		}, true, nil
+	}
+
+	var request idempotentRequest
+	err = db.GetContext(ctx, &request, `
+		SELECT idempotency_key, fingerprint, status_code, content_type, response_headers, response_body, locked_at
+		FROM idempotent_requests
+		WHERE idempotency_key = $1`, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// released in the meantime
+			return r.Lock(ctx, key, fingerprint)
+		}
+		return entities.IdempotentRequest{}, false, fmt.Errorf("get idempotent request: %w", err)
+	}
+
+	entity, err := request.toEntity()
+	if err != nil {
+		return entities.IdempotentRequest{}, false, err
+	}
+
+	return entity, false, nil
+}
+
+func (r *IdempotentRequestsRepo) Complete(ctx context.Context, key string, response entities.IdempotentResponse) error {
+	headers, err := json.Marshal(response.Headers)
+	if err != nil {
+		return fmt.Errorf("marshal response headers: %w", err)
+	}
+
+	_, err = r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE idempotent_requests
+		SET status_code = $1, content_type = $2, response_headers = $3, response_body = $4, completed_at = $5
+		WHERE idempotency_key = $6`,
+		response.StatusCode, response.ContentType, headers, response.Body, time.Now().UTC(), key,
+	)
+	if err != nil {
+		return fmt.Errorf("complete idempotent request: %w", err)
+	}
+
+	return nil
+}
+
+// Release removes the lock of a request which failed, so it can be retried with the same key.
+func (r *IdempotentRequestsRepo) Release(ctx context.Context, key string) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM idempotent_requests
+		WHERE idempotency_key = $1 AND status_code IS NULL`, key)
+	if err != nil {
+		return fmt.Errorf("release idempotent request: %w", err)
+	}
+
+	return nil
+}
+
+func (r idempotentRequest) toEntity() (entities.IdempotentRequest, error) {
+	request := entities.IdempotentRequest{
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		LockedAt:    r.LockedAt,
+	}
+
+	if r.StatusCode.Valid {
+		request.Response = &entities.IdempotentResponse{
+			StatusCode:  int(r.StatusCode.Int64),
+			ContentType: r.ContentType.String,
+			Body:        r.Body,
+		}
+
+		if len(r.Headers) > 0 {
+			if err := json.Unmarshal(r.Headers, &request.Response.Headers); err != nil {
+				return entities.IdempotentRequest{}, fmt.Errorf("unmarshal response headers: %w", err)
+			}
+		}
+	}
+
+	return request, nil
+}
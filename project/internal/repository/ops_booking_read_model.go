@@ -3,20 +3,24 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
-	trmsql "github.com/avito-tech/go-transaction-manager/drivers/sql/v2"
 	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
-	"github.com/avito-tech/go-transaction-manager/trm/v2/settings"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strings"
 	"tickets/internal/entities"
 	"time"
 )
 
+// OpsBookingReadModelRepo projects bookings and their tickets into ops_bookings and ops_tickets.
+// Every event updates only the row of its ticket and bumps the version and last_update of the booking.
+//
+// Events can arrive in any order: events of unknown bookings and tickets create placeholder rows,
+// and updates older than the stored ones are ignored.
 type OpsBookingReadModelRepo struct {
 	db        *sqlx.DB
 	getter    *trmsqlx.CtxGetter
@@ -25,6 +29,9 @@ type OpsBookingReadModelRepo struct {
 	eventBus Publisher
 }
 
+// Publisher publishes events in the transaction from ctx, so they are sent only when it's committed,
+// see events.OutboxBusFactory. Subscribers read the committed read model when they get the event.
+//
 //go:generate mockgen -destination=mocks/publisher_mock.go -package=mocks tickets/internal/repository Publisher
 type Publisher interface {
 	Publish(ctx context.Context, event any) error
@@ -44,572 +51,694 @@ func NewOpsBookingReadModelRepo(
 	}
 }
 
-func (r *OpsBookingReadModelRepo) GetByID(ctx context.Context, id uuid.UUID) (*entities.OpsBooking, error) {
-	return r.findReadModelByBookingID(ctx, id.String())
+// opsBookingRow without BookedAt is a placeholder waiting for BookingMade_v1.
+type opsBookingRow struct {
+	BookingID  uuid.UUID    `db:"booking_id"`
+	BookedAt   sql.NullTime `db:"booked_at"`
+	ShowID     *uuid.UUID   `db:"show_id"`
+	LastUpdate time.Time    `db:"last_update"`
+	Version    int64        `db:"version"`
+	UpdateSeq  int64        `db:"update_seq"`
 }
 
-func (r *OpsBookingReadModelRepo) GetByTicketID(ctx context.Context, ticketID uuid.UUID) (*entities.OpsBooking, error) {
-	return r.findReadModelByTicketID(ctx, ticketID.String())
+// opsTicketRow stores zero times of OpsTicket as NULLs.
+// Tickets of events without the booking ID don't have BookingID until TicketBookingConfirmed_v1 arrives.
+type opsTicketRow struct {
+	TicketID        uuid.UUID     `db:"ticket_id"`
+	BookingID       uuid.NullUUID `db:"booking_id"`
+	PriceAmount     string        `db:"price_amount"`
+	PriceCurrency   string        `db:"price_currency"`
+	CustomerEmail   string        `db:"customer_email"`
+	ConfirmedAt     sql.NullTime  `db:"confirmed_at"`
+	RefundedAt      sql.NullTime  `db:"refunded_at"`
+	RefundedAmount  string        `db:"refunded_amount"`
+	PrintedAt       sql.NullTime  `db:"printed_at"`
+	PrintedFileName string        `db:"printed_file_name"`
+	ReceiptIssuedAt sql.NullTime  `db:"receipt_issued_at"`
+	ReceiptNumber   string        `db:"receipt_number"`
+	TransferredAt   sql.NullTime  `db:"transferred_at"`
 }
 
-func (r *OpsBookingReadModelRepo) GetAll(ctx context.Context) ([]entities.OpsBooking, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT payload FROM read_model_ops_bookings")
+const opsBookingColumns = `booking_id, booked_at, show_id, last_update, version, update_seq`
+
+const opsTicketColumns = `ticket_id, booking_id, price_amount, price_currency, customer_email,
+	confirmed_at, refunded_at, refunded_amount, printed_at, printed_file_name,
+	receipt_issued_at, receipt_number, transferred_at`
+
+func (r *OpsBookingReadModelRepo) GetByID(ctx context.Context, id uuid.UUID) (*entities.OpsBooking, error) {
+	var booking opsBookingRow
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &booking, `
+		SELECT `+opsBookingColumns+` FROM ops_bookings WHERE booking_id = $1`, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return []entities.OpsBooking{}, nil
+			return nil, nil
 		}
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	bookings, err := r.withTickets(ctx, []opsBookingRow{booking})
+	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var bookings []entities.OpsBooking
-	for rows.Next() {
-		var payload []byte
-		if err := rows.Scan(&payload); err != nil {
-			return nil, err
-		}
+	return &bookings[0], nil
+}
 
-		booking, err := r.unmarshalReadModelFromDB(payload)
-		if err != nil {
-			return nil, err
+func (r *OpsBookingReadModelRepo) GetByTicketID(ctx context.Context, ticketID uuid.UUID) (*entities.OpsBooking, error) {
+	var bookingID uuid.NullUUID
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &bookingID, `
+		SELECT booking_id FROM ops_tickets WHERE ticket_id = $1`, ticketID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if !bookingID.Valid {
+		return nil, nil
+	}
+
+	return r.GetByID(ctx, bookingID.UUID)
+}
 
-		bookings = append(bookings, *booking)
+// GetAll returns all bookings except placeholders.
+func (r *OpsBookingReadModelRepo) GetAll(ctx context.Context) ([]entities.OpsBooking, error) {
+	var bookings []opsBookingRow
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &bookings, `
+		SELECT `+opsBookingColumns+` FROM ops_bookings
+		WHERE booked_at IS NOT NULL
+		ORDER BY booked_at, booking_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select bookings: %w", err)
 	}
 
-	return bookings, nil
+	return r.withTickets(ctx, bookings)
 }
 
 type Filters struct {
 	ReceiptIssueDate time.Time
+	CustomerEmail    string
+	ShowID           *uuid.UUID
+	// TicketStatus matches bookings with at least one ticket in the status.
+	TicketStatus entities.OpsTicketStatus
+	RefundState  entities.OpsBookingRefundState
+	BookedFrom   *time.Time
+	BookedTo     *time.Time
+	UpdatedFrom  *time.Time
+	UpdatedTo    *time.Time
 }
 
-func (r *OpsBookingReadModelRepo) GetWithFilters(ctx context.Context, filters Filters) ([]entities.OpsBooking, error) {
-	query := `
-SELECT payload FROM read_model_ops_bookings 
-	WHERE booking_id IN (
-	    SELECT booking_id FROM (
-	        SELECT booking_id, 
-	            DATE(jsonb_path_query(payload, '$.tickets.*.receipt_issued_at')::text) as receipt_issued_at 
-	        FROM 
-	            read_model_ops_bookings
-	    ) bookings_within_date 
-	    WHERE receipt_issued_at = $1)
-`
-
-	rows, err := r.db.QueryContext(ctx, query, filters.ReceiptIssueDate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
-	}
-	defer rows.Close()
+type OpsBookingsSort string
 
-	var bookings []entities.OpsBooking
-	for rows.Next() {
-		var payload []byte
-		if err := rows.Scan(&payload); err != nil {
-			return nil, err
-		}
+const (
+	OpsBookingsSortBookedAt   OpsBookingsSort = "booked_at"
+	OpsBookingsSortLastUpdate OpsBookingsSort = "last_update"
+)
 
-		booking, err := r.unmarshalReadModelFromDB(payload)
-		if err != nil {
-			return nil, err
-		}
+type OpsBookingsQuery struct {
+	Filters    Filters
+	SortBy     OpsBookingsSort
+	Descending bool
+	// Cursor is NextCursor of the previous page, empty for the first page.
+	Cursor string
+	Limit  int
+}
 
-		bookings = append(bookings, *booking)
-	}
+type OpsBookingsPage struct {
+	Bookings []entities.OpsBooking
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
 
-	return bookings, nil
+// opsTicketStatusConditions are the same rules as in OpsTicket.Status, for tickets aliased as t.
+var opsTicketStatusConditions = map[entities.OpsTicketStatus]string{
+	entities.OpsTicketStatusRefunded:  "t.refunded_at IS NOT NULL",
+	entities.OpsTicketStatusConfirmed: "t.refunded_at IS NULL AND t.confirmed_at IS NOT NULL",
+	entities.OpsTicketStatusPending:   "t.refunded_at IS NULL AND t.confirmed_at IS NULL",
 }
 
-func (r *OpsBookingReadModelRepo) OnBookingMadeEvent(ctx context.Context, event *entities.BookingMade_v1) error {
-	log.FromContext(ctx).Info("OnBookingMadeEvent, event: ", *event)
+const (
+	existsRefundedTicket    = "EXISTS (SELECT 1 FROM ops_tickets t WHERE t.booking_id = b.booking_id AND t.refunded_at IS NOT NULL)"
+	existsNotRefundedTicket = "EXISTS (SELECT 1 FROM ops_tickets t WHERE t.booking_id = b.booking_id AND t.refunded_at IS NULL)"
+)
 
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			booking := &entities.OpsBooking{
-				BookingID:  event.BookingID,
-				BookedAt:   event.BookedAt,
-				LastUpdate: time.Now().UTC(),
-				Tickets:    nil,
-			}
+// opsBookingRefundStateConditions are the same rules as in OpsBooking.RefundState.
+var opsBookingRefundStateConditions = map[entities.OpsBookingRefundState]string{
+	entities.OpsBookingRefundStateNone:    "NOT " + existsRefundedTicket,
+	entities.OpsBookingRefundStatePartial: existsRefundedTicket + " AND " + existsNotRefundedTicket,
+	entities.OpsBookingRefundStateFull:    existsRefundedTicket + " AND NOT " + existsNotRefundedTicket,
+}
 
-			payload, err := json.Marshal(booking)
-			if err != nil {
-				return err
-			}
-			res, err := r.db.ExecContext(ctx, `
-		INSERT INTO read_model_ops_bookings (booking_id, payload)
-		VALUES ($1, $2)
-		ON CONFLICT DO NOTHING
-	`, booking.BookingID, payload)
-			if err != nil {
-				return err
-			}
-			if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
-				return fmt.Errorf("booking with id %s already exists", booking.BookingID)
-			}
+// List returns a page of bookings, placeholders are not listed.
+// Pages are read with keyset pagination, so bookings added in the meantime don't shift the pages.
+// Sorted by last_update, bookings updated in the meantime move between pages: ascending they are listed
+// again on a later page, descending they are skipped when they weren't listed yet.
+func (r *OpsBookingReadModelRepo) List(ctx context.Context, query OpsBookingsQuery) (OpsBookingsPage, error) {
+	if query.SortBy != OpsBookingsSortBookedAt && query.SortBy != OpsBookingsSortLastUpdate {
+		return OpsBookingsPage{}, fmt.Errorf("unsupported sort %q", query.SortBy)
+	}
 
-			return r.eventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated{
-				Header:    entities.NewEventHeader(),
-				BookingID: booking.BookingID,
-			})
-		},
+	var (
+		conditions []string
+		args       []any
 	)
+	// every ? in the condition is replaced with the next arg
+	addCondition := func(condition string, conditionArgs ...any) {
+		for _, arg := range conditionArgs {
+			args = append(args, arg)
+			condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+		conditions = append(conditions, condition)
+	}
 
-}
+	addCondition("b.booked_at IS NOT NULL")
 
-func (r *OpsBookingReadModelRepo) OnBookingMadeV0Event(ctx context.Context, event *entities.BookingMade_v0) error {
-	log.FromContext(ctx).Info("OnBookingMadeEvent, event: ", *event)
+	f := query.Filters
+	if !f.ReceiptIssueDate.IsZero() {
+		from := time.Date(f.ReceiptIssueDate.Year(), f.ReceiptIssueDate.Month(), f.ReceiptIssueDate.Day(), 0, 0, 0, 0, time.UTC)
+		addCondition(
+			"EXISTS (SELECT 1 FROM ops_tickets t WHERE t.booking_id = b.booking_id AND t.receipt_issued_at >= ? AND t.receipt_issued_at < ?)",
+			from, from.AddDate(0, 0, 1),
+		)
+	}
+	if f.CustomerEmail != "" {
+		addCondition("EXISTS (SELECT 1 FROM ops_tickets t WHERE t.booking_id = b.booking_id AND t.customer_email = ?)", f.CustomerEmail)
+	}
+	if f.ShowID != nil {
+		addCondition("b.show_id = ?", *f.ShowID)
+	}
+	if f.TicketStatus != "" {
+		condition, ok := opsTicketStatusConditions[f.TicketStatus]
+		if !ok {
+			return OpsBookingsPage{}, fmt.Errorf("unsupported ticket status %q", f.TicketStatus)
+		}
+		addCondition("EXISTS (SELECT 1 FROM ops_tickets t WHERE t.booking_id = b.booking_id AND " + condition + ")")
+	}
+	if f.RefundState != "" {
+		condition, ok := opsBookingRefundStateConditions[f.RefundState]
+		if !ok {
+			return OpsBookingsPage{}, fmt.Errorf("unsupported refund state %q", f.RefundState)
+		}
+		addCondition(condition)
+	}
+	if f.BookedFrom != nil {
+		addCondition("b.booked_at >= ?", *f.BookedFrom)
+	}
+	if f.BookedTo != nil {
+		addCondition("b.booked_at <= ?", *f.BookedTo)
+	}
+	if f.UpdatedFrom != nil {
+		addCondition("b.last_update >= ?", *f.UpdatedFrom)
+	}
+	if f.UpdatedTo != nil {
+		addCondition("b.last_update <= ?", *f.UpdatedTo)
+	}
 
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			booking := &entities.OpsBooking{
-				BookingID:  event.BookingID,
-				BookedAt:   event.Header.PublishedAt,
-				LastUpdate: time.Now().UTC(),
-				Tickets:    nil,
-			}
+	sortColumn := "b." + string(query.SortBy)
+	comparison, order := ">", "ASC"
+	if query.Descending {
+		comparison, order = "<", "DESC"
+	}
 
-			payload, err := json.Marshal(booking)
-			if err != nil {
-				return err
-			}
-			res, err := r.db.ExecContext(ctx, `
-		INSERT INTO read_model_ops_bookings (booking_id, payload)
-		VALUES ($1, $2)
-		ON CONFLICT DO NOTHING
-	`, booking.BookingID, payload)
-			if err != nil {
-				return err
-			}
-			if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
-				return fmt.Errorf("booking with id %s already exists", booking.BookingID)
-			}
+	if query.Cursor != "" {
+		cursor, err := decodeOpsBookingsCursor(query.Cursor)
+		if err != nil {
+			return OpsBookingsPage{}, err
+		}
+		if cursor.SortBy != query.SortBy || cursor.Descending != query.Descending {
+			return OpsBookingsPage{}, fmt.Errorf("%w: cursor was created for a different sort", ErrInvalidCursor)
+		}
 
-			return r.eventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated{
-				Header:    entities.NewEventHeader(),
-				BookingID: booking.BookingID,
-			})
-		},
+		addCondition(fmt.Sprintf("(%s, b.booking_id) %s (?, ?)", sortColumn, comparison), cursor.Value, cursor.BookingID)
+	}
+
+	where := "WHERE " + strings.Join(conditions, " AND ")
+
+	// one more row tells if there is a next page
+	args = append(args, query.Limit+1)
+	sqlQuery := fmt.Sprintf(`
+		SELECT b.booking_id, b.booked_at, b.show_id, b.last_update, b.version, b.update_seq FROM ops_bookings b
+		%[2]s
+		ORDER BY %[1]s %[3]s, b.booking_id %[3]s
+		LIMIT $%[4]d`,
+		sortColumn, where, order, len(args),
 	)
 
-}
+	var rows []opsBookingRow
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, sqlQuery, args...)
+	if err != nil {
+		return OpsBookingsPage{}, fmt.Errorf("failed to execute query: %w", err)
+	}
 
-func (r *OpsBookingReadModelRepo) OnTicketBookingConfirmedEvent(ctx context.Context, event *entities.TicketBookingConfirmed_v1) error {
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByBookingID, err := r.findReadModelByBookingID(ctx, event.BookingID)
-			if err != nil {
-				return fmt.Errorf("OnTicketBookingConfirmedEvent. failed to find read model by booking BookingID: %w", err)
-			}
+	page := OpsBookingsPage{}
 
-			if findReadModelByBookingID == nil {
-				return nil
-			}
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		last := rows[len(rows)-1]
 
-			ticket := findReadModelByBookingID.Tickets[event.TicketID]
+		cursor := opsBookingsCursor{
+			SortBy:     query.SortBy,
+			Descending: query.Descending,
+			Value:      last.BookedAt.Time,
+			BookingID:  last.BookingID,
+		}
+		if query.SortBy == OpsBookingsSortLastUpdate {
+			cursor.Value = last.LastUpdate
+		}
 
-			ticket.ConfirmedAt = event.Header.PublishedAt
-			ticket.PriceAmount = event.Price.Amount
-			ticket.PriceCurrency = event.Price.Currency
-			ticket.CustomerEmail = event.CustomerEmail
+		page.NextCursor, err = encodeOpsBookingsCursor(cursor)
+		if err != nil {
+			return OpsBookingsPage{}, err
+		}
+	}
 
-			findReadModelByBookingID.Tickets[event.TicketID] = ticket
+	page.Bookings, err = r.withTickets(ctx, rows)
+	if err != nil {
+		return OpsBookingsPage{}, err
+	}
 
-			err = r.updateReadModel(ctx, findReadModelByBookingID)
-			if err != nil {
-				return fmt.Errorf("failed to update read model: %w", err)
-			}
+	return page, nil
+}
 
-			return nil
-		},
+// ListUpdatedAfter returns up to limit bookings updated after the update sequence, in the order of updates.
+// Placeholders are not listed.
+//
+// The update sequence is assigned when the update is committed (see InitializeDBSchema),
+// so bookings committed later always have a higher sequence and listing after the last seen one doesn't skip any.
+func (r *OpsBookingReadModelRepo) ListUpdatedAfter(ctx context.Context, updateSequence int64, limit int) ([]entities.OpsBooking, error) {
+	var rows []opsBookingRow
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, `
+		SELECT `+opsBookingColumns+` FROM ops_bookings
+		WHERE update_seq > $1 AND booked_at IS NOT NULL
+		ORDER BY update_seq
+		LIMIT $2`,
+		updateSequence, limit,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select updated bookings: %w", err)
+	}
+
+	return r.withTickets(ctx, rows)
 }
 
-func (r *OpsBookingReadModelRepo) OnTicketReceiptIssuedEvent(ctx context.Context, event *entities.TicketReceiptIssued_v1) error {
-	log.FromContext(ctx).Info("OnTicketReceiptIssuedEvent", "event:", *event)
+// withTickets loads tickets of all bookings with one query.
+func (r *OpsBookingReadModelRepo) withTickets(ctx context.Context, rows []opsBookingRow) ([]entities.OpsBooking, error) {
+	bookings := make([]entities.OpsBooking, 0, len(rows))
+	if len(rows) == 0 {
+		return bookings, nil
+	}
 
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByBookingID, err := r.findReadModelByBookingID(ctx, event.BookingId)
-			if err != nil {
-				return fmt.Errorf("OnTicketReceiptIssuedEvent. failed to find read model by booking BookingID: %w", err)
-			}
+	bookingIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		bookingIDs = append(bookingIDs, row.BookingID.String())
+	}
 
-			if findReadModelByBookingID == nil {
-				return nil
-			}
+	var tickets []opsTicketRow
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &tickets, `
+		SELECT `+opsTicketColumns+` FROM ops_tickets WHERE booking_id = ANY($1::uuid[])`,
+		pq.StringArray(bookingIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select tickets: %w", err)
+	}
 
-			ticket, ok := findReadModelByBookingID.Tickets[event.TicketId]
-			if !ok {
-				return fmt.Errorf("ticket with id %s not found in booking with id %s", event.TicketId, event.BookingId)
-			}
+	ticketsByBooking := map[uuid.UUID]map[string]entities.OpsTicket{}
+	for _, ticket := range tickets {
+		if ticketsByBooking[ticket.BookingID.UUID] == nil {
+			ticketsByBooking[ticket.BookingID.UUID] = map[string]entities.OpsTicket{}
+		}
+		ticketsByBooking[ticket.BookingID.UUID][ticket.TicketID.String()] = ticket.toEntity()
+	}
+
+	for _, row := range rows {
+		booking := entities.OpsBooking{
+			BookingID:      row.BookingID,
+			BookedAt:       fromNullTime(row.BookedAt),
+			LastUpdate:     row.LastUpdate.UTC(),
+			Version:        row.Version,
+			UpdateSequence: row.UpdateSeq,
+			Tickets:        ticketsByBooking[row.BookingID],
+		}
+		if row.ShowID != nil {
+			booking.ShowID = *row.ShowID
+		}
+		if booking.Tickets == nil {
+			booking.Tickets = map[string]entities.OpsTicket{}
+		}
 
-			ticket.ReceiptNumber = event.ReceiptNumber
-			ticket.ReceiptIssuedAt = event.IssuedAt
+		bookings = append(bookings, booking)
+	}
 
-			findReadModelByBookingID.Tickets[event.TicketId] = ticket
+	return bookings, nil
+}
 
-			err = r.updateReadModel(ctx, findReadModelByBookingID)
-			if err != nil {
-				return fmt.Errorf("failed to update read model: %w", err)
-			}
+func (t opsTicketRow) toEntity() entities.OpsTicket {
+	return entities.OpsTicket{
+		PriceAmount:     t.PriceAmount,
+		PriceCurrency:   t.PriceCurrency,
+		CustomerEmail:   t.CustomerEmail,
+		ConfirmedAt:     fromNullTime(t.ConfirmedAt),
+		RefundedAt:      fromNullTime(t.RefundedAt),
+		RefundedAmount:  t.RefundedAmount,
+		PrintedAt:       fromNullTime(t.PrintedAt),
+		PrintedFileName: t.PrintedFileName,
+		ReceiptIssuedAt: fromNullTime(t.ReceiptIssuedAt),
+		ReceiptNumber:   t.ReceiptNumber,
+		TransferredAt:   fromNullTime(t.TransferredAt),
+	}
+}
 
-			return nil
-		},
-	)
+func fromNullTime(t sql.NullTime) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return t.Time.UTC()
 }
 
-func (r *OpsBookingReadModelRepo) OnTicketReceiptIssuedV0Event(ctx context.Context, event *entities.TicketReceiptIssued_v0) error {
-	log.FromContext(ctx).Info("OnTicketReceiptIssuedEvent", "event:", *event)
+func (r *OpsBookingReadModelRepo) OnBookingMadeEvent(ctx context.Context, event *entities.BookingMade_v1) error {
+	log.FromContext(ctx).Info("OnBookingMadeEvent, event: ", *event)
 
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByTicketID, err := r.findReadModelByTicketID(ctx, event.TicketId)
-			if err != nil {
-				return fmt.Errorf("OnTicketReceiptIssuedEvent. failed to find read model by booking BookingID: %w", err)
-			}
+	return r.addBooking(ctx, event.BookingID, event.BookedAt, event.ShowID)
+}
 
-			ticket, ok := findReadModelByTicketID.Tickets[event.TicketId]
-			if !ok {
-				return fmt.Errorf("ticket with id %s not found", event.TicketId)
-			}
+func (r *OpsBookingReadModelRepo) OnBookingMadeV0Event(ctx context.Context, event *entities.BookingMade_v0) error {
+	log.FromContext(ctx).Info("OnBookingMadeEvent, event: ", *event)
 
-			ticket.ReceiptNumber = event.ReceiptNumber
-			ticket.ReceiptIssuedAt = event.IssuedAt
+	return r.addBooking(ctx, event.BookingID, event.Header.PublishedAt, event.ShowID)
+}
 
-			findReadModelByTicketID.Tickets[event.TicketId] = ticket
+// addBooking adds the booking or completes its placeholder created by events which arrived earlier.
+func (r *OpsBookingReadModelRepo) addBooking(ctx context.Context, bookingID uuid.UUID, bookedAt time.Time, showID uuid.UUID) error {
+	var showIDColumn *uuid.UUID
+	if showID != uuid.Nil {
+		showIDColumn = &showID
+	}
 
-			err = r.updateReadModel(ctx, findReadModelByTicketID)
-			if err != nil {
-				return fmt.Errorf("failed to update read model: %w", err)
-			}
+	return r.trManager.Do(ctx, func(ctx context.Context) error {
+		res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+			INSERT INTO ops_bookings (booking_id, booked_at, show_id, last_update, version)
+			VALUES ($1, $2, $3, $2, 1)
+			ON CONFLICT (booking_id) DO UPDATE SET
+				booked_at = EXCLUDED.booked_at,
+				show_id = EXCLUDED.show_id,
+				last_update = GREATEST(ops_bookings.last_update, EXCLUDED.last_update),
+				version = ops_bookings.version + 1
+			WHERE ops_bookings.booked_at IS NULL`,
+			bookingID, bookedAt, showIDColumn,
+		)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+			return fmt.Errorf("booking with id %s already exists", bookingID)
+		}
 
-			return nil
-		},
-	)
+		return r.publishUpdated(ctx, bookingID)
+	})
 }
 
-func (r *OpsBookingReadModelRepo) OnTicketPrintedEvent(ctx context.Context, event *entities.TicketPrinted_v1) error {
-	log.FromContext(ctx).Info("OnTicketPrintedEvent, ticketID:", event.TicketID, ", bookingID:", event.BookingID)
+func (r *OpsBookingReadModelRepo) OnTicketBookingConfirmedEvent(ctx context.Context, event *entities.TicketBookingConfirmed_v1) error {
+	return r.confirmTicket(
+		ctx,
+		event.BookingID,
+		event.TicketID,
+		event.Price,
+		event.CustomerEmail,
+		event.Header.PublishedAt,
+	)
+}
 
-	return r.trManager.DoWithSettings(
+func (r *OpsBookingReadModelRepo) OnTicketBookingConfirmedV0Event(ctx context.Context, event *entities.TicketBookingConfirmed_v0) error {
+	return r.confirmTicket(
 		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByTicketID, err := r.findReadModelByBookingID(ctx, event.BookingID)
-			if err != nil {
-				log.FromContext(ctx).Error("OnTicketPrintedEvent. failed to find read model by booking BookingID: ", event.BookingID, " error:", err)
-				return fmt.Errorf("OnTicketPrintedEvent. failed to find read model by booking BookingID: %w", err)
-			}
+		event.BookingId,
+		event.TicketId,
+		event.Price,
+		event.CustomerEmail,
+		event.Header.PublishedAt,
+	)
+}
 
-			if findReadModelByTicketID == nil {
-				return nil
-			}
+func (r *OpsBookingReadModelRepo) confirmTicket(
+	ctx context.Context,
+	bookingID string,
+	ticketID string,
+	price entities.Money,
+	customerEmail string,
+	confirmedAt time.Time,
+) error {
+	return r.applyTicketUpdate(ctx, opsTicketUpdate{
+		TicketID:      ticketID,
+		BookingID:     bookingID,
+		VersionColumn: "confirmed_at",
+		Columns: []opsTicketColumn{
+			{Name: "confirmed_at", Value: confirmedAt},
+			{Name: "price_amount", Value: price.AmountString()},
+			{Name: "price_currency", Value: price.Currency},
+			{
+				Name:  "customer_email",
+				Value: customerEmail,
+				// the ticket was already transferred to someone else
+				Merge: "CASE WHEN ops_tickets.transferred_at IS NULL THEN EXCLUDED.customer_email ELSE ops_tickets.customer_email END",
+			},
+			{
+				Name:  "refunded_amount",
+				Value: "",
+				// the ticket was refunded in full before the price was known
+				Merge: "CASE WHEN ops_tickets.refunded_at IS NOT NULL AND ops_tickets.refunded_amount = '' THEN EXCLUDED.price_amount ELSE ops_tickets.refunded_amount END",
+			},
+		},
+		UpdatedAt: confirmedAt,
+	})
+}
 
-			ticket, ok := findReadModelByTicketID.Tickets[event.TicketID]
-			if !ok {
-				log.FromContext(ctx).Error("ticket with id not found in booking", "ticketID:", event.TicketID, "bookingID:", event.BookingID)
-				return fmt.Errorf("ticket with id %s not found in booking with id %s", event.TicketID, event.BookingID)
-			}
+func (r *OpsBookingReadModelRepo) OnTicketBookingCanceledV0Event(ctx context.Context, event *entities.TicketBookingCanceled_v0) error {
+	return r.applyTicketUpdate(ctx, opsTicketUpdate{
+		TicketID:  event.TicketId,
+		BookingID: event.BookingId,
+		UpdatedAt: event.Header.PublishedAt,
+	})
+}
 
-			ticket.PrintedAt = event.PrintedAt
-			ticket.PrintedFileName = event.FileName
+func (r *OpsBookingReadModelRepo) OnTicketReceiptIssuedEvent(ctx context.Context, event *entities.TicketReceiptIssued_v1) error {
+	log.FromContext(ctx).Info("OnTicketReceiptIssuedEvent", "event:", *event)
 
-			findReadModelByTicketID.Tickets[event.TicketID] = ticket
+	return r.issueReceipt(ctx, event.BookingId, event.TicketId, event.ReceiptNumber, event.IssuedAt)
+}
 
-			err = r.updateReadModel(ctx, findReadModelByTicketID)
-			if err != nil {
-				log.FromContext(ctx).Error("failed to update read model", "error:", err)
-				return fmt.Errorf("failed to update read model: %w", err)
-			}
+func (r *OpsBookingReadModelRepo) OnTicketReceiptIssuedV0Event(ctx context.Context, event *entities.TicketReceiptIssued_v0) error {
+	log.FromContext(ctx).Info("OnTicketReceiptIssuedEvent", "event:", *event)
 
-			return nil
-		},
-	)
+	return r.issueReceipt(ctx, "", event.TicketId, event.ReceiptNumber, event.IssuedAt)
 }
 
-func (r *OpsBookingReadModelRepo) OnTicketPrintedV0Event(ctx context.Context, event *entities.TicketPrinted_v0) error {
-	log.FromContext(ctx).Info("OnTicketPrintedV0Event, ticketID:", event.TicketID)
-
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByTicketID, err := r.findReadModelByTicketID(ctx, event.TicketID)
-			if err != nil {
-				log.FromContext(ctx).Error("OnTicketPrintedEvent. failed to find read model by ticket BookingID: ", event.TicketID, ", ", " error:", err)
-				return fmt.Errorf("OnTicketPrintedEvent. failed to find read model by booking BookingID: %w", err)
-			}
+func (r *OpsBookingReadModelRepo) issueReceipt(ctx context.Context, bookingID string, ticketID string, receiptNumber string, issuedAt time.Time) error {
+	return r.applyTicketUpdate(ctx, opsTicketUpdate{
+		TicketID:      ticketID,
+		BookingID:     bookingID,
+		VersionColumn: "receipt_issued_at",
+		Columns: []opsTicketColumn{
+			{Name: "receipt_issued_at", Value: issuedAt},
+			{Name: "receipt_number", Value: receiptNumber},
+		},
+		UpdatedAt: issuedAt,
+	})
+}
 
-			ticket, ok := findReadModelByTicketID.Tickets[event.TicketID]
-			if !ok {
-				log.FromContext(ctx).Error("ticket with id not found in booking", "ticketID:", event.TicketID)
-				return fmt.Errorf("ticket with id %s not found", event.TicketID)
-			}
+func (r *OpsBookingReadModelRepo) OnTicketPrintedEvent(ctx context.Context, event *entities.TicketPrinted_v1) error {
+	log.FromContext(ctx).Info("OnTicketPrintedEvent, ticketID:", event.TicketID, ", bookingID:", event.BookingID)
 
-			ticket.PrintedAt = event.Header.PublishedAt
-			ticket.PrintedFileName = event.FileName
+	return r.printTicket(ctx, event.BookingID, event.TicketID, event.FileName, event.PrintedAt)
+}
 
-			findReadModelByTicketID.Tickets[event.TicketID] = ticket
+func (r *OpsBookingReadModelRepo) OnTicketPrintedV0Event(ctx context.Context, event *entities.TicketPrinted_v0) error {
+	log.FromContext(ctx).Info("OnTicketPrintedV0Event, ticketID:", event.TicketID)
 
-			err = r.updateReadModel(ctx, findReadModelByTicketID)
-			if err != nil {
-				log.FromContext(ctx).Error("failed to update read model", "error:", err)
-				return fmt.Errorf("failed to update read model: %w", err)
-			}
+	return r.printTicket(ctx, "", event.TicketID, event.FileName, event.Header.PublishedAt)
+}
 
-			return nil
+func (r *OpsBookingReadModelRepo) printTicket(ctx context.Context, bookingID string, ticketID string, fileName string, printedAt time.Time) error {
+	return r.applyTicketUpdate(ctx, opsTicketUpdate{
+		TicketID:      ticketID,
+		BookingID:     bookingID,
+		VersionColumn: "printed_at",
+		Columns: []opsTicketColumn{
+			{Name: "printed_at", Value: printedAt},
+			{Name: "printed_file_name", Value: fileName},
 		},
-	)
+		UpdatedAt: printedAt,
+	})
 }
 
 func (r *OpsBookingReadModelRepo) OnTicketRefundedEvent(ctx context.Context, event *entities.TicketRefunded_v1) error {
 	log.FromContext(ctx).Info("OnTicketRefundedEvent", "event:", *event)
 
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByTicketID, err := r.findReadModelByTicketID(ctx, event.TicketID)
-			if err != nil {
-				return fmt.Errorf("failed to find read model by ticket BookingID: %w", err)
-			}
+	refundedAmount := ""
+	if event.RefundedAmount != nil {
+		refundedAmount = event.RefundedAmount.AmountString()
+	}
 
-			ticket, ok := findReadModelByTicketID.Tickets[event.TicketID]
-			if !ok {
-				return fmt.Errorf("ticket with id %s not found in booking with id %s", event.TicketID, findReadModelByTicketID.BookingID)
-			}
+	return r.refundTicket(ctx, event.TicketID, refundedAmount, event.Header.PublishedAt)
+}
 
-			ticket.ConfirmedAt = event.Header.PublishedAt
-			findReadModelByTicketID.Tickets[event.TicketID] = ticket
+func (r *OpsBookingReadModelRepo) OnTicketRefundedV0Event(ctx context.Context, event *entities.TicketRefunded_v0) error {
+	log.FromContext(ctx).Info("OnTicketRefundedV0Event", "event:", *event)
 
-			err = r.updateReadModel(ctx, findReadModelByTicketID)
-			if err != nil {
-				return fmt.Errorf("failed to update read model: %w", err)
-			}
+	// partial refunds didn't exist yet
+	return r.refundTicket(ctx, event.TicketID, "", event.Header.PublishedAt)
+}
 
-			return nil
+// refundTicket refunds the price of the ticket when refundedAmount is empty.
+// Until the ticket is confirmed the price is not known, confirmTicket fills it in then.
+func (r *OpsBookingReadModelRepo) refundTicket(ctx context.Context, ticketID string, refundedAmount string, refundedAt time.Time) error {
+	return r.applyTicketUpdate(ctx, opsTicketUpdate{
+		TicketID:      ticketID,
+		VersionColumn: "refunded_at",
+		Columns: []opsTicketColumn{
+			{Name: "refunded_at", Value: refundedAt},
+			{
+				Name:  "refunded_amount",
+				Value: refundedAmount,
+				Merge: "COALESCE(NULLIF(EXCLUDED.refunded_amount, ''), ops_tickets.price_amount)",
+			},
 		},
-	)
+		UpdatedAt: refundedAt,
+	})
 }
 
-func (r *OpsBookingReadModelRepo) findReadModelByBookingID(
-	ctx context.Context,
-	bookingID string,
-) (*entities.OpsBooking, error) {
-	id, err := uuid.Parse(bookingID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse BookingID: %w", err)
-	}
-
-	var payload []byte
+func (r *OpsBookingReadModelRepo) OnTicketTransferredEvent(ctx context.Context, event *entities.TicketTransferred_v1) error {
+	log.FromContext(ctx).Info("OnTicketTransferredEvent, ticketID:", event.TicketID, ", bookingID:", event.BookingID)
 
-	err = r.getter.DefaultTrOrDB(ctx, r.db).QueryRowContext(
-		ctx,
-		"SELECT payload FROM read_model_ops_bookings WHERE booking_id = $1",
-		id,
-	).Scan(&payload)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	if payload == nil {
-		return nil, fmt.Errorf("booking with id %s not found", bookingID)
-	}
-
-	return r.unmarshalReadModelFromDB(payload)
+	return r.applyTicketUpdate(ctx, opsTicketUpdate{
+		TicketID:      event.TicketID,
+		BookingID:     event.BookingID,
+		VersionColumn: "transferred_at",
+		Columns: []opsTicketColumn{
+			{Name: "transferred_at", Value: event.TransferredAt},
+			{Name: "customer_email", Value: event.ToEmail},
+		},
+		UpdatedAt: event.TransferredAt,
+	})
 }
 
-func (r *OpsBookingReadModelRepo) findReadModelByTicketID(
-	ctx context.Context,
-	ticketID string,
-) (*entities.OpsBooking, error) {
-	id, err := uuid.Parse(ticketID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse BookingID: %w", err)
-	}
+// opsTicketUpdate sets the columns of the ticket, the ticket is added when it's not in the read model yet.
+type opsTicketUpdate struct {
+	TicketID string
+	// BookingID is empty for events which don't have it, the ticket is linked to the booking by a later event.
+	BookingID string
 
-	var payload []byte
+	// VersionColumn is the column with the time of the event which set Columns.
+	// The update is stale and ignored when the ticket already has the same or a later time there.
+	// Empty VersionColumn only adds the ticket.
+	VersionColumn string
+	Columns       []opsTicketColumn
 
-	err = r.getter.DefaultTrOrDB(ctx, r.db).QueryRowContext(
-		ctx,
-		"SELECT payload FROM read_model_ops_bookings WHERE payload::jsonb -> 'tickets' ? $1",
-		id,
-	).Scan(&payload)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, err
-	}
+	// UpdatedAt is the time of the event, last_update of the booking is moved to it unless it's later already.
+	UpdatedAt time.Time
+}
 
-	return r.unmarshalReadModelFromDB(payload)
+type opsTicketColumn struct {
+	Name  string
+	Value any
+	// Merge sets the column of an existing ticket, EXCLUDED.<Name> when empty.
+	Merge string
 }
 
-func (r *OpsBookingReadModelRepo) updateReadModel(ctx context.Context, readModel *entities.OpsBooking) error {
-	payload, err := r.marshalReadModelToDB(readModel)
+func (r *OpsBookingReadModelRepo) applyTicketUpdate(ctx context.Context, update opsTicketUpdate) error {
+	ticketID, err := uuid.Parse(update.TicketID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to parse TicketID: %w", err)
 	}
 
-	_, err = r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(
-		ctx,
-		"UPDATE read_model_ops_bookings SET payload = $1 WHERE booking_id = $2",
-		payload,
-		readModel.BookingID,
-	)
-	if err != nil {
-		return err
+	var bookingID uuid.NullUUID
+	if update.BookingID != "" {
+		bookingID.UUID, err = uuid.Parse(update.BookingID)
+		if err != nil {
+			return fmt.Errorf("failed to parse BookingID: %w", err)
+		}
+		bookingID.Valid = true
 	}
 
-	return r.eventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated{
-		Header:    entities.NewEventHeader(),
-		BookingID: readModel.BookingID,
-	})
-}
+	columns := []string{"ticket_id", "booking_id"}
+	values := []string{"$1", "$2"}
+	args := []any{ticketID, bookingID}
+	set := []string{"booking_id = COALESCE(ops_tickets.booking_id, EXCLUDED.booking_id)"}
 
-func (r *OpsBookingReadModelRepo) marshalReadModelToDB(readModel *entities.OpsBooking) ([]byte, error) {
-	return json.Marshal(readModel)
-}
+	for _, column := range update.Columns {
+		args = append(args, column.Value)
+		columns = append(columns, column.Name)
+		values = append(values, fmt.Sprintf("$%d", len(args)))
 
-func (r *OpsBookingReadModelRepo) unmarshalReadModelFromDB(payload []byte) (*entities.OpsBooking, error) {
-	var dbReadModel entities.OpsBooking
-	if err := json.Unmarshal(payload, &dbReadModel); err != nil {
-		return nil, err
+		merge := column.Merge
+		if merge == "" {
+			merge = "EXCLUDED." + column.Name
+		}
+		set = append(set, column.Name+" = "+merge)
 	}
 
-	if dbReadModel.Tickets == nil {
-		dbReadModel.Tickets = map[string]entities.OpsTicket{}
+	where := ""
+	if update.VersionColumn != "" {
+		where = fmt.Sprintf("WHERE ops_tickets.%[1]s IS NULL OR ops_tickets.%[1]s < EXCLUDED.%[1]s", update.VersionColumn)
 	}
 
-	return &dbReadModel, nil
-}
-
-func (r *OpsBookingReadModelRepo) OnTicketBookingConfirmedV0Event(ctx context.Context, event *entities.TicketBookingConfirmed_v0) error {
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByBookingID, err := r.findReadModelByBookingID(ctx, event.BookingId)
-			if err != nil {
-				return fmt.Errorf("OnTicketBookingConfirmedV0Event. failed to find read model by booking BookingID: %w", err)
-			}
-
-			ticket := findReadModelByBookingID.Tickets[event.TicketId]
-
-			ticket.ConfirmedAt = event.Header.PublishedAt
-			ticket.PriceAmount = event.Price.Amount
-			ticket.PriceCurrency = event.Price.Currency
-			ticket.CustomerEmail = event.CustomerEmail
-
-			findReadModelByBookingID.Tickets[event.TicketId] = ticket
-
-			err = r.updateReadModel(ctx, findReadModelByBookingID)
-			if err != nil {
-				return fmt.Errorf("failed to update read model: %w", err)
-			}
-
-			return nil
-		},
+	query := fmt.Sprintf(`
+		INSERT INTO ops_tickets (%s) VALUES (%s)
+		ON CONFLICT (ticket_id) DO UPDATE SET %s
+		%s
+		RETURNING booking_id`,
+		strings.Join(columns, ", "), strings.Join(values, ", "), strings.Join(set, ", "), where,
 	)
-}
-
-func (r *OpsBookingReadModelRepo) OnTicketBookingCanceledV0Event(ctx context.Context, event *entities.TicketBookingCanceled_v0) error {
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByBookingID, err := r.findReadModelByBookingID(ctx, event.BookingId)
-			if err != nil {
-				return fmt.Errorf("OnTicketBookingCanceledV0Event. failed to find read model by booking BookingID: %w", err)
-			}
-
-			ticket := findReadModelByBookingID.Tickets[event.TicketId]
-
-			findReadModelByBookingID.Tickets[event.TicketId] = ticket
 
-			err = r.updateReadModel(ctx, findReadModelByBookingID)
+	return r.trManager.Do(ctx, func(ctx context.Context) error {
+		if bookingID.Valid {
+			// tickets reference the booking
+			_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+				INSERT INTO ops_bookings (booking_id, last_update) VALUES ($1, $2)
+				ON CONFLICT DO NOTHING`,
+				bookingID.UUID, update.UpdatedAt,
+			)
 			if err != nil {
-				return fmt.Errorf("failed to update read model: %w", err)
+				return fmt.Errorf("failed to add booking placeholder: %w", err)
 			}
+		}
 
+		var ticketBookingID uuid.NullUUID
+		err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &ticketBookingID, query, args...)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.FromContext(ctx).Info("Ignoring stale update of ticket ", update.TicketID, ", ", update.VersionColumn, ": ", update.UpdatedAt)
 			return nil
-		},
-	)
-}
-
-func (r *OpsBookingReadModelRepo) OnTicketRefundedV0Event(ctx context.Context, event *entities.TicketRefunded_v0) error {
-	log.FromContext(ctx).Info("OnTicketRefundedV0Event", "event:", *event)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to upsert ticket: %w", err)
+		}
 
-	return r.trManager.DoWithSettings(
-		ctx,
-		trmsql.MustSettings(
-			settings.Must(settings.WithCancelable(true)),
-			trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}),
-		),
-		func(ctx context.Context) error {
-			findReadModelByTicketID, err := r.findReadModelByTicketID(ctx, event.TicketID)
-			if err != nil {
-				return fmt.Errorf("failed to find read model by ticket BookingID: %w", err)
-			}
+		if !ticketBookingID.Valid {
+			// the booking is updated when the ticket is linked to it
+			return nil
+		}
 
-			ticket, ok := findReadModelByTicketID.Tickets[event.TicketID]
-			if !ok {
-				return fmt.Errorf("ticket with id %s not found", event.TicketID)
-			}
+		return r.touchBooking(ctx, ticketBookingID.UUID, update.UpdatedAt)
+	})
+}
 
-			ticket.RefundedAt = event.Header.PublishedAt
-			findReadModelByTicketID.Tickets[event.TicketID] = ticket
+func (r *OpsBookingReadModelRepo) touchBooking(ctx context.Context, bookingID uuid.UUID, updatedAt time.Time) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE ops_bookings SET
+			last_update = GREATEST(last_update, $1),
+			version = version + 1
+		WHERE booking_id = $2`,
+		updatedAt, bookingID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update booking: %w", err)
+	}
 
-			err = r.updateReadModel(ctx, findReadModelByTicketID)
-			if err != nil {
-				return fmt.Errorf("failed to update read model: %w", err)
-			}
+	return r.publishUpdated(ctx, bookingID)
+}
 
-			return nil
-		},
-	)
+func (r *OpsBookingReadModelRepo) publishUpdated(ctx context.Context, bookingID uuid.UUID) error {
+	return r.eventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated{
+		Header:    entities.NewEventHeader(),
+		BookingID: bookingID,
+	})
 }
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = fmt.Errorf("invalid cursor")
+
+// opsBookingsCursor points to the last booking of the page, the next page starts after it.
+type opsBookingsCursor struct {
+	SortBy     OpsBookingsSort `json:"s"`
+	Descending bool            `json:"d"`
+	Value      time.Time       `json:"v"`
+	BookingID  uuid.UUID       `json:"id"`
+}
+
+func encodeOpsBookingsCursor(cursor opsBookingsCursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("marshal cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+func decodeOpsBookingsCursor(encoded string) (opsBookingsCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return opsBookingsCursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	var cursor opsBookingsCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return opsBookingsCursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+)
+
+type refundDecision struct {
+	DecisionID     uuid.UUID       `db:"decision_id"`
+	TicketID       string          `db:"ticket_id"`
+	Initiator      string          `db:"initiator"`
+	Outcome        string          `db:"outcome"`
+	Percent        int             `db:"percent"`
+	PriceAmount    decimal.Decimal `db:"price_amount"`
+	RefundedAmount decimal.Decimal `db:"refunded_amount"`
+	Currency       string          `db:"currency"`
+	Rule           string          `db:"rule"`
+	ShowStartTime  *time.Time      `db:"show_start_time"`
+	RequestedAt    time.Time       `db:"requested_at"`
+}
+
+var ErrRefundDecisionNotFound = errors.New("refund decision not found")
+
+type RefundDecisionsRepo struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewRefundDecisionsRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+) *RefundDecisionsRepo {
+	return &RefundDecisionsRepo{
+		db:     db,
+		getter: getter,
+	}
+}
+
+// Add stores the decision, unless a decision with the same idempotency key was stored before.
+// The stored decision is returned, so retried refund requests are never re-evaluated.
+func (r *RefundDecisionsRepo) Add(
+	ctx context.Context,
+	decision entities.RefundDecision,
+	idempotencyKey string,
+) (entities.RefundDecision, error) {
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO refund_decisions (
+			decision_id, idempotency_key, ticket_id, initiator, outcome, percent,
+			price_amount, refunded_amount, currency, rule, show_start_time, requested_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		)
+		ON CONFLICT (idempotency_key) DO NOTHING`,
+		decision.DecisionID,
+		idempotencyKey,
+		decision.TicketID,
+		decision.Initiator,
+		decision.Outcome,
+		decision.Percent,
+		decision.Price.Amount,
+		decision.RefundedAmount.Amount,
+		decision.Price.Currency,
+		decision.Rule,
+		decision.ShowStartTime,
+		decision.RequestedAt,
+	)
+	if err != nil {
+		return entities.RefundDecision{}, fmt.Errorf("insert refund decision: %w", err)
+	}
+
+	var stored refundDecision
+	err = db.GetContext(ctx, &stored, `
+		SELECT decision_id, ticket_id, initiator, outcome, percent,
+			price_amount, refunded_amount, currency, rule, show_start_time, requested_at
+		FROM refund_decisions
+		WHERE idempotency_key = $1`, idempotencyKey)
+	if err != nil {
+		return entities.RefundDecision{}, fmt.Errorf("get refund decision: %w", err)
+	}
+
+	return stored.toEntity(), nil
+}
+
+// GetByIdempotencyKey returns the decision stored by Add with the idempotency key.
+func (r *RefundDecisionsRepo) GetByIdempotencyKey(ctx context.Context, idempotencyKey string) (entities.RefundDecision, error) {
+	var stored refundDecision
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &stored, `
+		SELECT decision_id, ticket_id, initiator, outcome, percent,
+			price_amount, refunded_amount, currency, rule, show_start_time, requested_at
+		FROM refund_decisions
+		WHERE idempotency_key = $1`, idempotencyKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.RefundDecision{}, ErrRefundDecisionNotFound
+		}
+		return entities.RefundDecision{}, fmt.Errorf("get refund decision: %w", err)
+	}
+
+	return stored.toEntity(), nil
+}
+
+// GetByTicketID returns all decisions made for the ticket, the oldest first.
+func (r *RefundDecisionsRepo) GetByTicketID(ctx context.Context, ticketID uuid.UUID) ([]entities.RefundDecision, error) {
+	var decisions []refundDecision
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &decisions, `
+		SELECT decision_id, ticket_id, initiator, outcome, percent,
+			price_amount, refunded_amount, currency, rule, show_start_time, requested_at
+		FROM refund_decisions
+		WHERE ticket_id = $1
+		ORDER BY decided_at, decision_id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("select refund decisions: %w", err)
+	}
+
+	result := make([]entities.RefundDecision, 0, len(decisions))
+	for _, d := range decisions {
+		result = append(result, d.toEntity())
+	}
+
+	return result, nil
+}
+
+func (d refundDecision) toEntity() entities.RefundDecision {
+	return entities.RefundDecision{
+		DecisionID:     d.DecisionID,
+		TicketID:       d.TicketID,
+		Initiator:      entities.RefundInitiator(d.Initiator),
+		Outcome:        entities.RefundOutcome(d.Outcome),
+		Percent:        d.Percent,
+		Price:          entities.Money{Amount: d.PriceAmount, Currency: d.Currency},
+		RefundedAmount: entities.Money{Amount: d.RefundedAmount, Currency: d.Currency},
+		Rule:           d.Rule,
+		ShowStartTime:  d.ShowStartTime,
+		RequestedAt:    d.RequestedAt,
+	}
+}
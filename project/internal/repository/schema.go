@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
 	"github.com/jmoiron/sqlx"
+	"tickets/internal/entities"
 )
 
 func InitializeDBSchema(db *sqlx.DB) error {
@@ -47,6 +48,14 @@ CREATE TABLE IF NOT EXISTS bookings (
 		return fmt.Errorf("failed to create bookings table: %w", err)
 	}
 
+	_, err = db.ExecContext(context.Background(), `
+ALTER TABLE bookings
+ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT '';
+`)
+	if err != nil {
+		return fmt.Errorf("failed to add locale column to bookings table: %w", err)
+	}
+
 	_, err = db.ExecContext(context.Background(), `
 CREATE TABLE IF NOT EXISTS read_model_ops_bookings (
 	booking_id UUID PRIMARY KEY,
@@ -56,6 +65,132 @@ CREATE TABLE IF NOT EXISTS read_model_ops_bookings (
 		return fmt.Errorf("failed to create read_model_ops_bookings table: %w", err)
 	}
 
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS ops_bookings (
+	booking_id UUID PRIMARY KEY,
+	booked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	show_id UUID DEFAULT NULL,
+	last_update TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ops_bookings_booked_at_idx ON ops_bookings (booked_at, booking_id);
+CREATE INDEX IF NOT EXISTS ops_bookings_last_update_idx ON ops_bookings (last_update, booking_id);
+CREATE INDEX IF NOT EXISTS ops_bookings_show_id_idx ON ops_bookings (show_id);
+
+CREATE TABLE IF NOT EXISTS ops_tickets (
+	ticket_id UUID PRIMARY KEY,
+	booking_id UUID NOT NULL REFERENCES ops_bookings (booking_id),
+	price_amount VARCHAR(32) NOT NULL DEFAULT '',
+	price_currency VARCHAR(3) NOT NULL DEFAULT '',
+	customer_email VARCHAR(255) NOT NULL DEFAULT '',
+	confirmed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+	refunded_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+	refunded_amount VARCHAR(32) NOT NULL DEFAULT '',
+	printed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+	printed_file_name VARCHAR(255) NOT NULL DEFAULT '',
+	receipt_issued_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+	receipt_number VARCHAR(255) NOT NULL DEFAULT '',
+	transferred_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ops_tickets_booking_id_idx ON ops_tickets (booking_id);
+CREATE INDEX IF NOT EXISTS ops_tickets_customer_email_idx ON ops_tickets (customer_email);
+CREATE INDEX IF NOT EXISTS ops_tickets_receipt_issued_at_idx ON ops_tickets (receipt_issued_at);
+CREATE INDEX IF NOT EXISTS ops_tickets_refunded_idx ON ops_tickets (booking_id) WHERE refunded_at IS NOT NULL;
+`)
+	if err != nil {
+		return fmt.Errorf("create ops_bookings and ops_tickets tables: %w", err)
+	}
+
+	// the read model was stored as JSONB documents in read_model_ops_bookings before,
+	// zero times were stored as "0001-01-01T00:00:00Z"
+	_, err = db.ExecContext(context.Background(), `
+INSERT INTO ops_bookings (booking_id, booked_at, show_id, last_update)
+SELECT
+	booking_id,
+	(payload->>'booked_at')::timestamptz,
+	NULLIF(payload->>'show_id', '00000000-0000-0000-0000-000000000000')::uuid,
+	(payload->>'last_update')::timestamptz
+FROM read_model_ops_bookings
+ON CONFLICT DO NOTHING;
+
+INSERT INTO ops_tickets (
+	ticket_id, booking_id, price_amount, price_currency, customer_email,
+	confirmed_at, refunded_at, refunded_amount, printed_at, printed_file_name,
+	receipt_issued_at, receipt_number, transferred_at
+)
+SELECT
+	t.key::uuid,
+	b.booking_id,
+	COALESCE(t.value->>'price_amount', ''),
+	COALESCE(t.value->>'price_currency', ''),
+	COALESCE(t.value->>'customer_email', ''),
+	NULLIF(t.value->>'confirmed_at', '0001-01-01T00:00:00Z')::timestamptz,
+	NULLIF(t.value->>'refunded_at', '0001-01-01T00:00:00Z')::timestamptz,
+	COALESCE(t.value->>'refunded_amount', ''),
+	NULLIF(t.value->>'printed_at', '0001-01-01T00:00:00Z')::timestamptz,
+	COALESCE(t.value->>'printed_file_name', ''),
+	NULLIF(t.value->>'receipt_issued_at', '0001-01-01T00:00:00Z')::timestamptz,
+	COALESCE(t.value->>'receipt_number', ''),
+	NULLIF(t.value->>'transferred_at', '0001-01-01T00:00:00Z')::timestamptz
+FROM read_model_ops_bookings b,
+	jsonb_each(COALESCE(NULLIF(b.payload->'tickets', 'null'::jsonb), '{}'::jsonb)) t
+ON CONFLICT DO NOTHING;
+`)
+	if err != nil {
+		return fmt.Errorf("failed to migrate read_model_ops_bookings to ops_bookings: %w", err)
+	}
+
+	// events can arrive in any order, bookings without booked_at and tickets without booking_id are placeholders
+	// waiting for BookingMade_v1 and TicketBookingConfirmed_v1
+	_, err = db.ExecContext(context.Background(), `
+ALTER TABLE ops_bookings
+ALTER COLUMN booked_at DROP NOT NULL,
+ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE ops_tickets
+ALTER COLUMN booking_id DROP NOT NULL;
+`)
+	if err != nil {
+		return fmt.Errorf("failed to add placeholders to ops_bookings and ops_tickets tables: %w", err)
+	}
+
+	// update_seq orders the updates of all bookings, the default backfills existing bookings
+	_, err = db.ExecContext(context.Background(), `
+CREATE SEQUENCE IF NOT EXISTS ops_bookings_update_seq;
+
+ALTER TABLE ops_bookings
+ADD COLUMN IF NOT EXISTS update_seq BIGINT NOT NULL DEFAULT nextval('ops_bookings_update_seq');
+
+CREATE INDEX IF NOT EXISTS ops_bookings_update_seq_idx ON ops_bookings (update_seq);
+`)
+	if err != nil {
+		return fmt.Errorf("failed to add update_seq column to ops_bookings table: %w", err)
+	}
+
+	// update_seq is assigned when the transaction commits, the commits are serialized by the advisory lock,
+	// so the sequence grows in the order of commits: streams resumed after an update never miss
+	// an update of a transaction which took the sequence earlier but committed later
+	_, err = db.ExecContext(context.Background(), `
+CREATE OR REPLACE FUNCTION ops_bookings_assign_update_seq() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_advisory_xact_lock(hashtext('ops_bookings_update_seq'));
+	UPDATE ops_bookings SET update_seq = nextval('ops_bookings_update_seq') WHERE booking_id = NEW.booking_id;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ops_bookings_update_seq_trigger ON ops_bookings;
+
+CREATE CONSTRAINT TRIGGER ops_bookings_update_seq_trigger
+AFTER INSERT OR UPDATE OF version ON ops_bookings
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION ops_bookings_assign_update_seq();
+`)
+	if err != nil {
+		return fmt.Errorf("failed to add update_seq trigger to ops_bookings table: %w", err)
+	}
+
 	_, err = db.ExecContext(context.Background(), `
 ALTER TABLE tickets
 ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP DEFAULT NULL;
@@ -64,6 +199,139 @@ ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP DEFAULT NULL;
 		return fmt.Errorf("failed to add deleted_at column to tickets table: %w", err)
 	}
 
+	_, err = db.ExecContext(context.Background(), `
+ALTER TABLE tickets
+ADD COLUMN IF NOT EXISTS booking_id UUID DEFAULT NULL,
+ADD COLUMN IF NOT EXISTS show_id UUID DEFAULT NULL,
+ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'confirmed',
+ADD COLUMN IF NOT EXISTS status_updated_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS tickets_booking_id_idx ON tickets (booking_id);
+CREATE INDEX IF NOT EXISTS tickets_show_id_idx ON tickets (show_id);
+CREATE INDEX IF NOT EXISTS tickets_status_idx ON tickets (status);
+
+-- tickets removed before the status was introduced
+UPDATE tickets SET status = 'cancelled', status_updated_at = deleted_at
+WHERE deleted_at IS NOT NULL AND status = 'confirmed';
+`)
+	if err != nil {
+		return fmt.Errorf("failed to add lifecycle columns to tickets table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS ticket_status_history (
+	ticket_id UUID NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (ticket_id, status)
+);`)
+	if err != nil {
+		return fmt.Errorf("create ticket_status_history table: %w", err)
+	}
+
+	// prices used to be stored without the currency
+	_, err = db.ExecContext(context.Background(), `
+UPDATE tickets SET price_currency = $1 WHERE price_currency = ''
+`, entities.DefaultCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to set default currency of tickets: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+ALTER TABLE tickets
+ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+`)
+	if err != nil {
+		return fmt.Errorf("failed to add token_version column to tickets table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS ticket_transfers (
+	transfer_id UUID PRIMARY KEY,
+	ticket_id UUID NOT NULL,
+	from_email VARCHAR(255) NOT NULL,
+	to_email VARCHAR(255) NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	requires_acceptance BOOLEAN NOT NULL,
+	requester_ip VARCHAR(64) NOT NULL DEFAULT '',
+	requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	resolved_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ticket_transfers_ticket_id_idx ON ticket_transfers (ticket_id);
+CREATE INDEX IF NOT EXISTS ticket_transfers_to_email_idx ON ticket_transfers (to_email);
+
+-- only one transfer of the ticket can wait for acceptance
+CREATE UNIQUE INDEX IF NOT EXISTS ticket_transfers_pending_idx ON ticket_transfers (ticket_id)
+WHERE status = 'pending';
+`)
+	if err != nil {
+		return fmt.Errorf("create ticket_transfers table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS refund_decisions (
+	decision_id UUID PRIMARY KEY,
+	idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+	ticket_id UUID NOT NULL,
+	initiator VARCHAR(32) NOT NULL,
+	outcome VARCHAR(32) NOT NULL,
+	percent INTEGER NOT NULL,
+	price_amount NUMERIC(10, 2) NOT NULL,
+	refunded_amount NUMERIC(10, 2) NOT NULL,
+	currency CHAR(3) NOT NULL,
+	rule VARCHAR(255) NOT NULL,
+	show_start_time TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+	requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	decided_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS refund_decisions_ticket_id_idx ON refund_decisions (ticket_id);
+`)
+	if err != nil {
+		return fmt.Errorf("create refund_decisions table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS commands (
+	command_id UUID PRIMARY KEY,
+	command_name VARCHAR(255) NOT NULL,
+	status VARCHAR(32) NOT NULL,
+	failure_reason TEXT NOT NULL DEFAULT '',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	accepted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);`)
+	if err != nil {
+		return fmt.Errorf("create commands table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS tickets_status_batches (
+	batch_id UUID PRIMARY KEY,
+	idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+	ticket_ids TEXT[] NOT NULL,
+	processed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);`)
+	if err != nil {
+		return fmt.Errorf("create tickets_status_batches table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS idempotent_requests (
+	idempotency_key VARCHAR(255) PRIMARY KEY,
+	fingerprint CHAR(64) NOT NULL,
+	status_code INTEGER DEFAULT NULL,
+	content_type VARCHAR(255) DEFAULT NULL,
+	response_headers JSONB DEFAULT NULL,
+	response_body BYTEA DEFAULT NULL,
+	locked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	completed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);`)
+	if err != nil {
+		return fmt.Errorf("create idempotent_requests table: %w", err)
+	}
+
 	_, err = db.ExecContext(context.Background(), `
 CREATE TABLE IF NOT EXISTS events (
     event_id UUID PRIMARY KEY,
@@ -85,6 +353,105 @@ CREATE TABLE IF NOT EXISTS vip_bundles (
 		return fmt.Errorf("create vip_bundles table: %w", err)
 	}
 
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS booking_cancellations (
+	booking_id UUID PRIMARY KEY,
+	payload JSONB NOT NULL
+);`)
+	if err != nil {
+		return fmt.Errorf("create booking_cancellations table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS show_cancellations (
+	show_id UUID PRIMARY KEY,
+	reason VARCHAR(255) NOT NULL,
+	cancelled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	CONSTRAINT fk_show
+		FOREIGN KEY (show_id)
+		REFERENCES shows(id)
+		ON DELETE RESTRICT
+);`)
+	if err != nil {
+		return fmt.Errorf("create show_cancellations table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS read_model_show_availability (
+	show_id UUID PRIMARY KEY,
+	booked_tickets INTEGER NOT NULL,
+	cancelled BOOLEAN NOT NULL DEFAULT false,
+	last_update TIMESTAMP WITH TIME ZONE NOT NULL
+);`)
+	if err != nil {
+		return fmt.Errorf("create read_model_show_availability table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS read_model_show_availability_bookings (
+	booking_id UUID PRIMARY KEY,
+	show_id UUID NOT NULL,
+	number_of_tickets INTEGER NOT NULL,
+	cancelled BOOLEAN NOT NULL
+);`)
+	if err != nil {
+		return fmt.Errorf("create read_model_show_availability_bookings table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS read_model_show_attendance (
+	ticket_id UUID PRIMARY KEY,
+	show_id UUID NOT NULL,
+	checked_in_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS read_model_show_attendance_show_id_idx ON read_model_show_attendance (show_id);`)
+	if err != nil {
+		return fmt.Errorf("create read_model_show_attendance table: %w", err)
+	}
+
+	_, err = db.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+	subscription_id UUID PRIMARY KEY,
+	url TEXT NOT NULL,
+	event_names TEXT[] NOT NULL,
+	secret TEXT NOT NULL,
+	consecutive_failures INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	disabled_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	delivery_id UUID PRIMARY KEY,
+	subscription_id UUID NOT NULL REFERENCES webhook_subscriptions (subscription_id) ON DELETE CASCADE,
+	event_id UUID NOT NULL,
+	event_name TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	delivered_at TIMESTAMP WITH TIME ZONE,
+	UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+	delivery_id UUID NOT NULL REFERENCES webhook_deliveries (delivery_id) ON DELETE CASCADE,
+	attempted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	status_code INTEGER NOT NULL,
+	error TEXT NOT NULL,
+	duration_ms BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_attempts_delivery_idx ON webhook_delivery_attempts (delivery_id, attempted_at);`)
+	if err != nil {
+		return fmt.Errorf("create webhook tables: %w", err)
+	}
+
 	log.FromContext(context.Background()).Info("Database schema initialized")
 	return nil
 }
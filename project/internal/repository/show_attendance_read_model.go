@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"tickets/internal/entities"
+)
+
+// ShowAttendanceReadModelRepo keeps checked in tickets per show.
+type ShowAttendanceReadModelRepo struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewShowAttendanceReadModelRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+) *ShowAttendanceReadModelRepo {
+	return &ShowAttendanceReadModelRepo{
+		db:     db,
+		getter: getter,
+	}
+}
+
+func (r *ShowAttendanceReadModelRepo) GetByShowID(ctx context.Context, showID uuid.UUID) (*entities.ShowAttendance, error) {
+	attendance := entities.ShowAttendance{
+		ShowID: showID,
+	}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).QueryRowContext(ctx, `
+		SELECT
+			COUNT(t.ticket_id),
+			MAX(t.checked_in_at),
+			COALESCE((SELECT booked_tickets FROM read_model_show_availability WHERE show_id = $1), 0)
+		FROM read_model_show_attendance t
+		WHERE t.show_id = $1`,
+		showID,
+	).Scan(&attendance.CheckedIn, &attendance.LastCheckedInAt, &attendance.BookedTickets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get show attendance: %w", err)
+	}
+
+	return &attendance, nil
+}
+
+func (r *ShowAttendanceReadModelRepo) OnTicketCheckedInEvent(ctx context.Context, event *entities.TicketCheckedIn_v1) error {
+	log.FromContext(ctx).Info("ShowAttendance OnTicketCheckedInEvent, ticketID: ", event.TicketID)
+
+	if event.ShowID == "" {
+		// tickets stored before they were linked with shows
+		return nil
+	}
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO read_model_show_attendance (ticket_id, show_id, checked_in_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		event.TicketID, event.ShowID, event.CheckedInAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add checked in ticket: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"tickets/internal/entities"
+	"time"
+)
+
+// ShowAvailabilityReadModelRepo keeps the number of booked tickets per show,
+// so the availability can be read without summing the bookings table.
+//
+// Seats are given back only when the whole booking is cancelled (BookingCancelled_v1),
+// the same way as in the bookings table which is used by the booking transaction.
+// Refunds of single tickets (TicketRefunded_v1) are not handled on purpose: the seat stays booked
+// in the bookings table, so the projection would show seats which can't be booked.
+// Refunds done by a booking or show cancellation free the seats through BookingCancelled_v1.
+type ShowAvailabilityReadModelRepo struct {
+	db        *sqlx.DB
+	getter    *trmsqlx.CtxGetter
+	trManager *trmanager.Manager
+}
+
+func NewShowAvailabilityReadModelRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+	trManager *trmanager.Manager,
+) *ShowAvailabilityReadModelRepo {
+	return &ShowAvailabilityReadModelRepo{
+		db:        db,
+		getter:    getter,
+		trManager: trManager,
+	}
+}
+
+type ShowAvailabilityFilters struct {
+	Venue            string
+	StartTimeFrom    *time.Time
+	StartTimeTo      *time.Time
+	OnlyAvailable    bool
+	IncludeCancelled bool
+}
+
+const showAvailabilitySelect = `
+	SELECT
+		s.id,
+		s.title,
+		s.venue,
+		s.start_time,
+		s.number_of_tickets,
+		COALESCE(a.booked_tickets, 0),
+		(c.show_id IS NOT NULL OR COALESCE(a.cancelled, false)),
+		a.last_update
+	FROM shows s
+	LEFT JOIN read_model_show_availability a ON a.show_id = s.id
+	LEFT JOIN show_cancellations c ON c.show_id = s.id`
+
+func (r *ShowAvailabilityReadModelRepo) GetByShowID(ctx context.Context, showID uuid.UUID) (*entities.ShowAvailability, error) {
+	row := r.getter.DefaultTrOrDB(ctx, r.db).QueryRowxContext(
+		ctx,
+		showAvailabilitySelect+`
+	WHERE s.id = $1`,
+		showID,
+	)
+
+	availability, err := scanShowAvailability(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShowNotFound
+		}
+		return nil, fmt.Errorf("failed to get show availability: %w", err)
+	}
+
+	return availability, nil
+}
+
+func (r *ShowAvailabilityReadModelRepo) GetWithFilters(ctx context.Context, filters ShowAvailabilityFilters) ([]entities.ShowAvailability, error) {
+	query := showAvailabilitySelect + `
+	WHERE ($1::text = '' OR s.venue = $1)
+		AND ($2::timestamptz IS NULL OR s.start_time >= $2)
+		AND ($3::timestamptz IS NULL OR s.start_time <= $3)
+		AND (NOT $4::boolean OR s.number_of_tickets > COALESCE(a.booked_tickets, 0))
+		AND (($5::boolean AND NOT $4::boolean) OR (c.show_id IS NULL AND NOT COALESCE(a.cancelled, false)))
+	ORDER BY s.start_time, s.id`
+
+	rows, err := r.getter.DefaultTrOrDB(ctx, r.db).QueryxContext(
+		ctx,
+		query,
+		filters.Venue,
+		filters.StartTimeFrom,
+		filters.StartTimeTo,
+		filters.OnlyAvailable,
+		filters.IncludeCancelled,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	shows := []entities.ShowAvailability{}
+	for rows.Next() {
+		availability, err := scanShowAvailability(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan show availability: %w", err)
+		}
+		shows = append(shows, *availability)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate shows: %w", err)
+	}
+
+	return shows, nil
+}
+
+func (r *ShowAvailabilityReadModelRepo) OnBookingMadeEvent(ctx context.Context, event *entities.BookingMade_v1) error {
+	log.FromContext(ctx).Info("ShowAvailability OnBookingMadeEvent, bookingID: ", event.BookingID)
+
+	return r.trManager.Do(ctx, func(ctx context.Context) error {
+		res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+			INSERT INTO read_model_show_availability_bookings (booking_id, show_id, number_of_tickets, cancelled)
+			VALUES ($1, $2, $3, false)
+			ON CONFLICT DO NOTHING`,
+			event.BookingID, event.ShowID, event.NumberOfTickets,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert booking: %w", err)
+		}
+		if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+			// re-delivery, or the booking was already cancelled
+			return nil
+		}
+
+		return r.addBookedTickets(ctx, event.ShowID, event.NumberOfTickets)
+	})
+}
+
+func (r *ShowAvailabilityReadModelRepo) OnBookingCancelledEvent(ctx context.Context, event *entities.BookingCancelled_v1) error {
+	log.FromContext(ctx).Info("ShowAvailability OnBookingCancelledEvent, bookingID: ", event.BookingID)
+
+	return r.trManager.Do(ctx, func(ctx context.Context) error {
+		// xmax is 0 for a freshly inserted row, so booked is false when BookingMade_v1 was not processed yet
+		var booked bool
+		err := r.getter.DefaultTrOrDB(ctx, r.db).QueryRowContext(ctx, `
+			INSERT INTO read_model_show_availability_bookings (booking_id, show_id, number_of_tickets, cancelled)
+			VALUES ($1, $2, $3, true)
+			ON CONFLICT (booking_id) DO UPDATE SET cancelled = true
+				WHERE NOT read_model_show_availability_bookings.cancelled
+			RETURNING xmax <> 0`,
+			event.BookingID, event.ShowID, event.NumberOfTickets,
+		).Scan(&booked)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// re-delivery
+				return nil
+			}
+			return fmt.Errorf("failed to cancel booking: %w", err)
+		}
+		if !booked {
+			// BookingMade_v1 was not processed yet, it will be ignored when it arrives
+			return nil
+		}
+
+		return r.addBookedTickets(ctx, event.ShowID, -event.NumberOfTickets)
+	})
+}
+
+func (r *ShowAvailabilityReadModelRepo) OnShowCancelledEvent(ctx context.Context, event *entities.ShowCancelled_v1) error {
+	log.FromContext(ctx).Info("ShowAvailability OnShowCancelledEvent, showID: ", event.ShowID)
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO read_model_show_availability (show_id, booked_tickets, cancelled, last_update)
+		VALUES ($1, 0, true, $2)
+		ON CONFLICT (show_id) DO UPDATE SET cancelled = true, last_update = $2`,
+		event.ShowID, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark show as cancelled: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ShowAvailabilityReadModelRepo) addBookedTickets(ctx context.Context, showID uuid.UUID, numberOfTickets int) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO read_model_show_availability (show_id, booked_tickets, cancelled, last_update)
+		VALUES ($1, GREATEST($2, 0), false, $3)
+		ON CONFLICT (show_id) DO UPDATE SET
+			booked_tickets = GREATEST(read_model_show_availability.booked_tickets + $2, 0),
+			last_update = $3`,
+		showID, numberOfTickets, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update show availability: %w", err)
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanShowAvailability(row rowScanner) (*entities.ShowAvailability, error) {
+	var a entities.ShowAvailability
+	var lastUpdate *time.Time
+
+	err := row.Scan(
+		&a.ShowID,
+		&a.Title,
+		&a.Venue,
+		&a.StartTime,
+		&a.NumberOfTickets,
+		&a.BookedTickets,
+		&a.IsCancelled,
+		&lastUpdate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastUpdate != nil {
+		a.LastUpdate = *lastUpdate
+	}
+
+	if !a.IsCancelled && a.NumberOfTickets > a.BookedTickets {
+		a.AvailableTickets = a.NumberOfTickets - a.BookedTickets
+	}
+
+	return &a, nil
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	"github.com/google/uuid"
@@ -9,6 +11,10 @@ import (
 	"tickets/internal/entities"
 )
 
+var ErrShowNotFound = errors.New("show not found")
+
+var ErrShowAlreadyCancelled = errors.New("show already cancelled")
+
 type ShowsRepo struct {
 	db     *sqlx.DB
 	getter *trmsqlx.CtxGetter
@@ -51,18 +57,121 @@ func (r *ShowsRepo) CreateShow(ctx context.Context, show entities.Show) (uuid.UU
 }
 
 func (r *ShowsRepo) GetShow(ctx context.Context, id uuid.UUID) (*entities.Show, error) {
+	return r.getShow(ctx, id, false)
+}
+
+// GetShowForUpdate locks the show for the duration of the transaction from ctx,
+// bookings and capacity changes of the show wait for each other.
+func (r *ShowsRepo) GetShowForUpdate(ctx context.Context, id uuid.UUID) (*entities.Show, error) {
+	return r.getShow(ctx, id, true)
+}
+
+// UpdateShow locks the show for the duration of the transaction from ctx, like GetShowForUpdate.
+// Bookings lock the show too, so in a READ COMMITTED transaction bookings counted after the lock
+// include every booking made before, and none can be made until the transaction ends.
+func (r *ShowsRepo) UpdateShow(
+	ctx context.Context,
+	id uuid.UUID,
+	updateFn func(show entities.Show) (entities.Show, error),
+) (entities.Show, error) {
+	show, err := r.getShow(ctx, id, true)
+	if err != nil {
+		return entities.Show{}, err
+	}
+
+	updatedShow, err := updateFn(*show)
+	if err != nil {
+		return entities.Show{}, err
+	}
+
+	query := `
+	   UPDATE shows
+	   SET number_of_tickets = $2, start_time = $3, title = $4, venue = $5
+	   WHERE id = $1`
+
+	_, err = r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query,
+		id,
+		updatedShow.NumberOfTickets,
+		updatedShow.StartTime,
+		updatedShow.Title,
+		updatedShow.Venue,
+	)
+	if err != nil {
+		return entities.Show{}, fmt.Errorf("failed to update show: %w", err)
+	}
+
+	return updatedShow, nil
+}
+
+func (r *ShowsRepo) CancelShow(ctx context.Context, cancellation entities.ShowCancellation) error {
+	query := `
+	   INSERT INTO show_cancellations (
+		  show_id, reason, cancelled_at
+	   ) VALUES (
+		  $1, $2, $3
+	   ) ON CONFLICT DO NOTHING`
+
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query,
+		cancellation.ShowID,
+		cancellation.Reason,
+		cancellation.CancelledAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel show: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return ErrShowAlreadyCancelled
+	}
+
+	return nil
+}
+
+func (r *ShowsRepo) GetShowCancellation(ctx context.Context, showID uuid.UUID) (*entities.ShowCancellation, error) {
+	var cancellation entities.ShowCancellation
+
+	query := `
+	   SELECT show_id, reason, cancelled_at
+	   FROM show_cancellations
+	   WHERE show_id = $1`
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).QueryRowContext(ctx, query, showID).
+		Scan(&cancellation.ShowID, &cancellation.Reason, &cancellation.CancelledAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entities.ErrShowNotCancelled
+		}
+		return nil, fmt.Errorf("failed to get show cancellation: %w", err)
+	}
+
+	return &cancellation, nil
+}
+
+func (r *ShowsRepo) getShow(ctx context.Context, id uuid.UUID, forUpdate bool) (*entities.Show, error) {
 	var show entities.Show
 
 	query := `
 	   SELECT
-		  id, dead_nation_id, number_of_tickets, start_time, title, venue
-	   FROM shows
-	   WHERE id = $1`
+		  s.id, s.dead_nation_id, s.number_of_tickets, s.start_time, s.title, s.venue, c.cancelled_at
+	   FROM shows s
+	   LEFT JOIN show_cancellations c ON c.show_id = s.id
+	   WHERE s.id = $1`
+	if forUpdate {
+		query += `
+	   FOR UPDATE OF s`
+	}
 
 	err := r.getter.DefaultTrOrDB(ctx, r.db).QueryRowContext(ctx, query, id).
-		Scan(&show.Id, &show.DeadNationId, &show.NumberOfTickets, &show.StartTime, &show.Title, &show.Venue)
+		Scan(&show.Id, &show.DeadNationId, &show.NumberOfTickets, &show.StartTime, &show.Title, &show.Venue, &show.CancelledAt)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShowNotFound
+		}
 		return nil, fmt.Errorf("failed to get show: %w", err)
 	}
 
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrTicketTransferNotFound = fmt.Errorf("ticket transfer not found")
+
+type ticketTransfer struct {
+	TransferID         uuid.UUID  `db:"transfer_id"`
+	TicketID           uuid.UUID  `db:"ticket_id"`
+	FromEmail          string     `db:"from_email"`
+	ToEmail            string     `db:"to_email"`
+	Status             string     `db:"status"`
+	RequiresAcceptance bool       `db:"requires_acceptance"`
+	RequesterIP        string     `db:"requester_ip"`
+	RequestedAt        time.Time  `db:"requested_at"`
+	ResolvedAt         *time.Time `db:"resolved_at"`
+}
+
+type TicketTransfersRepo struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewTicketTransfersRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+) *TicketTransfersRepo {
+	return &TicketTransfersRepo{
+		db:     db,
+		getter: getter,
+	}
+}
+
+func (r *TicketTransfersRepo) Add(ctx context.Context, transfer entities.TicketTransfer) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO ticket_transfers (
+			transfer_id, ticket_id, from_email, to_email, status, requires_acceptance, requester_ip, requested_at, resolved_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)`,
+		transfer.TransferID,
+		transfer.TicketID,
+		transfer.FromEmail,
+		transfer.ToEmail,
+		transfer.Status,
+		transfer.RequiresAcceptance,
+		transfer.RequesterIP,
+		transfer.RequestedAt,
+		transfer.ResolvedAt,
+	)
+	if err != nil {
+		if isErrorUniqueViolation(err) {
+			return entities.ErrTicketTransferPending
+		}
+		return fmt.Errorf("insert ticket transfer: %w", err)
+	}
+
+	return nil
+}
+
+// GetForUpdate locks the transfer for the duration of the transaction from ctx.
+func (r *TicketTransfersRepo) GetForUpdate(ctx context.Context, transferID uuid.UUID) (entities.TicketTransfer, error) {
+	var transfer ticketTransfer
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &transfer, `
+		SELECT transfer_id, ticket_id, from_email, to_email, status, requires_acceptance, requester_ip, requested_at, resolved_at
+		FROM ticket_transfers
+		WHERE transfer_id = $1
+		FOR UPDATE`, transferID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.TicketTransfer{}, ErrTicketTransferNotFound
+		}
+		return entities.TicketTransfer{}, fmt.Errorf("get ticket transfer: %w", err)
+	}
+
+	return transfer.toEntity(), nil
+}
+
+func (r *TicketTransfersRepo) Resolve(
+	ctx context.Context,
+	transferID uuid.UUID,
+	status entities.TicketTransferStatus,
+	resolvedAt time.Time,
+) error {
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE ticket_transfers
+		SET status = $1, resolved_at = $2
+		WHERE transfer_id = $3`,
+		status, resolvedAt, transferID,
+	)
+	if err != nil {
+		return fmt.Errorf("update ticket transfer: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return ErrTicketTransferNotFound
+	}
+
+	return nil
+}
+
+// GetByTicketID returns the transfer history of the ticket, the oldest transfer first.
+func (r *TicketTransfersRepo) GetByTicketID(ctx context.Context, ticketID uuid.UUID) ([]entities.TicketTransfer, error) {
+	var transfers []ticketTransfer
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &transfers, `
+		SELECT transfer_id, ticket_id, from_email, to_email, status, requires_acceptance, requester_ip, requested_at, resolved_at
+		FROM ticket_transfers
+		WHERE ticket_id = $1
+		ORDER BY requested_at, transfer_id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("select ticket transfers: %w", err)
+	}
+
+	result := make([]entities.TicketTransfer, 0, len(transfers))
+	for _, t := range transfers {
+		result = append(result, t.toEntity())
+	}
+
+	return result, nil
+}
+
+func (t ticketTransfer) toEntity() entities.TicketTransfer {
+	return entities.TicketTransfer{
+		TransferID:         t.TransferID,
+		TicketID:           t.TicketID,
+		FromEmail:          t.FromEmail,
+		ToEmail:            t.ToEmail,
+		Status:             entities.TicketTransferStatus(t.Status),
+		RequiresAcceptance: t.RequiresAcceptance,
+		RequesterIP:        t.RequesterIP,
+		RequestedAt:        t.RequestedAt,
+		ResolvedAt:         t.ResolvedAt,
+	}
+}
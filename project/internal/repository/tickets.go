@@ -2,83 +2,234 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	trmanager "github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
-	"strconv"
+	"github.com/shopspring/decimal"
 	"tickets/internal/entities"
 	"time"
 )
 
 // Ticket represents the ticket entity
 type Ticket struct {
-	ID            uuid.UUID  `db:"ticket_id"`
-	PriceAmount   float64    `db:"price_amount"`
-	PriceCurrency string     `db:"price_currency"`
-	CustomerEmail string     `db:"customer_email"`
-	DeletedAt     *time.Time `db:"deleted_at"`
+	ID            uuid.UUID       `db:"ticket_id"`
+	PriceAmount   decimal.Decimal `db:"price_amount"`
+	PriceCurrency string          `db:"price_currency"`
+	CustomerEmail string          `db:"customer_email"`
+	BookingID     *uuid.UUID      `db:"booking_id"`
+	ShowID        *uuid.UUID      `db:"show_id"`
+	Status        string          `db:"status"`
+	TokenVersion  int             `db:"token_version"`
+	DeletedAt     *time.Time      `db:"deleted_at"`
 }
 
 type TicketsRepo struct {
-	db *sqlx.DB
+	db        *sqlx.DB
+	getter    *trmsqlx.CtxGetter
+	trManager *trmanager.Manager
 }
 
-func NewTicketsRepo(db *sqlx.DB) *TicketsRepo {
-	return &TicketsRepo{db: db}
+func NewTicketsRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+	trManager *trmanager.Manager,
+) *TicketsRepo {
+	return &TicketsRepo{
+		db:        db,
+		getter:    getter,
+		trManager: trManager,
+	}
 }
 
+// Create stores the confirmed ticket. The show is taken from the booking, if it's known.
 func (r *TicketsRepo) Create(ctx context.Context, t *entities.Ticket) error {
-	query := `
-        INSERT INTO tickets (
-            ticket_id, price_amount, price_currency, customer_email
-        ) VALUES (
-            $1, $2, $3, $4
-        ) ON CONFLICT DO NOTHING`
-
 	ticket, err := domainToModel(t)
 	if err != nil {
 		return fmt.Errorf("failed to convert entities to model: %w", err)
 	}
 
-	_, err = r.db.ExecContext(ctx, query,
-		ticket.ID,
-		ticket.PriceAmount,
-		ticket.PriceCurrency,
-		ticket.CustomerEmail,
-	)
-	return err
+	return r.trManager.Do(ctx, func(ctx context.Context) error {
+		query := `
+        INSERT INTO tickets (
+            ticket_id, price_amount, price_currency, customer_email, booking_id, show_id, status, status_updated_at
+        ) VALUES (
+            $1, $2, $3, $4, $5, (SELECT show_id FROM bookings WHERE id = $5), $6, $7
+        ) ON CONFLICT DO NOTHING`
+
+		now := time.Now().UTC()
+
+		res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query,
+			ticket.ID,
+			ticket.PriceAmount,
+			ticket.PriceCurrency,
+			ticket.CustomerEmail,
+			ticket.BookingID,
+			entities.TicketStatusConfirmed,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert ticket: %w", err)
+		}
+
+		if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+			// already stored
+			return nil
+		}
+
+		return r.addStatusHistory(ctx, ticket.ID, entities.TicketStatusConfirmed, now)
+	})
 }
 
 var ErrTicketNotFound = fmt.Errorf("ticket not found")
 
-func (r *TicketsRepo) Delete(ctx context.Context, ticketID uuid.UUID) error {
-	query := `
-		UPDATE tickets SET deleted_at = $1 WHERE ticket_id = $2`
+// UpdateStatus records the status change in the history, and moves the ticket to the new status
+// if the lifecycle allows that. Recording the same status twice is a no-op, so events can be re-delivered.
+func (r *TicketsRepo) UpdateStatus(ctx context.Context, ticketID uuid.UUID, status entities.TicketStatus, changedAt time.Time) error {
+	if !status.IsValid() {
+		return fmt.Errorf("invalid ticket status: %s", status)
+	}
+
+	return r.trManager.Do(ctx, func(ctx context.Context) error {
+		ticket, err := r.GetForUpdate(ctx, ticketID)
+		if err != nil {
+			return err
+		}
+
+		err = r.addStatusHistory(ctx, ticketID, status, changedAt)
+		if err != nil {
+			return err
+		}
+
+		if !entities.TicketStatus(ticket.Status).CanTransitionTo(status) {
+			return nil
+		}
+
+		_, err = r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+			UPDATE tickets SET status = $1, status_updated_at = $2 WHERE ticket_id = $3`,
+			status, changedAt, ticketID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update ticket status: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetForUpdate locks the ticket for the duration of the transaction from ctx.
+func (r *TicketsRepo) GetForUpdate(ctx context.Context, ticketID uuid.UUID) (*entities.Ticket, error) {
+	var ticket Ticket
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &ticket, `
+		SELECT ticket_id, price_amount, price_currency, customer_email, booking_id, show_id, status, token_version
+		FROM tickets
+		WHERE ticket_id = $1
+		FOR UPDATE`, ticketID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	t := modelToDomain(ticket)
+	return &t, nil
+}
+
+func (r *TicketsRepo) Get(ctx context.Context, ticketID uuid.UUID) (*entities.Ticket, error) {
+	var ticket Ticket
 
-	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), ticketID)
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &ticket, `
+		SELECT ticket_id, price_amount, price_currency, customer_email, booking_id, show_id, status, token_version
+		FROM tickets
+		WHERE ticket_id = $1`, ticketID)
 	if err != nil {
-		return fmt.Errorf("failed to delete ticket: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	t := modelToDomain(ticket)
+	return &t, nil
+}
+
+// ChangeCustomer moves the ticket to another customer and invalidates check-in codes printed so far.
+func (r *TicketsRepo) ChangeCustomer(ctx context.Context, ticketID uuid.UUID, customerEmail string) error {
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE tickets
+		SET customer_email = $1, token_version = token_version + 1
+		WHERE ticket_id = $2`,
+		customerEmail, ticketID,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", ErrTicketNotFound)
+		return fmt.Errorf("failed to change ticket customer: %w", err)
 	}
 
-	if rowsAffected == 0 {
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
 		return ErrTicketNotFound
 	}
-	return err
+
+	return nil
+}
+
+func (r *TicketsRepo) GetStatusHistory(ctx context.Context, ticketID uuid.UUID) ([]entities.TicketStatusChange, error) {
+	var history []struct {
+		TicketID  uuid.UUID `db:"ticket_id"`
+		Status    string    `db:"status"`
+		ChangedAt time.Time `db:"changed_at"`
+	}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &history, `
+		SELECT ticket_id, status, changed_at
+		FROM ticket_status_history
+		WHERE ticket_id = $1
+		ORDER BY changed_at, status`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket status history: %w", err)
+	}
+	if len(history) == 0 {
+		return nil, ErrTicketNotFound
+	}
+
+	changes := make([]entities.TicketStatusChange, 0, len(history))
+	for _, h := range history {
+		changes = append(changes, entities.TicketStatusChange{
+			TicketID:  h.TicketID.String(),
+			Status:    entities.TicketStatus(h.Status),
+			ChangedAt: h.ChangedAt,
+		})
+	}
+
+	return changes, nil
+}
+
+type TicketsFilters struct {
+	Status    entities.TicketStatus
+	BookingID *uuid.UUID
+	ShowID    *uuid.UUID
 }
 
-func (r *TicketsRepo) List(ctx context.Context) ([]entities.Ticket, error) {
+// List returns tickets matching the filters. Without the status filter cancelled tickets are skipped.
+func (r *TicketsRepo) List(ctx context.Context, filters TicketsFilters) ([]entities.Ticket, error) {
 	var tickets []Ticket
 	query := `
-		SELECT ticket_id, price_amount, price_currency, customer_email
+		SELECT ticket_id, price_amount, price_currency, customer_email, booking_id, show_id, status, token_version
 		FROM tickets
-		WHERE deleted_at IS NULL`
+		WHERE deleted_at IS NULL
+			AND (($1::text = '' AND status <> 'cancelled') OR status = $1)
+			AND ($2::uuid IS NULL OR booking_id = $2)
+			AND ($3::uuid IS NULL OR show_id = $3)`
 
-	err := r.db.SelectContext(ctx, &tickets, query)
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &tickets, query, filters.Status, filters.BookingID, filters.ShowID)
 	if err != nil {
 		return nil, err
 	}
@@ -91,15 +242,44 @@ func (r *TicketsRepo) List(ctx context.Context) ([]entities.Ticket, error) {
 	return convertedTickets, nil
 }
 
+func (r *TicketsRepo) addStatusHistory(
+	ctx context.Context,
+	ticketID uuid.UUID,
+	status entities.TicketStatus,
+	changedAt time.Time,
+) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO ticket_status_history (ticket_id, status, changed_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		ticketID, status, changedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add ticket status history: %w", err)
+	}
+
+	return nil
+}
+
 func modelToDomain(ticket Ticket) entities.Ticket {
-	return entities.Ticket{
+	t := entities.Ticket{
 		TicketId:      ticket.ID.String(),
+		Status:        ticket.Status,
 		CustomerEmail: ticket.CustomerEmail,
+		TokenVersion:  ticket.TokenVersion,
 		Price: entities.Money{
-			Amount:   strconv.FormatFloat(ticket.PriceAmount, 'f', 2, 64),
+			Amount:   ticket.PriceAmount,
 			Currency: ticket.PriceCurrency,
 		},
 	}
+	if ticket.BookingID != nil {
+		t.BookingId = ticket.BookingID.String()
+	}
+	if ticket.ShowID != nil {
+		t.ShowId = ticket.ShowID.String()
+	}
+
+	return t
 }
 
 func domainToModel(ticket *entities.Ticket) (*Ticket, error) {
@@ -108,15 +288,21 @@ func domainToModel(ticket *entities.Ticket) (*Ticket, error) {
 		return nil, err
 	}
 
-	amount, err := strconv.ParseFloat(ticket.Price.Amount, 64)
-	if err != nil {
-		return nil, err
+	var bookingID *uuid.UUID
+	if ticket.BookingId != "" {
+		id, err := uuid.Parse(ticket.BookingId)
+		if err != nil {
+			return nil, err
+		}
+		bookingID = &id
 	}
 
 	return &Ticket{
 		ID:            id,
-		PriceAmount:   amount,
+		PriceAmount:   ticket.Price.Amount,
 		PriceCurrency: ticket.Price.Currency,
 		CustomerEmail: ticket.CustomerEmail,
+		BookingID:     bookingID,
+		Status:        ticket.Status,
 	}, nil
 }
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"tickets/internal/entities"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ticketsStatusBatch struct {
+	BatchID        uuid.UUID      `db:"batch_id"`
+	IdempotencyKey string         `db:"idempotency_key"`
+	TicketIDs      pq.StringArray `db:"ticket_ids"`
+	ProcessedAt    time.Time      `db:"processed_at"`
+}
+
+type TicketsStatusBatchesRepo struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewTicketsStatusBatchesRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+) *TicketsStatusBatchesRepo {
+	return &TicketsStatusBatchesRepo{
+		db:     db,
+		getter: getter,
+	}
+}
+
+// Add stores the batch, unless a batch with the same idempotency key was stored before.
+// The stored batch is returned, the caller can tell it's a retried request by a different BatchID.
+//
+// A concurrent request with the same key waits on the unique key until the first transaction is done.
+func (r *TicketsStatusBatchesRepo) Add(
+	ctx context.Context,
+	batch entities.TicketsStatusBatch,
+) (entities.TicketsStatusBatch, error) {
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO tickets_status_batches (batch_id, idempotency_key, ticket_ids, processed_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (idempotency_key) DO NOTHING`,
+		batch.BatchID,
+		batch.IdempotencyKey,
+		pq.StringArray(batch.TicketIDs),
+		batch.ProcessedAt,
+	)
+	if err != nil {
+		return entities.TicketsStatusBatch{}, fmt.Errorf("insert tickets status batch: %w", err)
+	}
+
+	var stored ticketsStatusBatch
+	err = db.GetContext(ctx, &stored, `
+		SELECT batch_id, idempotency_key, ticket_ids, processed_at
+		FROM tickets_status_batches
+		WHERE idempotency_key = $1`, batch.IdempotencyKey)
+	if err != nil {
+		return entities.TicketsStatusBatch{}, fmt.Errorf("get tickets status batch: %w", err)
+	}
+
+	return entities.TicketsStatusBatch{
+		BatchID:        stored.BatchID,
+		IdempotencyKey: stored.IdempotencyKey,
+		TicketIDs:      stored.TicketIDs,
+		ProcessedAt:    stored.ProcessedAt,
+	}, nil
+}
@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"tickets/internal/entities"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrWebhookSubscriptionNotFound = fmt.Errorf("webhook subscription not found")
+	ErrWebhookDeliveryNotFound     = fmt.Errorf("webhook delivery not found")
+)
+
+type webhookSubscription struct {
+	SubscriptionID      uuid.UUID      `db:"subscription_id"`
+	URL                 string         `db:"url"`
+	EventNames          pq.StringArray `db:"event_names"`
+	Secret              string         `db:"secret"`
+	ConsecutiveFailures int            `db:"consecutive_failures"`
+	CreatedAt           time.Time      `db:"created_at"`
+	DisabledAt          *time.Time     `db:"disabled_at"`
+}
+
+type webhookDelivery struct {
+	DeliveryID     uuid.UUID  `db:"delivery_id"`
+	SubscriptionID uuid.UUID  `db:"subscription_id"`
+	EventID        uuid.UUID  `db:"event_id"`
+	EventName      string     `db:"event_name"`
+	Payload        []byte     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LastError      string     `db:"last_error"`
+	CreatedAt      time.Time  `db:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+}
+
+type webhookDeliveryAttempt struct {
+	DeliveryID  uuid.UUID `db:"delivery_id"`
+	AttemptedAt time.Time `db:"attempted_at"`
+	StatusCode  int       `db:"status_code"`
+	Error       string    `db:"error"`
+	DurationMs  int64     `db:"duration_ms"`
+}
+
+const webhookSubscriptionColumns = `subscription_id, url, event_names, secret, consecutive_failures, created_at, disabled_at`
+
+const webhookDeliveryColumns = `delivery_id, subscription_id, event_id, event_name, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+type WebhooksRepo struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewWebhooksRepo(
+	db *sqlx.DB,
+	getter *trmsqlx.CtxGetter,
+) *WebhooksRepo {
+	return &WebhooksRepo{
+		db:     db,
+		getter: getter,
+	}
+}
+
+func (r *WebhooksRepo) AddSubscription(ctx context.Context, subscription entities.WebhookSubscription) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (`+webhookSubscriptionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		subscription.SubscriptionID,
+		subscription.URL,
+		pq.Array(subscription.EventNames),
+		subscription.Secret,
+		subscription.ConsecutiveFailures,
+		subscription.CreatedAt,
+		subscription.DisabledAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert webhook subscription: %w", err)
+	}
+
+	return nil
+}
+
+// GetSubscription returns the subscription with its secret.
+func (r *WebhooksRepo) GetSubscription(ctx context.Context, subscriptionID uuid.UUID) (entities.WebhookSubscription, error) {
+	var subscription webhookSubscription
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &subscription, `
+		SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE subscription_id = $1`, subscriptionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.WebhookSubscription{}, ErrWebhookSubscriptionNotFound
+		}
+		return entities.WebhookSubscription{}, fmt.Errorf("get webhook subscription: %w", err)
+	}
+
+	return subscription.toEntity(), nil
+}
+
+// ListSubscriptions returns all subscriptions without their secrets, the oldest first.
+func (r *WebhooksRepo) ListSubscriptions(ctx context.Context) ([]entities.WebhookSubscription, error) {
+	var subscriptions []webhookSubscription
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &subscriptions, `
+		SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		ORDER BY created_at, subscription_id`)
+	if err != nil {
+		return nil, fmt.Errorf("select webhook subscriptions: %w", err)
+	}
+
+	result := make([]entities.WebhookSubscription, 0, len(subscriptions))
+	for _, s := range subscriptions {
+		result = append(result, s.toEntity().WithoutSecret())
+	}
+
+	return result, nil
+}
+
+// EnableSubscription resumes the deliveries of the subscription and resets its failures.
+func (r *WebhooksRepo) EnableSubscription(ctx context.Context, subscriptionID uuid.UUID) error {
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET disabled_at = NULL, consecutive_failures = 0
+		WHERE subscription_id = $1`, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("update webhook subscription: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return ErrWebhookSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// RecordDeliveryFailure counts a delivery which failed after all attempts,
+// and disables the subscription when it reaches disableAfter consecutive failures.
+// It returns true when the subscription is disabled.
+func (r *WebhooksRepo) RecordDeliveryFailure(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	disableAfter int,
+	failedAt time.Time,
+) (bool, error) {
+	var disabled bool
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &disabled, `
+		UPDATE webhook_subscriptions
+		SET
+			consecutive_failures = consecutive_failures + 1,
+			disabled_at = CASE
+				WHEN disabled_at IS NULL AND consecutive_failures + 1 >= $2 THEN $3
+				ELSE disabled_at
+			END
+		WHERE subscription_id = $1
+		RETURNING disabled_at IS NOT NULL`,
+		subscriptionID, disableAfter, failedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrWebhookSubscriptionNotFound
+		}
+		return false, fmt.Errorf("update webhook subscription failures: %w", err)
+	}
+
+	return disabled, nil
+}
+
+func (r *WebhooksRepo) ResetFailures(ctx context.Context, subscriptionID uuid.UUID) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET consecutive_failures = 0
+		WHERE subscription_id = $1 AND consecutive_failures > 0`, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("reset webhook subscription failures: %w", err)
+	}
+
+	return nil
+}
+
+// AddDeliveries queues the event for all subscriptions of the event, including the disabled ones,
+// so their deliveries are sent when they are enabled again.
+// Events which are already queued are skipped, so it's safe to call it for redelivered events.
+func (r *WebhooksRepo) AddDeliveries(
+	ctx context.Context,
+	eventID uuid.UUID,
+	eventName string,
+	payload []byte,
+	createdAt time.Time,
+) (int, error) {
+	var subscriptionIDs []uuid.UUID
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &subscriptionIDs, `
+		SELECT subscription_id
+		FROM webhook_subscriptions
+		WHERE $1 = ANY(event_names)`, eventName)
+	if err != nil {
+		return 0, fmt.Errorf("select webhook subscriptions of %s: %w", eventName, err)
+	}
+
+	var added int
+	for _, subscriptionID := range subscriptionIDs {
+		res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, 0, $7, '', $7, NULL)
+			ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+			uuid.New(),
+			subscriptionID,
+			eventID,
+			eventName,
+			payload,
+			entities.WebhookDeliveryStatusPending,
+			createdAt,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("insert webhook delivery: %w", err)
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		added += int(count)
+	}
+
+	return added, nil
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries of enabled subscriptions which are due at now,
+// and postpones them until leaseUntil, so other dispatchers don't send them in the meantime.
+// Deliveries which are not updated before leaseUntil, for example when the dispatcher crashed, are sent again.
+func (r *WebhooksRepo) ClaimDueDeliveries(
+	ctx context.Context,
+	now time.Time,
+	leaseUntil time.Time,
+	limit int,
+) ([]entities.WebhookDelivery, error) {
+	var deliveries []webhookDelivery
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &deliveries, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE delivery_id IN (
+			SELECT d.delivery_id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.subscription_id = d.subscription_id
+			WHERE d.status = $4 AND d.next_attempt_at <= $1 AND s.disabled_at IS NULL
+			ORDER BY d.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING `+webhookDeliveryColumns,
+		now, leaseUntil, limit, entities.WebhookDeliveryStatusPending,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+
+	return webhookDeliveriesToEntities(deliveries), nil
+}
+
+func (r *WebhooksRepo) GetDelivery(ctx context.Context, deliveryID uuid.UUID) (entities.WebhookDelivery, error) {
+	return r.getDelivery(ctx, deliveryID, "")
+}
+
+// GetDeliveryForUpdate locks the delivery for the duration of the transaction from ctx.
+func (r *WebhooksRepo) GetDeliveryForUpdate(ctx context.Context, deliveryID uuid.UUID) (entities.WebhookDelivery, error) {
+	return r.getDelivery(ctx, deliveryID, "FOR UPDATE")
+}
+
+func (r *WebhooksRepo) getDelivery(ctx context.Context, deliveryID uuid.UUID, lock string) (entities.WebhookDelivery, error) {
+	var delivery webhookDelivery
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &delivery, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE delivery_id = $1
+		`+lock, deliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entities.WebhookDelivery{}, ErrWebhookDeliveryNotFound
+		}
+		return entities.WebhookDelivery{}, fmt.Errorf("get webhook delivery: %w", err)
+	}
+
+	return delivery.toEntity(), nil
+}
+
+// UpdateDelivery saves the state of the delivery, the event is never changed.
+func (r *WebhooksRepo) UpdateDelivery(ctx context.Context, delivery entities.WebhookDelivery) error {
+	res, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, delivered_at = $6
+		WHERE delivery_id = $1`,
+		delivery.DeliveryID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastError,
+		delivery.DeliveredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("update webhook delivery: %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if count == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
+
+// ListDeliveries returns up to limit deliveries of the subscription, the newest first.
+func (r *WebhooksRepo) ListDeliveries(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	limit int,
+) ([]entities.WebhookDelivery, error) {
+	var deliveries []webhookDelivery
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &deliveries, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC, delivery_id
+		LIMIT $2`, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("select webhook deliveries: %w", err)
+	}
+
+	return webhookDeliveriesToEntities(deliveries), nil
+}
+
+func (r *WebhooksRepo) AddAttempt(ctx context.Context, attempt entities.WebhookDeliveryAttempt) error {
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempted_at, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)`,
+		attempt.DeliveryID,
+		attempt.AttemptedAt,
+		attempt.StatusCode,
+		attempt.Error,
+		attempt.Duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert webhook delivery attempt: %w", err)
+	}
+
+	return nil
+}
+
+// ListAttempts returns all attempts of the delivery, including the ones before redeliveries, the oldest first.
+func (r *WebhooksRepo) ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]entities.WebhookDeliveryAttempt, error) {
+	var attempts []webhookDeliveryAttempt
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &attempts, `
+		SELECT delivery_id, attempted_at, status_code, error, duration_ms
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempted_at`, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("select webhook delivery attempts: %w", err)
+	}
+
+	result := make([]entities.WebhookDeliveryAttempt, 0, len(attempts))
+	for _, a := range attempts {
+		result = append(result, entities.WebhookDeliveryAttempt{
+			DeliveryID:  a.DeliveryID,
+			AttemptedAt: a.AttemptedAt,
+			StatusCode:  a.StatusCode,
+			Error:       a.Error,
+			Duration:    time.Duration(a.DurationMs) * time.Millisecond,
+		})
+	}
+
+	return result, nil
+}
+
+func (s webhookSubscription) toEntity() entities.WebhookSubscription {
+	return entities.WebhookSubscription{
+		SubscriptionID:      s.SubscriptionID,
+		URL:                 s.URL,
+		EventNames:          s.EventNames,
+		Secret:              s.Secret,
+		ConsecutiveFailures: s.ConsecutiveFailures,
+		CreatedAt:           s.CreatedAt,
+		DisabledAt:          s.DisabledAt,
+	}
+}
+
+func (d webhookDelivery) toEntity() entities.WebhookDelivery {
+	return entities.WebhookDelivery{
+		DeliveryID:     d.DeliveryID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventName:      d.EventName,
+		Payload:        d.Payload,
+		Status:         entities.WebhookDeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+}
+
+func webhookDeliveriesToEntities(deliveries []webhookDelivery) []entities.WebhookDelivery {
+	result := make([]entities.WebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		result = append(result, d.toEntity())
+	}
+
+	return result
+}
@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// ContentTypeCloudEvents is the content type of messages in the CloudEvents structured mode,
+// their payload is the JSON envelope with the attributes and the data.
+const ContentTypeCloudEvents = "application/cloudevents+json"
+
+const cloudEventsSpecVersion = "1.0"
+
+// CloudEventsMetadataPrefix prefixes the attributes in the metadata of messages in the CloudEvents binary mode,
+// like the ce_ headers of the Kafka binding. The datacontenttype attribute is the content_type metadata.
+const CloudEventsMetadataPrefix = "ce_"
+
+// CloudEventsMode is the binding of CloudEvents 1.0 to messages.
+type CloudEventsMode string
+
+const (
+	// CloudEventsBinary keeps the payload and puts the attributes into the metadata,
+	// consumers which don't know CloudEvents read the messages as before.
+	CloudEventsBinary CloudEventsMode = "binary"
+	// CloudEventsStructured wraps the payload into the JSON envelope with the attributes,
+	// only consumers using Marshaler can read it.
+	CloudEventsStructured CloudEventsMode = "structured"
+)
+
+func (m CloudEventsMode) Validate() error {
+	switch m {
+	case "", CloudEventsBinary, CloudEventsStructured:
+		return nil
+	default:
+		return fmt.Errorf("unknown CloudEvents mode %q", m)
+	}
+}
+
+// CloudEventAttributes are the context attributes of the event, specversion and datacontenttype are set from the message.
+type CloudEventAttributes struct {
+	ID      string
+	Source  string
+	Type    string
+	Subject string
+	// Time is omitted when it's zero.
+	Time time.Time
+}
+
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+// ToCloudEvent binds the message marshaled by Marshaler to the CloudEvent with the attributes.
+func ToCloudEvent(msg *message.Message, mode CloudEventsMode, attributes CloudEventAttributes) error {
+	contentType := msg.Metadata.Get(ContentTypeMetadataKey)
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+
+	var eventTime *time.Time
+	if !attributes.Time.IsZero() {
+		t := attributes.Time.UTC()
+		eventTime = &t
+	}
+
+	switch mode {
+	case CloudEventsBinary:
+		msg.Metadata.Set(CloudEventsMetadataPrefix+"specversion", cloudEventsSpecVersion)
+		msg.Metadata.Set(CloudEventsMetadataPrefix+"id", attributes.ID)
+		msg.Metadata.Set(CloudEventsMetadataPrefix+"source", attributes.Source)
+		msg.Metadata.Set(CloudEventsMetadataPrefix+"type", attributes.Type)
+		if attributes.Subject != "" {
+			msg.Metadata.Set(CloudEventsMetadataPrefix+"subject", attributes.Subject)
+		}
+		if eventTime != nil {
+			msg.Metadata.Set(CloudEventsMetadataPrefix+"time", eventTime.Format(time.RFC3339Nano))
+		}
+		msg.Metadata.Set(ContentTypeMetadataKey, contentType)
+
+		return nil
+	case CloudEventsStructured:
+		event := cloudEvent{
+			SpecVersion:     cloudEventsSpecVersion,
+			ID:              attributes.ID,
+			Source:          attributes.Source,
+			Type:            attributes.Type,
+			Subject:         attributes.Subject,
+			Time:            eventTime,
+			DataContentType: contentType,
+		}
+		if contentType == ContentTypeJSON {
+			event.Data = json.RawMessage(msg.Payload)
+		} else {
+			event.DataBase64 = msg.Payload
+		}
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal CloudEvent %s: %w", attributes.ID, err)
+		}
+
+		msg.Payload = payload
+		msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeCloudEvents)
+
+		return nil
+	default:
+		return fmt.Errorf("unknown CloudEvents mode %q", mode)
+	}
+}
+
+// fromStructuredCloudEvent returns the message with the data of the CloudEvent in the structured mode,
+// with the content type of the data.
+func fromStructuredCloudEvent(msg *message.Message) (*message.Message, error) {
+	var event cloudEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		return nil, fmt.Errorf("received invalid message %s: %w: invalid CloudEvent: %w", msg.UUID, ErrInvalidPayload, err)
+	}
+	if event.SpecVersion != cloudEventsSpecVersion {
+		return nil, fmt.Errorf("received invalid message %s: %w: unsupported CloudEvents version %q", msg.UUID, ErrInvalidPayload, event.SpecVersion)
+	}
+
+	payload := []byte(event.Data)
+	if event.DataBase64 != nil {
+		payload = event.DataBase64
+	}
+
+	// the CloudEvents default
+	contentType := event.DataContentType
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+
+	data := msg.Copy()
+	data.Payload = payload
+	data.Metadata.Set(ContentTypeMetadataKey, contentType)
+	data.SetContext(msg.Context())
+
+	return data, nil
+}
@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BreakingChanges returns the changes between the old and new schema of a message which break
+// producers or consumers running the old version, or messages already published with it:
+// removed properties, changed types or formats, and properties which became required or optional.
+// Adding optional properties is compatible.
+func BreakingChanges(old, new *Schema) []string {
+	var changes []string
+	breakingChanges(old, new, "$", &changes)
+	sort.Strings(changes)
+
+	return changes
+}
+
+func breakingChanges(old, new *Schema, path string, changes *[]string) {
+	if !sameTypes(old.Type, new.Type) {
+		*changes = append(*changes, fmt.Sprintf("%s: type changed from %s to %s", path, old.Type, new.Type))
+		return
+	}
+	if old.Format != new.Format {
+		*changes = append(*changes, fmt.Sprintf("%s: format changed from %q to %q", path, old.Format, new.Format))
+	}
+
+	oldRequired := set(old.Required)
+	newRequired := set(new.Required)
+
+	for name, oldProperty := range old.Properties {
+		propertyPath := path + "." + name
+
+		newProperty, ok := new.Properties[name]
+		if !ok {
+			*changes = append(*changes, propertyPath+": removed")
+			continue
+		}
+
+		_, wasRequired := oldRequired[name]
+		_, isRequired := newRequired[name]
+		switch {
+		case wasRequired && !isRequired:
+			*changes = append(*changes, propertyPath+": became optional")
+		case !wasRequired && isRequired:
+			*changes = append(*changes, propertyPath+": became required")
+		}
+
+		breakingChanges(oldProperty, newProperty, propertyPath, changes)
+	}
+
+	for name := range new.Properties {
+		if _, ok := old.Properties[name]; ok {
+			continue
+		}
+		if _, ok := newRequired[name]; ok {
+			*changes = append(*changes, path+"."+name+": added as required")
+		}
+	}
+
+	if old.Items != nil && new.Items != nil {
+		breakingChanges(old.Items, new.Items, path+"[]", changes)
+	}
+	if old.AdditionalProperties != nil && new.AdditionalProperties != nil {
+		breakingChanges(old.AdditionalProperties, new.AdditionalProperties, path+"{}", changes)
+	}
+}
+
+func sameTypes(a, b Types) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, t := range a {
+		if !b.Has(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func set(values []string) map[string]struct{} {
+	s := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		s[v] = struct{}{}
+	}
+	return s
+}
@@ -0,0 +1,308 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"tickets/internal/protobuf"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// ValidatingMarshaler validates the payloads of sent and received messages with the registry.
+//
+// Marshal fails for invalid payloads and messages without a schema, so broken producers fail fast.
+// Unmarshal fails with ErrInvalidPayload for invalid payloads, such messages should go to the poison queue,
+// because retries never fix them. Received messages without a schema are not validated.
+type ValidatingMarshaler struct {
+	cqrs.CommandEventMarshaler
+	registry *Registry
+}
+
+func NewValidatingMarshaler(marshaler cqrs.CommandEventMarshaler, registry *Registry) ValidatingMarshaler {
+	return ValidatingMarshaler{
+		CommandEventMarshaler: marshaler,
+		registry:              registry,
+	}
+}
+
+// NewJSONMarshaler returns the JSON marshaler of the buses, validating with the embedded schemas.
+func NewJSONMarshaler() (ValidatingMarshaler, error) {
+	registry, err := Embedded()
+	if err != nil {
+		return ValidatingMarshaler{}, fmt.Errorf("invalid embedded schemas: %w", err)
+	}
+
+	return NewValidatingMarshaler(cqrs.JSONMarshaler{GenerateName: cqrs.StructName}, registry), nil
+}
+
+func (m ValidatingMarshaler) Marshal(v any) (*message.Message, error) {
+	msg, err := m.CommandEventMarshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	name := m.Name(v)
+	if err := m.registry.Validate(name, msg.Payload); err != nil {
+		return nil, fmt.Errorf("refusing to send invalid %s: %w", name, err)
+	}
+
+	return msg, nil
+}
+
+func (m ValidatingMarshaler) Unmarshal(msg *message.Message, v any) error {
+	err := m.registry.Validate(m.NameFromMessage(msg), msg.Payload)
+	if err != nil && !errors.Is(err, ErrNoSchema) {
+		return fmt.Errorf("received invalid message %s: %w", msg.UUID, err)
+	}
+
+	return m.CommandEventMarshaler.Unmarshal(msg, v)
+}
+
+const (
+	// ContentTypeMetadataKey is the metadata with the content type of the payload,
+	// messages without it were sent before protobuf was introduced, they are JSON.
+	ContentTypeMetadataKey = "content_type"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// ProtobufMarshaler marshals the messages with their protobuf definitions from ProtoFile.
+//
+// It validates the messages with the registry like ValidatingMarshaler, using the payload they would have as JSON:
+// Marshal fails for invalid messages and messages without a schema,
+// Unmarshal fails with ErrInvalidPayload for invalid messages, so they go to the poison queue.
+type ProtobufMarshaler struct {
+	file     *protobuf.File
+	registry *Registry
+	// naming is the same as of the JSON messages
+	naming cqrs.JSONMarshaler
+}
+
+func NewProtobufMarshaler() (ProtobufMarshaler, error) {
+	file, err := protoFile()
+	if err != nil {
+		return ProtobufMarshaler{}, fmt.Errorf("invalid protobuf definitions: %w", err)
+	}
+
+	registry, err := Embedded()
+	if err != nil {
+		return ProtobufMarshaler{}, fmt.Errorf("invalid embedded schemas: %w", err)
+	}
+
+	return ProtobufMarshaler{
+		file:     file,
+		registry: registry,
+		naming:   cqrs.JSONMarshaler{GenerateName: cqrs.StructName},
+	}, nil
+}
+
+func (m ProtobufMarshaler) Marshal(v any) (*message.Message, error) {
+	name := m.Name(v)
+
+	jsonPayload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.registry.Validate(name, jsonPayload); err != nil {
+		return nil, fmt.Errorf("refusing to send invalid %s: %w", name, err)
+	}
+
+	payload, err := m.file.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeProtobuf)
+	msg.Metadata.Set("name", name)
+
+	return msg, nil
+}
+
+// Unmarshal fails with ErrInvalidPayload for payloads which are not the message from the metadata
+// or don't match its schema. Received messages without a schema are not validated.
+func (m ProtobufMarshaler) Unmarshal(msg *message.Message, v any) error {
+	name := m.NameFromMessage(msg)
+
+	// the whole message is validated, v can have only some of its fields
+	jsonPayload, err := m.toJSON(name, msg.Payload)
+	if err != nil && !errors.Is(err, ErrNoSchema) {
+		return fmt.Errorf("received invalid message %s: %w: %w", msg.UUID, ErrInvalidPayload, err)
+	}
+	if err == nil {
+		if err := m.registry.Validate(name, jsonPayload); err != nil {
+			return fmt.Errorf("received invalid message %s: %w", msg.UUID, err)
+		}
+	}
+
+	err = m.file.Unmarshal(name, msg.Payload, v)
+	if err != nil {
+		return fmt.Errorf("received invalid message %s: %w: %w", msg.UUID, ErrInvalidPayload, err)
+	}
+
+	return nil
+}
+
+// toJSON decodes the payload to the Go type of the message and returns it as JSON,
+// it fails with ErrNoSchema for messages which are not Events or Commands.
+func (m ProtobufMarshaler) toJSON(name string, payload []byte) ([]byte, error) {
+	t, ok := messageTypes()[name]
+	if !ok {
+		return nil, fmt.Errorf("%w of %s", ErrNoSchema, name)
+	}
+
+	v := reflect.New(t)
+	if err := m.file.Unmarshal(name, payload, v.Interface()); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v.Interface())
+}
+
+func (m ProtobufMarshaler) Name(v any) string {
+	return m.naming.Name(v)
+}
+
+func (m ProtobufMarshaler) NameFromMessage(msg *message.Message) string {
+	return m.naming.NameFromMessage(msg)
+}
+
+type Encoding string
+
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+// Encodings select the encoding of sent messages by their names.
+// Each message is sent to its own topic, so ByName selects the encoding of the topic.
+// Messages not in ByName use Default, JSON when it's empty.
+type Encodings struct {
+	Default Encoding
+	ByName  map[string]Encoding
+}
+
+func (e Encodings) For(name string) Encoding {
+	if encoding, ok := e.ByName[name]; ok {
+		return encoding
+	}
+	if e.Default == "" {
+		return EncodingJSON
+	}
+	return e.Default
+}
+
+func (e Encodings) Validate() error {
+	encodings := map[string]Encoding{"default": e.Default}
+	for name, encoding := range e.ByName {
+		encodings[name] = encoding
+	}
+
+	for name, encoding := range encodings {
+		switch encoding {
+		case "", EncodingJSON, EncodingProtobuf:
+		default:
+			return fmt.Errorf("unknown encoding %q of %s", encoding, name)
+		}
+	}
+
+	return nil
+}
+
+// Marshaler sends messages with the encoding selected by Encodings,
+// and receives messages of both encodings by their content type, so JSON and protobuf producers can coexist.
+// It receives CloudEvents in the structured mode too, see ToCloudEvent.
+// Switch a message to protobuf only when all its consumers use Marshaler.
+type Marshaler struct {
+	json      ValidatingMarshaler
+	protobuf  ProtobufMarshaler
+	encodings Encodings
+}
+
+// NewMarshaler returns the marshaler of the buses, sending messages with the encodings.
+func NewMarshaler(encodings Encodings) (Marshaler, error) {
+	jsonMarshaler, err := NewJSONMarshaler()
+	if err != nil {
+		return Marshaler{}, err
+	}
+
+	protobufMarshaler, err := NewProtobufMarshaler()
+	if err != nil {
+		return Marshaler{}, err
+	}
+
+	return Marshaler{
+		json:      jsonMarshaler,
+		protobuf:  protobufMarshaler,
+		encodings: encodings,
+	}, nil
+}
+
+func (m Marshaler) Marshal(v any) (*message.Message, error) {
+	if m.encodings.For(m.Name(v)) == EncodingProtobuf {
+		return m.protobuf.Marshal(v)
+	}
+
+	msg, err := m.json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeJSON)
+
+	return msg, nil
+}
+
+func (m Marshaler) Unmarshal(msg *message.Message, v any) error {
+	switch contentType := msg.Metadata.Get(ContentTypeMetadataKey); contentType {
+	case "", ContentTypeJSON:
+		return m.json.Unmarshal(msg, v)
+	case ContentTypeProtobuf:
+		return m.protobuf.Unmarshal(msg, v)
+	case ContentTypeCloudEvents:
+		data, err := fromStructuredCloudEvent(msg)
+		if err != nil {
+			return err
+		}
+		return m.Unmarshal(data, v)
+	default:
+		return fmt.Errorf("received invalid message %s: %w: unknown content type %q", msg.UUID, ErrInvalidPayload, contentType)
+	}
+}
+
+func (m Marshaler) Name(v any) string {
+	return m.json.Name(v)
+}
+
+func (m Marshaler) NameFromMessage(msg *message.Message) string {
+	return m.json.NameFromMessage(msg)
+}
+
+// JSONPayload returns the payload of the message as JSON, for handlers which read or store the raw payload.
+func (m Marshaler) JSONPayload(msg *message.Message) ([]byte, error) {
+	switch msg.Metadata.Get(ContentTypeMetadataKey) {
+	case ContentTypeProtobuf:
+		return m.protobufToJSON(msg)
+	case ContentTypeCloudEvents:
+		data, err := fromStructuredCloudEvent(msg)
+		if err != nil {
+			return nil, err
+		}
+		return m.JSONPayload(data)
+	default:
+		return msg.Payload, nil
+	}
+}
+
+func (m Marshaler) protobufToJSON(msg *message.Message) ([]byte, error) {
+	payload, err := m.protobuf.toJSON(m.NameFromMessage(msg), msg.Payload)
+	if err != nil && !errors.Is(err, ErrNoSchema) {
+		return nil, fmt.Errorf("received invalid message %s: %w: %w", msg.UUID, ErrInvalidPayload, err)
+	}
+
+	return payload, err
+}
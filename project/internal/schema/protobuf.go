@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"tickets/internal/protobuf"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+)
+
+// ProtoFile has the protobuf definitions of Events and Commands, generated from their protobuf tags.
+const ProtoFile = "proto/tickets.proto"
+
+// FieldNumbersFile has the numbers of all fields ever in ProtoFile, including the removed ones,
+// so a number of a removed field is never given to a new field.
+const FieldNumbersFile = "proto/field_numbers.json"
+
+const protoPackage = "tickets"
+
+var protoFile = sync.OnceValues(func() (*protobuf.File, error) {
+	return protobuf.NewFile(protoPackage, messages())
+})
+
+// messageTypes are the Go types of Events and Commands by their names.
+var messageTypes = sync.OnceValue(func() map[string]reflect.Type {
+	types := map[string]reflect.Type{}
+	for _, msg := range messages() {
+		types[cqrs.StructName(msg)] = reflect.TypeOf(msg)
+	}
+	return types
+})
+
+func messages() []any {
+	return append(append([]any{}, Events...), Commands...)
+}
+
+// GenerateProto returns the content of ProtoFile.
+func GenerateProto() ([]byte, error) {
+	file, err := protoFile()
+	if err != nil {
+		return nil, err
+	}
+
+	header := []byte("// Code generated by go generate ./internal/schema. DO NOT EDIT.\n\n")
+	return append(header, file.Proto()...), nil
+}
+
+// ReadProto reads ProtoFile, it returns nil when it doesn't exist yet.
+func ReadProto(fsys fs.FS) ([]byte, error) {
+	content, err := fs.ReadFile(fsys, ProtoFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return content, err
+}
+
+// WriteProto writes ProtoFile to dir.
+func WriteProto(dir string, content []byte) error {
+	path := filepath.Join(dir, filepath.FromSlash(ProtoFile))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o644)
+}
+
+// CompareProto returns the breaking changes between the old and new ProtoFile.
+func CompareProto(old, new []byte) ([]string, error) {
+	if old == nil {
+		return nil, nil
+	}
+
+	changes, err := protobuf.BreakingChanges(old, new)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, change := range changes {
+		changes[i] = fmt.Sprintf("%s: %s", ProtoFile, change)
+	}
+
+	return changes, nil
+}
+
+// ReadFieldNumbers reads FieldNumbersFile, it returns no numbers when it doesn't exist yet.
+func ReadFieldNumbers(fsys fs.FS) (protobuf.FieldNumbers, error) {
+	content, err := fs.ReadFile(fsys, FieldNumbersFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return protobuf.FieldNumbers{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers protobuf.FieldNumbers
+	if err := json.Unmarshal(content, &numbers); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", FieldNumbersFile, err)
+	}
+
+	return numbers, nil
+}
+
+// WriteFieldNumbers writes FieldNumbersFile to dir.
+func WriteFieldNumbers(dir string, numbers protobuf.FieldNumbers) error {
+	content, err := json.MarshalIndent(numbers, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(FieldNumbersFile))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// RecordFieldNumbers returns the recorded numbers with the new fields of ProtoFile added,
+// and the fields which changed or reused a recorded number. They always break compatibility,
+// even with all fields of the previous ProtoFile kept.
+func RecordFieldNumbers(recorded protobuf.FieldNumbers, proto []byte) (protobuf.FieldNumbers, []string, error) {
+	numbers, conflicts, err := recorded.Record(proto)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, conflict := range conflicts {
+		conflicts[i] = fmt.Sprintf("%s: %s", FieldNumbersFile, conflict)
+	}
+
+	return numbers, conflicts, nil
+}
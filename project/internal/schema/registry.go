@@ -0,0 +1,260 @@
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"tickets/internal/entities"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+)
+
+//go:generate go run tickets/cmd/schemas generate -dir .
+
+// Events are all events sent by the event buses, their schemas are in events/.
+var Events = []any{
+	entities.BookingMade_v1{},
+	entities.BookingCancellationInitialized_v1{},
+	entities.BookingCancelled_v1{},
+	entities.TicketBookingConfirmed_v1{},
+	entities.TicketBookingCanceled_v1{},
+	entities.TicketReceiptIssued_v1{},
+	entities.TicketPrinted_v1{},
+	entities.TicketRefunded_v1{},
+	entities.TicketCheckedIn_v1{},
+	entities.TicketTransferRequested_v1{},
+	entities.TicketTransferred_v1{},
+	entities.ShowCancelled_v1{},
+	entities.VipBundleInitialized_v1{},
+	entities.VipBundleFinalized_v1{},
+	entities.BookingFailed_v1{},
+	entities.FlightBooked_v1{},
+	entities.FlightBookingFailed_v1{},
+	entities.TaxiBooked_v1{},
+	entities.TaxiBookingFailed_v1{},
+	entities.InternalOpsReadModelUpdated{},
+}
+
+// Commands are all commands sent by the command bus, their schemas are in commands/.
+var Commands = []any{
+	entities.RefundTicket{},
+	entities.BookShowTickets{},
+	entities.BookFlight{},
+	entities.BookTaxi{},
+	entities.CancelFlightTickets{},
+}
+
+var ErrNoSchema = errors.New("no schema")
+
+//go:embed events/*.json commands/*.json
+var embedded embed.FS
+
+// Registry has the schemas of messages by their names.
+type Registry struct {
+	schemas map[string]*Schema
+}
+
+var (
+	embeddedRegistry     *Registry
+	embeddedRegistryErr  error
+	embeddedRegistryOnce sync.Once
+)
+
+// Embedded returns the registry of the schemas checked into the repository.
+func Embedded() (*Registry, error) {
+	embeddedRegistryOnce.Do(func() {
+		files, err := Read(embedded)
+		if err != nil {
+			embeddedRegistryErr = err
+			return
+		}
+		embeddedRegistry = NewRegistry(files)
+	})
+
+	return embeddedRegistry, embeddedRegistryErr
+}
+
+// NewRegistry creates the registry from the schema files, see Read.
+func NewRegistry(files map[string]*Schema) *Registry {
+	schemas := make(map[string]*Schema, len(files))
+	for _, s := range files {
+		schemas[s.Title] = s
+	}
+
+	return &Registry{schemas: schemas}
+}
+
+func (r *Registry) Get(name string) (*Schema, bool) {
+	s, ok := r.schemas[name]
+	return s, ok
+}
+
+// Validate validates the payload of the message with the name,
+// it returns ErrNoSchema for messages without a schema.
+func (r *Registry) Validate(name string, payload []byte) error {
+	s, ok := r.schemas[name]
+	if !ok {
+		return fmt.Errorf("%w of %s, run go generate ./internal/schema", ErrNoSchema, name)
+	}
+
+	return s.Validate(payload)
+}
+
+// GenerateAll returns the schemas of Events and Commands by their file paths, e.g. events/BookingMade_v1.json.
+func GenerateAll() (map[string]*Schema, error) {
+	files := map[string]*Schema{}
+
+	for dir, messages := range map[string][]any{"events": Events, "commands": Commands} {
+		for _, msg := range messages {
+			s, err := Generate(msg)
+			if err != nil {
+				return nil, err
+			}
+
+			name := cqrs.StructName(msg)
+			if s.Title != name {
+				return nil, fmt.Errorf("schema title %s is not the message name %s", s.Title, name)
+			}
+
+			files[path.Join(dir, name+".json")] = s
+		}
+	}
+
+	return files, nil
+}
+
+// Read reads the schema files from events/ and commands/.
+func Read(fsys fs.FS) (map[string]*Schema, error) {
+	files := map[string]*Schema{}
+
+	for _, dir := range []string{"events", "commands"} {
+		paths, err := fs.Glob(fsys, dir+"/*.json")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range paths {
+			content, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return nil, err
+			}
+
+			var s Schema
+			if err := json.Unmarshal(content, &s); err != nil {
+				return nil, fmt.Errorf("invalid schema %s: %w", p, err)
+			}
+			files[p] = &s
+		}
+	}
+
+	return files, nil
+}
+
+// Write replaces the schema files in dir with files.
+func Write(dir string, files map[string]*Schema) error {
+	for _, subdir := range []string{"events", "commands"} {
+		old, err := filepath.Glob(filepath.Join(dir, subdir, "*.json"))
+		if err != nil {
+			return err
+		}
+		for _, p := range old {
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	for p, s := range files {
+		content, err := Marshal(s)
+		if err != nil {
+			return err
+		}
+
+		filePath := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, content, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Marshal formats the schema as it's checked into the repository.
+func Marshal(s *Schema) ([]byte, error) {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(content, '\n'), nil
+}
+
+// Compare returns the breaking changes between the old and new schema files, including removed messages.
+func Compare(old, new map[string]*Schema) []string {
+	var changes []string
+
+	for p, oldSchema := range old {
+		newSchema, ok := new[p]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s: removed", p))
+			continue
+		}
+
+		for _, change := range BreakingChanges(oldSchema, newSchema) {
+			changes = append(changes, fmt.Sprintf("%s: %s", p, change))
+		}
+	}
+
+	sort.Strings(changes)
+	return changes
+}
+
+// Diff returns the paths of files which are different in old and new, including added and removed ones.
+func Diff(old, new map[string]*Schema) ([]string, error) {
+	paths := map[string]struct{}{}
+	for p := range old {
+		paths[p] = struct{}{}
+	}
+	for p := range new {
+		paths[p] = struct{}{}
+	}
+
+	var diff []string
+	for p := range paths {
+		oldContent, err := marshalOrEmpty(old[p])
+		if err != nil {
+			return nil, err
+		}
+		newContent, err := marshalOrEmpty(new[p])
+		if err != nil {
+			return nil, err
+		}
+
+		if oldContent != newContent {
+			diff = append(diff, p)
+		}
+	}
+
+	sort.Strings(diff)
+	return diff, nil
+}
+
+func marshalOrEmpty(s *Schema) (string, error) {
+	if s == nil {
+		return "", nil
+	}
+
+	content, err := Marshal(s)
+	return strings.TrimSpace(string(content)), err
+}
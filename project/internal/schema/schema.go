@@ -0,0 +1,221 @@
+// Package schema generates JSON Schemas of events and commands from the entities structs,
+// and validates the payloads against the schemas checked into the repository.
+//
+// Only the subset of JSON Schema needed for our structs is supported: types, formats,
+// properties, required properties and items.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+type Schema struct {
+	Schema string `json:"$schema,omitempty"`
+	Title  string `json:"title,omitempty"`
+
+	Type   Types  `json:"type,omitempty"`
+	Format string `json:"format,omitempty"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+
+	// Items is the schema of array items.
+	Items *Schema `json:"items,omitempty"`
+	// AdditionalProperties is the schema of map values, properties not listed in structs are always allowed,
+	// so consumers accept payloads of newer producers.
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+}
+
+// Types is marshaled as a string when there is one type.
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+func (t Types) Has(typ string) bool {
+	for _, tt := range t {
+		if tt == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Types) String() string {
+	return strings.Join(t, "|")
+}
+
+// optionalTag marks fields which can be missing in payloads, for example in events published before they were added:
+//
+//	DecisionID uuid.UUID `json:"decision_id" jsonschema:"optional"`
+//
+// Fields with omitempty and pointers are always optional.
+const optionalTag = "optional"
+
+// overrides are types marshaled with custom MarshalJSON.
+var overrides = map[reflect.Type]func() *Schema{
+	reflect.TypeOf(time.Time{}): func() *Schema {
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	},
+	reflect.TypeOf(uuid.UUID{}): func() *Schema {
+		return &Schema{Type: Types{"string"}, Format: "uuid"}
+	},
+	reflect.TypeOf(decimal.Decimal{}): func() *Schema {
+		return &Schema{Type: Types{"string", "number"}}
+	},
+	reflect.TypeOf(entities.Money{}): func() *Schema {
+		return &Schema{
+			Type: Types{"object"},
+			Properties: map[string]*Schema{
+				// the amount is sent as a string, numbers are accepted from older producers
+				"amount":   {Type: Types{"string", "number"}},
+				"currency": {Type: Types{"string"}},
+			},
+			Required: []string{"amount"},
+		}
+	},
+}
+
+// Generate returns the schema of the message struct, the title is the message name.
+func Generate(v any) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", t)
+	}
+
+	s, err := generate(t, nil)
+	if err != nil {
+		return nil, fmt.Errorf("generate schema of %s: %w", t.Name(), err)
+	}
+	s.Schema = draft
+	s.Title = t.Name()
+
+	return s, nil
+}
+
+func generate(t reflect.Type, path []string) (*Schema, error) {
+	if override, ok := overrides[t]; ok {
+		return override(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s, err := generate(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+		return nullable(s), nil
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}, nil
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is base64
+			return &Schema{Type: Types{"string", "null"}}, nil
+		}
+		items, err := generate(t.Elem(), append(path, "[]"))
+		if err != nil {
+			return nil, err
+		}
+		// nil slices are marshaled as null
+		return &Schema{Type: Types{"array", "null"}, Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: map keys must be strings", strings.Join(path, "."))
+		}
+		values, err := generate(t.Elem(), append(path, "{}"))
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Types{"object", "null"}, AdditionalProperties: values}, nil
+	case reflect.Struct:
+		return generateStruct(t, path)
+	}
+
+	return nil, fmt.Errorf("%s: unsupported type %s", strings.Join(path, "."), t)
+}
+
+func generateStruct(t reflect.Type, path []string) (*Schema, error) {
+	s := &Schema{
+		Type:       Types{"object"},
+		Properties: map[string]*Schema{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := generate(field.Type, append(path, name))
+		if err != nil {
+			return nil, err
+		}
+		s.Properties[name] = property
+
+		isOptional := strings.Contains(options, "omitempty") ||
+			field.Type.Kind() == reflect.Pointer ||
+			field.Tag.Get("jsonschema") == optionalTag
+		if !isOptional {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	sort.Strings(s.Required)
+
+	return s, nil
+}
+
+func nullable(s *Schema) *Schema {
+	if !s.Type.Has("null") {
+		s.Type = append(s.Type, "null")
+	}
+	return s
+}
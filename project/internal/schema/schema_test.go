@@ -0,0 +1,478 @@
+package schema
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"testing"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemas_UpToDate(t *testing.T) {
+	current, err := GenerateAll()
+	require.NoError(t, err)
+
+	recorded, err := Read(embedded)
+	require.NoError(t, err)
+
+	assert.Empty(t, Compare(recorded, current), "breaking changes, add a new version of the message instead")
+
+	outdated, err := Diff(recorded, current)
+	require.NoError(t, err)
+	assert.Empty(t, outdated, "run go generate ./internal/schema")
+}
+
+func TestSchemas_MatchMarshaledMessages(t *testing.T) {
+	registry, err := Embedded()
+	require.NoError(t, err)
+
+	// zero values catch types marshaled with custom MarshalJSON without an override
+	for _, msg := range append(append([]any{}, Events...), Commands...) {
+		payload, err := json.Marshal(msg)
+		require.NoError(t, err)
+
+		assert.NoError(t, registry.Validate(cqrs.StructName(msg), payload))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	registry, err := Embedded()
+	require.NoError(t, err)
+
+	valid := entities.TicketBookingConfirmed_v1{
+		Header:        entities.NewEventHeader(),
+		TicketID:      uuid.NewString(),
+		CustomerEmail: "customer@example.com",
+		Price:         entities.MustNewMoney("12.50", "EUR"),
+		BookingID:     uuid.NewString(),
+	}
+	payload, err := json.Marshal(valid)
+	require.NoError(t, err)
+
+	withChanges := func(change func(payload map[string]any)) []byte {
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(payload, &decoded))
+		change(decoded)
+
+		changed, err := json.Marshal(decoded)
+		require.NoError(t, err)
+		return changed
+	}
+
+	testCases := []struct {
+		name       string
+		payload    []byte
+		violations []string
+	}{
+		{
+			name:    "valid",
+			payload: payload,
+		},
+		{
+			name: "unknown properties are allowed",
+			payload: withChanges(func(p map[string]any) {
+				p["show_id"] = uuid.NewString()
+			}),
+		},
+		{
+			name: "amount sent as a number",
+			payload: withChanges(func(p map[string]any) {
+				p["price"] = map[string]any{"amount": 12.5, "currency": "EUR"}
+			}),
+		},
+		{
+			name: "typo in a property name",
+			payload: withChanges(func(p map[string]any) {
+				p["bookingid"] = p["booking_id"]
+				delete(p, "booking_id")
+			}),
+			violations: []string{`$: missing required property "booking_id"`},
+		},
+		{
+			name: "wrong types",
+			payload: withChanges(func(p map[string]any) {
+				p["ticket_id"] = 1
+				p["price"] = map[string]any{"amount": true}
+			}),
+			violations: []string{
+				"$.price.amount: expected string|number, got boolean",
+				"$.ticket_id: expected string, got integer",
+			},
+		},
+		{
+			name: "wrong formats",
+			payload: withChanges(func(p map[string]any) {
+				p["header"].(map[string]any)["published_at"] = "yesterday"
+			}),
+			violations: []string{`$.header.published_at: "yesterday" is not a RFC 3339 date-time`},
+		},
+		{
+			name:       "not an object",
+			payload:    []byte(`[]`),
+			violations: []string{"$: expected object, got array"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := registry.Validate("TicketBookingConfirmed_v1", tc.payload)
+			if tc.violations == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.ErrorIs(t, err, ErrInvalidPayload)
+			assert.Equal(t, "TicketBookingConfirmed_v1", validationErr.Name)
+			assert.Equal(t, tc.violations, validationErr.Violations)
+		})
+	}
+
+	t.Run("uuid format", func(t *testing.T) {
+		err := registry.Validate("BookingMade_v1", []byte(`{
+			"header": {"id": "1", "published_at": "2025-01-01T00:00:00Z", "idempotency_key": "1"},
+			"booking_id": "not-uuid",
+			"number_of_tickets": 1.5,
+			"customer_email": "customer@example.com",
+			"show_id": "`+uuid.NewString()+`",
+			"booked_at": "`+time.Now().Format(time.RFC3339Nano)+`"
+		}`))
+
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []string{
+			`$.booking_id: "not-uuid" is not a UUID`,
+			"$.number_of_tickets: expected integer, got number",
+		}, validationErr.Violations)
+	})
+}
+
+func TestBreakingChanges(t *testing.T) {
+	old := &Schema{
+		Type: Types{"object"},
+		Properties: map[string]*Schema{
+			"ticket_id": {Type: Types{"string"}, Format: "uuid"},
+			"price": {
+				Type: Types{"object"},
+				Properties: map[string]*Schema{
+					"amount": {Type: Types{"string"}},
+				},
+				Required: []string{"amount"},
+			},
+			"reason": {Type: Types{"string"}},
+			"tags":   {Type: Types{"array", "null"}, Items: &Schema{Type: Types{"string"}}},
+		},
+		Required: []string{"price", "ticket_id"},
+	}
+
+	testCases := []struct {
+		name     string
+		change   func(s *Schema)
+		expected []string
+	}{
+		{
+			name: "optional property added",
+			change: func(s *Schema) {
+				s.Properties["show_id"] = &Schema{Type: Types{"string"}}
+			},
+		},
+		{
+			name: "types reordered",
+			change: func(s *Schema) {
+				s.Properties["tags"].Type = Types{"null", "array"}
+			},
+		},
+		{
+			name: "required property added",
+			change: func(s *Schema) {
+				s.Properties["show_id"] = &Schema{Type: Types{"string"}}
+				s.Required = append(s.Required, "show_id")
+			},
+			expected: []string{"$.show_id: added as required"},
+		},
+		{
+			name: "property removed",
+			change: func(s *Schema) {
+				delete(s.Properties["price"].Properties, "amount")
+				s.Properties["price"].Required = nil
+			},
+			expected: []string{"$.price.amount: removed"},
+		},
+		{
+			name: "type and format changed",
+			change: func(s *Schema) {
+				s.Properties["ticket_id"].Format = ""
+				s.Properties["tags"].Items.Type = Types{"integer"}
+			},
+			expected: []string{
+				`$.tags[]: type changed from string to integer`,
+				`$.ticket_id: format changed from "uuid" to ""`,
+			},
+		},
+		{
+			name: "required and optional swapped",
+			change: func(s *Schema) {
+				s.Required = []string{"price", "reason"}
+			},
+			expected: []string{"$.reason: became required", "$.ticket_id: became optional"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := json.Marshal(old)
+			require.NoError(t, err)
+
+			var changed Schema
+			require.NoError(t, json.Unmarshal(content, &changed))
+			tc.change(&changed)
+
+			assert.Equal(t, tc.expected, BreakingChanges(old, &changed))
+		})
+	}
+}
+
+func TestValidatingMarshaler(t *testing.T) {
+	marshaler, err := NewJSONMarshaler()
+	require.NoError(t, err)
+
+	t.Run("marshal valid message", func(t *testing.T) {
+		msg, err := marshaler.Marshal(entities.InternalOpsReadModelUpdated{
+			Header:    entities.NewEventHeader(),
+			BookingID: uuid.New(),
+		})
+		require.NoError(t, err)
+
+		var event entities.InternalOpsReadModelUpdated
+		assert.NoError(t, marshaler.Unmarshal(msg, &event))
+	})
+
+	t.Run("marshal message without schema", func(t *testing.T) {
+		type Unknown_v1 struct{}
+
+		_, err := marshaler.Marshal(Unknown_v1{})
+		assert.ErrorIs(t, err, ErrNoSchema)
+	})
+
+	t.Run("unmarshal invalid message", func(t *testing.T) {
+		msg := message.NewMessage(uuid.NewString(), []byte(`{"header": {}, "booking_id": "1"}`))
+		msg.Metadata.Set("name", "InternalOpsReadModelUpdated")
+
+		var event entities.InternalOpsReadModelUpdated
+		err := marshaler.Unmarshal(msg, &event)
+		assert.ErrorIs(t, err, ErrInvalidPayload)
+		assert.ErrorContains(t, err, `$.booking_id: "1" is not a UUID`)
+	})
+
+	t.Run("unmarshal message without schema", func(t *testing.T) {
+		msg := message.NewMessage(uuid.NewString(), []byte(`{"foo": "bar"}`))
+		msg.Metadata.Set("name", "Unknown_v1")
+
+		var v struct {
+			Foo string `json:"foo"`
+		}
+		require.NoError(t, marshaler.Unmarshal(msg, &v))
+		assert.Equal(t, "bar", v.Foo)
+	})
+}
+
+func TestProto_UpToDate(t *testing.T) {
+	current, err := GenerateProto()
+	require.NoError(t, err)
+
+	recorded, err := ReadProto(os.DirFS("."))
+	require.NoError(t, err)
+	require.NotNil(t, recorded, "%s is missing, run go generate ./internal/schema", ProtoFile)
+
+	changes, err := CompareProto(recorded, current)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "breaking changes, add a new version of the message instead")
+
+	assert.Equal(t, string(recorded), string(current), "run go generate ./internal/schema")
+}
+
+func TestProto_FieldNumbers(t *testing.T) {
+	current, err := GenerateProto()
+	require.NoError(t, err)
+
+	recorded, err := ReadFieldNumbers(os.DirFS("."))
+	require.NoError(t, err)
+	require.NotEmpty(t, recorded, "%s is missing, run go generate ./internal/schema", FieldNumbersFile)
+
+	numbers, conflicts, err := RecordFieldNumbers(recorded, current)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts, "field numbers must never change or be reused, add a new version of the message instead")
+
+	assert.Equal(t, recorded, numbers, "run go generate ./internal/schema")
+}
+
+func TestMarshaler(t *testing.T) {
+	event := entities.TicketBookingConfirmed_v1{
+		Header:        entities.NewEventHeader(),
+		TicketID:      uuid.NewString(),
+		CustomerEmail: "customer@example.com",
+		Price:         entities.MustNewMoney("12.50", "EUR"),
+		BookingID:     uuid.NewString(),
+	}
+	// both encodings decode timestamps in UTC
+	event.Header.PublishedAt = time.Date(2024, 6, 1, 12, 30, 0, 123, time.UTC)
+
+	jsonMarshaler, err := NewMarshaler(Encodings{})
+	require.NoError(t, err)
+	protobufMarshaler, err := NewMarshaler(Encodings{
+		ByName: map[string]Encoding{"TicketBookingConfirmed_v1": EncodingProtobuf},
+	})
+	require.NoError(t, err)
+
+	jsonMsg, err := jsonMarshaler.Marshal(event)
+	require.NoError(t, err)
+	assert.Equal(t, ContentTypeJSON, jsonMsg.Metadata.Get(ContentTypeMetadataKey))
+
+	protobufMsg, err := protobufMarshaler.Marshal(event)
+	require.NoError(t, err)
+	assert.Equal(t, ContentTypeProtobuf, protobufMsg.Metadata.Get(ContentTypeMetadataKey))
+	assert.Equal(t, "TicketBookingConfirmed_v1", protobufMsg.Metadata.Get("name"))
+
+	t.Run("other messages use the default encoding", func(t *testing.T) {
+		msg, err := protobufMarshaler.Marshal(entities.InternalOpsReadModelUpdated{
+			Header:    entities.NewEventHeader(),
+			BookingID: uuid.New(),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ContentTypeJSON, msg.Metadata.Get(ContentTypeMetadataKey))
+	})
+
+	t.Run("unmarshal detects the content type", func(t *testing.T) {
+		legacyMsg := message.NewMessage(uuid.NewString(), jsonMsg.Payload)
+		legacyMsg.Metadata.Set("name", "TicketBookingConfirmed_v1")
+
+		for name, msg := range map[string]*message.Message{
+			"json":                 jsonMsg,
+			"protobuf":             protobufMsg,
+			"without content type": legacyMsg,
+		} {
+			t.Run(name, func(t *testing.T) {
+				var received entities.TicketBookingConfirmed_v1
+				require.NoError(t, jsonMarshaler.Unmarshal(msg, &received))
+
+				// protobuf keeps the amount without trailing zeros
+				assert.True(t, event.Price.Equal(received.Price), "%s != %s", event.Price, received.Price)
+				received.Price = event.Price
+				assert.Equal(t, event, received)
+			})
+		}
+	})
+
+	t.Run("unmarshal unknown content type", func(t *testing.T) {
+		msg := message.NewMessage(uuid.NewString(), jsonMsg.Payload)
+		msg.Metadata.Set("name", "TicketBookingConfirmed_v1")
+		msg.Metadata.Set(ContentTypeMetadataKey, "text/xml")
+
+		var received entities.TicketBookingConfirmed_v1
+		assert.ErrorIs(t, jsonMarshaler.Unmarshal(msg, &received), ErrInvalidPayload)
+	})
+
+	t.Run("unmarshal invalid protobuf", func(t *testing.T) {
+		msg := message.NewMessage(uuid.NewString(), []byte{0xff, 0xff})
+		msg.Metadata.Set("name", "TicketBookingConfirmed_v1")
+		msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeProtobuf)
+
+		var received entities.TicketBookingConfirmed_v1
+		assert.ErrorIs(t, jsonMarshaler.Unmarshal(msg, &received), ErrInvalidPayload)
+	})
+
+	t.Run("unmarshal invalid CloudEvent", func(t *testing.T) {
+		for name, payload := range map[string]string{
+			"not json":            `<event/>`,
+			"unsupported version": `{"specversion": "0.3", "id": "1", "source": "/svc-tickets", "type": "TicketBookingConfirmed_v1"}`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				msg := message.NewMessage(uuid.NewString(), []byte(payload))
+				msg.Metadata.Set("name", "TicketBookingConfirmed_v1")
+				msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeCloudEvents)
+
+				var received entities.TicketBookingConfirmed_v1
+				assert.ErrorIs(t, jsonMarshaler.Unmarshal(msg, &received), ErrInvalidPayload)
+			})
+		}
+	})
+
+	t.Run("json payload", func(t *testing.T) {
+		payload, err := jsonMarshaler.JSONPayload(protobufMsg)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(jsonMsg.Payload), string(payload))
+
+		payload, err = jsonMarshaler.JSONPayload(jsonMsg)
+		require.NoError(t, err)
+		assert.Equal(t, string(jsonMsg.Payload), string(payload))
+	})
+}
+
+func TestProtobufMarshaler_Validation(t *testing.T) {
+	marshaler, err := NewProtobufMarshaler()
+	require.NoError(t, err)
+
+	// the schemas of the entities match every value of their Go types,
+	// so a stricter schema stands for a consumer which validates more than the producer
+	embedded, ok := marshaler.registry.Get("TicketBookingConfirmed_v1")
+	require.True(t, ok)
+	strict := *embedded
+	strict.Properties = maps.Clone(embedded.Properties)
+	strict.Properties["ticket_id"] = &Schema{Type: Types{"string"}, Format: "uuid"}
+
+	strictMarshaler := marshaler
+	strictMarshaler.registry = NewRegistry(map[string]*Schema{"events/TicketBookingConfirmed_v1.json": &strict})
+
+	event := entities.TicketBookingConfirmed_v1{
+		Header:        entities.NewEventHeader(),
+		TicketID:      "1",
+		CustomerEmail: "customer@example.com",
+		Price:         entities.MustNewMoney("12.50", "EUR"),
+		BookingID:     uuid.NewString(),
+	}
+
+	t.Run("marshal invalid message", func(t *testing.T) {
+		_, err := strictMarshaler.Marshal(event)
+		assert.ErrorIs(t, err, ErrInvalidPayload)
+		assert.ErrorContains(t, err, `refusing to send invalid TicketBookingConfirmed_v1`)
+		assert.ErrorContains(t, err, `$.ticket_id: "1" is not a UUID`)
+	})
+
+	t.Run("marshal message without schema", func(t *testing.T) {
+		type Unknown_v1 struct{}
+
+		_, err := marshaler.Marshal(Unknown_v1{})
+		assert.ErrorIs(t, err, ErrNoSchema)
+	})
+
+	t.Run("unmarshal invalid message", func(t *testing.T) {
+		msg, err := marshaler.Marshal(event)
+		require.NoError(t, err)
+
+		var received entities.TicketBookingConfirmed_v1
+		err = strictMarshaler.Unmarshal(msg, &received)
+		assert.ErrorIs(t, err, ErrInvalidPayload)
+		assert.ErrorContains(t, err, `$.ticket_id: "1" is not a UUID`)
+	})
+
+	t.Run("unmarshal validates the whole message", func(t *testing.T) {
+		msg, err := marshaler.Marshal(event)
+		require.NoError(t, err)
+
+		var received struct {
+			BookingID string `json:"booking_id"`
+		}
+		require.NoError(t, marshaler.Unmarshal(msg, &received))
+		assert.Equal(t, event.BookingID, received.BookingID)
+
+		assert.ErrorIs(t, strictMarshaler.Unmarshal(msg, &received), ErrInvalidPayload)
+	})
+}
@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidPayload = errors.New("payload doesn't match the schema")
+
+// ValidationError lists all violations of the payload, with JSON paths like $.price.amount.
+type ValidationError struct {
+	Name       string
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, strings.Join(e.Violations, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidPayload
+}
+
+// Validate returns a *ValidationError when the JSON payload doesn't match the schema.
+func (s *Schema) Validate(payload []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Name: s.Title, Violations: []string{"$: invalid JSON: " + err.Error()}}
+	}
+
+	var violations []string
+	s.validate(value, "$", &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Name: s.Title, Violations: violations}
+	}
+
+	return nil
+}
+
+func (s *Schema) validate(value any, path string, violations *[]string) {
+	typ := jsonType(value)
+	if len(s.Type) > 0 && !s.Type.Has(typ) && !(typ == "integer" && s.Type.Has("number")) {
+		*violations = append(*violations, fmt.Sprintf("%s: expected %s, got %s", path, s.Type, typ))
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		if err := checkFormat(s.Format, v); err != nil {
+			*violations = append(*violations, fmt.Sprintf("%s: %s", path, err))
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			if !ok {
+				property = s.AdditionalProperties
+			}
+			if property != nil {
+				property.validate(v[name], path+"."+name, violations)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	}
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+func checkFormat(format string, value string) error {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+			return fmt.Errorf("%q is not a RFC 3339 date-time", value)
+		}
+	case "uuid":
+		if _, err := uuid.Parse(value); err != nil {
+			return fmt.Errorf("%q is not a UUID", value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package tickettoken
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	signer, err := NewSigner("secret")
+	require.NoError(t, err)
+	otherSigner, err := NewSigner("other-secret")
+	require.NoError(t, err)
+
+	ticketID := uuid.New()
+
+	t.Run("valid token", func(t *testing.T) {
+		claims, err := signer.Verify(signer.Sign(ticketID.String(), 3))
+		require.NoError(t, err)
+		assert.Equal(t, Claims{TicketID: ticketID, Version: 3}, claims)
+	})
+
+	t.Run("legacy token without version", func(t *testing.T) {
+		token := signer.Sign(ticketID.String(), 0)
+		// printed before transfers existed, the payload is only the ticket ID
+		assert.Equal(t, 1, strings.Count(token, "."))
+
+		claims, err := signer.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, Claims{TicketID: ticketID, Version: 0}, claims)
+	})
+
+	t.Run("tokens of other versions differ", func(t *testing.T) {
+		assert.NotEqual(t, signer.Sign(ticketID.String(), 0), signer.Sign(ticketID.String(), 1))
+		assert.NotEqual(t, signer.Sign(ticketID.String(), 1), signer.Sign(ticketID.String(), 2))
+	})
+
+	token := signer.Sign(ticketID.String(), 0)
+	separator := strings.LastIndex(token, ".")
+	payload, signature := token[:separator], token[separator+1:]
+
+	// flips the first character, so the signature is still valid base64
+	tampered := "A" + signature[1:]
+	if signature[0] == 'A' {
+		tampered = "B" + signature[1:]
+	}
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{
+			name:  "tampered signature",
+			token: payload + "." + tampered,
+		},
+		{
+			name:  "tampered ticket ID",
+			token: uuid.NewString() + "." + signature,
+		},
+		{
+			name:  "version added to the payload",
+			token: payload + ".1." + signature,
+		},
+		{
+			name:  "malformed base64",
+			token: payload + ".!!not-base64!!",
+		},
+		{
+			name:  "without signature",
+			token: ticketID.String(),
+		},
+		{
+			name:  "empty",
+			token: "",
+		},
+		{
+			name:  "non-UUID payload",
+			token: signer.Sign("not-a-uuid", 0),
+		},
+		{
+			name:  "non-numeric version",
+			token: signer.Sign(ticketID.String()+".first", 0),
+		},
+		{
+			name:  "negative version",
+			token: signer.Sign(ticketID.String()+".-1", 0),
+		},
+		{
+			name:  "wrong secret",
+			token: otherSigner.Sign(ticketID.String(), 0),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := signer.Verify(tc.token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestNewSigner_EmptySecret(t *testing.T) {
+	_, err := NewSigner("")
+	assert.Error(t, err)
+}
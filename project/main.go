@@ -7,8 +7,10 @@ import (
 	"os"
 	"os/signal"
 	"tickets/internal/app"
+	"tickets/internal/entities"
 	"tickets/internal/infrastructure/clients"
 	"tickets/internal/observability"
+	"tickets/internal/tickettoken"
 
 	commonClients "github.com/ThreeDotsLabs/go-event-driven/common/clients"
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
@@ -70,6 +72,16 @@ func main() {
 
 	tp := observability.ConfigureTraceProvider()
 
+	ticketSigner, err := tickettoken.NewSigner(os.Getenv("TICKET_SIGNING_SECRET"))
+	if err != nil {
+		panic(err)
+	}
+
+	refundPolicy, err := entities.ParseRefundPolicy(os.Getenv("REFUND_POLICY"))
+	if err != nil {
+		panic(err)
+	}
+
 	a, err := app.NewApp(
 		wlogger,
 		spreadsheetsClient,
@@ -81,6 +93,8 @@ func main() {
 		rdb,
 		db,
 		tp,
+		ticketSigner,
+		refundPolicy,
 	)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"tickets/internal/entities"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bookingCancellationResponse struct {
+	Status  string `json:"status"`
+	Tickets []struct {
+		TicketID string `json:"ticket_id"`
+		Status   string `json:"status"`
+	} `json:"tickets"`
+}
+
+func (suite *ComponentTestSuite) TestCancelBooking() {
+	// far enough before the show for the full refund
+	showID := suite.insertShow(100, time.Now().Add(30*24*time.Hour))
+	bookingID := suite.insertBooking(showID, 2)
+	ticketIDs := []string{
+		suite.insertTicket(bookingID, showID, "confirmed").String(),
+		suite.insertTicket(bookingID, showID, "printed").String(),
+	}
+
+	var mu sync.Mutex
+	refunded := map[string]int{}
+	suite.paymentsMock.EXPECT().
+		Refund(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, ticketID, _, _ string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			refunded[ticketID]++
+			return nil
+		}).
+		MinTimes(2)
+	suite.receiptsMock.EXPECT().
+		VoidReceipt(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		MinTimes(2)
+
+	resp := suite.postJSON(fmt.Sprintf("/bookings/%s/cancel", bookingID), map[string]string{"reason": "can't make it"}, nil)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusAccepted, resp.StatusCode)
+
+	require.EventuallyWithT(
+		suite.T(),
+		func(t *assert.CollectT) {
+			var cancellation bookingCancellationResponse
+			status := suite.getJSON(fmt.Sprintf("/bookings/%s/cancellation", bookingID), &cancellation)
+			if !assert.Equal(t, http.StatusOK, status) {
+				return
+			}
+
+			assert.Equal(t, "cancelled", cancellation.Status)
+			assert.Len(t, cancellation.Tickets, 2)
+			for _, ticket := range cancellation.Tickets {
+				assert.Contains(t, ticketIDs, ticket.TicketID)
+				assert.Equal(t, "refunded", ticket.Status)
+			}
+		},
+		15*time.Second,
+		100*time.Millisecond,
+	)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, ticketID := range ticketIDs {
+		assert.Contains(suite.T(), refunded, ticketID)
+	}
+}
+
+func (suite *ComponentTestSuite) TestCancelBookingWithUnconfirmedTickets() {
+	showID := suite.insertShow(100, time.Now().Add(30*24*time.Hour))
+	bookingID := suite.insertBooking(showID, 2)
+	// the second ticket is not confirmed yet
+	suite.insertTicket(bookingID, showID, "confirmed")
+
+	resp := suite.postJSON(fmt.Sprintf("/bookings/%s/cancel", bookingID), map[string]string{}, nil)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusConflict, resp.StatusCode)
+
+	// nothing is cancelled, so the request can be retried when the tickets are confirmed
+	status := suite.getJSON(fmt.Sprintf("/bookings/%s/cancellation", bookingID), nil)
+	assert.Equal(suite.T(), http.StatusNotFound, status)
+}
+
+func (suite *ComponentTestSuite) TestCancelUnknownBooking() {
+	resp := suite.postJSON(fmt.Sprintf("/bookings/%s/cancel", "5f5e7fd8-5e2b-4b4e-9b8a-9d6c0a9f7a11"), map[string]string{}, nil)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+// The show is cancelled while the customer's cancellation is not done yet, the organizer refunds all tickets.
+func (suite *ComponentTestSuite) TestCancelShowDuringCustomerCancellation() {
+	showID := suite.insertShow(100, time.Now().Add(30*24*time.Hour))
+	bookingID := suite.insertBooking(showID, 2)
+	deniedTicketID := suite.insertTicket(bookingID, showID, "confirmed").String()
+	pendingTicketID := suite.insertTicket(bookingID, showID, "confirmed").String()
+
+	// the refund of the first ticket was denied to the customer, the second one didn't get a decision yet
+	cancellation := entities.BookingCancellation{
+		BookingID:       bookingID,
+		ShowID:          showID,
+		NumberOfTickets: 2,
+		CustomerEmail:   "customer@example.com",
+		Reason:          "can't make it",
+		Initiator:       entities.RefundInitiatorCustomer,
+		Tickets: map[string]entities.BookingCancellationTicket{
+			deniedTicketID:  {Status: entities.BookingCancellationTicketRefundDenied},
+			pendingTicketID: {Status: entities.BookingCancellationTicketPending},
+		},
+		Attempts:    1,
+		RequestedAt: time.Now().UTC(),
+	}
+	payload, err := json.Marshal(cancellation)
+	require.NoError(suite.T(), err)
+	_, err = suite.db.ExecContext(suite.ctx, `INSERT INTO booking_cancellations (booking_id, payload) VALUES ($1, $2)`, bookingID, payload)
+	require.NoError(suite.T(), err)
+	_, err = suite.db.ExecContext(suite.ctx, `
+		INSERT INTO refund_decisions (
+			decision_id, idempotency_key, ticket_id, initiator, outcome, percent,
+			price_amount, refunded_amount, currency, rule, requested_at
+		) VALUES ($1, $2, $3, 'customer', 'denied', 0, 100, 0, 'USD', 'test', NOW())`,
+		uuid.New(), cancellation.RefundIdempotencyKey(deniedTicketID), deniedTicketID,
+	)
+	require.NoError(suite.T(), err)
+
+	var mu sync.Mutex
+	refunded := map[string]int{}
+	suite.paymentsMock.EXPECT().
+		Refund(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, ticketID, _, _ string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			refunded[ticketID]++
+			return nil
+		}).
+		MinTimes(2)
+	suite.receiptsMock.EXPECT().
+		VoidReceipt(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		MinTimes(2)
+
+	resp := suite.postJSON(fmt.Sprintf("/shows/%s/cancel", showID), map[string]string{"reason": "venue flooded"}, nil)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusAccepted, resp.StatusCode)
+
+	require.EventuallyWithT(
+		suite.T(),
+		func(t *assert.CollectT) {
+			var cancellation bookingCancellationResponse
+			status := suite.getJSON(fmt.Sprintf("/bookings/%s/cancellation", bookingID), &cancellation)
+			if !assert.Equal(t, http.StatusOK, status) {
+				return
+			}
+
+			assert.Equal(t, "cancelled", cancellation.Status)
+			assert.Len(t, cancellation.Tickets, 2)
+			for _, ticket := range cancellation.Tickets {
+				assert.Equal(t, "refunded", ticket.Status, ticket.TicketID)
+			}
+		},
+		15*time.Second,
+		100*time.Millisecond,
+	)
+
+	var initiator string
+	err = suite.db.GetContext(suite.ctx, &initiator, `SELECT payload ->> 'initiator' FROM booking_cancellations WHERE booking_id = $1`, bookingID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), string(entities.RefundInitiatorOrganizer), initiator)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(suite.T(), refunded, deniedTicketID, "denied to the customer, refunded by the organizer")
+	assert.Contains(suite.T(), refunded, pendingTicketID)
+}
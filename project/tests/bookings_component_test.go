@@ -101,6 +101,7 @@ func (suite *ComponentTestSuite) TestBookTickets() {
 		ShowID          uuid.UUID `db:"show_id"`
 		NumberOfTickets int       `db:"number_of_tickets"`
 		CustomerEmail   string    `db:"customer_email"`
+		Locale          string    `db:"locale"`
 	}
 
 	err = suite.db.GetContext(suite.ctx, &booking, `
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *ComponentTestSuite) TestCheckIn() {
+	showID := suite.insertShow(100, time.Now().Add(time.Hour))
+	bookingID := suite.insertBooking(showID, 1)
+	ticketID := suite.insertTicket(bookingID, showID, "printed")
+	token := suite.ticketSigner.Sign(ticketID.String(), 0)
+
+	resp := suite.postJSON("/check-in", map[string]string{"token": token}, nil)
+	defer resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var checkedIn struct {
+		TicketID string `json:"ticket_id"`
+		ShowID   string `json:"show_id"`
+	}
+	require.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&checkedIn))
+	assert.Equal(suite.T(), ticketID.String(), checkedIn.TicketID)
+	assert.Equal(suite.T(), showID.String(), checkedIn.ShowID)
+
+	// the ticket is scanned again
+	again := suite.postJSON("/check-in", map[string]string{"token": token}, nil)
+	again.Body.Close()
+	assert.Equal(suite.T(), http.StatusConflict, again.StatusCode)
+}
+
+func (suite *ComponentTestSuite) TestCheckInInvalidTickets() {
+	showID := suite.insertShow(100, time.Now().Add(time.Hour))
+	bookingID := suite.insertBooking(showID, 1)
+	refundedTicketID := suite.insertTicket(bookingID, showID, "refunded")
+
+	testCases := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "refunded ticket",
+			token:          suite.ticketSigner.Sign(refundedTicketID.String(), 0),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "unknown ticket",
+			token:          suite.ticketSigner.Sign(uuid.NewString(), 0),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid token",
+			token:          refundedTicketID.String() + ".invalid",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			resp := suite.postJSON("/check-in", map[string]string{"token": tc.token}, nil)
+			resp.Body.Close()
+			assert.Equal(suite.T(), tc.expectedStatus, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type trackedCommandResponse struct {
+	CommandID   string `json:"command_id"`
+	CommandName string `json:"command_name"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+}
+
+func (suite *ComponentTestSuite) TestGetCommandOfRefund() {
+	// far enough before the show for the full refund
+	showID := suite.insertShow(100, time.Now().Add(30*24*time.Hour))
+	bookingID := suite.insertBooking(showID, 1)
+	ticketID := suite.insertTicket(bookingID, showID, "confirmed")
+
+	suite.paymentsMock.EXPECT().
+		Refund(gomock.Any(), ticketID.String(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		MinTimes(1)
+	suite.receiptsMock.EXPECT().
+		VoidReceipt(gomock.Any(), ticketID.String(), gomock.Any()).
+		Return(nil).
+		MinTimes(1)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPut, fmt.Sprintf("http://localhost:8080/ticket-refund/%s", ticketID), nil)
+	require.NoError(suite.T(), err)
+
+	resp, err := suite.httpClient.Do(req)
+	require.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	require.Equal(suite.T(), http.StatusAccepted, resp.StatusCode)
+
+	var accepted struct {
+		CommandID string `json:"command_id"`
+	}
+	require.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&accepted))
+	assert.Equal(suite.T(), "/commands/"+accepted.CommandID, resp.Header.Get("Location"))
+
+	require.EventuallyWithT(
+		suite.T(),
+		func(t *assert.CollectT) {
+			var command trackedCommandResponse
+			status := suite.getJSON("/commands/"+accepted.CommandID, &command)
+			if !assert.Equal(t, http.StatusOK, status) {
+				return
+			}
+
+			assert.Equal(t, accepted.CommandID, command.CommandID)
+			assert.Equal(t, "RefundTicket", command.CommandName)
+			assert.Equal(t, "succeeded", command.Status)
+			assert.GreaterOrEqual(t, command.Attempts, 1)
+		},
+		15*time.Second,
+		100*time.Millisecond,
+	)
+}
+
+func (suite *ComponentTestSuite) TestGetUnknownCommand() {
+	assert.Equal(suite.T(), http.StatusNotFound, suite.getJSON("/commands/"+uuid.NewString(), nil))
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.getJSON("/commands/not-a-uuid", nil))
+}
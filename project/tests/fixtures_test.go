@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *ComponentTestSuite) insertShow(numberOfTickets int, startTime time.Time) uuid.UUID {
+	showID := uuid.New()
+	_, err := suite.db.ExecContext(suite.ctx, `
+		INSERT INTO shows (id, dead_nation_id, number_of_tickets, start_time, title, venue)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		showID,
+		uuid.New(),
+		numberOfTickets,
+		startTime,
+		"Test Show",
+		"Test Venue",
+	)
+	require.NoError(suite.T(), err)
+
+	return showID
+}
+
+func (suite *ComponentTestSuite) insertBooking(showID uuid.UUID, numberOfTickets int) uuid.UUID {
+	bookingID := uuid.New()
+	_, err := suite.db.ExecContext(suite.ctx, `
+		INSERT INTO bookings (id, show_id, number_of_tickets, customer_email)
+		VALUES ($1, $2, $3, $4)`,
+		bookingID,
+		showID,
+		numberOfTickets,
+		"customer@example.com",
+	)
+	require.NoError(suite.T(), err)
+
+	return bookingID
+}
+
+func (suite *ComponentTestSuite) insertTicket(bookingID uuid.UUID, showID uuid.UUID, status string) uuid.UUID {
+	ticketID := uuid.New()
+	_, err := suite.db.ExecContext(suite.ctx, `
+		INSERT INTO tickets (ticket_id, price_amount, price_currency, customer_email, booking_id, show_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		ticketID,
+		"100.00",
+		"USD",
+		"customer@example.com",
+		bookingID,
+		showID,
+		status,
+	)
+	require.NoError(suite.T(), err)
+
+	return ticketID
+}
+
+// postJSON sends the request to the app and returns the response, the caller closes its body.
+func (suite *ComponentTestSuite) postJSON(path string, body any, headers map[string]string) *http.Response {
+	payload, err := json.Marshal(body)
+	require.NoError(suite.T(), err)
+
+	httpReq, err := http.NewRequest(http.MethodPost, "http://localhost:8080"+path, bytes.NewBuffer(payload))
+	require.NoError(suite.T(), err)
+	httpReq.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := suite.httpClient.Do(httpReq)
+	require.NoError(suite.T(), err)
+
+	return resp
+}
+
+func (suite *ComponentTestSuite) getJSON(path string, v any) int {
+	resp, err := suite.httpClient.Get("http://localhost:8080" + path)
+	require.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	if v != nil && resp.StatusCode == http.StatusOK {
+		require.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(v))
+	}
+
+	return resp.StatusCode
+}
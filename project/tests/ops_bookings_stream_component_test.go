@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamedEvent struct {
+	ID   string
+	Data string
+}
+
+type streamedBooking struct {
+	BookingID uuid.UUID `json:"booking_id"`
+	ShowID    uuid.UUID `json:"show_id"`
+}
+
+// openStream opens the Server-Sent Events stream, the events are sent to the channel until the stream is closed.
+func (suite *ComponentTestSuite) openStream(path string, lastEventID string) (<-chan streamedEvent, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(suite.ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost:8080"+path, nil)
+	require.NoError(suite.T(), err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	// streams have no timeout
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	require.Equal(suite.T(), "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan streamedEvent, 100)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		var event streamedEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				// an empty line ends the event
+				if event.Data != "" {
+					events <- event
+				}
+				event = streamedEvent{}
+			case strings.HasPrefix(line, ":"):
+				// keep-alive comment
+			case strings.HasPrefix(line, "id: "):
+				event.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				// multi-line data is joined with new lines, it fails to unmarshal as a booking
+				if event.Data != "" {
+					event.Data += "\n"
+				}
+				event.Data += strings.TrimPrefix(line, "data: ")
+			default:
+				// fails to unmarshal as a booking
+				event.Data += line
+			}
+		}
+	}()
+
+	return events, cancel
+}
+
+// waitForBookingEvent returns the first streamed event of the booking.
+func (suite *ComponentTestSuite) waitForBookingEvent(events <-chan streamedEvent, bookingID uuid.UUID) streamedEvent {
+	timeout := time.After(15 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			require.True(suite.T(), ok, "stream closed before the booking was streamed")
+
+			var booking streamedBooking
+			require.NoError(suite.T(), json.Unmarshal([]byte(event.Data), &booking), "invalid event %q", event.Data)
+			if booking.BookingID == bookingID {
+				return event
+			}
+		case <-timeout:
+			suite.FailNow("booking was not streamed")
+		}
+	}
+}
+
+func (suite *ComponentTestSuite) bookTickets(showID uuid.UUID, numberOfTickets int) uuid.UUID {
+	suite.deadNationMock.EXPECT().BookTickets(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	resp := suite.postJSON("/book-tickets", map[string]any{
+		"show_id":           showID,
+		"number_of_tickets": numberOfTickets,
+		"customer_email":    "customer@example.com",
+	}, nil)
+	defer resp.Body.Close()
+	require.Equal(suite.T(), http.StatusCreated, resp.StatusCode)
+
+	var booked struct {
+		BookingID uuid.UUID `json:"booking_id"`
+	}
+	require.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&booked))
+
+	return booked.BookingID
+}
+
+func (suite *ComponentTestSuite) TestOpsBookingsStream() {
+	showID := suite.insertShow(100, time.Now().Add(24*time.Hour))
+
+	allBookings, cancelAll := suite.openStream("/ops/bookings/stream", "")
+	defer cancelAll()
+
+	bookingID := suite.bookTickets(showID, 1)
+
+	event := suite.waitForBookingEvent(allBookings, bookingID)
+	updateSequence, err := strconv.ParseInt(event.ID, 10, 64)
+	require.NoError(suite.T(), err, "the event ID is the update sequence")
+	assert.Positive(suite.T(), updateSequence)
+
+	var booking streamedBooking
+	require.NoError(suite.T(), json.Unmarshal([]byte(event.Data), &booking))
+	assert.Equal(suite.T(), showID, booking.ShowID)
+
+	suite.Run("booking stream sends the current state first", func() {
+		events, cancel := suite.openStream("/ops/bookings/"+bookingID.String()+"/stream", "")
+		defer cancel()
+
+		first := suite.waitForBookingEvent(events, bookingID)
+		firstSequence, err := strconv.ParseInt(first.ID, 10, 64)
+		require.NoError(suite.T(), err)
+		assert.GreaterOrEqual(suite.T(), firstSequence, updateSequence)
+	})
+
+	suite.Run("resumed stream replays the missed updates", func() {
+		events, cancel := suite.openStream("/ops/bookings/stream", strconv.FormatInt(updateSequence-1, 10))
+		defer cancel()
+
+		replayed := suite.waitForBookingEvent(events, bookingID)
+		assert.NotEmpty(suite.T(), replayed.ID)
+	})
+
+	suite.Run("disconnected client", func() {
+		events, cancel := suite.openStream("/ops/bookings/stream", "")
+		cancel()
+
+		// the stream ends, and the updates are still streamed to other clients
+		require.Eventually(suite.T(), func() bool {
+			select {
+			case _, ok := <-events:
+				return !ok
+			default:
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+
+		otherBookingID := suite.bookTickets(showID, 1)
+		suite.waitForBookingEvent(allBookings, otherBookingID)
+	})
+}
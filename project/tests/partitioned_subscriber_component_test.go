@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"tickets/internal/interfaces/message/events"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPartitionLeaseTTL = 2 * time.Second
+
+// partitionedConsumer collects the messages received by one PartitionedSubscriber.
+type partitionedConsumer struct {
+	subscriber *events.PartitionedSubscriber
+	cancel     context.CancelFunc
+
+	mu       sync.Mutex
+	received map[string][]string
+	// ack acks the messages right away, otherwise they are left pending
+	ack bool
+}
+
+func (c *partitionedConsumer) receivedFrom(topic string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.received[topic]...)
+}
+
+func (suite *ComponentTestSuite) newPartitionedConsumer(topic string, consumerGroup string, partitions int, ack bool) *partitionedConsumer {
+	subscriber, err := events.NewPartitionedSubscriber(suite.redisClient, events.PartitionedSubscriberConfig{
+		ConsumerGroup: consumerGroup,
+		Partitions:    partitions,
+		LeaseTTL:      testPartitionLeaseTTL,
+	}, watermill.NopLogger{})
+	require.NoError(suite.T(), err)
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	messages, err := subscriber.Subscribe(ctx, topic)
+	require.NoError(suite.T(), err)
+
+	consumer := &partitionedConsumer{
+		subscriber: subscriber,
+		cancel:     cancel,
+		received:   map[string][]string{},
+		ack:        ack,
+	}
+	go func() {
+		for msg := range messages {
+			consumer.mu.Lock()
+			stream := msg.Metadata.Get("stream")
+			consumer.received[stream] = append(consumer.received[stream], msg.UUID)
+			consumer.mu.Unlock()
+
+			if consumer.ack {
+				msg.Ack()
+			}
+		}
+	}()
+
+	suite.T().Cleanup(consumer.stop)
+
+	return consumer
+}
+
+// stop stops the consumer without acking the received messages, as if it crashed.
+func (c *partitionedConsumer) stop() {
+	c.cancel()
+	_ = c.subscriber.Close()
+}
+
+func (suite *ComponentTestSuite) publishToStream(stream string) string {
+	publisher, err := redisstream.NewPublisher(redisstream.PublisherConfig{Client: suite.redisClient}, watermill.NopLogger{})
+	require.NoError(suite.T(), err)
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte("{}"))
+	// the topic isn't in the message received from the subscriber
+	msg.Metadata.Set("stream", stream)
+	require.NoError(suite.T(), publisher.Publish(stream, msg))
+
+	return msg.UUID
+}
+
+func partitionLease(consumerGroup string, stream string) string {
+	return fmt.Sprintf("partition_leases.%s.%s", consumerGroup, stream)
+}
+
+func (suite *ComponentTestSuite) TestPartitionedSubscriberCompetingConsumers() {
+	topic := "test-events." + uuid.NewString()
+	consumerGroup := "test-group." + uuid.NewString()
+	const partitions = 4
+
+	first := suite.newPartitionedConsumer(topic, consumerGroup, partitions, true)
+	second := suite.newPartitionedConsumer(topic, consumerGroup, partitions, true)
+
+	published := map[string][]string{}
+	for i := 0; i < partitions; i++ {
+		stream := events.PartitionTopic(topic, i)
+		for j := 0; j < 3; j++ {
+			published[stream] = append(published[stream], suite.publishToStream(stream))
+		}
+	}
+
+	// each partition is consumed only by the consumer holding its lease, in the published order
+	require.EventuallyWithT(suite.T(), func(t *assert.CollectT) {
+		for stream, uuids := range published {
+			fromFirst, fromSecond := first.receivedFrom(stream), second.receivedFrom(stream)
+			if len(fromFirst) > 0 {
+				assert.Equal(t, uuids, fromFirst, stream)
+				assert.Empty(t, fromSecond, stream)
+			} else {
+				assert.Equal(t, uuids, fromSecond, stream)
+			}
+		}
+	}, 15*time.Second, 100*time.Millisecond)
+
+	for stream := range published {
+		owner, err := suite.redisClient.Get(suite.ctx, partitionLease(consumerGroup, stream)).Result()
+		require.NoError(suite.T(), err)
+		assert.NotEmpty(suite.T(), owner)
+
+		ttl, err := suite.redisClient.PTTL(suite.ctx, partitionLease(consumerGroup, stream)).Result()
+		require.NoError(suite.T(), err)
+		assert.LessOrEqual(suite.T(), ttl, testPartitionLeaseTTL, "the lease expires when it's not renewed")
+	}
+}
+
+func (suite *ComponentTestSuite) TestPartitionedSubscriberLeaseExpiry() {
+	topic := "test-events." + uuid.NewString()
+	consumerGroup := "test-group." + uuid.NewString()
+	stream := events.PartitionTopic(topic, 0)
+
+	// held by a consumer which crashed without releasing it
+	err := suite.redisClient.Set(suite.ctx, partitionLease(consumerGroup, stream), "crashed-consumer", testPartitionLeaseTTL).Err()
+	require.NoError(suite.T(), err)
+
+	consumer := suite.newPartitionedConsumer(topic, consumerGroup, 1, true)
+	msgUUID := suite.publishToStream(stream)
+
+	time.Sleep(testPartitionLeaseTTL / 2)
+	assert.Empty(suite.T(), consumer.receivedFrom(stream), "the partition is consumed while another consumer holds the lease")
+
+	require.Eventually(suite.T(), func() bool {
+		return assert.ObjectsAreEqual([]string{msgUUID}, consumer.receivedFrom(stream))
+	}, 3*testPartitionLeaseTTL, 100*time.Millisecond)
+}
+
+func (suite *ComponentTestSuite) TestPartitionedSubscriberPendingHandover() {
+	topic := "test-events." + uuid.NewString()
+	consumerGroup := "test-group." + uuid.NewString()
+	stream := events.PartitionTopic(topic, 0)
+
+	crashing := suite.newPartitionedConsumer(topic, consumerGroup, 1, false)
+	msgUUID := suite.publishToStream(stream)
+
+	require.Eventually(suite.T(), func() bool {
+		return len(crashing.receivedFrom(stream)) == 1
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// the message is left pending and the lease is released
+	crashing.stop()
+
+	taking := suite.newPartitionedConsumer(topic, consumerGroup, 1, true)
+
+	// claimed after it was idle for the lease TTL
+	require.Eventually(suite.T(), func() bool {
+		return assert.ObjectsAreEqual([]string{msgUUID}, taking.receivedFrom(stream))
+	}, 20*time.Second, 100*time.Millisecond)
+
+	require.Eventually(suite.T(), func() bool {
+		pending, err := suite.redisClient.XPending(suite.ctx, stream, consumerGroup).Result()
+		return err == nil && pending.Count == 0
+	}, 5*time.Second, 100*time.Millisecond, "the claimed message is acked")
+}
+
+func (suite *ComponentTestSuite) TestPartitionedSubscriberDrainsUnpartitionedTopic() {
+	topic := "test-events." + uuid.NewString()
+	consumerGroup := "test-group." + uuid.NewString()
+	partition := events.PartitionTopic(topic, 0)
+
+	// published before the event was partitioned
+	oldMsgUUID := suite.publishToStream(topic)
+	newMsgUUID := suite.publishToStream(partition)
+
+	consumer := suite.newPartitionedConsumer(topic, consumerGroup, 1, false)
+
+	require.Eventually(suite.T(), func() bool {
+		return assert.ObjectsAreEqual([]string{oldMsgUUID}, consumer.receivedFrom(topic))
+	}, 10*time.Second, 100*time.Millisecond)
+
+	time.Sleep(2 * time.Second)
+	assert.Empty(suite.T(), consumer.receivedFrom(partition), "the partition is consumed before the old topic is drained")
+
+	// acks the pending message of the old topic
+	ids, err := suite.redisClient.XRange(suite.ctx, topic, "-", "+").Result()
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), ids, 1)
+	require.NoError(suite.T(), suite.redisClient.XAck(suite.ctx, topic, consumerGroup, ids[0].ID).Err())
+
+	require.Eventually(suite.T(), func() bool {
+		return assert.ObjectsAreEqual([]string{newMsgUUID}, consumer.receivedFrom(partition))
+	}, 10*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refundTicket requests the refund with PUT /ticket-refund and returns the status of the response.
+func (suite *ComponentTestSuite) refundTicket(ticketID uuid.UUID, idempotencyKey string) int {
+	req, err := http.NewRequestWithContext(
+		context.Background(),
+		http.MethodPut,
+		fmt.Sprintf("http://localhost:8080/ticket-refund/%s", ticketID),
+		nil,
+	)
+	require.NoError(suite.T(), err)
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := suite.httpClient.Do(req)
+	require.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func (suite *ComponentTestSuite) TestRefundTicketOnlyOnce() {
+	showID := suite.insertShow(100, time.Now().Add(30*24*time.Hour))
+	bookingID := suite.insertBooking(showID, 1)
+	ticketID := suite.insertTicket(bookingID, showID, "confirmed")
+
+	refunded := make(chan struct{})
+	suite.paymentsMock.EXPECT().
+		Refund(gomock.Any(), ticketID.String(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, string, string, string) error {
+			// the second request is sent while the refund is not done yet
+			<-refunded
+			return nil
+		}).
+		Times(1)
+	suite.receiptsMock.EXPECT().
+		VoidReceipt(gomock.Any(), ticketID.String(), gomock.Any()).
+		Return(nil).
+		MinTimes(1)
+
+	idempotencyKey := uuid.NewString()
+	require.Equal(suite.T(), http.StatusAccepted, suite.refundTicket(ticketID, idempotencyKey))
+
+	// a retry gets the first decision
+	assert.Equal(suite.T(), http.StatusAccepted, suite.refundTicket(ticketID, idempotencyKey))
+	// a new request waits for the approved refund
+	assert.Equal(suite.T(), http.StatusConflict, suite.refundTicket(ticketID, uuid.NewString()))
+
+	close(refunded)
+
+	require.EventuallyWithT(
+		suite.T(),
+		func(t *assert.CollectT) {
+			var status string
+			err := suite.db.GetContext(suite.ctx, &status, `SELECT status FROM tickets WHERE ticket_id = $1`, ticketID)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "refunded", status)
+		},
+		15*time.Second,
+		100*time.Millisecond,
+	)
+
+	// refunded tickets are never refunded again
+	assert.Equal(suite.T(), http.StatusConflict, suite.refundTicket(ticketID, uuid.NewString()))
+
+	var decisions int
+	err := suite.db.GetContext(suite.ctx, &decisions, `SELECT COUNT(*) FROM refund_decisions WHERE ticket_id = $1`, ticketID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, decisions)
+}
+
+func (suite *ComponentTestSuite) TestRefundCancelledTicket() {
+	showID := suite.insertShow(100, time.Now().Add(30*24*time.Hour))
+	bookingID := suite.insertBooking(showID, 1)
+	ticketID := suite.insertTicket(bookingID, showID, "cancelled")
+
+	assert.Equal(suite.T(), http.StatusConflict, suite.refundTicket(ticketID, uuid.NewString()))
+}
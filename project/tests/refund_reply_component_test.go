@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type opsRefundResponse struct {
+	CommandID string `json:"command_id"`
+	Result    struct {
+		TicketID       string `json:"ticket_id"`
+		RefundedAmount struct {
+			Amount string `json:"amount"`
+		} `json:"refunded_amount"`
+	} `json:"result"`
+}
+
+// opsRefund refunds the ticket with PUT /ops/ticket-refund, which waits for the reply of the command.
+func (suite *ComponentTestSuite) opsRefund(ticketID uuid.UUID) (int, http.Header, opsRefundResponse) {
+	req, err := http.NewRequestWithContext(
+		context.Background(),
+		http.MethodPut,
+		fmt.Sprintf("http://localhost:8080/ops/ticket-refund/%s", ticketID),
+		nil,
+	)
+	require.NoError(suite.T(), err)
+
+	// longer than the reply timeout of the app
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	require.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	var body opsRefundResponse
+	require.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&body))
+
+	return resp.StatusCode, resp.Header, body
+}
+
+// Replies of commands handled out of order are delivered to the request which sent the command.
+func (suite *ComponentTestSuite) TestOpsRefundReplyCorrelation() {
+	showID := suite.insertShow(100, time.Now().Add(30*24*time.Hour))
+	bookingID := suite.insertBooking(showID, 2)
+	slowTicketID := suite.insertTicket(bookingID, showID, "confirmed")
+	fastTicketID := suite.insertTicket(bookingID, showID, "confirmed")
+
+	suite.paymentsMock.EXPECT().
+		Refund(gomock.Any(), slowTicketID.String(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, string, string, string) error {
+			// replied after the fast ticket
+			time.Sleep(time.Second)
+			return nil
+		}).
+		MinTimes(1)
+	suite.paymentsMock.EXPECT().
+		Refund(gomock.Any(), fastTicketID.String(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		MinTimes(1)
+	suite.receiptsMock.EXPECT().
+		VoidReceipt(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		MinTimes(2)
+
+	var wg sync.WaitGroup
+	responses := map[uuid.UUID]opsRefundResponse{}
+	statuses := map[uuid.UUID]int{}
+	var mu sync.Mutex
+
+	for _, ticketID := range []uuid.UUID{slowTicketID, fastTicketID} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, _, body := suite.opsRefund(ticketID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[ticketID] = status
+			responses[ticketID] = body
+		}()
+	}
+	wg.Wait()
+
+	for _, ticketID := range []uuid.UUID{slowTicketID, fastTicketID} {
+		assert.Equal(suite.T(), http.StatusOK, statuses[ticketID])
+		assert.Equal(suite.T(), ticketID.String(), responses[ticketID].Result.TicketID)
+		assert.Equal(suite.T(), "100.00", responses[ticketID].Result.RefundedAmount.Amount)
+	}
+	assert.NotEqual(suite.T(), responses[slowTicketID].CommandID, responses[fastTicketID].CommandID)
+}
+
+// The sender stops waiting after the reply timeout, the command is still handled and can be polled.
+func (suite *ComponentTestSuite) TestOpsRefundReplyTimeout() {
+	showID := suite.insertShow(100, time.Now().Add(30*24*time.Hour))
+	bookingID := suite.insertBooking(showID, 1)
+	ticketID := suite.insertTicket(bookingID, showID, "confirmed")
+
+	suite.paymentsMock.EXPECT().
+		Refund(gomock.Any(), ticketID.String(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, string, string, string) error {
+			// longer than the reply timeout of the app
+			time.Sleep(12 * time.Second)
+			return nil
+		}).
+		MinTimes(1)
+	suite.receiptsMock.EXPECT().
+		VoidReceipt(gomock.Any(), ticketID.String(), gomock.Any()).
+		Return(nil).
+		MinTimes(1)
+
+	status, header, body := suite.opsRefund(ticketID)
+	require.Equal(suite.T(), http.StatusAccepted, status)
+	require.NotEmpty(suite.T(), body.CommandID)
+	assert.Equal(suite.T(), "/commands/"+body.CommandID, header.Get("Location"))
+
+	require.EventuallyWithT(
+		suite.T(),
+		func(t *assert.CollectT) {
+			var command trackedCommandResponse
+			if !assert.Equal(t, http.StatusOK, suite.getJSON("/commands/"+body.CommandID, &command)) {
+				return
+			}
+			assert.Equal(t, "succeeded", command.Status)
+		},
+		20*time.Second,
+		100*time.Millisecond,
+	)
+
+	// the reply stream of the request is deleted when the sender stops waiting
+	streams, err := suite.redisClient.Keys(suite.ctx, "commands_replies.*").Result()
+	require.NoError(suite.T(), err)
+	for _, stream := range streams {
+		ttl, err := suite.redisClient.TTL(suite.ctx, stream).Result()
+		require.NoError(suite.T(), err)
+		assert.Greater(suite.T(), ttl, time.Duration(0), "reply stream %s never expires", stream)
+	}
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *ComponentTestSuite) TestShowAvailabilityETag() {
+	showID := suite.insertShow(10, time.Now().Add(24*time.Hour))
+	url := fmt.Sprintf("http://localhost:8080/shows/%s/availability", showID)
+
+	resp, err := suite.httpClient.Get(url)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(suite.T(), etag)
+	assert.Contains(suite.T(), resp.Header.Get("Cache-Control"), "max-age=")
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(suite.T(), err)
+	req.Header.Set("If-None-Match", etag)
+
+	resp, err = suite.httpClient.Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotModified, resp.StatusCode)
+
+	// the projection counted a booking, so the cached response is stale
+	_, err = suite.db.ExecContext(suite.ctx, `
+		INSERT INTO read_model_show_availability (show_id, booked_tickets, cancelled, last_update)
+		VALUES ($1, 2, false, now())`,
+		showID,
+	)
+	require.NoError(suite.T(), err)
+
+	resp, err = suite.httpClient.Do(req)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.NotEqual(suite.T(), etag, resp.Header.Get("ETag"))
+	assert.NotEmpty(suite.T(), resp.Header.Get("Last-Modified"))
+}
@@ -7,6 +7,8 @@ import (
 	"testing"
 	"tickets/internal/app"
 	"tickets/internal/app/mocks"
+	"tickets/internal/entities"
+	"tickets/internal/tickettoken"
 	"time"
 
 	"github.com/ThreeDotsLabs/watermill"
@@ -16,6 +18,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/sdk/trace"
 )
 
 type ComponentTestSuite struct {
@@ -29,10 +32,11 @@ type ComponentTestSuite struct {
 	transportationMock *mocks.MockTransportationService
 	ctx                context.Context
 	//redisContainer   testcontainers.Container
-	redisClient *redis.Client
-	db          *sqlx.DB
-	app         *app.App
-	httpClient  *http.Client
+	redisClient  *redis.Client
+	db           *sqlx.DB
+	app          *app.App
+	httpClient   *http.Client
+	ticketSigner *tickettoken.Signer
 }
 
 func TestComponentTestSuite(t *testing.T) {
@@ -57,6 +61,7 @@ func (suite *ComponentTestSuite) SetupSuite() {
 	suite.filesMock = mocks.NewMockFileStorageService(suite.ctrl)
 	suite.deadNationMock = mocks.NewMockDeadNationService(suite.ctrl)
 	suite.paymentsMock = mocks.NewMockPaymentsService(suite.ctrl)
+	suite.transportationMock = mocks.NewMockTransportationService(suite.ctrl)
 
 	suite.ctx = context.Background()
 	suite.httpClient = &http.Client{Timeout: 5 * time.Second}
@@ -90,6 +95,9 @@ func (suite *ComponentTestSuite) SetupSuite() {
 
 	suite.db = sqlx.MustConnect("postgres", os.Getenv("POSTGRES_URL"))
 
+	suite.ticketSigner, err = tickettoken.NewSigner("component-tests-secret")
+	require.NoError(suite.T(), err)
+
 	// Initialize the app
 	suite.app, err = app.NewApp(
 		watermill.NopLogger{},
@@ -101,6 +109,9 @@ func (suite *ComponentTestSuite) SetupSuite() {
 		suite.transportationMock,
 		suite.redisClient,
 		suite.db,
+		trace.NewTracerProvider(),
+		suite.ticketSigner,
+		entities.DefaultRefundPolicy(),
 	)
 	require.NoError(suite.T(), err, "Failed to initialize the app")
 
@@ -98,7 +98,7 @@ func (suite *ComponentTestSuite) TestConfirmedTickets() {
 			entities.IssueReceiptRequest{
 				IdempotencyKey: idempotencyKey + ticketID,
 				TicketID:       ticketID,
-				Price:          entities.Money{Amount: amount, Currency: currency},
+				Price:          entities.MustNewMoney(amount, currency),
 			}).
 		Return(&entities.IssueReceiptResponse{
 			ReceiptNumber: "123",
@@ -187,7 +187,7 @@ func (suite *ComponentTestSuite) TestIdempotencyConfirmedTickets() {
 			entities.IssueReceiptRequest{
 				IdempotencyKey: idempotencyKey + ticketID,
 				TicketID:       ticketID,
-				Price:          entities.Money{Amount: amount, Currency: currency},
+				Price:          entities.MustNewMoney(amount, currency),
 			},
 		).
 		Return(&entities.IssueReceiptResponse{